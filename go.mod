@@ -11,6 +11,7 @@ require (
 	github.com/spf13/cobra v1.10.2
 	github.com/tetratelabs/wazero v1.11.0
 	go.yaml.in/yaml/v3 v3.0.4
+	golang.org/x/term v0.40.0
 	gorm.io/gorm v1.31.1
 )
 
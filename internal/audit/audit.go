@@ -0,0 +1,199 @@
+// Package audit keeps an append-only, hash-chained log of changes to notes.
+// Each entry records the hash of the one before it, so editing or removing
+// an earlier line breaks the chain and Verify reports where.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Actions recorded in Entry.Action.
+const (
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+	ActionRemoved = "removed"
+)
+
+// Entry is one line of the audit log.
+type Entry struct {
+	Time    string  `json:"time"` // RFC3339, UTC
+	Action  string  `json:"action"`
+	ItemID  string  `json:"item_id"`
+	Title   string  `json:"title,omitempty"`
+	Project string  `json:"project,omitempty"`
+	Source  *string `json:"source,omitempty"`
+	// Prev is the Hash of the previous entry, empty for the first.
+	Prev string `json:"prev"`
+	// Hash is the SHA-256 of the entry with Hash left empty.
+	Hash string `json:"hash"`
+}
+
+// mu serializes appends within the process; the chain assumes one writer
+// at a time.
+var mu sync.Mutex
+
+// Append adds e to the log at path, creating it with perm if needed, and
+// fills in its Prev and Hash.
+func Append(path string, e Entry, perm os.FileMode) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	prev, err := lastHash(path)
+	if err != nil {
+		return err
+	}
+
+	e.Prev = prev
+	e.Hash = ""
+
+	e.Hash, err = hashEntry(e)
+	if err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, perm)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	return f.Close()
+}
+
+// lastHash returns the Hash of the last entry in the log at path, reading
+// only the end of the file, or "" when the log is missing or empty.
+func lastHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	line, err := lastLine(f, info.Size())
+	if err != nil || len(line) == 0 {
+		return "", err
+	}
+
+	var e Entry
+	if err := json.Unmarshal(line, &e); err != nil {
+		return "", fmt.Errorf("audit log last line: %w", err)
+	}
+
+	return e.Hash, nil
+}
+
+// lastLine returns the last non-blank line of f, which is size bytes long,
+// reading backwards from the end a chunk at a time.
+func lastLine(f *os.File, size int64) ([]byte, error) {
+	const chunk = 4096
+
+	var tail []byte
+
+	for off := size; off > 0; {
+		n := min(chunk, off)
+		off -= n
+
+		buf := make([]byte, n)
+		if _, err := f.ReadAt(buf, off); err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+
+		tail = append(buf, tail...)
+
+		trimmed := bytes.TrimRight(tail, " \t\r\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return bytes.TrimSpace(trimmed[i+1:]), nil
+		}
+	}
+
+	return bytes.TrimSpace(tail), nil
+}
+
+// Read returns every entry in the log at path, oldest first.
+func Read(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for n := 1; scanner.Scan(); n++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("audit log line %d: %w", n, err)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, scanner.Err()
+}
+
+// Verify checks the hash chain and returns the index of the first entry that
+// was altered or does not follow its predecessor, or -1 if the chain is
+// intact.
+func Verify(entries []Entry) int {
+	prev := ""
+
+	for i, e := range entries {
+		want := e.Hash
+		e.Hash = ""
+
+		got, err := hashEntry(e)
+		if err != nil || got != want || e.Prev != prev {
+			return i
+		}
+
+		prev = want
+	}
+
+	return -1
+}
+
+// hashEntry returns the hex SHA-256 of e's JSON encoding.
+func hashEntry(e Entry) (string, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAppendReadVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	for _, e := range []Entry{
+		{Time: "2026-01-01T00:00:00Z", Action: ActionCreated, ItemID: "a", Title: "First"},
+		{Time: "2026-01-02T00:00:00Z", Action: ActionUpdated, ItemID: "a", Title: "First"},
+		{Time: "2026-01-03T00:00:00Z", Action: ActionRemoved, ItemID: "a", Title: "First"},
+	} {
+		if err := Append(path, e, 0600); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("Read() = %d entries, want 3", len(entries))
+	}
+
+	if entries[0].Prev != "" || entries[1].Prev != entries[0].Hash || entries[2].Prev != entries[1].Hash {
+		t.Error("entries are not chained by hash")
+	}
+
+	if i := Verify(entries); i != -1 {
+		t.Errorf("Verify() = %d, want -1", i)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	for _, title := range []string{"One", "Two", "Three"} {
+		if err := Append(path, Entry{Action: ActionCreated, ItemID: title, Title: title}, 0600); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(strings.Replace(string(data), `"title":"Two"`, `"title":"Changed"`, 1)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	if i := Verify(entries); i != 1 {
+		t.Errorf("Verify() after editing entry 1 = %d, want 1", i)
+	}
+
+	// Dropping an entry breaks the chain at the one that followed it.
+	if i := Verify([]Entry{entries[0], entries[2]}); i != 1 {
+		t.Errorf("Verify() after removing an entry = %d, want 1", i)
+	}
+}
+
+func TestAppendChainsLongEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	// Entries longer than the chunk lastLine reads at a time.
+	long := strings.Repeat("x", 5000)
+
+	for i := range 3 {
+		if err := Append(path, Entry{Action: ActionCreated, ItemID: long[:i+1], Title: long}, 0600); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	// A trailing blank line, as an editor might leave, is skipped.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = f.WriteString("\n")
+	_ = f.Close()
+
+	if err := Append(path, Entry{Action: ActionRemoved, ItemID: "x"}, 0600); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	if len(entries) != 4 || Verify(entries) != -1 {
+		t.Errorf("Read() = %d entries, Verify() = %d; want 4 chained entries", len(entries), Verify(entries))
+	}
+}
@@ -3,8 +3,15 @@ package config
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"pantry/internal/db"
+	"pantry/internal/fsutil"
 
 	"go.yaml.in/yaml/v3"
 )
@@ -15,6 +22,40 @@ type EmbeddingConfig struct {
 	Model    string  `yaml:"model"`
 	BaseURL  *string `yaml:"base_url"`
 	APIKey   *string `yaml:"api_key"`
+	Metric   string  `yaml:"metric,omitempty"` // l2 | cosine | l1; empty means l2
+	// OnStore says when a stored note is embedded: sync (the default) before
+	// Store returns, async in the background, or off, leaving it to the next
+	// reindex.
+	OnStore string `yaml:"on_store,omitempty"`
+	// Fallback lists backup providers tried in order when this one fails.
+	// Only provider, model, base_url and api_key are used; every provider in
+	// the chain must return vectors of the same dimension.
+	Fallback []EmbeddingConfig `yaml:"fallback,omitempty"`
+}
+
+// Values of EmbeddingConfig.OnStore. Empty means OnStoreSync.
+const (
+	OnStoreSync  = "sync"
+	OnStoreAsync = "async"
+	OnStoreOff   = "off"
+)
+
+// SetModel switches to provider and model, leaving empty ones unchanged.
+// Switching provider drops the base URL, which belongs to the old provider;
+// Ollama gets its local default.
+func (e *EmbeddingConfig) SetModel(provider, model string) {
+	if provider != "" && provider != e.Provider {
+		e.Provider = provider
+		e.BaseURL = nil
+
+		if e.Provider == "ollama" {
+			e.BaseURL = stringPtr("http://localhost:11434")
+		}
+	}
+
+	if model != "" {
+		e.Model = model
+	}
 }
 
 // ContextConfig holds context retrieval configuration.
@@ -23,10 +64,193 @@ type ContextConfig struct {
 	TopupRecent bool   `yaml:"topup_recent"`
 }
 
+// DedupConfig controls how Store looks for an existing note to update.
+type DedupConfig struct {
+	Scope string `yaml:"scope"` // project | global
+	// TitleMatch decides when a keyword match's title counts as the same
+	// note: exact (case-insensitive, the default), normalized (punctuation
+	// and stopwords ignored) or fuzzy (Levenshtein ratio of at least
+	// FuzzyThreshold).
+	TitleMatch string `yaml:"title_match,omitempty"`
+	// FuzzyThreshold is the minimum title similarity (0..1) for fuzzy
+	// matching. 0 means DefaultFuzzyThreshold.
+	FuzzyThreshold float64 `yaml:"fuzzy_threshold,omitempty"`
+}
+
+// SearchConfig holds search tuning.
+type SearchConfig struct {
+	// AnchorWeight is the share of the anchor note's vector when blending it
+	// with the query vector for search --within (0..1).
+	AnchorWeight float64 `yaml:"anchor_weight"`
+	// RecencyHalfLifeDays halves a result's score for every this many days of
+	// age. 0 disables the recency boost.
+	RecencyHalfLifeDays float64 `yaml:"recency_half_life_days,omitempty"`
+	// Timeout bounds the query embedding of a hybrid search; past it the
+	// keyword results are returned alone. 0 means no limit.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// CacheTTL keeps search results for reuse by identical searches for this
+	// long; any write clears them. 0 disables the cache.
+	CacheTTL time.Duration `yaml:"cache_ttl,omitempty"`
+	// FTSWeights sets how much a keyword match counts in each indexed
+	// column (title, what, why, impact, tags, category, project, source).
+	// Columns left out keep their DefaultFTSWeights value.
+	FTSWeights map[string]float64 `yaml:"fts_weights,omitempty"`
+}
+
+// DefaultFTSWeights rank a title match above one in the tags or what, and
+// those above a match in the longer why and impact text.
+var DefaultFTSWeights = map[string]float64{
+	"title":    10,
+	"tags":     5,
+	"what":     3,
+	"why":      1,
+	"impact":   1,
+	"category": 1,
+	"project":  1,
+	"source":   1,
+}
+
+// ColumnWeights returns DefaultFTSWeights overridden by FTSWeights.
+func (c SearchConfig) ColumnWeights() map[string]float64 {
+	weights := maps.Clone(DefaultFTSWeights)
+	maps.Copy(weights, c.FTSWeights)
+
+	return weights
+}
+
+// DefaultsConfig holds values applied when a note leaves a field unset.
+type DefaultsConfig struct {
+	// CLISource is the source of notes stored with the CLI without --source.
+	// An explicit empty value leaves such notes without a source.
+	CLISource string `yaml:"cli_source"`
+	// MCPSource is the source of notes stored over MCP without one. Empty
+	// uses the name the connected client reports, such as claude-code.
+	MCPSource string `yaml:"mcp_source,omitempty"`
+}
+
+// CategoriesConfig holds per-category settings.
+type CategoriesConfig struct {
+	// DefaultTags are added to every note stored with the category, e.g.
+	// "bug": ["bug"], so tag filters find notes an agent forgot to tag.
+	DefaultTags map[string][]string `yaml:"default_tags,omitempty"`
+}
+
+// AuditConfig controls the audit log of note changes.
+type AuditConfig struct {
+	// Enabled appends every store, update and removal to audit.log in the
+	// pantry home.
+	Enabled bool `yaml:"enabled"`
+}
+
+// HooksConfig holds shell commands run after note changes. All are off
+// when empty.
+type HooksConfig struct {
+	// PostStore runs after every store, with the note as JSON on stdin and
+	// PANTRY_* environment variables.
+	PostStore string `yaml:"post_store,omitempty"`
+	// Timeout is how long a hook may run before it is killed, so closing
+	// pantry never waits on a stuck one. 0 uses DefaultHookTimeout.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// DefaultHookTimeout is the default for HooksConfig.Timeout.
+const DefaultHookTimeout = 30 * time.Second
+
+// DefaultCLISource is the default for DefaultsConfig.CLISource.
+const DefaultCLISource = "cli"
+
+// DefaultAnchorWeight weighs the anchor note and the query equally.
+const DefaultAnchorWeight = 0.5
+
+// PermissionsConfig sets the modes of files and directories pantry creates,
+// as octal strings such as "0600". Empty values keep the defaults.
+type PermissionsConfig struct {
+	FileMode string `yaml:"file_mode,omitempty"`
+	DirMode  string `yaml:"dir_mode,omitempty"`
+}
+
+// Modes returns the parsed file and directory modes.
+func (p PermissionsConfig) Modes() (file, dir os.FileMode, err error) {
+	file, err = fsutil.ParseMode(p.FileMode, fsutil.DefaultFileMode)
+	if err != nil {
+		return 0, 0, fmt.Errorf("permissions.file_mode: %w", err)
+	}
+
+	dir, err = fsutil.ParseMode(p.DirMode, fsutil.DefaultDirMode)
+	if err != nil {
+		return 0, 0, fmt.Errorf("permissions.dir_mode: %w", err)
+	}
+
+	return file, dir, nil
+}
+
+// Dedup scopes accepted by DedupConfig.Scope.
+const (
+	DedupScopeProject = "project"
+	DedupScopeGlobal  = "global"
+)
+
+// Title match modes accepted by DedupConfig.TitleMatch. Empty means exact.
+const (
+	TitleMatchExact      = "exact"
+	TitleMatchNormalized = "normalized"
+	TitleMatchFuzzy      = "fuzzy"
+)
+
+// DefaultFuzzyThreshold is the default for DedupConfig.FuzzyThreshold; it
+// accepts "Fixed auth bug" for "Fix auth bug".
+const DefaultFuzzyThreshold = 0.8
+
 // Config holds the complete configuration.
 type Config struct {
 	Embedding EmbeddingConfig `yaml:"embedding"`
 	Context   ContextConfig   `yaml:"context"`
+	Dedup     DedupConfig     `yaml:"dedup"`
+	Search    SearchConfig    `yaml:"search"`
+	Defaults  DefaultsConfig  `yaml:"defaults"`
+	// Categories is opt-in; nothing is applied when it is unset.
+	Categories CategoriesConfig `yaml:"categories,omitempty"`
+	Audit      AuditConfig      `yaml:"audit,omitempty"`
+	Hooks      HooksConfig      `yaml:"hooks,omitempty"`
+	// Permissions applies to the database, notes files and this config.
+	Permissions PermissionsConfig `yaml:"permissions,omitempty"`
+	// SourceAliases maps source spellings to a canonical name, e.g.
+	// "claude": "claude-code". Keys are matched after normalization.
+	SourceAliases map[string]string `yaml:"source_aliases,omitempty"`
+}
+
+// Overrides are per-invocation settings such as CLI flags. ApplyOverrides
+// applies them over a config from LoadConfig, so the precedence is
+// override > env > file. Empty fields are ignored.
+type Overrides struct {
+	EmbeddingProvider string
+	EmbeddingModel    string
+	EmbeddingBaseURL  string
+}
+
+// overrides is set once at startup, before any config is loaded.
+var overrides Overrides
+
+// SetOverrides registers o to be applied by every later ApplyOverrides call.
+func SetOverrides(o Overrides) {
+	overrides = o
+}
+
+// ApplyOverrides applies the overrides registered with SetOverrides to c.
+// LoadConfig leaves them out, so a config loaded to be changed and saved
+// never picks up one-off values; apply them only to the config a run uses.
+func ApplyOverrides(c *Config) {
+	overrides.apply(c)
+}
+
+// apply copies the non-empty overrides into c. Switching provider without a
+// base URL drops the file's base URL, which belongs to the other provider.
+func (o Overrides) apply(c *Config) {
+	c.Embedding.SetModel(o.EmbeddingProvider, o.EmbeddingModel)
+
+	if o.EmbeddingBaseURL != "" {
+		c.Embedding.BaseURL = &o.EmbeddingBaseURL
+	}
 }
 
 // GetPantryHome returns the pantry home directory.
@@ -40,8 +264,48 @@ func GetPantryHome() string {
 	return filepath.Join(userHome, ".pantry")
 }
 
-// LoadConfig loads configuration from a YAML file.
+// LoadConfig loads configuration from a YAML file, then applies the
+// environment variables.
 func LoadConfig(path string) (*Config, error) {
+	config, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Environment variable overrides (take precedence over file values).
+	// Useful for MCP servers launched by host applications that inject secrets
+	// via the environment rather than writing them to disk.
+	if v := os.Getenv("PANTRY_EMBEDDING_PROVIDER"); v != "" {
+		config.Embedding.Provider = v
+	}
+
+	if v := os.Getenv("PANTRY_EMBEDDING_MODEL"); v != "" {
+		config.Embedding.Model = v
+	}
+
+	if v := os.Getenv("PANTRY_EMBEDDING_API_KEY"); v != "" {
+		config.Embedding.APIKey = &v
+	}
+
+	if v := os.Getenv("PANTRY_EMBEDDING_BASE_URL"); v != "" {
+		config.Embedding.BaseURL = &v
+	}
+
+	if v := os.Getenv("PANTRY_CONTEXT_SEMANTIC"); v != "" {
+		config.Context.Semantic = v
+	}
+
+	if v := os.Getenv("PANTRY_DEDUP_SCOPE"); v != "" {
+		config.Dedup.Scope = v
+	}
+
+	return config, nil
+}
+
+// LoadFile loads configuration from a YAML file with the defaults filled in,
+// but without the environment variables LoadConfig applies. Load with it to
+// change and save config.yaml, so those values stay out of the file.
+func LoadFile(path string) (*Config, error) {
 	config := &Config{
 		Embedding: EmbeddingConfig{
 			Provider: "ollama",
@@ -51,6 +315,15 @@ func LoadConfig(path string) (*Config, error) {
 			Semantic:    "auto",
 			TopupRecent: true,
 		},
+		Dedup: DedupConfig{
+			Scope: DedupScopeProject,
+		},
+		Search: SearchConfig{
+			AnchorWeight: DefaultAnchorWeight,
+		},
+		Defaults: DefaultsConfig{
+			CLISource: DefaultCLISource,
+		},
 	}
 
 	data, err := os.ReadFile(path)
@@ -84,27 +357,8 @@ func LoadConfig(path string) (*Config, error) {
 		config.Context.Semantic = "auto"
 	}
 
-	// Environment variable overrides (take precedence over file values).
-	// Useful for MCP servers launched by host applications that inject secrets
-	// via the environment rather than writing them to disk.
-	if v := os.Getenv("PANTRY_EMBEDDING_PROVIDER"); v != "" {
-		config.Embedding.Provider = v
-	}
-
-	if v := os.Getenv("PANTRY_EMBEDDING_MODEL"); v != "" {
-		config.Embedding.Model = v
-	}
-
-	if v := os.Getenv("PANTRY_EMBEDDING_API_KEY"); v != "" {
-		config.Embedding.APIKey = &v
-	}
-
-	if v := os.Getenv("PANTRY_EMBEDDING_BASE_URL"); v != "" {
-		config.Embedding.BaseURL = &v
-	}
-
-	if v := os.Getenv("PANTRY_CONTEXT_SEMANTIC"); v != "" {
-		config.Context.Semantic = v
+	if config.Dedup.Scope == "" {
+		config.Dedup.Scope = DedupScopeProject
 	}
 
 	return config, nil
@@ -113,9 +367,9 @@ func LoadConfig(path string) (*Config, error) {
 // Validate returns an error if the configuration contains invalid values.
 // Call this after LoadConfig to surface misconfiguration at startup.
 func (c *Config) Validate() error {
-	validProviders := map[string]bool{"ollama": true, "openai": true, "openrouter": true}
+	validProviders := map[string]bool{"ollama": true, "openai": true, "openrouter": true, "cohere": true, "mock": true}
 	if !validProviders[c.Embedding.Provider] {
-		return fmt.Errorf("invalid embedding.provider %q: must be one of ollama, openai, openrouter", c.Embedding.Provider)
+		return fmt.Errorf("invalid embedding.provider %q: must be one of ollama, openai, openrouter, cohere, mock", c.Embedding.Provider)
 	}
 
 	if c.Embedding.Model == "" {
@@ -127,12 +381,98 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid context.semantic %q: must be one of auto, always, never", c.Context.Semantic)
 	}
 
-	if c.Embedding.Provider == "openai" || c.Embedding.Provider == "openrouter" {
+	if c.Embedding.Metric != "" && !slices.Contains(db.Metrics, c.Embedding.Metric) {
+		return fmt.Errorf("invalid embedding.metric %q: must be one of %s", c.Embedding.Metric, strings.Join(db.Metrics, ", "))
+	}
+
+	switch c.Embedding.OnStore {
+	case "", OnStoreSync, OnStoreAsync, OnStoreOff:
+	default:
+		return fmt.Errorf("invalid embedding.on_store %q: must be one of sync, async, off", c.Embedding.OnStore)
+	}
+
+	if err := ValidateDedupScope(c.Dedup.Scope); err != nil {
+		return err
+	}
+
+	validTitleMatch := map[string]bool{"": true, TitleMatchExact: true, TitleMatchNormalized: true, TitleMatchFuzzy: true}
+	if !validTitleMatch[c.Dedup.TitleMatch] {
+		return fmt.Errorf("invalid dedup.title_match %q: must be one of exact, normalized, fuzzy", c.Dedup.TitleMatch)
+	}
+
+	if c.Dedup.FuzzyThreshold < 0 || c.Dedup.FuzzyThreshold > 1 {
+		return fmt.Errorf("invalid dedup.fuzzy_threshold %v: must be between 0 and 1", c.Dedup.FuzzyThreshold)
+	}
+
+	if _, _, err := c.Permissions.Modes(); err != nil {
+		return err
+	}
+
+	if c.Search.RecencyHalfLifeDays < 0 {
+		return fmt.Errorf("invalid search.recency_half_life_days %v: must not be negative", c.Search.RecencyHalfLifeDays)
+	}
+
+	if c.Search.Timeout < 0 {
+		return fmt.Errorf("invalid search.timeout %v: must not be negative", c.Search.Timeout)
+	}
+
+	if c.Search.CacheTTL < 0 {
+		return fmt.Errorf("invalid search.cache_ttl %v: must not be negative", c.Search.CacheTTL)
+	}
+
+	if c.Hooks.Timeout < 0 {
+		return fmt.Errorf("invalid hooks.timeout %v: must not be negative", c.Hooks.Timeout)
+	}
+
+	if c.Search.AnchorWeight < 0 || c.Search.AnchorWeight > 1 {
+		return fmt.Errorf("invalid search.anchor_weight %v: must be between 0 and 1", c.Search.AnchorWeight)
+	}
+
+	for col, w := range c.Search.FTSWeights {
+		if _, ok := DefaultFTSWeights[col]; !ok {
+			return fmt.Errorf("invalid search.fts_weights column %q: must be one of title, what, why, impact, tags, category, project, source", col)
+		}
+
+		if w < 0 {
+			return fmt.Errorf("invalid search.fts_weights.%s %v: must not be negative", col, w)
+		}
+	}
+
+	if c.Embedding.Provider == "openai" || c.Embedding.Provider == "openrouter" || c.Embedding.Provider == "cohere" {
 		if c.Embedding.APIKey == nil || *c.Embedding.APIKey == "" {
 			return fmt.Errorf("embedding.api_key is required for provider %q", c.Embedding.Provider)
 		}
 	}
 
+	for i, fb := range c.Embedding.Fallback {
+		if !validProviders[fb.Provider] {
+			return fmt.Errorf("invalid embedding.fallback[%d].provider %q: must be one of ollama, openai, openrouter, cohere, mock", i, fb.Provider)
+		}
+
+		if fb.Model == "" {
+			return fmt.Errorf("embedding.fallback[%d].model must not be empty", i)
+		}
+
+		if fb.Provider == "openai" || fb.Provider == "openrouter" || fb.Provider == "cohere" {
+			if fb.APIKey == nil || *fb.APIKey == "" {
+				return fmt.Errorf("embedding.fallback[%d].api_key is required for provider %q", i, fb.Provider)
+			}
+		}
+
+		if len(fb.Fallback) > 0 {
+			return fmt.Errorf("embedding.fallback[%d] must not have its own fallback; list every backup under embedding.fallback", i)
+		}
+	}
+
+	return nil
+}
+
+// ValidateDedupScope returns an error unless scope is "project" or "global".
+func ValidateDedupScope(scope string) error {
+	if scope != DedupScopeProject && scope != DedupScopeGlobal {
+		return fmt.Errorf("invalid dedup.scope %q: must be one of project, global", scope)
+	}
+
 	return nil
 }
 
@@ -143,11 +483,16 @@ func SaveConfig(path string, config *Config) error {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+	fileMode, dirMode, err := config.Permissions.Modes()
+	if err != nil {
+		return err
+	}
+
+	if err := fsutil.MkdirAll(filepath.Dir(path), dirMode); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := fsutil.WriteFile(path, data, fileMode); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -162,16 +507,68 @@ func GetDefaultConfigTemplate() string {
 # Embedding provider for semantic search.
 # Without this, keyword search (FTS5) still works.
 embedding:
-  provider: ollama              # ollama | openai | openrouter
+  provider: ollama              # ollama | openai | openrouter | cohere | mock (offline, hashed words)
   model: nomic-embed-text
   base_url: http://localhost:11434
-  # api_key: sk-...            # required for openai/openrouter
+  # api_key: sk-...            # required for openai/openrouter/cohere
+  # api_key: env:OPENAI_API_KEY # or resolve at runtime: env:VAR, exec:cmd, keychain:name
+  # metric: cosine              # vector distance: l2 (default) | cosine | l1; changing it needs a reindex
+  # fallback:                   # backup providers tried in order when this one fails;
+  #   - provider: ollama        # they must return vectors of the same dimension
+  #     model: nomic-embed-text
 
 # How items are retrieved at session start.
 # "auto" uses vectors when available, falls back to keywords.
 context:
   semantic: auto                # auto | always | never
   topup_recent: true            # also include recent items
+
+# Where store looks for an existing note with the same title to update.
+# "global" updates a matching note in any project; the note keeps its project.
+dedup:
+  scope: project                # project | global
+  # title_match: exact          # exact | normalized (ignore punctuation, stopwords) | fuzzy
+  # fuzzy_threshold: 0.8        # title similarity needed by fuzzy (0..1)
+
+# search --within <id> blends the anchor note's vector with the query's.
+search:
+  anchor_weight: 0.5            # 0 = query only, 1 = anchor only
+  # recency_half_life_days: 30  # halve scores every 30 days of age; 0 = off
+  # timeout: 2s                 # keyword results only if embedding the query takes longer
+  # cache_ttl: 30s              # reuse results of identical searches; cleared on any write
+
+# Source recorded for notes stored without one.
+defaults:
+  cli_source: cli               # "" = no source
+  # mcp_source: agent           # default: the MCP client's name
+
+# Tags added to every note of a category, so tag filters stay reliable.
+# categories:
+#   default_tags:
+#     bug: [bug]
+#     decision: [decision]
+
+# Record every store, update and removal in audit.log (see pantry audit).
+# audit:
+#   enabled: true
+
+# Shell command run in the background after each store, with the note as
+# JSON on stdin and PANTRY_ITEM_ID, PANTRY_ACTION, PANTRY_PROJECT,
+# PANTRY_TITLE and PANTRY_FILE_PATH set.
+# hooks:
+#   post_store: 'cd ~/.pantry/shelves && git add -A && git commit -qm "pantry: $PANTRY_TITLE"'
+#   timeout: 30s                # a hook running longer is killed
+
+# Modes for the database, notes files and this config (octal).
+# Use 0600 / 0700 to keep the pantry private on a shared host.
+# permissions:
+#   file_mode: "0644"
+#   dir_mode: "0755"
+
+# Sources are lowercased and spaces become dashes ("Claude Code" -> claude-code).
+# Map other spellings to one canonical name here.
+# source_aliases:
+#   claude: claude-code
 `
 }
 
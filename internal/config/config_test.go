@@ -1,7 +1,10 @@
 package config
 
 import (
+	"errors"
+	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 )
 
@@ -75,3 +78,251 @@ func TestSaveConfig(t *testing.T) {
 		t.Errorf("LoadConfig() Model = %q, want %q", loaded.Embedding.Model, "test-model")
 	}
 }
+
+func TestResolveSecret_Plaintext(t *testing.T) {
+	got, err := ResolveSecret("sk-plain")
+	if err != nil {
+		t.Fatalf("ResolveSecret() error = %v", err)
+	}
+
+	if got != "sk-plain" {
+		t.Errorf("ResolveSecret() = %q, want %q", got, "sk-plain")
+	}
+}
+
+func TestResolveSecret_Env(t *testing.T) {
+	t.Setenv("PANTRY_TEST_SECRET", "sk-from-env")
+
+	got, err := ResolveSecret("env:PANTRY_TEST_SECRET")
+	if err != nil {
+		t.Fatalf("ResolveSecret() error = %v", err)
+	}
+
+	if got != "sk-from-env" {
+		t.Errorf("ResolveSecret() = %q, want %q", got, "sk-from-env")
+	}
+}
+
+func TestResolveSecret_EnvMissing(t *testing.T) {
+	if _, err := ResolveSecret("env:PANTRY_TEST_SECRET_UNSET"); err == nil {
+		t.Error("ResolveSecret() should fail for an unset environment variable")
+	}
+}
+
+func TestResolveSecret_Exec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("exec: test uses a POSIX shell")
+	}
+
+	got, err := ResolveSecret("exec:echo sk-from-exec")
+	if err != nil {
+		t.Fatalf("ResolveSecret() error = %v", err)
+	}
+
+	if got != "sk-from-exec" {
+		t.Errorf("ResolveSecret() = %q, want %q", got, "sk-from-exec")
+	}
+}
+
+func TestResolveSecret_ExecFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("exec: test uses a POSIX shell")
+	}
+
+	if _, err := ResolveSecret("exec:exit 3"); err == nil {
+		t.Error("ResolveSecret() should fail when the command exits non-zero")
+	}
+
+	if _, err := ResolveSecret("exec:true"); err == nil {
+		t.Error("ResolveSecret() should fail when the command prints nothing")
+	}
+}
+
+func TestResolveSecret_KeychainUnsupported(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "linux" {
+		t.Skip("keychain is supported on this platform")
+	}
+
+	_, err := ResolveSecret("keychain:pantry-openai")
+	if !errors.Is(err, ErrKeychainUnsupported) {
+		t.Errorf("ResolveSecret() error = %v, want ErrKeychainUnsupported", err)
+	}
+}
+
+func TestValidate_DedupScope(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if cfg.Dedup.Scope != DedupScopeProject {
+		t.Errorf("default dedup.scope = %q, want %q", cfg.Dedup.Scope, DedupScopeProject)
+	}
+
+	cfg.Dedup.Scope = "everywhere"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should reject an unknown dedup.scope")
+	}
+
+	cfg.Dedup.Scope = DedupScopeProject
+	cfg.Dedup.TitleMatch = "loose"
+
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should reject an unknown dedup.title_match")
+	}
+
+	cfg.Dedup.TitleMatch = TitleMatchFuzzy
+	cfg.Dedup.FuzzyThreshold = 1.5
+
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should reject dedup.fuzzy_threshold above 1")
+	}
+}
+
+func TestValidate_AnchorWeight(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if cfg.Search.AnchorWeight != DefaultAnchorWeight {
+		t.Errorf("default search.anchor_weight = %v, want %v", cfg.Search.AnchorWeight, DefaultAnchorWeight)
+	}
+
+	cfg.Search.AnchorWeight = 1.5
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should reject search.anchor_weight above 1")
+	}
+}
+
+func TestSearchConfig_FTSWeights(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	cfg.Search.FTSWeights = map[string]float64{"title": 20}
+
+	weights := cfg.Search.ColumnWeights()
+	if weights["title"] != 20 || weights["what"] != DefaultFTSWeights["what"] {
+		t.Errorf("ColumnWeights() = %v, want title overridden and the rest defaulted", weights)
+	}
+
+	if DefaultFTSWeights["title"] != 10 {
+		t.Error("ColumnWeights() changed DefaultFTSWeights")
+	}
+
+	for _, bad := range []map[string]float64{{"details": 1}, {"why": -1}} {
+		cfg.Search.FTSWeights = bad
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Validate() should reject search.fts_weights %v", bad)
+		}
+	}
+}
+
+func TestValidate_OnStore(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	for _, mode := range []string{"", OnStoreSync, OnStoreAsync, OnStoreOff} {
+		cfg.Embedding.OnStore = mode
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() with embedding.on_store %q error = %v", mode, err)
+		}
+	}
+
+	cfg.Embedding.OnStore = "later"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should reject embedding.on_store later")
+	}
+}
+
+func TestValidate_Fallback(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	cfg.Embedding.Fallback = []EmbeddingConfig{{Provider: "ollama", Model: "nomic-embed-text"}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() with an ollama fallback error = %v", err)
+	}
+
+	for name, fb := range map[string]EmbeddingConfig{
+		"unknown provider": {Provider: "bogus", Model: "m"},
+		"empty model":      {Provider: "ollama"},
+		"missing api_key":  {Provider: "openai", Model: "text-embedding-3-small"},
+		"nested fallback":  {Provider: "mock", Model: "mock", Fallback: []EmbeddingConfig{{Provider: "mock", Model: "mock"}}},
+	} {
+		cfg.Embedding.Fallback = []EmbeddingConfig{fb}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Validate() should reject a fallback with %s", name)
+		}
+	}
+}
+
+func TestApplyOverrides_BeatEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	data := "embedding:\n  provider: ollama\n  model: nomic-embed-text\n  base_url: http://gpu-box:11434\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	t.Setenv("PANTRY_EMBEDDING_MODEL", "from-env")
+
+	SetOverrides(Overrides{EmbeddingProvider: "openai", EmbeddingModel: "text-embedding-3-small"})
+	t.Cleanup(func() { SetOverrides(Overrides{}) })
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if cfg.Embedding.Model != "from-env" {
+		t.Errorf("LoadConfig() model = %q, want the overrides left to ApplyOverrides", cfg.Embedding.Model)
+	}
+
+	ApplyOverrides(cfg)
+
+	if cfg.Embedding.Provider != "openai" {
+		t.Errorf("provider = %q, want openai", cfg.Embedding.Provider)
+	}
+
+	if cfg.Embedding.Model != "text-embedding-3-small" {
+		t.Errorf("model = %q, want the override to beat PANTRY_EMBEDDING_MODEL", cfg.Embedding.Model)
+	}
+
+	if cfg.Embedding.BaseURL != nil {
+		t.Errorf("base_url = %q, want the ollama URL dropped when switching provider", *cfg.Embedding.BaseURL)
+	}
+}
+
+func TestLoadConfig_DefaultSources(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if cfg.Defaults.CLISource != DefaultCLISource || cfg.Defaults.MCPSource != "" {
+		t.Errorf("Defaults = %+v, want cli_source %q and no mcp_source", cfg.Defaults, DefaultCLISource)
+	}
+
+	if err := os.WriteFile(path, []byte("defaults:\n  cli_source: \"\"\n  mcp_source: agent\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if cfg.Defaults.CLISource != "" || cfg.Defaults.MCPSource != "agent" {
+		t.Errorf("Defaults = %+v, want empty cli_source and mcp_source agent", cfg.Defaults)
+	}
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Secret reference prefixes accepted by ResolveSecret.
+const (
+	secretPrefixEnv      = "env:"
+	secretPrefixExec     = "exec:"
+	secretPrefixKeychain = "keychain:"
+)
+
+// ErrKeychainUnsupported is returned when a keychain: reference is used on a
+// platform without a supported secret store.
+var ErrKeychainUnsupported = errors.New("keychain secrets are not supported on this platform")
+
+// ResolveSecret resolves a config value that may reference a secret stored
+// outside config.yaml. Supported forms:
+//
+//	env:VAR         value of environment variable VAR
+//	exec:command    trimmed stdout of running command via the shell
+//	keychain:name   generic password stored under service name in the OS keychain
+//
+// Any other value is returned unchanged, so plaintext keys keep working.
+func ResolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, secretPrefixEnv):
+		name := strings.TrimPrefix(value, secretPrefixEnv)
+
+		v, ok := os.LookupEnv(name)
+		if !ok || v == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+
+		return v, nil
+
+	case strings.HasPrefix(value, secretPrefixExec):
+		command := strings.TrimPrefix(value, secretPrefixExec)
+		if strings.TrimSpace(command) == "" {
+			return "", errors.New("exec: secret reference has no command")
+		}
+
+		return runSecretCommand(shellCommand(command))
+
+	case strings.HasPrefix(value, secretPrefixKeychain):
+		name := strings.TrimPrefix(value, secretPrefixKeychain)
+		if name == "" {
+			return "", errors.New("keychain: secret reference has no name")
+		}
+
+		args, err := keychainCommand(name)
+		if err != nil {
+			return "", err
+		}
+
+		return runSecretCommand(args)
+
+	default:
+		return value, nil
+	}
+}
+
+// shellCommand wraps command for execution by the platform shell.
+func shellCommand(command string) []string {
+	if runtime.GOOS == "windows" {
+		return []string{"cmd", "/C", command}
+	}
+
+	return []string{"sh", "-c", command}
+}
+
+// keychainCommand returns the command used to look up name in the OS keychain.
+func keychainCommand(name string) ([]string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"security", "find-generic-password", "-s", name, "-w"}, nil
+	case "linux":
+		return []string{"secret-tool", "lookup", "service", name}, nil
+	default:
+		return nil, fmt.Errorf("%w (%s)", ErrKeychainUnsupported, runtime.GOOS)
+	}
+}
+
+// runSecretCommand runs args and returns its trimmed stdout.
+func runSecretCommand(args []string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(args[0], args[1:]...) //nolint:gosec
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg != "" {
+			return "", fmt.Errorf("secret command %s failed: %w: %s", args[0], err, msg)
+		}
+
+		return "", fmt.Errorf("secret command %s failed: %w", args[0], err)
+	}
+
+	secret := strings.TrimSpace(stdout.String())
+	if secret == "" {
+		return "", fmt.Errorf("secret command %s returned no output", args[0])
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"pantry/internal/audit"
+	"pantry/internal/models"
+)
+
+// AuditLogFile is the name of the audit log in the pantry home.
+const AuditLogFile = "audit.log"
+
+// auditEnabled reports whether audit.enabled is set.
+func (s *Service) auditEnabled() bool {
+	s.embeddingMu.RLock()
+	defer s.embeddingMu.RUnlock()
+
+	return s.config.Audit.Enabled
+}
+
+// auditItem records action on itemID, described by item when it is known.
+func (s *Service) auditItem(action, itemID string, item *models.Item) {
+	e := audit.Entry{Action: action, ItemID: itemID}
+	if item != nil {
+		e.Title, e.Project, e.Source = item.Title, item.Project, item.Source
+	}
+
+	s.writeAudit(e)
+}
+
+// writeAudit appends e to the audit log. The change it records has already
+// been made, so a failure is reported on stderr rather than returned.
+func (s *Service) writeAudit(e audit.Entry) {
+	e.Time = time.Now().UTC().Format(time.RFC3339)
+
+	fileMode, _ := s.modes()
+
+	if err := audit.Append(s.auditPath, e, fileMode); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to write audit log: %v\n", err)
+	}
+}
+
+// AuditLog returns the entries of the audit log, oldest first. It is empty
+// when nothing has been audited yet.
+func (s *Service) AuditLog() ([]audit.Entry, error) {
+	entries, err := audit.Read(s.auditPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	return entries, err
+}
@@ -0,0 +1,311 @@
+package core
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pantry/internal/config"
+	"pantry/internal/db"
+	"pantry/internal/fsutil"
+)
+
+// ErrPantryExists is returned by Restore when the target home already holds
+// a database and overwriting was not asked for.
+var ErrPantryExists = errors.New("pantry home already has a database")
+
+// backupFiles are the files of the pantry home a backup holds besides
+// shelves/. Missing ones are left out.
+var backupFiles = []string{"index.db", "config.yaml", ".pantryignore"}
+
+// backupManifest is the first entry of a backup. It records the home the
+// backup was taken from, so Restore can repoint the notes file paths stored
+// in the database.
+const backupManifest = "pantry-backup.json"
+
+// manifest is the content of backupManifest.
+type manifest struct {
+	PantryHome string `json:"pantry_home"`
+}
+
+// Backup writes the database, config.yaml, .pantryignore and every file under
+// shelves/ to w as a gzipped tar archive with paths relative to the pantry
+// home. The database's write-ahead log is checkpointed first so index.db
+// holds every committed change. Returns the number of files archived.
+func (s *Service) Backup(w io.Writer) (int, error) {
+	if err := s.db.Checkpoint(); err != nil {
+		return 0, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	n := 0
+
+	m, err := json.Marshal(manifest{PantryHome: s.pantryHome})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tw.WriteHeader(&tar.Header{Name: backupManifest, Mode: 0o644, Size: int64(len(m)), ModTime: time.Now()}); err != nil {
+		return 0, err
+	}
+
+	if _, err := tw.Write(m); err != nil {
+		return 0, err
+	}
+
+	add := func(name, p string, info fs.FileInfo) error {
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+
+		hdr.Name = name
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", name, err)
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(p) //nolint:gosec
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+
+		if _, err := io.Copy(tw, f); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", name, err)
+		}
+
+		n++
+
+		return nil
+	}
+
+	for _, name := range backupFiles {
+		p := filepath.Join(s.pantryHome, name)
+
+		info, err := os.Stat(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return n, err
+		}
+
+		if err := add(name, p, info); err != nil {
+			return n, err
+		}
+	}
+
+	err = filepath.WalkDir(s.shelvesDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.pantryHome, p)
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		return add(filepath.ToSlash(rel), p, info)
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return n, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return n, err
+	}
+
+	return n, gz.Close()
+}
+
+// Restore unpacks an archive written by Backup into pantryHome, which is
+// created if needed. A home that already has a non-empty index.db is refused
+// with ErrPantryExists unless overwrite is set; files from the archive then
+// replace the existing ones. Entries outside the files Backup writes are
+// rejected. When the backup came from another home, the notes file paths in
+// the database are moved to this one. Returns the number of files restored.
+func Restore(r io.Reader, pantryHome string, overwrite bool) (int, error) {
+	n, from, err := unpackBackup(r, pantryHome, overwrite)
+	if err != nil || from == "" || filepath.Clean(from) == filepath.Clean(pantryHome) {
+		return n, err
+	}
+
+	database, err := db.NewDB(filepath.Join(pantryHome, "index.db"))
+	if err != nil {
+		return n, fmt.Errorf("failed to open the restored database: %w", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	if _, err := database.RewriteFilePaths(filepath.Join(from, "shelves"), filepath.Join(pantryHome, "shelves")); err != nil {
+		return n, fmt.Errorf("failed to update note file paths: %w", err)
+	}
+
+	return n, nil
+}
+
+// unpackBackup extracts the archive for Restore and returns the number of
+// files written and the home recorded in the manifest.
+func unpackBackup(r io.Reader, pantryHome string, overwrite bool) (int, string, error) {
+	if info, err := os.Stat(filepath.Join(pantryHome, "index.db")); err == nil && info.Size() > 0 && !overwrite {
+		return 0, "", fmt.Errorf("%w: %s", ErrPantryExists, pantryHome)
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, "", fmt.Errorf("not a pantry backup: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+
+	// Directories the archive does not list get the configured mode: that of
+	// the config.yaml already in pantryHome, then of the restored one.
+	dirMode := homeDirMode(pantryHome)
+
+	if err := fsutil.MkdirAll(pantryHome, dirMode); err != nil {
+		return 0, "", err
+	}
+
+	tr := tar.NewReader(gz)
+	n := 0
+
+	var m manifest
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return n, m.PantryHome, nil
+		}
+
+		if err != nil {
+			return n, "", fmt.Errorf("failed to read backup: %w", err)
+		}
+
+		if hdr.Name == backupManifest {
+			if err := json.NewDecoder(tr).Decode(&m); err != nil {
+				return n, "", fmt.Errorf("invalid backup manifest: %w", err)
+			}
+
+			continue
+		}
+
+		name, err := backupEntryName(hdr.Name)
+		if err != nil {
+			return n, "", err
+		}
+
+		target := filepath.Join(pantryHome, filepath.FromSlash(name))
+		mode := hdr.FileInfo().Mode().Perm()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := fsutil.MkdirAll(target, mode|0o700); err != nil {
+				return n, "", err
+			}
+		case tar.TypeReg:
+			if err := fsutil.MkdirAll(filepath.Dir(target), dirMode); err != nil {
+				return n, "", err
+			}
+
+			// A stale log from the replaced database would be applied to
+			// the restored one.
+			if name == "index.db" {
+				for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+					_ = os.Remove(target + suffix)
+				}
+			}
+
+			if err := restoreFile(target, tr, mode); err != nil {
+				return n, "", fmt.Errorf("failed to restore %s: %w", name, err)
+			}
+
+			if name == "config.yaml" {
+				dirMode = homeDirMode(pantryHome)
+			}
+
+			n++
+		default:
+			return n, "", fmt.Errorf("unexpected entry %q in backup", hdr.Name)
+		}
+	}
+}
+
+// homeDirMode returns the directory mode set by permissions.dir_mode in the
+// config.yaml of pantryHome, or the default when there is none or it cannot
+// be read.
+func homeDirMode(pantryHome string) os.FileMode {
+	cfg, err := config.LoadFile(filepath.Join(pantryHome, "config.yaml"))
+	if err != nil {
+		return fsutil.DefaultDirMode
+	}
+
+	_, dir, err := cfg.Permissions.Modes()
+	if err != nil {
+		return fsutil.DefaultDirMode
+	}
+
+	return dir
+}
+
+// backupEntryName checks that an archive entry is one Backup writes and
+// returns its cleaned, slash-separated path.
+func backupEntryName(name string) (string, error) {
+	clean := path.Clean(strings.TrimSuffix(name, "/"))
+
+	switch {
+	case path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../"):
+	case clean == "shelves" || strings.HasPrefix(clean, "shelves/"):
+		return clean, nil
+	default:
+		for _, f := range backupFiles {
+			if clean == f {
+				return clean, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("unexpected entry %q in backup", name)
+}
+
+// restoreFile writes the contents of r to target with mode, replacing any
+// existing file.
+func restoreFile(target string, r io.Reader, mode os.FileMode) error {
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode) //nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
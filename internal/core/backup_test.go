@@ -0,0 +1,152 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"pantry/internal/models"
+)
+
+func TestBackupRestore_RoundTrip(t *testing.T) {
+	src := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(src, ".pantryignore"), []byte("internal-host-\\d+\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	svc, err := NewService(src)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	details := "Rotate signing keys weekly"
+	if _, err := svc.Store(models.RawItemInput{Title: "Use JWT auth", What: "Replaced sessions with JWT", Details: &details}, "api"); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	var archive bytes.Buffer
+
+	n, err := svc.Backup(&archive)
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+
+	_ = svc.Close()
+
+	// index.db, .pantryignore and one notes file.
+	if n != 3 {
+		t.Errorf("Backup() archived %d files, want 3", n)
+	}
+
+	dst := filepath.Join(t.TempDir(), "restored")
+
+	if _, err := Restore(bytes.NewReader(archive.Bytes()), dst, false); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	restored, err := NewService(dst)
+	if err != nil {
+		t.Fatalf("NewService(restored) error = %v", err)
+	}
+
+	defer restored.Close()
+
+	results, err := restored.Search("JWT", 5, nil, nil, false)
+	if err != nil || len(results) != 1 || results[0].Title != "Use JWT auth" {
+		t.Fatalf("Search() after restore = %v, %v; want the stored note", results, err)
+	}
+
+	if !strings.HasPrefix(results[0].FilePath, filepath.Join(dst, "shelves")) {
+		t.Errorf("restored file path = %s, want it under %s", results[0].FilePath, dst)
+	}
+
+	if _, err := os.Stat(results[0].FilePath); err != nil {
+		t.Errorf("restored notes file: %v", err)
+	}
+
+	if got, _ := restored.GetDetails(results[0].ID); got == nil || got.Body != details {
+		t.Errorf("details after restore = %v, want %q", got, details)
+	}
+
+	if _, err := Restore(bytes.NewReader(archive.Bytes()), dst, false); !errors.Is(err, ErrPantryExists) {
+		t.Errorf("Restore() over an existing pantry error = %v, want ErrPantryExists", err)
+	}
+}
+
+func TestRestore_RejectsUnexpectedEntries(t *testing.T) {
+	for _, name := range []string{"../escape.txt", "/etc/passwd", "shelves/../../escape.txt", "notes.txt"} {
+		var buf bytes.Buffer
+
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: 1, Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+
+		_, _ = tw.Write([]byte("x"))
+		_ = tw.Close()
+		_ = gz.Close()
+
+		home := filepath.Join(t.TempDir(), "home")
+
+		if _, err := Restore(&buf, home, false); err == nil {
+			t.Errorf("Restore() accepted entry %q", name)
+		}
+
+		if _, err := os.Stat(filepath.Join(filepath.Dir(home), "escape.txt")); err == nil {
+			t.Errorf("Restore() wrote %q outside the pantry home", name)
+		}
+	}
+}
+
+func TestRestore_UsesConfiguredDirMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions")
+	}
+
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	// No directory entries: Restore creates shelves/api itself. config.yaml
+	// comes first, as Backup writes it.
+	for _, entry := range [][2]string{
+		{"config.yaml", "permissions:\n  dir_mode: \"0700\"\n"},
+		{"shelves/api/2025-01-01-notes.md", "# notes\n"},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: entry[0], Mode: 0o600, Size: int64(len(entry[1])), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+
+		_, _ = tw.Write([]byte(entry[1]))
+	}
+
+	_ = tw.Close()
+	_ = gz.Close()
+
+	home := filepath.Join(t.TempDir(), "home")
+
+	if _, err := Restore(&buf, home, false); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	for _, dir := range []string{"shelves", "shelves/api"} {
+		info, err := os.Stat(filepath.Join(home, dir))
+		if err != nil {
+			t.Fatalf("Stat(%s) error = %v", dir, err)
+		}
+
+		if got := info.Mode().Perm(); got != 0o700 {
+			t.Errorf("%s mode = %o, want 700 from permissions.dir_mode", dir, got)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"pantry/internal/models"
+)
+
+// searchCacheMaxEntries bounds the search cache; expired entries are dropped
+// once it is reached.
+const searchCacheMaxEntries = 256
+
+// searchCache holds recent Search results for search.cache_ttl. Every write
+// through the Service clears it and bumps gen, so a search that was already
+// running when the write happened cannot store its stale result.
+// Writes by other processes are only picked up once an entry expires.
+type searchCache struct {
+	mu      sync.Mutex
+	gen     uint64
+	entries map[string]searchCacheEntry
+}
+
+type searchCacheEntry struct {
+	results []models.SearchResult
+	expires time.Time
+}
+
+// generation returns the current generation, to be passed to put.
+func (c *searchCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.gen
+}
+
+// get returns a copy of the live results cached under key.
+func (c *searchCache) get(key string, now time.Time) ([]models.SearchResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || !now.Before(e.expires) {
+		return nil, false
+	}
+
+	return slices.Clone(e.results), true
+}
+
+// put caches results under key for ttl, unless the cache was invalidated
+// since gen was read.
+func (c *searchCache) put(key string, results []models.SearchResult, gen uint64, ttl time.Duration, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+
+	if c.entries == nil {
+		c.entries = make(map[string]searchCacheEntry)
+	}
+
+	if len(c.entries) >= searchCacheMaxEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+
+		if len(c.entries) >= searchCacheMaxEntries {
+			clear(c.entries)
+		}
+	}
+
+	c.entries[key] = searchCacheEntry{results: slices.Clone(results), expires: now.Add(ttl)}
+}
+
+// invalidate retires every cached result.
+func (c *searchCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	clear(c.entries)
+}
+
+// searchCacheKey identifies a Search call by its normalized query, limit,
+// filters and options.
+func searchCacheKey(query string, limit int, project, source *string, useVectors bool, o *searchOptions) string {
+	return fmt.Sprintf("%s\x00%d\x00%s\x00%s\x00%t\x00%s",
+		strings.Join(strings.Fields(strings.ToLower(query)), " "),
+		limit, optKey(project), optKey(source), useVectors, strings.Join(o.cacheKey, "\x00"))
+}
+
+// optKey renders an optional filter for searchCacheKey, keeping nil apart
+// from "".
+func optKey(s *string) string {
+	if s == nil {
+		return "\x01"
+	}
+
+	return "=" + *s
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultCheckpoint is the checkpoint name used when none is given.
+const DefaultCheckpoint = "last"
+
+// checkpointMetaPrefix prefixes the meta keys that hold named checkpoints.
+const checkpointMetaPrefix = "checkpoint:"
+
+// SetCheckpoint records the current time under name, so a later listing can
+// ask for only the notes changed since then (see WithChangedSince). An
+// existing checkpoint of that name is moved forward. Returns the recorded
+// time.
+func (s *Service) SetCheckpoint(name string) (time.Time, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.Time{}, &ValidationError{Field: "checkpoint", Message: "name must not be empty"}
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	if err := s.db.SetMeta(checkpointMetaPrefix+name, now.Format(time.RFC3339)); err != nil {
+		return time.Time{}, fmt.Errorf("failed to record checkpoint: %w", err)
+	}
+
+	return now, nil
+}
+
+// CheckpointTime returns the time recorded for checkpoint name. ok is false
+// when no such checkpoint exists.
+func (s *Service) CheckpointTime(name string) (t time.Time, ok bool, err error) {
+	value, ok, err := s.db.GetMeta(checkpointMetaPrefix + strings.TrimSpace(name))
+	if err != nil || !ok {
+		return time.Time{}, false, err
+	}
+
+	t, err = time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid checkpoint %q: %w", name, err)
+	}
+
+	return t, true, nil
+}
+
+// SinceTime resolves since, a checkpoint name or a date (YYYY-MM-DD or
+// RFC3339), to the time WithChangedSince takes. A checkpoint wins over a date
+// of the same spelling.
+func (s *Service) SinceTime(since string) (time.Time, error) {
+	t, ok, err := s.CheckpointTime(since)
+	if err != nil || ok {
+		return t, err
+	}
+
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return t, nil
+	}
+
+	if t, err := time.Parse(time.DateOnly, since); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, &ValidationError{Field: "since", Message: fmt.Sprintf("%q is no checkpoint (see 'pantry checkpoint') and not a YYYY-MM-DD or RFC3339 time", since)}
+}
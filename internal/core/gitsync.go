@@ -0,0 +1,199 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pantry/internal/db"
+)
+
+// ErrNotGitRepo is returned by GitSync when the pantry home is not inside a
+// git work tree.
+var ErrNotGitRepo = errors.New("pantry home is not a git repository")
+
+// gitSyncTitles caps the note titles listed in a GitSync commit message.
+const gitSyncTitles = 10
+
+// gitSyncLockFile, in the repository's git directory, is held by a running
+// GitSync so that runs started by back-to-back stores take turns instead of
+// failing on git's index.lock.
+const gitSyncLockFile = "pantry-git-sync.lock"
+
+const (
+	// gitSyncLockWait bounds how long GitSync waits for another run.
+	gitSyncLockWait = 20 * time.Second
+	// gitSyncLockStale is the age past which a lock is taken to be left by a
+	// run that was killed, and is removed.
+	gitSyncLockStale = 2 * time.Minute
+)
+
+// GitSyncResult describes what GitSync did.
+type GitSyncResult struct {
+	// Committed is false when shelves/ had no changes.
+	Committed bool
+	// Files are the changed notes files, relative to the pantry home.
+	Files   []string
+	Message string
+	Pushed  bool
+}
+
+// GitSync commits the changes under shelves/ when the pantry home is a git
+// repository, with a message naming the changed files and the notes most
+// recently changed in them, and with push runs git push afterwards. Other
+// files in the repository are neither staged nor committed. Concurrent runs,
+// in this process or others, are serialized.
+func (s *Service) GitSync(push bool) (GitSyncResult, error) {
+	var result GitSyncResult
+
+	if _, err := exec.LookPath("git"); err != nil {
+		return result, fmt.Errorf("git not found in PATH: %w", err)
+	}
+
+	if _, err := s.git("rev-parse", "--is-inside-work-tree"); err != nil {
+		return result, fmt.Errorf("%w: run 'git init' in %s first", ErrNotGitRepo, s.pantryHome)
+	}
+
+	gitDir, err := s.git("rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return result, err
+	}
+
+	unlock, err := lockGitSync(filepath.Join(strings.TrimSpace(gitDir), gitSyncLockFile))
+	if err != nil {
+		return result, err
+	}
+
+	defer unlock()
+
+	shelves, err := filepath.Rel(s.pantryHome, s.shelvesDir)
+	if err != nil {
+		return result, err
+	}
+
+	if _, err := s.git("add", "-A", "--", shelves); err != nil {
+		return result, err
+	}
+
+	changed, err := s.git("diff", "--cached", "--name-only", "--relative", "--", shelves)
+	if err != nil {
+		return result, err
+	}
+
+	for _, f := range strings.Split(changed, "\n") {
+		if f != "" {
+			result.Files = append(result.Files, f)
+		}
+	}
+
+	if len(result.Files) == 0 {
+		return result, nil
+	}
+
+	result.Message = s.gitSyncMessage(result.Files)
+
+	if _, err := s.git("commit", "-q", "-m", result.Message, "--", shelves); err != nil {
+		return result, err
+	}
+
+	result.Committed = true
+
+	if push {
+		if _, err := s.git("push", "-q"); err != nil {
+			return result, err
+		}
+
+		result.Pushed = true
+	}
+
+	return result, nil
+}
+
+// lockGitSync creates the lock file at path, waiting up to gitSyncLockWait
+// for another run to remove it, and returns the function that removes it.
+func lockGitSync(path string) (func(), error) {
+	deadline := time.Now().Add(gitSyncLockWait)
+
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600) //nolint:gosec
+		if err == nil {
+			_ = f.Close()
+
+			return func() { _ = os.Remove(path) }, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock git-sync: %w", err)
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > gitSyncLockStale {
+			_ = os.Remove(path)
+
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("another git-sync is still running; remove %s if it is not", path)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// gitSyncMessage builds the commit message for files: a subject counting
+// them and a list of the notes most recently changed in them.
+func (s *Service) gitSyncMessage(files []string) string {
+	subject := "pantry: update " + files[0]
+	if len(files) > 1 {
+		subject = fmt.Sprintf("pantry: update %d notes files", len(files))
+	}
+
+	inFiles := make(map[string]bool, len(files))
+	for _, f := range files {
+		inFiles[filepath.Clean(filepath.Join(s.pantryHome, f))] = true
+	}
+
+	recent, err := s.db.ListRecent(gitSyncTitles*len(files), nil, nil, db.WithOrderUpdated())
+	if err != nil {
+		return subject
+	}
+
+	var lines []string
+
+	for _, r := range recent {
+		if inFiles[filepath.Clean(r.FilePath)] && len(lines) < gitSyncTitles {
+			lines = append(lines, fmt.Sprintf("- %s (%s)", r.Title, r.Project))
+		}
+	}
+
+	if len(lines) == 0 {
+		return subject
+	}
+
+	return subject + "\n\n" + strings.Join(lines, "\n") + "\n"
+}
+
+// git runs git in the pantry home and returns its stdout. The error carries
+// git's own message.
+func (s *Service) git(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", append([]string{"-C", s.pantryHome}, args...)...) //nolint:gosec
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, msg)
+		}
+
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+
+	return stdout.String(), nil
+}
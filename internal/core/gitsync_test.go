@@ -0,0 +1,151 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"pantry/internal/models"
+)
+
+// newGitHome returns a pantry home that is a fresh git repository, skipping
+// the test when git is not installed.
+func newGitHome(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	home := t.TempDir()
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "commit.gpgsign", "false"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", home}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	return home
+}
+
+func TestService_GitSync(t *testing.T) {
+	svc, err := NewService(newGitHome(t))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	defer svc.Close()
+
+	if _, err := svc.Store(models.RawItemInput{Title: "Synced note", What: "w"}, "proj"); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	result, err := svc.GitSync(false)
+	if err != nil {
+		t.Fatalf("GitSync() error = %v", err)
+	}
+
+	if !result.Committed || len(result.Files) != 1 || !strings.HasPrefix(result.Files[0], "shelves/proj/") {
+		t.Fatalf("GitSync() = %+v, want one commit of shelves/proj", result)
+	}
+
+	log, err := svc.git("log", "--format=%B")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(log, "Synced note (proj)") {
+		t.Errorf("commit message = %q, want it to list the note", log)
+	}
+
+	files, err := svc.git("show", "--name-only", "--format=")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(files, "index.db") || strings.Contains(files, "config.yaml") {
+		t.Errorf("commit included files outside shelves/: %q", files)
+	}
+
+	again, err := svc.GitSync(false)
+	if err != nil {
+		t.Fatalf("second GitSync() error = %v", err)
+	}
+
+	if again.Committed {
+		t.Error("second GitSync() committed with nothing changed")
+	}
+}
+
+func TestService_GitSyncNotRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	svc, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	defer svc.Close()
+
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(svc.pantryHome))
+
+	if _, err := svc.GitSync(false); !errors.Is(err, ErrNotGitRepo) {
+		t.Errorf("GitSync() outside a repository error = %v, want ErrNotGitRepo", err)
+	}
+}
+
+func TestService_GitSyncConcurrent(t *testing.T) {
+	svc, err := NewService(newGitHome(t))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	defer svc.Close()
+
+	var wg sync.WaitGroup
+
+	errs := make([]error, 4)
+
+	// Each run stores its own note, so most have something to commit while
+	// another holds git's index.
+	for i := range errs {
+		wg.Go(func() {
+			if _, err := svc.Store(models.RawItemInput{Title: fmt.Sprintf("Note %d", i), What: "w"}, fmt.Sprintf("proj-%d", i)); err != nil {
+				errs[i] = err
+
+				return
+			}
+
+			_, errs[i] = svc.GitSync(false)
+		})
+	}
+
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("run %d error = %v", i, err)
+		}
+	}
+
+	if status, err := svc.git("status", "--porcelain", "--", "shelves"); err != nil || status != "" {
+		t.Errorf("git status after the runs = %q, %v; want every note committed", status, err)
+	}
+
+	gitDir, _ := svc.git("rev-parse", "--absolute-git-dir")
+	if _, err := os.Stat(filepath.Join(strings.TrimSpace(gitDir), gitSyncLockFile)); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"pantry/internal/config"
+	"pantry/internal/models"
+)
+
+// postStoreHook is the JSON a hooks.post_store command reads on stdin.
+type postStoreHook struct {
+	ID       string  `json:"id"`
+	Action   string  `json:"action"`
+	Project  string  `json:"project"`
+	Title    string  `json:"title"`
+	Source   *string `json:"source,omitempty"`
+	FilePath string  `json:"file_path"`
+}
+
+// runPostStoreHook starts hooks.post_store, if set, for the stored note in
+// the background. Close waits for it, up to hooks.timeout; a failure is
+// reported on stderr and never undoes the store.
+func (s *Service) runPostStoreHook(result map[string]any, raw models.RawItemInput) {
+	s.embeddingMu.RLock()
+	command, timeout := s.config.Hooks.PostStore, s.config.Hooks.Timeout
+	s.embeddingMu.RUnlock()
+
+	if command == "" {
+		return
+	}
+
+	if timeout <= 0 {
+		timeout = config.DefaultHookTimeout
+	}
+
+	h := postStoreHook{Title: raw.Title, Source: s.canonicalSource(raw.Source)}
+	h.ID, _ = result["id"].(string)
+	h.Action, _ = result["action"].(string)
+	h.Project, _ = result["project"].(string)
+	h.FilePath, _ = result["file_path"].(string)
+
+	if item, ok := result["item"].(map[string]any); ok {
+		h.Title, _ = item["title"].(string)
+	}
+
+	s.hooks.Go(func() {
+		if err := runHook(command, h, timeout); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: post_store hook failed: %v\n", err)
+		}
+	})
+}
+
+// runHook runs command through the shell with h as JSON on stdin and as
+// PANTRY_* environment variables, killing it after timeout.
+func runHook(command string, h postStoreHook, timeout time.Duration) error {
+	payload, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command) //nolint:gosec
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command) //nolint:gosec
+	}
+
+	// A background child of the shell can hold stderr open after the shell
+	// is killed; stop waiting for it shortly after.
+	cmd.WaitDelay = time.Second
+
+	var stderr bytes.Buffer
+
+	cmd.Stdin = bytes.NewReader(append(payload, '\n'))
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(),
+		"PANTRY_ITEM_ID="+h.ID,
+		"PANTRY_ACTION="+h.Action,
+		"PANTRY_PROJECT="+h.Project,
+		"PANTRY_TITLE="+h.Title,
+		"PANTRY_FILE_PATH="+h.FilePath,
+	)
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("killed after %v", timeout)
+		}
+
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,392 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"pantry/internal/db"
+	"pantry/internal/search"
+)
+
+// SearchOption customizes a single Service.Search call.
+type SearchOption func(*searchOptions)
+
+// searchOptions holds the settings applied by SearchOption values.
+type searchOptions struct {
+	queryOpts      []db.QueryOption
+	embeddingModel string
+	anchorID       string
+	halfLifeDays   *float64
+	fallbackAll    bool
+	timeout        *time.Duration
+	near           *nearBoost
+	groupThreshold float64
+	dedupBy        string
+	caseSensitive  bool
+	minFTS         *int
+	ctx            context.Context
+	// degraded is set by a search whose query embedding timed out or was
+	// cancelled, leaving keyword results only; those are not cached.
+	degraded bool
+	// cacheKey records each option and its arguments, so the search cache
+	// only shares results between identical searches.
+	cacheKey []string
+}
+
+// nearBoost is the soft form of WithNear.
+type nearBoost struct {
+	target time.Time
+	window time.Duration
+}
+
+// WithProjectGlob restricts a search to projects matching a shell-style glob
+// such as "acme-*".
+func WithProjectGlob(pattern string) SearchOption {
+	return func(o *searchOptions) {
+		o.queryOpts = append(o.queryOpts, db.WithProjectGlob(pattern))
+		o.cacheKey = append(o.cacheKey, "glob="+pattern)
+	}
+}
+
+// WithMeta restricts a search to notes whose metadata has key set to value.
+func WithMeta(key, value string) SearchOption {
+	return func(o *searchOptions) {
+		o.queryOpts = append(o.queryOpts, db.WithMeta(key, value))
+		o.cacheKey = append(o.cacheKey, "meta="+key+"="+value)
+	}
+}
+
+// WithEmbeddingModel embeds the query with model instead of the configured one,
+// using the configured provider. Stored vectors are not re-embedded, so the
+// model must produce vectors of the indexed dimension.
+func WithEmbeddingModel(model string) SearchOption {
+	return func(o *searchOptions) {
+		o.embeddingModel = model
+		o.cacheKey = append(o.cacheKey, "model="+model)
+	}
+}
+
+// WithinAnchor searches semantically near the note anchorID: its vector is
+// blended with the query vector (weighted by search.anchor_weight) and the
+// result merged with a keyword search for the query. The anchor itself is
+// left out of the results.
+func WithinAnchor(anchorID string) SearchOption {
+	return func(o *searchOptions) {
+		o.anchorID = anchorID
+		o.cacheKey = append(o.cacheKey, "within="+anchorID)
+	}
+}
+
+// WithRecencyHalfLife overrides search.recency_half_life_days for one search:
+// scores are halved for every days of a note's age. 0 turns the boost off.
+func WithRecencyHalfLife(days float64) SearchOption {
+	return func(o *searchOptions) {
+		o.halfLifeDays = &days
+		o.cacheKey = append(o.cacheKey, fmt.Sprintf("halflife=%v", days))
+	}
+}
+
+// WithFallbackAll tops up a project-scoped search that returns fewer than
+// limit results with matches from other projects, marked CrossProject.
+// It has no effect on unscoped searches.
+func WithFallbackAll() SearchOption {
+	return func(o *searchOptions) {
+		o.fallbackAll = true
+		o.cacheKey = append(o.cacheKey, "fallback=all")
+	}
+}
+
+// WithTimeout bounds the query embedding of a hybrid search, overriding
+// search.timeout. When it runs out the keyword results are returned alone.
+// 0 means no limit.
+func WithTimeout(d time.Duration) SearchOption {
+	return func(o *searchOptions) {
+		o.timeout = &d
+		o.cacheKey = append(o.cacheKey, "timeout="+d.String())
+	}
+}
+
+// WithNear favours notes created around target. With restrict, only notes
+// within window of target are returned; otherwise scores are halved for
+// every window of distance from target, and recent-note listings are ordered
+// by closeness to it.
+func WithNear(target time.Time, window time.Duration, restrict bool) SearchOption {
+	return func(o *searchOptions) {
+		o.cacheKey = append(o.cacheKey, fmt.Sprintf("near=%s/%s/%t", target.UTC().Format(time.RFC3339), window, restrict))
+
+		if restrict {
+			o.queryOpts = append(o.queryOpts, db.WithCreatedBetween(target.Add(-window), target.Add(window)))
+
+			return
+		}
+
+		o.near = &nearBoost{target: target, window: window}
+		o.queryOpts = append(o.queryOpts, db.WithOrderNear(target))
+	}
+}
+
+// WithRecentlyUpdated lists recent notes by when they were last changed
+// rather than created. Searches with a query are not affected.
+func WithRecentlyUpdated() SearchOption {
+	return func(o *searchOptions) {
+		o.cacheKey = append(o.cacheKey, "updated")
+		o.queryOpts = append(o.queryOpts, db.WithOrderUpdated())
+	}
+}
+
+// WithChangedSince restricts results to notes created or updated at or after
+// t, so a repeated context pull returns only what changed in between.
+func WithChangedSince(t time.Time) SearchOption {
+	return func(o *searchOptions) {
+		o.cacheKey = append(o.cacheKey, "since="+t.UTC().Format(time.RFC3339))
+		o.queryOpts = append(o.queryOpts, db.WithChangedSince(t))
+	}
+}
+
+// WithGroupThreshold collapses results whose title and what overlap by at
+// least threshold (0..1) into the best-ranked of them, which lists the others
+// in Similar. 0 turns grouping off.
+func WithGroupThreshold(threshold float64) SearchOption {
+	return func(o *searchOptions) {
+		o.groupThreshold = threshold
+		o.cacheKey = append(o.cacheKey, fmt.Sprintf("group=%v", threshold))
+	}
+}
+
+// WithDeduplicateBy keeps only the best-ranked of the results sharing field,
+// one of search.DedupFields. It applies after WithGroupThreshold.
+func WithDeduplicateBy(field string) SearchOption {
+	return func(o *searchOptions) {
+		o.dedupBy = field
+		o.cacheKey = append(o.cacheKey, "dedup="+field)
+	}
+}
+
+// WithCaseSensitive keeps only results holding the query terms in the case
+// they were typed (see search.MatchCase). It applies before grouping.
+func WithCaseSensitive() SearchOption {
+	return func(o *searchOptions) {
+		o.caseSensitive = true
+		o.cacheKey = append(o.cacheKey, "case")
+	}
+}
+
+// WithMinFTSResults overrides search.DefaultMinFTSResults for one search: the
+// query is embedded for a hybrid merge only when fewer keyword matches than
+// n are found. A high n embeds almost every query; 0 never does.
+func WithMinFTSResults(n int) SearchOption {
+	return func(o *searchOptions) {
+		o.minFTS = &n
+		o.cacheKey = append(o.cacheKey, fmt.Sprintf("minfts=%d", n))
+	}
+}
+
+// WithSearchContext embeds the query under ctx, so cancelling it abandons an
+// in-flight embedding call and Search returns ctx's error. It does not change
+// the results, so it is not part of the cache key.
+func WithSearchContext(ctx context.Context) SearchOption {
+	return func(o *searchOptions) {
+		o.ctx = ctx
+	}
+}
+
+// context returns the WithSearchContext context, or context.Background().
+func (o *searchOptions) context() context.Context {
+	return orBackground(o.ctx)
+}
+
+// orBackground returns ctx, or context.Background() when it is nil.
+func orBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+
+	return ctx
+}
+
+// minFTSResults returns the WithMinFTSResults threshold or the default.
+func (o *searchOptions) minFTSResults() int {
+	if o.minFTS != nil {
+		return *o.minFTS
+	}
+
+	return search.DefaultMinFTSResults
+}
+
+func newSearchOptions(opts []SearchOption) *searchOptions {
+	o := &searchOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// StoreOption customizes a single Service.Store call.
+type StoreOption func(*storeOptions)
+
+// storeOptions holds the settings applied by StoreOption values.
+type storeOptions struct {
+	dedupScope  string
+	forceCreate bool
+	updateID    string
+	dryRun      bool
+	ctx         context.Context
+}
+
+// WithDedupScope overrides the configured dedup.scope ("project" or "global")
+// for one Store call.
+func WithDedupScope(scope string) StoreOption {
+	return func(o *storeOptions) {
+		o.dedupScope = scope
+	}
+}
+
+// WithForceCreate always creates a new note, even when dedup would have
+// updated an existing one. The similar note is then linked as related_to.
+func WithForceCreate() StoreOption {
+	return func(o *storeOptions) {
+		o.forceCreate = true
+	}
+}
+
+// WithDryRun makes Store work out what it would do, after redaction, project
+// resolution and the dedup lookup, without writing anything. The result has
+// "dry_run" set, no "id" for a note it would create, and "item" shows the
+// note as it would be stored.
+func WithDryRun() StoreOption {
+	return func(o *storeOptions) {
+		o.dryRun = true
+	}
+}
+
+// WithStoreContext embeds the note under ctx. Cancelling it abandons the
+// embedding call; the note itself is still stored, without a vector until
+// the next reindex. With embedding.on_store async the background embedding
+// is not cancelled with ctx, since Store has already returned.
+func WithStoreContext(ctx context.Context) StoreOption {
+	return func(o *storeOptions) {
+		o.ctx = ctx
+	}
+}
+
+func newStoreOptions(opts []StoreOption) *storeOptions {
+	o := &storeOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// ReindexOption customizes a Service.Reindex call.
+type ReindexOption func(*reindexOptions)
+
+// reindexOptions holds the settings applied by ReindexOption values.
+type reindexOptions struct {
+	resume      bool
+	concurrency int
+	provider    string
+	model       string
+	ctx         context.Context
+}
+
+// WithResume continues an interrupted reindex: the vector table is kept and
+// only items without a vector, or with one a fallback provider made, are
+// embedded.
+func WithResume() ReindexOption {
+	return func(o *reindexOptions) {
+		o.resume = true
+	}
+}
+
+// WithConcurrency embeds up to n notes at once. Vectors are still written one
+// at a time, and progress counts completed notes. n below 1 means 1.
+func WithConcurrency(n int) ReindexOption {
+	return func(o *reindexOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithModel reindexes with model, and with provider when it is not empty,
+// instead of the configured ones. config.yaml is switched to them only once
+// every note has been embedded, so a failed attempt leaves searches on the
+// old model.
+func WithModel(provider, model string) ReindexOption {
+	return func(o *reindexOptions) {
+		o.provider = provider
+		o.model = model
+	}
+}
+
+// WithReindexContext runs the reindex under ctx. Cancelling it stops the run
+// like any other embedding failure: vectors already written are kept and
+// --resume continues from there.
+func WithReindexContext(ctx context.Context) ReindexOption {
+	return func(o *reindexOptions) {
+		o.ctx = ctx
+	}
+}
+
+func newReindexOptions(opts []ReindexOption) *reindexOptions {
+	o := &reindexOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithUpdateID updates the note with this ID instead of looking for one by
+// title: tags are merged, details appended and the note re-embedded. It is
+// an error if no such note exists.
+func WithUpdateID(id string) StoreOption {
+	return func(o *storeOptions) {
+		o.updateID = id
+	}
+}
+
+// MergeStrategy decides what Import does with a note whose ID already exists.
+type MergeStrategy string
+
+// Merge strategies accepted by WithMergeStrategy.
+const (
+	// MergeSkip keeps the existing note.
+	MergeSkip MergeStrategy = "skip"
+	// MergeOverwrite replaces the existing note with the imported one.
+	MergeOverwrite MergeStrategy = "overwrite"
+	// MergeNewest keeps whichever note was updated last.
+	MergeNewest MergeStrategy = "newest"
+	// MergeAppendDetails keeps the existing note and appends the imported
+	// details to its own.
+	MergeAppendDetails MergeStrategy = "append-details"
+)
+
+// MergeStrategies lists every MergeStrategy.
+var MergeStrategies = []MergeStrategy{MergeSkip, MergeOverwrite, MergeNewest, MergeAppendDetails}
+
+// ImportOption customizes a Service.Import call.
+type ImportOption func(*importOptions)
+
+// importOptions holds the settings applied by ImportOption values.
+type importOptions struct {
+	merge MergeStrategy
+}
+
+// WithMergeStrategy sets how Import resolves a note whose ID already exists.
+// The default is MergeSkip.
+func WithMergeStrategy(m MergeStrategy) ImportOption {
+	return func(o *importOptions) {
+		o.merge = m
+	}
+}
+
+func newImportOptions(opts []ImportOption) *importOptions {
+	o := &importOptions{merge: MergeSkip}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// vcsMarkers are the entries whose presence in a directory or one of its
+// parents marks it as part of a checked-out project.
+var vcsMarkers = []string{".git", ".hg", ".svn", ".jj"}
+
+// projectInferenceWarning returns a warning when a project name taken from
+// dir, the working directory, is unlikely to be the one meant: dir could not
+// be determined, is the filesystem root, the home directory or the temp
+// directory, or is not inside a version-controlled tree. It returns "" when
+// dir looks like a project.
+func projectInferenceWarning(dir, home string) string {
+	project := filepath.Base(dir)
+
+	var reason string
+
+	switch {
+	case dir == "unknown":
+		reason = "the current directory could not be determined"
+	case filepath.Dir(dir) == dir:
+		reason = "the current directory is the filesystem root"
+	case home != "" && filepath.Clean(dir) == filepath.Clean(home):
+		reason = "the current directory is your home directory"
+	case filepath.Clean(dir) == filepath.Clean(os.TempDir()):
+		reason = "the current directory is the temp directory"
+	case !underVCS(dir):
+		reason = "the current directory is not inside a repository"
+	default:
+		return ""
+	}
+
+	return fmt.Sprintf("project %q was inferred because %s; set the project explicitly (--project) to keep notes together", project, reason)
+}
+
+// underVCS reports whether dir or any of its parents holds a vcsMarkers entry.
+func underVCS(dir string) bool {
+	for {
+		for _, marker := range vcsMarkers {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return true
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+
+		dir = parent
+	}
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pantry/internal/models"
+)
+
+func TestProjectInferenceWarning(t *testing.T) {
+	root := t.TempDir()
+	home := filepath.Join(root, "home", "alice")
+	repo := filepath.Join(home, "src", "pantry")
+	plain := filepath.Join(home, "Downloads")
+
+	for _, dir := range []string{filepath.Join(repo, ".git", "objects"), filepath.Join(repo, "internal"), plain} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		want string // substring of the warning; "" for none
+	}{
+		{"home directory", home, "home directory"},
+		{"filesystem root", string(filepath.Separator), "filesystem root"},
+		{"unknown", "unknown", "could not be determined"},
+		{"no repository", plain, "not inside a repository"},
+		{"repository root", repo, ""},
+		{"repository subdirectory", filepath.Join(repo, "internal"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := projectInferenceWarning(tt.dir, home)
+
+			if tt.want == "" {
+				if got != "" {
+					t.Errorf("projectInferenceWarning(%s) = %q, want none", tt.dir, got)
+				}
+
+				return
+			}
+
+			if !strings.Contains(got, tt.want) || !strings.Contains(got, "--project") {
+				t.Errorf("projectInferenceWarning(%s) = %q, want a warning about the %s", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_Store_ProjectWarning(t *testing.T) {
+	svc, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	defer svc.Close()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(home)
+
+	result, err := svc.Store(models.RawItemInput{Title: "Stored from home", What: "w"}, "")
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	if _, ok := result["project_warning"].(string); !ok {
+		t.Error("Store() from the home directory should warn about the inferred project")
+	}
+
+	result, err = svc.Store(models.RawItemInput{Title: "Stored with a project", What: "w"}, "proj")
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	if w, ok := result["project_warning"]; ok {
+		t.Errorf("Store() with an explicit project warned: %v", w)
+	}
+}
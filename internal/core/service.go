@@ -2,17 +2,21 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"pantry/internal/audit"
 	"pantry/internal/config"
 	"pantry/internal/db"
 	"pantry/internal/embeddings"
+	"pantry/internal/fsutil"
 	"pantry/internal/models"
 	"pantry/internal/redaction"
 	"pantry/internal/search"
@@ -23,6 +27,11 @@ const (
 	// DedupScoreThreshold is the minimum normalized FTS score (0–1) combined
 	// with an exact title match required to treat a new store as an update.
 	DedupScoreThreshold = 0.7
+
+	// NearDuplicateTitleSimilarity is the minimum title similarity (0–1) at
+	// which a dedup-strength match with a different title is reported as a
+	// possible duplicate instead of being merged.
+	NearDuplicateTitleSimilarity = 0.6
 )
 
 // Option is a functional option for NewService.
@@ -33,6 +42,15 @@ func WithStore(s db.Store) Option {
 	return func(svc *Service) { svc.db = s }
 }
 
+// WithEmbeddingProvider injects an embedding provider instead of building one
+// from config, primarily for testing.
+func WithEmbeddingProvider(p embeddings.Provider) Option {
+	return func(svc *Service) {
+		svc.embeddingOnce.Do(func() {})
+		svc.embeddingProvider = p
+	}
+}
+
 // Service is the main orchestrator for pantry operations.
 type Service struct {
 	pantryHome     string
@@ -40,17 +58,26 @@ type Service struct {
 	dbPath         string
 	configPath     string
 	ignorePath     string
+	auditPath      string
+	hooks          sync.WaitGroup // running hooks.post_store commands
+	embeds         sync.WaitGroup // embeddings queued by embedding.on_store async
+	embedQueue     sync.Mutex     // runs queued embeddings one at a time
 	config         *config.Config
 	db             db.Store
 	compiledIgnore []*regexp.Regexp // pre-compiled from .pantryignore
 
 	// Lazy-initialized, protected by sync.Once for safety under concurrent access.
+	// embeddingMu guards config and the lazily-initialized caches so
+	// ReloadConfig can swap them while requests are in flight.
+	embeddingMu       sync.RWMutex
 	embeddingOnce     sync.Once
 	embeddingProvider embeddings.Provider
 	embeddingErr      error
 
 	vectorsOnce      sync.Once
 	vectorsAvailable bool
+
+	searchCache searchCache
 }
 
 // NewService creates a new pantry service. Pass Option values to override
@@ -64,11 +91,7 @@ func NewService(pantryHome string, opts ...Option) (*Service, error) {
 	dbPath := filepath.Join(pantryHome, "index.db")
 	configPath := filepath.Join(pantryHome, "config.yaml")
 	ignorePath := filepath.Join(pantryHome, ".pantryignore")
-
-	// Ensure shelves directory exists
-	if err := os.MkdirAll(shelvesDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create shelves directory: %w", err)
-	}
+	auditPath := filepath.Join(pantryHome, AuditLogFile)
 
 	// Load and validate configuration
 	cfg, err := config.LoadConfig(configPath)
@@ -76,10 +99,24 @@ func NewService(pantryHome string, opts ...Option) (*Service, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
+	config.ApplyOverrides(cfg)
+
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
+	fileMode, dirMode, _ := cfg.Permissions.Modes() // validated above
+
+	// Ensure shelves directory exists
+	if err := fsutil.MkdirAll(shelvesDir, dirMode); err != nil {
+		return nil, fmt.Errorf("failed to create shelves directory: %w", err)
+	}
+
+	// Create the database file with the configured mode; SQLite keeps it.
+	if err := fsutil.Touch(dbPath, fileMode); err != nil {
+		return nil, fmt.Errorf("failed to create database file: %w", err)
+	}
+
 	// Initialize database
 	database, err := db.NewDB(dbPath)
 	if err != nil {
@@ -98,6 +135,7 @@ func NewService(pantryHome string, opts ...Option) (*Service, error) {
 		dbPath:         dbPath,
 		configPath:     configPath,
 		ignorePath:     ignorePath,
+		auditPath:      auditPath,
 		config:         cfg,
 		db:             database,
 		compiledIgnore: redaction.CompilePatterns(ignorePatterns),
@@ -113,16 +151,104 @@ func NewService(pantryHome string, opts ...Option) (*Service, error) {
 // GetEmbeddingProvider returns the embedding provider, lazily initializing if needed.
 // Safe for concurrent use.
 func (s *Service) GetEmbeddingProvider() (embeddings.Provider, error) {
+	s.embeddingMu.RLock()
+	defer s.embeddingMu.RUnlock()
+
 	s.embeddingOnce.Do(func() {
-		s.embeddingProvider, s.embeddingErr = embeddings.NewProvider(s.config.Embedding)
+		s.embeddingProvider, s.embeddingErr = newProvider(s.config.Embedding, s.db.EmbeddingDim())
 	})
 
 	return s.embeddingProvider, s.embeddingErr
 }
 
+// chainProbeTimeout bounds the probe of a fallback chain in newProvider.
+const chainProbeTimeout = 10 * time.Second
+
+// newProvider builds the provider cfg describes. A fallback chain is probed
+// first, so its providers are known to agree on one dimension (indexDim when
+// none answers) before any of their vectors is stored.
+func newProvider(cfg config.EmbeddingConfig, indexDim int) (embeddings.Provider, error) {
+	p, err := embeddings.NewProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	chain, ok := p.(*embeddings.FallbackProvider)
+	if !ok {
+		return p, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), chainProbeTimeout)
+	defer cancel()
+
+	if err := chain.Probe(ctx, indexDim); err != nil {
+		embeddings.CloseIdleConnections(p)
+
+		return nil, fmt.Errorf("embedding.fallback: %w", err)
+	}
+
+	return p, nil
+}
+
+// embedText embeds text with provider and returns the model that made the
+// vector: in a fallback chain the model of the provider that answered,
+// otherwise model.
+func embedText(ctx context.Context, provider embeddings.Provider, model, text string) ([]float32, string, error) {
+	if chain, ok := provider.(*embeddings.FallbackProvider); ok {
+		return chain.EmbedModel(ctx, text)
+	}
+
+	embedding, err := provider.Embed(ctx, text)
+
+	return embedding, model, err
+}
+
+// ReloadConfig re-reads config.yaml and resets the embedding provider and
+// vector availability caches, so the next call to GetEmbeddingProvider builds
+// a provider from the new settings. Idle
+// connections of the old provider are released. On error the current
+// configuration is kept.
+func (s *Service) ReloadConfig() error {
+	defer s.searchCache.invalidate()
+
+	cfg, err := config.LoadConfig(s.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	config.ApplyOverrides(cfg)
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	s.setConfig(cfg)
+
+	return nil
+}
+
+// setConfig swaps in cfg and resets the caches built from the old one.
+func (s *Service) setConfig(cfg *config.Config) {
+	s.embeddingMu.Lock()
+	old := s.embeddingProvider
+	s.config = cfg
+	s.embeddingOnce = sync.Once{}
+	s.embeddingProvider = nil
+	s.embeddingErr = nil
+	s.vectorsOnce = sync.Once{}
+	s.embeddingMu.Unlock()
+
+	if old != nil {
+		embeddings.CloseIdleConnections(old)
+	}
+}
+
 // VectorsAvailable checks if vector operations are available.
 // Safe for concurrent use.
 func (s *Service) VectorsAvailable() bool {
+	s.embeddingMu.RLock()
+	defer s.embeddingMu.RUnlock()
+
 	s.vectorsOnce.Do(func() {
 		s.vectorsAvailable = s.db.HasVecTable()
 	})
@@ -132,11 +258,75 @@ func (s *Service) VectorsAvailable() bool {
 
 // CountItems returns the total number of stored notes, optionally filtered.
 func (s *Service) CountItems(project *string, source *string) (int64, error) {
-	return s.db.CountItems(project, source)
+	return s.db.CountItems(project, s.canonicalSource(source))
+}
+
+// Store stores an item in the pantry. The result's "redactions" entry maps
+// each redacted field to the number of spans scrubbed from it. A new note
+// that closely matches an existing one under a slightly different title
+// carries that note's id and title in "possible_duplicate".
+// WithUpdateID targets an existing note directly, skipping the dedup lookup.
+// If a note with the same title already exists within the dedup scope it is
+// updated in place instead; with global scope the updated note keeps its own
+// project, which is reported under "project" in the result. An empty project
+// is taken from the working directory; if that looks like the wrong place,
+// such as the home directory, "project_warning" says so. With audit.enabled
+// the change is recorded in the audit log, and hooks.post_store is started;
+// neither happens for WithDryRun.
+func (s *Service) Store(raw models.RawItemInput, project string, opts ...StoreOption) (map[string]any, error) {
+	defer s.searchCache.invalidate()
+
+	result, err := s.store(raw, project, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if project == "" {
+		dir := getCurrentDir()
+		home, _ := os.UserHomeDir()
+
+		if stored, _ := result["project"].(string); stored == filepath.Base(dir) {
+			if warning := projectInferenceWarning(dir, home); warning != "" {
+				result["project_warning"] = warning
+			}
+		}
+	}
+
+	if dryRun, _ := result["dry_run"].(bool); dryRun {
+		return result, nil
+	}
+
+	if s.auditEnabled() {
+		id, _ := result["id"].(string)
+		action, _ := result["action"].(string)
+		storedProject, _ := result["project"].(string)
+
+		title := raw.Title
+		if item, ok := result["item"].(map[string]any); ok {
+			title, _ = item["title"].(string)
+		}
+
+		s.writeAudit(audit.Entry{Action: action, ItemID: id, Title: title, Project: storedProject, Source: s.canonicalSource(raw.Source)})
+	}
+
+	s.runPostStoreHook(result, raw)
+
+	return result, nil
 }
 
-// Store stores an item in the pantry.
-func (s *Service) Store(raw models.RawItemInput, project string) (map[string]any, error) {
+// store is Store without the audit entry.
+func (s *Service) store(raw models.RawItemInput, project string, opts ...StoreOption) (map[string]any, error) {
+	o := newStoreOptions(opts)
+	if o.dedupScope == "" {
+		s.embeddingMu.RLock()
+		o.dedupScope = s.config.Dedup.Scope
+		s.embeddingMu.RUnlock()
+	}
+
+	if err := config.ValidateDedupScope(o.dedupScope); err != nil {
+		return nil, err
+	}
+
 	if project == "" {
 		project = filepath.Base(getCurrentDir())
 	}
@@ -144,32 +334,56 @@ func (s *Service) Store(raw models.RawItemInput, project string) (map[string]any
 	today := time.Now().UTC().Format("2006-01-02")
 	projectDir := filepath.Join(s.shelvesDir, project)
 
+	fileMode, dirMode := s.modes()
+
 	// Ensure project directory exists
-	if err := os.MkdirAll(projectDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create project directory: %w", err)
+	if !o.dryRun {
+		if err := fsutil.MkdirAll(projectDir, dirMode); err != nil {
+			return nil, fmt.Errorf("failed to create project directory: %w", err)
+		}
 	}
 
 	// Redact all text fields using pre-compiled patterns
-	raw.What = redaction.RedactCompiled(raw.What, s.compiledIgnore)
-	if raw.Why != nil {
-		redacted := redaction.RedactCompiled(*raw.Why, s.compiledIgnore)
-		raw.Why = &redacted
+	redactions := s.redactRaw(&raw)
+	redacted := len(redactions) > 0
+
+	raw.Source = s.canonicalSource(raw.Source)
+
+	if defaults := s.categoryDefaultTags(raw.Category); len(defaults) > 0 {
+		raw.Tags = mergeTags(raw.Tags, defaults)
 	}
 
-	if raw.Impact != nil {
-		redacted := redaction.RedactCompiled(*raw.Impact, s.compiledIgnore)
-		raw.Impact = &redacted
+	if o.updateID != "" && o.dryRun {
+		return s.dryRunUpdateByID(o.updateID, raw, today, redactions)
 	}
 
-	if raw.Details != nil {
-		redacted := redaction.RedactCompiled(*raw.Details, s.compiledIgnore)
-		raw.Details = &redacted
+	if o.updateID != "" {
+		return s.updateByID(orBackground(o.ctx), o.updateID, raw, today, redactions)
 	}
 
-	// Dedup check: look for similar existing item in same project
-	if result, err := s.tryDedup(raw, project, today); err != nil {
+	// Dedup check: look for similar existing item within the dedup scope
+	similar, near, err := s.findDedupCandidate(raw, project, o.dedupScope)
+	if err != nil {
 		return nil, err
-	} else if result != nil {
+	}
+
+	if o.dryRun {
+		if similar != nil && !o.forceCreate {
+			return s.dryRunUpdate(*similar, raw, today, redactions)
+		}
+
+		return dryRunStore(raw, project, filepath.Join(projectDir, today+"-notes.md"), similar, near, redactions), nil
+	}
+
+	if similar != nil && !o.forceCreate {
+		result, err := s.mergeInto(*similar, raw, today)
+		if err != nil {
+			return nil, err
+		}
+
+		result["item"] = s.storedItemSummary(similar.ID, redacted)
+		result["redactions"] = redactions
+
 		return result, nil
 	}
 
@@ -178,7 +392,7 @@ func (s *Service) Store(raw models.RawItemInput, project string) (map[string]any
 	item := models.FromRaw(raw, project, filePath)
 
 	// Write markdown file
-	if _, err := storage.WriteNoteItem(projectDir, item, today, raw.Details); err != nil {
+	if _, err := storage.WriteNoteItem(projectDir, item, today, raw.Details, fileMode); err != nil {
 		return nil, fmt.Errorf("failed to write session file: %w", err)
 	}
 
@@ -188,209 +402,243 @@ func (s *Service) Store(raw models.RawItemInput, project string) (map[string]any
 		return nil, fmt.Errorf("failed to insert item: %w", err)
 	}
 
-	// Generate and store embedding
-	provider, err := s.GetEmbeddingProvider()
-	if err == nil {
-		embedText := fmt.Sprintf("%s %s %s %s %s", item.Title, item.What, getString(item.Why), getString(item.Impact), strings.Join(item.Tags, " "))
+	s.embedItem(orBackground(o.ctx), rowid, item)
 
-		embedding, err := provider.Embed(context.Background(), embedText)
-		if err == nil {
-			if err := s.db.EnsureVecTable(len(embedding)); err == nil {
-				_ = s.db.InsertVector(rowid, embedding)
-			}
-		}
+	result := map[string]any{
+		"id":         item.ID,
+		"file_path":  filePath,
+		"action":     "created",
+		"project":    project,
+		"item":       s.storedItemSummary(item.ID, redacted),
+		"redactions": redactions,
 	}
 
-	return map[string]any{
-		"id":        item.ID,
-		"file_path": filePath,
-		"action":    "created",
-	}, nil
-}
+	// Forced past a dedup match: keep the relationship instead of losing it.
+	if similar != nil {
+		if err := s.db.InsertLink(item.ID, similar.ID, models.LinkRelatedTo); err != nil {
+			return nil, fmt.Errorf("failed to link similar note: %w", err)
+		}
 
-// Search searches items using hybrid FTS + vector search.
-func (s *Service) Search(query string, limit int, project *string, source *string, useVectors bool) ([]models.SearchResult, error) {
-	provider, err := s.GetEmbeddingProvider()
-	if err != nil || !useVectors || !s.VectorsAvailable() {
-		// FTS-only path
-		return s.db.FTSSearch(query, limit, project, source)
+		result["related_to"] = similar.ID
 	}
 
-	// Use tiered search: FTS first, embed only if sparse results
-	return search.TieredSearch(context.Background(), s.db, provider, query, limit, search.DefaultMinFTSResults, project, source)
-}
-
-// GetContext gets item pointers for context injection.
-func (s *Service) GetContext(limit int, project *string, source *string, query *string, semanticMode string, topupRecent bool) ([]models.SearchResult, int64, error) {
-	total, err := s.db.CountItems(project, source)
-	if err != nil {
-		return nil, 0, err
+	// Not merged, but probably the same note under a reworded title.
+	if near != nil {
+		result["possible_duplicate"] = map[string]any{
+			"id":    near.ID,
+			"title": near.Title,
+		}
 	}
 
-	var results []models.SearchResult
+	return result, nil
+}
 
-	if query != nil {
-		useVectors := semanticMode == "always" || (semanticMode == "auto" && s.VectorsAvailable())
+// redactRaw redacts the title, free-text fields and metadata values of raw in
+// place and returns the number of redactions per field. Unchanged fields are
+// omitted.
+func (s *Service) redactRaw(raw *models.RawItemInput) map[string]int {
+	counts := make(map[string]int)
 
-		results, err = s.Search(*query, limit, project, source, useVectors)
-		if err != nil {
-			return nil, 0, err
+	redact := func(field, text string) string {
+		out := redaction.RedactCompiled(text, s.compiledIgnore)
+		if n := redaction.Count(text, out); n > 0 {
+			counts[field] = n
 		}
 
-		if topupRecent && len(results) < limit {
-			results = s.topupWithRecent(results, limit, project, source)
-		}
-	} else {
-		results, err = s.db.ListRecent(limit, project, source)
-		if err != nil {
-			return nil, 0, err
-		}
+		return out
 	}
 
-	return results, total, nil
-}
-
-// GetDetails gets full details for an item.
-func (s *Service) GetDetails(itemID string) (*models.ItemDetail, error) {
-	return s.db.GetDetails(itemID)
-}
+	raw.Title = redact("title", raw.Title)
+	raw.What = redact("what", raw.What)
 
-// Remove removes an item from pantry.
-func (s *Service) Remove(itemID string) (bool, error) {
-	return s.db.DeleteItem(itemID)
-}
+	if raw.Why != nil {
+		redacted := redact("why", *raw.Why)
+		raw.Why = &redacted
+	}
 
-// Reindex rebuilds the vector table with current embedding provider.
-func (s *Service) Reindex(progressCallback func(current, total int)) (map[string]any, error) {
-	provider, err := s.GetEmbeddingProvider()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get embedding provider: %w", err)
+	if raw.Impact != nil {
+		redacted := redact("impact", *raw.Impact)
+		raw.Impact = &redacted
 	}
 
-	// Detect dimension from provider
-	probe, err := provider.Embed(context.Background(), "dimension probe")
-	if err != nil {
-		return nil, fmt.Errorf("failed to probe embedding dimension: %w", err)
+	if raw.Details != nil {
+		redacted := redact("details", *raw.Details)
+		raw.Details = &redacted
 	}
 
-	dim := len(probe)
+	if len(raw.Metadata) > 0 {
+		metadata := make(map[string]string, len(raw.Metadata))
+		for k, v := range raw.Metadata {
+			metadata[k] = redact("metadata", v)
+		}
 
-	// Drop and recreate vec table
-	if err := s.db.DropVecTable(); err != nil {
-		return nil, fmt.Errorf("failed to drop vec table: %w", err)
+		raw.Metadata = metadata
 	}
 
-	if err := s.db.SetEmbeddingDim(dim); err != nil {
-		return nil, err
-	}
+	return counts
+}
 
-	if err := s.db.EnsureVecTable(dim); err != nil {
-		return nil, err
+// storedItemSummary reads back the persisted item so callers see its final
+// state after redaction and any dedup merge. Returns nil if the read fails.
+func (s *Service) storedItemSummary(itemID string, redacted bool) map[string]any {
+	item, _, err := s.db.GetItem(itemID)
+	if err != nil || item == nil {
+		return nil
 	}
 
-	// Re-embed all items
-	items, err := s.db.ListAllForReindex()
-	if err != nil {
-		return nil, err
+	return itemSummary(item, redacted)
+}
+
+// itemSummary describes item with the keys Store reports it under.
+func itemSummary(item *models.Item, redacted bool) map[string]any {
+	return map[string]any{
+		"title":         item.Title,
+		"what":          item.What,
+		"why":           item.Why,
+		"impact":        item.Impact,
+		"tags":          item.Tags,
+		"category":      item.Category,
+		"related_files": item.RelatedFiles,
+		"metadata":      item.Metadata,
+		"source":        item.Source,
+		"project":       item.Project,
+		"redacted":      redacted,
 	}
+}
 
-	total := len(items)
+// Search searches items using hybrid FTS + vector search, with keyword
+// matches ranked by the search.fts_weights column weights. When a recency
+// half-life is set (search.recency_half_life_days or WithRecencyHalfLife),
+// scores then decay with each note's age. WithFallbackAll fills a sparse
+// project-scoped result from other projects. A query embedding that takes
+// longer than search.timeout (or WithTimeout) is abandoned in favour of the
+// keyword results. WithGroupThreshold folds near-duplicates together and
+// WithDeduplicateBy keeps one result per title or what. With
+// search.cache_ttl set, results are reused for identical searches until it
+// passes or the pantry is written to. A limit of 0 returns as many results
+// as there are keyword matches (see CountMatches).
+func (s *Service) Search(query string, limit int, project *string, source *string, useVectors bool, opts ...SearchOption) ([]models.SearchResult, error) {
+	o := newSearchOptions(opts)
+	source = s.canonicalSource(source)
+
+	if limit == 0 {
+		n, err := s.db.CountFTS(query, project, source, o.queryOpts...)
+		if err != nil {
+			return nil, err
+		}
 
-	for i, item := range items {
-		tags := ""
-		if tagsVal, ok := item["tags"].([]string); ok {
-			tags = strings.Join(tagsVal, " ")
+		if n == 0 {
+			return []models.SearchResult{}, nil
 		}
 
-		embedText := fmt.Sprintf("%s %s %s %s %s",
-			getStringFromMap(item, "title"),
-			getStringFromMap(item, "what"),
-			getStringFromMap(item, "why"),
-			getStringFromMap(item, "impact"),
-			tags)
+		limit = int(n)
+	}
 
-		embedding, err := provider.Embed(context.Background(), embedText)
-		if err != nil {
-			continue
+	s.embeddingMu.RLock()
+	ttl := s.config.Search.CacheTTL
+	weights := s.config.Search.ColumnWeights()
+	s.embeddingMu.RUnlock()
+
+	o.queryOpts = append(o.queryOpts, db.WithColumnWeights(weights))
+
+	if ttl <= 0 {
+		results, err := s.groupedSearch(query, limit, project, source, useVectors, o)
+		if err == nil {
+			err = o.context().Err()
 		}
 
-		rowid, ok := item["rowid"].(int64)
-		if !ok {
-			continue
+		if err != nil {
+			return nil, err
 		}
 
-		_ = s.db.InsertVector(rowid, embedding)
+		return results, nil
+	}
 
-		if progressCallback != nil {
-			progressCallback(i+1, total)
-		}
+	key := searchCacheKey(query, limit, project, source, useVectors, o)
+	if cached, ok := s.searchCache.get(key, time.Now()); ok {
+		return cached, nil
 	}
 
-	return map[string]any{
-		"count": total,
-		"dim":   dim,
-		"model": s.config.Embedding.Model,
-	}, nil
-}
+	gen := s.searchCache.generation()
 
-// Close closes the service and cleans up resources.
-func (s *Service) Close() error {
-	return s.db.Close()
-}
+	results, err := s.groupedSearch(query, limit, project, source, useVectors, o)
+	if err != nil {
+		return nil, err
+	}
 
-// tryDedup checks if a matching item already exists and updates it.
-// Returns (result, nil) if a duplicate was found and updated, (nil, nil) if no duplicate, or (nil, err) on failure.
-func (s *Service) tryDedup(raw models.RawItemInput, project, today string) (map[string]any, error) {
-	dedupQuery := fmt.Sprintf("%s %s", raw.Title, raw.What)
+	// Keyword results left by a cancelled embedding are not worth caching.
+	if err := o.context().Err(); err != nil {
+		return nil, err
+	}
 
-	candidates, err := s.db.FTSSearch(dedupQuery, 5, &project, nil)
-	if err != nil || len(candidates) == 0 {
-		//nolint:nilerr,nilnil
-		return nil, nil
+	// Nor are those left by one that timed out: the next search may embed
+	// in time.
+	if o.degraded {
+		return results, nil
 	}
 
-	broad, _ := s.db.FTSSearch(dedupQuery, 5, nil, nil)
+	s.searchCache.put(key, results, gen, ttl, time.Now())
 
-	maxScore := 0.0
-	if len(broad) > 0 {
-		maxScore = broad[0].Score
-	}
+	return results, nil
+}
 
-	top := candidates[0]
+// groupedSearch runs fallbackSearch and, with WithCaseSensitive, drops
+// results with the query terms in another case, with WithGroupThreshold
+// collapses near-duplicates, then with WithDeduplicateBy drops results
+// repeating a field. It ranks a wider pool so the page stays full after
+// them; a case-sensitive search ranks every keyword match.
+func (s *Service) groupedSearch(query string, limit int, project *string, source *string, useVectors bool, o *searchOptions) ([]models.SearchResult, error) {
+	if o.groupThreshold <= 0 && o.dedupBy == "" && !o.caseSensitive {
+		return s.fallbackSearch(query, limit, project, source, useVectors, o)
+	}
 
-	normalized := 0.0
-	if maxScore > 0 {
-		normalized = top.Score / maxScore
+	if o.dedupBy != "" && !slices.Contains(search.DedupFields, o.dedupBy) {
+		return nil, fmt.Errorf("invalid deduplicate field %q: must be one of %s", o.dedupBy, strings.Join(search.DedupFields, ", "))
 	}
 
-	titleMatch := strings.EqualFold(strings.TrimSpace(raw.Title), strings.TrimSpace(top.Title))
-	if normalized < DedupScoreThreshold || !titleMatch {
-		return nil, nil //nolint:nilnil
+	pool := limit * 3
+
+	if o.caseSensitive {
+		n, err := s.db.CountFTS(query, project, source, o.queryOpts...)
+		if err != nil {
+			return nil, err
+		}
+
+		pool = max(pool, int(n))
 	}
 
-	mergedTags := mergeTags(top.Tags, raw.Tags)
+	results, err := s.fallbackSearch(query, pool, project, source, useVectors, o)
+	if err != nil {
+		return nil, err
+	}
 
-	detailsAppend := ""
-	if raw.Details != nil {
-		detailsAppend = fmt.Sprintf("--- updated %s ---\n%s", today, *raw.Details)
+	if o.caseSensitive {
+		results = search.MatchCase(results, query)
 	}
 
-	if err := s.db.UpdateItem(top.ID, &raw.What, raw.Why, raw.Impact, mergedTags, &detailsAppend); err != nil {
-		return nil, fmt.Errorf("failed to update item: %w", err)
+	results = search.CollapseSimilar(results, o.groupThreshold)
+	results = search.DeduplicateBy(results, o.dedupBy)
+
+	if len(results) > limit {
+		results = results[:limit]
 	}
 
-	return map[string]any{
-		"id":        top.ID,
-		"file_path": top.FilePath,
-		"action":    "updated",
-	}, nil
+	return results, nil
 }
 
-// topupWithRecent appends recent items not already in results until limit is reached.
-func (s *Service) topupWithRecent(results []models.SearchResult, limit int, project *string, source *string) []models.SearchResult {
-	recent, err := s.db.ListRecent(limit, project, source)
+// fallbackSearch runs boostedSearch and, with WithFallbackAll, tops up a
+// sparse project-scoped result from other projects.
+func (s *Service) fallbackSearch(query string, limit int, project *string, source *string, useVectors bool, o *searchOptions) ([]models.SearchResult, error) {
+	results, err := s.boostedSearch(query, limit, project, source, useVectors, o)
+	if err != nil || !o.fallbackAll || project == nil || len(results) >= limit {
+		return results, err
+	}
+
+	// Too few hits in the project: top up from every project, the way
+	// GetContext tops up with recent notes.
+	wider, err := s.boostedSearch(query, limit, nil, source, useVectors, o)
 	if err != nil {
-		return results
+		return nil, err
 	}
 
 	seen := make(map[string]bool, len(results))
@@ -398,35 +646,1709 @@ func (s *Service) topupWithRecent(results []models.SearchResult, limit int, proj
 		seen[r.ID] = true
 	}
 
-	for _, r := range recent {
-		if !seen[r.ID] {
-			results = append(results, r)
-			if len(results) >= limit {
-				break
-			}
+	for _, r := range wider {
+		if len(results) >= limit {
+			break
+		}
+
+		if seen[r.ID] || r.Project == *project {
+			continue
 		}
+
+		r.CrossProject = true
+		results = append(results, r)
 	}
 
-	return results
+	return results, nil
 }
 
-// Helper functions
+// boostedSearch runs search and applies the recency and WithNear boosts, if
+// set.
+func (s *Service) boostedSearch(query string, limit int, project *string, source *string, useVectors bool, o *searchOptions) ([]models.SearchResult, error) {
+	s.embeddingMu.RLock()
+	halfLife := s.config.Search.RecencyHalfLifeDays
+	s.embeddingMu.RUnlock()
 
-// getCurrentDir returns the current working directory, or "unknown" if it
-// cannot be determined. This prevents filepath.Base("") returning "." which
-// would silently be stored as a project name.
-func getCurrentDir() string {
-	dir, err := os.Getwd()
+	if o.halfLifeDays != nil {
+		halfLife = *o.halfLifeDays
+	}
+
+	if halfLife <= 0 && o.near == nil {
+		return s.search(query, limit, project, source, useVectors, o)
+	}
+
+	// Rank a wider pool so a newer note just outside the page can move up.
+	results, err := s.search(query, limit*2, project, source, useVectors, o)
 	if err != nil {
-		return "unknown"
+		return nil, err
 	}
 
-	return dir
-}
+	if halfLife > 0 {
+		search.RecencyBoost(results, halfLife, time.Now().UTC())
+	}
 
-func getString(s *string) string {
-	if s == nil {
-		return ""
+	if o.near != nil {
+		search.NearBoost(results, o.near.target, o.near.window)
+	}
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+// search runs the ranking selected by o, without the recency boost.
+func (s *Service) search(query string, limit int, project *string, source *string, useVectors bool, o *searchOptions) ([]models.SearchResult, error) {
+	if o.anchorID != "" {
+		return s.searchWithin(query, limit, project, source, o)
+	}
+
+	if o.embeddingModel != "" {
+		return s.searchWithModel(query, limit, project, source, o)
+	}
+
+	provider, err := s.GetEmbeddingProvider()
+	if err != nil || !useVectors || !s.VectorsAvailable() {
+		// FTS-only path
+		return s.db.FTSSearch(query, limit, project, source, o.queryOpts...)
+	}
+
+	s.embeddingMu.RLock()
+	timeout := s.config.Search.Timeout
+	s.embeddingMu.RUnlock()
+
+	if o.timeout != nil {
+		timeout = *o.timeout
+	}
+
+	ctx := o.context()
+
+	// A provider that runs past the deadline fails the embedding, and
+	// TieredSearch then returns the keyword results it already has.
+	if timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Use tiered search: FTS first, embed only if sparse results
+	results, err := search.TieredSearch(ctx, s.db, provider, query, limit, o.minFTSResults(), project, source, o.queryOpts...)
+
+	if ctx.Err() != nil {
+		o.degraded = true
+	}
+
+	return results, err
+}
+
+// searchWithin implements WithinAnchor: vector search around a blend of the
+// anchor note and the query, merged with FTS on the query terms. The anchor
+// may be named by an ID prefix.
+func (s *Service) searchWithin(query string, limit int, project *string, source *string, o *searchOptions) ([]models.SearchResult, error) {
+	provider, err := s.GetEmbeddingProvider()
+	if err != nil {
+		return nil, fmt.Errorf("--within needs an embedding provider: %w", err)
+	}
+
+	if !s.VectorsAvailable() {
+		return nil, errors.New("no vector index to search; run 'pantry reindex' first")
+	}
+
+	anchor, err := s.existingItem(o.anchorID)
+	if err != nil {
+		return nil, fmt.Errorf("anchor note: %w", err)
+	}
+
+	ctx := o.context()
+
+	anchorVec, err := provider.Embed(ctx, itemEmbedText(*anchor))
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed anchor note: %w", err)
+	}
+
+	queryVec, err := provider.Embed(embeddings.WithInputType(ctx, embeddings.InputQuery), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	s.embeddingMu.RLock()
+	weight := s.config.Search.AnchorWeight
+	s.embeddingMu.RUnlock()
+
+	blended, err := blendVectors(anchorVec, queryVec, weight)
+	if err != nil {
+		return nil, err
+	}
+
+	ftsResults, err := s.db.FTSSearch(query, limit*2, project, source, o.queryOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// One extra so dropping the anchor still leaves limit*2 candidates.
+	vecResults, err := s.db.VectorSearch(blended, limit*2+1, project, source, o.queryOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	isAnchor := func(r models.SearchResult) bool { return r.ID == anchor.ID }
+	ftsResults = slices.DeleteFunc(ftsResults, isAnchor)
+	vecResults = slices.DeleteFunc(vecResults, isAnchor)
+
+	return search.MergeResults(ftsResults, vecResults, search.DefaultFTSWeight, search.DefaultVecWeight, limit), nil
+}
+
+// blendVectors returns weight*anchor + (1-weight)*query.
+func blendVectors(anchor, query []float32, weight float64) ([]float32, error) {
+	if len(anchor) != len(query) {
+		return nil, fmt.Errorf("%w: anchor has %d, query has %d", db.ErrDimensionMismatch, len(anchor), len(query))
+	}
+
+	w := float32(weight)
+	out := make([]float32, len(anchor))
+
+	for i := range anchor {
+		out[i] = w*anchor[i] + (1-w)*query[i]
+	}
+
+	return out, nil
+}
+
+// itemEmbedText is the text embedded for a note, as Store and Reindex build it.
+func itemEmbedText(item models.Item) string {
+	return fmt.Sprintf("%s %s %s %s %s", item.Title, item.What, getString(item.Why), getString(item.Impact), strings.Join(item.Tags, " "))
+}
+
+// searchWithModel runs a tiered search whose query embedding comes from an
+// ad-hoc provider for o.embeddingModel. Unlike the configured provider, a
+// failure here is an error rather than a silent FTS fallback, since the caller
+// asked for that model explicitly.
+func (s *Service) searchWithModel(query string, limit int, project *string, source *string, o *searchOptions) ([]models.SearchResult, error) {
+	if !s.VectorsAvailable() {
+		return nil, errors.New("no vector index to compare against; run 'pantry reindex' first")
+	}
+
+	s.embeddingMu.RLock()
+	cfg := s.config.Embedding
+	s.embeddingMu.RUnlock()
+
+	cfg.Model = o.embeddingModel
+
+	provider, err := embeddings.NewProvider(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider for model %q: %w", cfg.Model, err)
+	}
+
+	defer embeddings.CloseIdleConnections(provider)
+
+	ctx := o.context()
+
+	embedding, err := provider.Embed(embeddings.WithInputType(ctx, embeddings.InputQuery), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query with model %q: %w", cfg.Model, err)
+	}
+
+	if dim := s.db.EmbeddingDim(); dim != len(embedding) {
+		return nil, fmt.Errorf("%w: index has %d, model %q returned %d", db.ErrDimensionMismatch, dim, cfg.Model, len(embedding))
+	}
+
+	return search.TieredSearch(ctx, s.db, staticProvider(embedding), query, limit, o.minFTSResults(), project, source, o.queryOpts...)
+}
+
+// staticProvider returns a precomputed embedding, so a query that was already
+// embedded is not sent to the provider twice.
+type staticProvider []float32
+
+func (p staticProvider) Embed(context.Context, string) ([]float32, error) {
+	return p, nil
+}
+
+// Facets counts all keyword matches for query by category, source and project.
+func (s *Service) Facets(query string, project *string, source *string, opts ...SearchOption) (db.Facets, error) {
+	o := newSearchOptions(opts)
+
+	return s.db.FTSFacets(query, project, s.canonicalSource(source), o.queryOpts...)
+}
+
+// CountMatches counts all keyword matches for query, without fetching them.
+func (s *Service) CountMatches(query string, project *string, source *string, opts ...SearchOption) (int64, error) {
+	o := newSearchOptions(opts)
+
+	return s.db.CountFTS(query, project, s.canonicalSource(source), o.queryOpts...)
+}
+
+// GetContext gets item pointers for context injection. A limit of 0 lists
+// every note in scope, or every keyword match with a query.
+func (s *Service) GetContext(limit int, project *string, source *string, query *string, semanticMode string, topupRecent bool, opts ...SearchOption) ([]models.SearchResult, int64, error) {
+	o := newSearchOptions(opts)
+	source = s.canonicalSource(source)
+
+	total, err := s.db.CountItems(project, source, o.queryOpts...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if limit == 0 && query == nil {
+		limit = int(total)
+	}
+
+	var results []models.SearchResult
+
+	if query != nil {
+		useVectors := semanticMode == "always" || (semanticMode == "auto" && s.VectorsAvailable())
+
+		results, err = s.Search(*query, limit, project, source, useVectors, opts...)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if topupRecent && len(results) < limit {
+			results = s.topupWithRecent(results, limit, project, source, o.queryOpts...)
+		}
+	} else {
+		results, err = s.db.ListRecent(limit, project, source, o.queryOpts...)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return results, total, nil
+}
+
+// GetDetails gets full details for an item.
+func (s *Service) GetDetails(itemID string) (*models.ItemDetail, error) {
+	return s.db.GetDetails(itemID)
+}
+
+// maxSuggestions caps the spellings Suggest returns.
+const maxSuggestions = 3
+
+// Suggest returns respellings of query built from words in project's notes
+// (all notes when nil), for a search that found nothing. It is empty when
+// no query word is misspelled or none has a close match.
+func (s *Service) Suggest(query string, project *string) ([]string, error) {
+	vocab, err := s.db.Terms(project)
+	if err != nil {
+		return nil, err
+	}
+
+	return search.Suggest(vocab, query, maxSuggestions), nil
+}
+
+// ListSources returns each distinct source with its number of notes.
+func (s *Service) ListSources() ([]db.FacetCount, error) {
+	return s.db.ListSources()
+}
+
+// Stats returns aggregate counts over the notes of project, or every note
+// when nil. See db.DB.Stats.
+func (s *Service) Stats(project *string) (db.StoreStats, error) {
+	return s.db.Stats(project)
+}
+
+// Growth counts the notes created per day, week or month (db.PeriodDay,
+// db.PeriodWeek, db.PeriodMonth) for project, or all projects when nil.
+func (s *Service) Growth(granularity string, project *string) ([]db.PeriodCount, error) {
+	return s.db.CountByPeriod(granularity, project)
+}
+
+// ExportedItem is a note with its details, as returned by Export.
+type ExportedItem struct {
+	models.Item
+	Details *string
+}
+
+// Export returns every note in creation order, with its details body, for
+// project (or all projects when project is nil).
+func (s *Service) Export(project *string) ([]ExportedItem, error) {
+	items, err := s.db.ListItems(project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+
+	exported := make([]ExportedItem, len(items))
+
+	for i, item := range items {
+		exported[i].Item = item
+
+		detail, err := s.db.GetDetails(item.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get details for %s: %w", item.ID, err)
+		}
+
+		if detail != nil {
+			exported[i].Details = &detail.Body
+		}
+	}
+
+	return exported, nil
+}
+
+// ImportResult counts the notes Import added, the existing ones its merge
+// strategy changed, and the ones it skipped.
+type ImportResult struct {
+	Imported int
+	Updated  int
+	Skipped  int
+}
+
+// Import adds exported notes, keeping their IDs and timestamps. A note whose
+// ID already exists is resolved by the merge strategy (MergeSkip unless set
+// with WithMergeStrategy); one that repeats an earlier note in items is
+// skipped. Every note is validated first, then the merges and the batch of new
+// notes are written in one transaction, so a failure leaves the pantry as it
+// was. Each new note is then appended to its project's notes file for the day
+// it was created, and changed notes are embedded when a provider is available.
+func (s *Service) Import(items []ExportedItem, opts ...ImportOption) (ImportResult, error) {
+	defer s.searchCache.invalidate()
+
+	var result ImportResult
+
+	o := newImportOptions(opts)
+	if !slices.Contains(MergeStrategies, o.merge) {
+		return result, &ValidationError{Field: "merge_strategy", Message: fmt.Sprintf("unknown strategy %q", o.merge)}
+	}
+
+	// Validate every note first, so a bad one leaves nothing written.
+	if err := validateImport(items); err != nil {
+		return result, err
+	}
+
+	var (
+		merges  []ExportedItem
+		batch   []models.Item
+		details []*string
+		skipped int
+	)
+
+	seen := make(map[string]bool, len(items))
+
+	for _, exported := range items {
+		if seen[exported.ID] {
+			skipped++
+
+			continue
+		}
+
+		seen[exported.ID] = true
+
+		existing, err := s.itemByExactID(exported.ID)
+		if err != nil {
+			return result, err
+		}
+
+		if existing != nil {
+			merges = append(merges, exported)
+
+			continue
+		}
+
+		batch = append(batch, s.importedItem(exported))
+		details = append(details, exported.Details)
+	}
+
+	var (
+		updated []string
+		rowids  []int64
+	)
+
+	err := s.db.WithTx(func(tx db.Store) error {
+		for _, exported := range merges {
+			changed, err := mergeImported(tx, exported, o.merge)
+			if err != nil {
+				return fmt.Errorf("failed to merge note %s: %w", exported.ID, err)
+			}
+
+			if changed {
+				updated = append(updated, exported.ID)
+			} else {
+				skipped++
+			}
+		}
+
+		var err error
+
+		rowids, err = tx.InsertItems(batch, details)
+		if err != nil {
+			return fmt.Errorf("failed to import notes: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result = ImportResult{Imported: len(batch), Updated: len(updated), Skipped: skipped}
+
+	fileMode, dirMode := s.modes()
+	audited := s.auditEnabled()
+
+	var errs []error
+
+	for _, id := range updated {
+		s.reembed(context.Background(), id)
+
+		if audited {
+			item, _, _ := s.db.GetItem(id)
+			s.auditItem(audit.ActionUpdated, id, item)
+		}
+	}
+
+	for i, item := range batch {
+		projectDir := filepath.Dir(item.FilePath)
+
+		err := fsutil.MkdirAll(projectDir, dirMode)
+		if err == nil {
+			_, err = storage.WriteNoteItem(projectDir, item, noteDate(item), details[i], fileMode)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to write session file for %s: %w", item.ID, err))
+		}
+
+		s.embedItem(context.Background(), rowids[i], item)
+
+		if audited {
+			s.auditItem(audit.ActionCreated, item.ID, &item)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// importedItem builds the note Import inserts for exported, filed in its
+// project's notes file for the day it was created.
+func (s *Service) importedItem(exported ExportedItem) models.Item {
+	date := noteDate(exported.Item)
+	projectDir := filepath.Join(s.shelvesDir, exported.Project)
+
+	raw := models.RawItemInput{
+		Title:        exported.Title,
+		What:         exported.What,
+		Why:          exported.Why,
+		Impact:       exported.Impact,
+		Tags:         exported.Tags,
+		Category:     exported.Category,
+		RelatedFiles: exported.RelatedFiles,
+		Source:       exported.Source,
+		Metadata:     exported.Metadata,
+	}
+
+	item := models.FromRaw(raw, exported.Project, filepath.Join(projectDir, date+"-notes.md"))
+	item.ID = exported.ID
+	item.CreatedAt = exported.CreatedAt
+
+	if exported.UpdatedAt != "" {
+		item.UpdatedAt = exported.UpdatedAt
+	} else {
+		item.UpdatedAt = exported.CreatedAt
+	}
+
+	return item
+}
+
+// validateImport checks that every note in items has the fields Import
+// needs, naming the first one missing, and that each project names a single
+// directory under the shelves, since an export may come from anywhere.
+func validateImport(items []ExportedItem) error {
+	for i, exported := range items {
+		required := []struct{ field, value string }{
+			{"id", exported.ID}, {"title", exported.Title}, {"what", exported.What}, {"project", exported.Project},
+		}
+		for _, r := range required {
+			if r.value == "" {
+				return &ValidationError{Field: r.field, Message: fmt.Sprintf("is required (note %d)", i+1)}
+			}
+		}
+
+		if !validProjectDir(exported.Project) {
+			return &ValidationError{
+				Field:   "project",
+				Message: fmt.Sprintf("%q must be a plain directory name, without path separators or \"..\" (note %d)", exported.Project, i+1),
+			}
+		}
+	}
+
+	return nil
+}
+
+// validProjectDir reports whether project can be joined to the shelves
+// directory without leaving it.
+func validProjectDir(project string) bool {
+	return project != "." &&
+		!strings.ContainsAny(project, `/\`) &&
+		!strings.Contains(project, "..") &&
+		!filepath.IsAbs(project) && filepath.VolumeName(project) == ""
+}
+
+// mergeImported resolves imported against the stored note with the same ID,
+// by strategy, writing through store, and reports whether that note changed.
+// An overwritten note keeps its project and notes file, which is not
+// rewritten (use Replay for that), and its creation time when the import has
+// none.
+func mergeImported(store db.Store, imported ExportedItem, strategy MergeStrategy) (bool, error) {
+	existing, _, err := store.GetItem(imported.ID)
+	if err != nil {
+		return false, err
+	}
+
+	if existing == nil {
+		return false, db.ErrNotFound
+	}
+
+	switch strategy {
+	case MergeNewest:
+		if !updatedAfter(imported.Item, *existing) {
+			return false, nil
+		}
+
+		fallthrough
+	case MergeOverwrite:
+		item := imported.Item
+		if item.CreatedAt == "" {
+			item.CreatedAt = existing.CreatedAt
+		}
+
+		if item.UpdatedAt == "" {
+			item.UpdatedAt = item.CreatedAt
+		}
+
+		if err := store.ReplaceItem(item, imported.Details); err != nil {
+			return false, err
+		}
+
+		return true, nil
+	case MergeAppendDetails:
+		if imported.Details == nil || *imported.Details == "" {
+			return false, nil
+		}
+
+		current, err := store.GetDetails(existing.ID)
+		if err != nil {
+			return false, err
+		}
+
+		// Re-importing the same export must not append the details again.
+		if current != nil && strings.Contains(current.Body, *imported.Details) {
+			return false, nil
+		}
+
+		if err := store.UpdateItem(existing.ID, nil, nil, nil, nil, imported.Details, false); err != nil {
+			return false, err
+		}
+
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// updatedAfter reports whether a was last updated after b. A note without an
+// update time counts as updated when it was created.
+func updatedAfter(a, b models.Item) bool {
+	last := func(item models.Item) time.Time {
+		ts := item.UpdatedAt
+		if ts == "" {
+			ts = item.CreatedAt
+		}
+
+		t, _ := time.Parse(time.RFC3339, ts)
+
+		return t
+	}
+
+	return last(a).After(last(b))
+}
+
+// GetItem returns a single item by ID or unique ID prefix, or nil if it does
+// not exist.
+func (s *Service) GetItem(itemID string) (*models.Item, error) {
+	item, _, err := s.db.GetItem(itemID)
+
+	return item, err
+}
+
+// itemByExactID returns the note whose ID is exactly id, or nil. Unlike
+// GetItem it never matches a prefix, for IDs that come from outside pantry
+// such as an import.
+func (s *Service) itemByExactID(id string) (*models.Item, error) {
+	item, _, err := s.db.GetItem(id)
+	if errors.Is(err, db.ErrIDPrefixTooShort) || errors.Is(err, db.ErrAmbiguousIDPrefix) {
+		return nil, nil //nolint:nilnil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if item != nil && item.ID != id {
+		return nil, nil //nolint:nilnil
+	}
+
+	return item, nil
+}
+
+// Siblings returns the other notes stored in the same notes file as the note
+// with itemID, that is the same project and day, in creation order.
+func (s *Service) Siblings(itemID string) ([]models.Item, error) {
+	item, err := s.existingItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.db.ListByFilePath(item.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(items, func(i models.Item) bool { return i.ID == item.ID }), nil
+}
+
+// Link records a typed link from one note to another. Both notes must exist
+// and linkType must be one of models.ValidLinkTypes. Either ID may be a
+// unique prefix.
+func (s *Service) Link(fromID, toID, linkType string) error {
+	if !slices.Contains(models.ValidLinkTypes, linkType) {
+		return fmt.Errorf("invalid link type %q: must be one of %s", linkType, strings.Join(models.ValidLinkTypes, ", "))
+	}
+
+	ids := []string{fromID, toID}
+
+	for i, id := range ids {
+		item, err := s.existingItem(id)
+		if err != nil {
+			return err
+		}
+
+		ids[i] = item.ID
+	}
+
+	if ids[0] == ids[1] {
+		return errors.New("a note cannot link to itself")
+	}
+
+	return s.db.InsertLink(ids[0], ids[1], linkType)
+}
+
+// Links returns the links from and to a note, given by ID or unique prefix.
+func (s *Service) Links(itemID string) ([]models.NoteLink, error) {
+	item, err := s.existingItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.db.ListLinks(item.ID)
+}
+
+// existingItem returns the note with itemID or a unique prefix of it, and
+// db.ErrNotFound when there is none.
+func (s *Service) existingItem(itemID string) (*models.Item, error) {
+	item, _, err := s.db.GetItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	if item == nil {
+		return nil, fmt.Errorf("%w: %s", db.ErrNotFound, itemID)
+	}
+
+	return item, nil
+}
+
+// Remove removes an item from pantry.
+func (s *Service) Remove(itemID string) (bool, error) {
+	defer s.searchCache.invalidate()
+
+	if !s.auditEnabled() {
+		return s.db.DeleteItem(itemID)
+	}
+
+	// Look the note up first; the entry names what was removed, by full ID.
+	item, _, err := s.db.GetItem(itemID)
+	if err != nil || item == nil {
+		return false, err
+	}
+
+	deleted, err := s.db.DeleteItem(item.ID)
+	if err != nil || !deleted {
+		return deleted, err
+	}
+
+	s.auditItem(audit.ActionRemoved, item.ID, item)
+
+	return true, nil
+}
+
+// Update changes fields of an existing note; nil fields are left as they are.
+// details is appended to the details body, or replaces it when
+// replaceDetails is set. Text is redacted as in Store. A change to what, why,
+// impact or tags re-embeds the note. The notes markdown is not rewritten; use
+// Replay for that.
+func (s *Service) Update(itemID string, what, why, impact *string, tags []string, details *string, replaceDetails bool) error {
+	defer s.searchCache.invalidate()
+
+	redact := func(text *string) *string {
+		if text == nil {
+			return nil
+		}
+
+		out := redaction.RedactCompiled(*text, s.compiledIgnore)
+
+		return &out
+	}
+
+	// Resolve a prefix once, so the update, re-embed and audit entry all
+	// use the full ID.
+	item, err := s.existingItem(itemID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.UpdateItem(item.ID, redact(what), redact(why), redact(impact), tags, redact(details), replaceDetails); err != nil {
+		return err
+	}
+
+	if what != nil || why != nil || impact != nil || tags != nil {
+		s.reembed(context.Background(), item.ID)
+	}
+
+	if s.auditEnabled() {
+		if updated, _, _ := s.db.GetItem(item.ID); updated != nil {
+			item = updated
+		}
+
+		s.auditItem(audit.ActionUpdated, item.ID, item)
+	}
+
+	return nil
+}
+
+// MigrateLegacyShelf moves notes written by older versions under shelf/ into
+// shelves/ and points the affected items at their new files. See
+// storage.MigrateLegacyShelf for how conflicts are handled.
+func (s *Service) MigrateLegacyShelf() (storage.MigrationResult, error) {
+	defer s.searchCache.invalidate()
+
+	legacyDir := filepath.Join(s.pantryHome, storage.LegacyShelfDir)
+	_, dirMode := s.modes()
+
+	result, err := storage.MigrateLegacyShelf(legacyDir, s.shelvesDir, dirMode)
+	if err != nil {
+		return result, fmt.Errorf("failed to migrate %s: %w", legacyDir, err)
+	}
+
+	if len(result.Moved) > 0 {
+		if _, err := s.db.RewriteFilePaths(legacyDir, s.shelvesDir); err != nil {
+			return result, fmt.Errorf("failed to update note file paths: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// Replay regenerates the daily notes markdown under shelves/ from the database.
+// With an item ID, the notes file containing that item is rebuilt; otherwise every
+// notes file for project (or for all projects when project is nil) is rebuilt.
+// Existing files are replaced, since the database is the source of truth.
+// Returns the paths of the files written.
+func (s *Service) Replay(itemID string, project *string) ([]string, error) {
+	var items []models.Item
+
+	if itemID != "" {
+		item, _, err := s.db.GetItem(itemID)
+		if err != nil {
+			return nil, err
+		}
+
+		if item == nil {
+			return nil, fmt.Errorf("%w: %s", db.ErrNotFound, itemID)
+		}
+
+		siblings, err := s.db.ListItems(&item.Project)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, sib := range siblings {
+			if noteDate(sib) == noteDate(*item) {
+				items = append(items, sib)
+			}
+		}
+	} else {
+		var err error
+
+		items, err = s.db.ListItems(project)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fileMode, dirMode := s.modes()
+
+	var files []string
+
+	seen := make(map[string]bool)
+
+	for _, item := range items {
+		date := noteDate(item)
+		projectDir := filepath.Join(s.shelvesDir, item.Project)
+		filePath := filepath.Join(projectDir, date+"-notes.md")
+
+		if !seen[filePath] {
+			seen[filePath] = true
+			files = append(files, filePath)
+
+			if err := fsutil.MkdirAll(projectDir, dirMode); err != nil {
+				return nil, fmt.Errorf("failed to create project directory: %w", err)
+			}
+
+			if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to replace notes file: %w", err)
+			}
+		}
+
+		var details *string
+
+		detail, err := s.db.GetDetails(item.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if detail != nil {
+			details = &detail.Body
+		}
+
+		if _, err := storage.WriteNoteItem(projectDir, item, date, details, fileMode); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// noteDate returns the YYYY-MM-DD notes file date an item was stored under.
+func noteDate(item models.Item) string {
+	if len(item.CreatedAt) >= 10 {
+		return item.CreatedAt[:10]
+	}
+
+	return time.Now().UTC().Format("2006-01-02")
+}
+
+// metaReindexModel records which model the current vector index was built
+// with, so a resumed reindex does not mix vectors from different models.
+const metaReindexModel = "reindex_model"
+
+// Reindex rebuilds the vector table with current embedding provider.
+// With WithResume, the existing vectors are kept and only items without one
+// are embedded, provided the model and dimension still match. With WithModel,
+// the index is rebuilt for the given model and config.yaml is updated to it
+// once the rebuild succeeds; if it fails, the previous index is restored.
+// Otherwise an embedding failure stops the reindex and the items embedded so
+// far are kept so it can be resumed.
+func (s *Service) Reindex(progressCallback func(current, total int), opts ...ReindexOption) (map[string]any, error) {
+	defer s.searchCache.invalidate()
+
+	o := newReindexOptions(opts)
+
+	var (
+		provider embeddings.Provider
+		next     *config.Config
+		err      error
+	)
+
+	if o.model != "" {
+		next, provider, err = s.switchedProvider(o.provider, o.model)
+		if provider != nil {
+			defer embeddings.CloseIdleConnections(provider)
+		}
+	} else {
+		provider, err = s.GetEmbeddingProvider()
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get embedding provider: %w", err)
+	}
+
+	// Detect dimension from provider
+	ctx := orBackground(o.ctx)
+
+	probe, err := provider.Embed(ctx, "dimension probe")
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe embedding dimension: %w", err)
+	}
+
+	dim := len(probe)
+	model := s.embeddingModel()
+
+	resumeCmd := "pantry reindex --resume"
+	if next != nil {
+		model = next.Embedding.Model
+		resumeCmd = fmt.Sprintf("pantry reindex --model %s --resume", model)
+	}
+
+	var (
+		items []map[string]any
+		// restore undoes a rebuild for another model, putting back the index
+		// searches still use; nil when there is nothing to put back.
+		restore func(error) error
+	)
+
+	if o.resume && s.db.HasVecTable() {
+		if err := s.checkResumable(model, dim); err != nil {
+			return nil, err
+		}
+
+		items, err = s.db.ListMissingVectors(model)
+	} else {
+		if next != nil && s.db.HasVecTable() {
+			if restore, err = s.backupVecTable(); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := s.resetVecTable(model, dim); err != nil {
+			return nil, restoreOr(restore, err)
+		}
+
+		items, err = s.db.ListAllForReindex()
+	}
+
+	if err != nil {
+		return nil, restoreOr(restore, err)
+	}
+
+	total := len(items)
+
+	if err := s.embedItems(ctx, provider, model, items, max(o.concurrency, 1), progressCallback); err != nil {
+		if restore != nil {
+			return nil, restore(err)
+		}
+
+		return nil, fmt.Errorf("%w (run '%s' to continue)", err, resumeCmd)
+	}
+
+	if next != nil {
+		if err := s.saveEmbeddingModel(next.Embedding); err != nil {
+			return nil, restoreOr(restore, fmt.Errorf("notes were reindexed with %s but the config was not updated: %w", model, err))
+		}
+
+		if err := s.db.DropVecBackup(); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to drop the previous vector index: %v\n", err)
+		}
+
+		s.setConfig(next)
+	}
+
+	return map[string]any{
+		"count":   total,
+		"dim":     dim,
+		"model":   model,
+		"resumed": o.resume,
+	}, nil
+}
+
+// backupVecTable saves the vector index before a rebuild for another model.
+// The returned function restores it, along with the model it was built with,
+// and wraps the error that stopped the rebuild.
+func (s *Service) backupVecTable() (func(error) error, error) {
+	oldModel, ok, err := s.db.GetMeta(metaReindexModel)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		oldModel = s.embeddingModel()
+	}
+
+	if err := s.db.BackupVecTable(); err != nil {
+		return nil, fmt.Errorf("failed to back up vec table: %w", err)
+	}
+
+	return func(cause error) error {
+		err := s.db.RestoreVecTable()
+		if err == nil {
+			err = s.db.SetMeta(metaReindexModel, oldModel)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%w; restoring the previous index also failed: %w", cause, err)
+		}
+
+		return fmt.Errorf("%w; the previous index was restored", cause)
+	}, nil
+}
+
+// restoreOr returns restore(err), or err when there is nothing to restore.
+func restoreOr(restore func(error) error, err error) error {
+	if restore == nil {
+		return err
+	}
+
+	return restore(err)
+}
+
+// saveEmbeddingModel switches config.yaml to emb's provider and model. The
+// rest of the file is kept as written: environment variables and overrides
+// in the running config are not saved.
+func (s *Service) saveEmbeddingModel(emb config.EmbeddingConfig) error {
+	cfg, err := config.LoadFile(s.configPath)
+	if err != nil {
+		return err
+	}
+
+	cfg.Embedding.SetModel(emb.Provider, emb.Model)
+
+	return config.SaveConfig(s.configPath, cfg)
+}
+
+// switchedProvider returns a copy of the config using provider and model for
+// embeddings (see config.EmbeddingConfig.SetModel), and a provider built from
+// it. The service's own config is left alone.
+func (s *Service) switchedProvider(provider, model string) (*config.Config, embeddings.Provider, error) {
+	s.embeddingMu.RLock()
+	next := *s.config
+	s.embeddingMu.RUnlock()
+
+	next.Embedding.SetModel(provider, model)
+
+	if err := next.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	// The index is rebuilt for the new model, so its dimension does not
+	// constrain the chain.
+	p, err := newProvider(next.Embedding, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &next, p, nil
+}
+
+// embeddedItem is a note embedded by an embedItems worker.
+type embeddedItem struct {
+	item      map[string]any
+	embedding []float32
+	model     string
+	err       error
+}
+
+// embedItems embeds items with up to workers calls in flight and inserts each
+// vector as it arrives, recording the model that made it (see embedText).
+// Inserts stay on the calling goroutine, so SQLite sees one writer, and
+// progress is called with a count that only goes up. The first failure stops
+// the run; vectors inserted before it are kept.
+func (s *Service) embedItems(ctx context.Context, provider embeddings.Provider, model string, items []map[string]any, workers int, progress func(current, total int)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan map[string]any)
+	results := make(chan embeddedItem)
+
+	go func() {
+		defer close(jobs)
+
+		for _, item := range items {
+			select {
+			case jobs <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+
+	for range workers {
+		wg.Go(func() {
+			for item := range jobs {
+				embedding, used, err := embedText(ctx, provider, model, reindexText(item))
+
+				select {
+				case results <- embeddedItem{item: item, embedding: embedding, model: used, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		})
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	total := len(items)
+	done := 0
+
+	var failed error
+
+	for r := range results {
+		if failed != nil {
+			continue // drain until the workers have stopped
+		}
+
+		err := r.err
+		if err == nil {
+			if rowid, ok := r.item["rowid"].(int64); ok {
+				err = s.db.InsertVector(rowid, r.embedding)
+			}
+
+			if id, ok := r.item["id"].(string); ok && err == nil && r.model != "" {
+				err = s.db.SetVectorModel(id, r.model)
+			}
+		}
+
+		if err != nil {
+			failed = fmt.Errorf("reindex stopped after %d of %d notes: %w", done, total, err)
+
+			cancel()
+
+			continue
+		}
+
+		done++
+
+		if progress != nil {
+			progress(done, total)
+		}
+	}
+
+	// A cancelled ctx stops the workers without an error of their own.
+	if failed == nil && done < total {
+		return fmt.Errorf("reindex stopped after %d of %d notes: %w", done, total, ctx.Err())
+	}
+
+	return failed
+}
+
+// reindexText is the text embedded for an item listed for reindexing.
+func reindexText(item map[string]any) string {
+	tags := ""
+	if tagsVal, ok := item["tags"].([]string); ok {
+		tags = strings.Join(tagsVal, " ")
+	}
+
+	return fmt.Sprintf("%s %s %s %s %s",
+		getStringFromMap(item, "title"),
+		getStringFromMap(item, "what"),
+		getStringFromMap(item, "why"),
+		getStringFromMap(item, "impact"),
+		tags)
+}
+
+// resetVecTable drops the vector table and recreates it for dim, recording
+// model as the one the index is built with.
+func (s *Service) resetVecTable(model string, dim int) error {
+	if err := s.db.DropVecTable(); err != nil {
+		return fmt.Errorf("failed to drop vec table: %w", err)
+	}
+
+	if err := s.db.SetMeta(metaReindexModel, model); err != nil {
+		return err
+	}
+
+	return s.db.EnsureVecTable(dim, s.vecMetric())
+}
+
+// checkResumable returns an error if the existing index was built with a
+// different model or dimension than the one a resumed reindex would use.
+func (s *Service) checkResumable(model string, dim int) error {
+	if stored := s.db.EmbeddingDim(); stored != dim {
+		return fmt.Errorf("%w: index has %d, provider returned %d; run 'pantry reindex' without --resume", db.ErrDimensionMismatch, stored, dim)
+	}
+
+	if err := s.db.EnsureVecTable(dim, s.vecMetric()); err != nil {
+		return err
+	}
+
+	stored, ok, err := s.db.GetMeta(metaReindexModel)
+	if err != nil {
+		return err
+	}
+
+	if ok && stored != model {
+		return fmt.Errorf("index was built with model %q, current model is %q; run 'pantry reindex' without --resume", stored, model)
+	}
+
+	return nil
+}
+
+// RebuildFTS rebuilds the keyword index from the stored items without
+// touching vectors. Returns the number of items indexed.
+func (s *Service) RebuildFTS() (int64, error) {
+	defer s.searchCache.invalidate()
+
+	return s.db.RebuildFTS()
+}
+
+// Checkpoint empties the database's write-ahead log into the database file.
+// See db.DB.Checkpoint.
+func (s *Service) Checkpoint() error {
+	return s.db.Checkpoint()
+}
+
+// VerifyVectors reports items_vec rows whose item no longer exists. With fix,
+// those rows are deleted and the number removed is returned as well.
+func (s *Service) VerifyVectors(fix bool) ([]int64, int64, error) {
+	defer s.searchCache.invalidate()
+
+	orphans, err := s.db.OrphanVectors()
+	if err != nil || !fix || len(orphans) == 0 {
+		return orphans, 0, err
+	}
+
+	removed, err := s.db.DeleteOrphanVectors()
+
+	return orphans, removed, err
+}
+
+// PruneVectors drops the vector table along with its recorded dimension and
+// metric, leaving search to the keyword index; VectorsAvailable reports false
+// afterwards. A later reindex, or a store with embedding.on_store other than
+// off, builds a new table. Returns the number of vectors dropped.
+func (s *Service) PruneVectors() (int64, error) {
+	defer s.searchCache.invalidate()
+
+	stats, err := s.db.Stats(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.db.DropVecTable(); err != nil {
+		return 0, fmt.Errorf("failed to drop vec table: %w", err)
+	}
+
+	s.embeddingMu.Lock()
+	s.vectorsOnce = sync.Once{}
+	s.embeddingMu.Unlock()
+
+	return stats.Vectors, nil
+}
+
+// CheckEmbeddingDimension embeds a probe text with the configured provider and
+// compares its dimension with the indexed vectors'. indexed is 0 when nothing
+// is indexed yet. A difference is reported as db.ErrDimensionMismatch, the
+// error a later store would otherwise run into.
+func (s *Service) CheckEmbeddingDimension(ctx context.Context) (indexed, probed int, err error) {
+	provider, err := s.GetEmbeddingProvider()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	embedding, err := provider.Embed(ctx, "pantry doctor probe")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	indexed, probed = s.db.EmbeddingDim(), len(embedding)
+	if indexed != 0 && indexed != probed {
+		return indexed, probed, fmt.Errorf("%w: index has %d, provider returns %d. Run 'pantry reindex' to rebuild", db.ErrDimensionMismatch, indexed, probed)
+	}
+
+	return indexed, probed, nil
+}
+
+// DumpSchema returns the database schema and stored meta values.
+func (s *Service) DumpSchema() (*db.SchemaDump, error) {
+	return s.db.DumpSchema()
+}
+
+// Close closes the service and cleans up resources.
+func (s *Service) Close() error {
+	// Let post-store hooks and queued embeddings finish before the process
+	// can exit. A hook still running after hooks.timeout is killed.
+	s.hooks.Wait()
+	s.embeds.Wait()
+
+	s.embeddingMu.RLock()
+	provider := s.embeddingProvider
+	s.embeddingMu.RUnlock()
+
+	if provider != nil {
+		embeddings.CloseIdleConnections(provider)
+	}
+
+	return s.db.Close()
+}
+
+// vecMetric returns the configured vector distance metric.
+func (s *Service) vecMetric() string {
+	s.embeddingMu.RLock()
+	defer s.embeddingMu.RUnlock()
+
+	return s.config.Embedding.Metric
+}
+
+// modes returns the configured file and directory modes.
+func (s *Service) modes() (file, dir os.FileMode) {
+	s.embeddingMu.RLock()
+	defer s.embeddingMu.RUnlock()
+
+	file, dir, _ = s.config.Permissions.Modes() // validated on load
+
+	return file, dir
+}
+
+// embeddingModel returns the configured embedding model name.
+func (s *Service) embeddingModel() string {
+	s.embeddingMu.RLock()
+	defer s.embeddingMu.RUnlock()
+
+	return s.config.Embedding.Model
+}
+
+// findDedupCandidate returns the existing note that raw would be merged into:
+// the top keyword match within scope whose title matches under
+// dedup.title_match and whose normalized score reaches DedupScoreThreshold.
+// When that match's title is only close (see NearDuplicateTitleSimilarity) it
+// is returned as near instead. Both are nil when there is no such match.
+func (s *Service) findDedupCandidate(raw models.RawItemInput, project, scope string) (match, near *models.SearchResult, err error) {
+	dedupQuery := fmt.Sprintf("%s %s", raw.Title, raw.What)
+
+	scopeProject := &project
+	if scope == config.DedupScopeGlobal {
+		scopeProject = nil
+	}
+
+	candidates, err := s.db.FTSSearch(dedupQuery, 5, scopeProject, nil)
+	if err != nil || len(candidates) == 0 {
+		//nolint:nilerr
+		return nil, nil, nil
+	}
+
+	broad, _ := s.db.FTSSearch(dedupQuery, 5, nil, nil)
+
+	maxScore := 0.0
+	if len(broad) > 0 {
+		maxScore = broad[0].Score
+	}
+
+	top := candidates[0]
+
+	normalized := 0.0
+	if maxScore > 0 {
+		normalized = top.Score / maxScore
+	}
+
+	if normalized < DedupScoreThreshold {
+		return nil, nil, nil
+	}
+
+	mode, threshold := s.dedupTitleMatch()
+	if titlesMatch(raw.Title, top.Title, mode, threshold) {
+		return &top, nil, nil
+	}
+
+	if titleSimilarity(raw.Title, top.Title) >= NearDuplicateTitleSimilarity {
+		return nil, &top, nil
+	}
+
+	return nil, nil, nil
+}
+
+// dedupTitleMatch returns the configured dedup.title_match mode and fuzzy
+// threshold, with the threshold defaulted.
+func (s *Service) dedupTitleMatch() (string, float64) {
+	s.embeddingMu.RLock()
+	defer s.embeddingMu.RUnlock()
+
+	threshold := s.config.Dedup.FuzzyThreshold
+	if threshold == 0 {
+		threshold = config.DefaultFuzzyThreshold
+	}
+
+	return s.config.Dedup.TitleMatch, threshold
+}
+
+// mergeInto updates the existing note top with raw's fields, merging tags and
+// metadata and appending details.
+func (s *Service) mergeInto(top models.SearchResult, raw models.RawItemInput, today string) (map[string]any, error) {
+	return mergeItem(s.db, top, raw, today)
+}
+
+// mergeItem is mergeInto writing through store.
+func mergeItem(store db.Store, top models.SearchResult, raw models.RawItemInput, today string) (map[string]any, error) {
+	mergedTags := mergeTags(top.Tags, raw.Tags)
+
+	detailsAppend := ""
+	if raw.Details != nil {
+		detailsAppend = fmt.Sprintf("--- updated %s ---\n%s", today, *raw.Details)
+	}
+
+	if err := store.UpdateItem(top.ID, &raw.What, raw.Why, raw.Impact, mergedTags, &detailsAppend, false); err != nil {
+		return nil, fmt.Errorf("failed to update item: %w", err)
+	}
+
+	if len(raw.Metadata) > 0 {
+		if err := store.SetMetadata(top.ID, raw.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to update metadata: %w", err)
+		}
+	}
+
+	return map[string]any{
+		"id":        top.ID,
+		"file_path": top.FilePath,
+		"action":    "updated",
+		"project":   top.Project,
+	}, nil
+}
+
+// dryRunStore builds Store's result for a WithDryRun call that creates a new
+// note in project, written to filePath. similar is the dedup match that
+// forceCreate went past, if any, and near a possible duplicate.
+func dryRunStore(raw models.RawItemInput, project, filePath string, similar, near *models.SearchResult, redactions map[string]int) map[string]any {
+	item := models.FromRaw(raw, project, filePath)
+
+	result := map[string]any{
+		"dry_run":    true,
+		"file_path":  filePath,
+		"action":     "created",
+		"project":    project,
+		"item":       itemSummary(&item, len(redactions) > 0),
+		"redactions": redactions,
+	}
+
+	if similar != nil {
+		result["related_to"] = similar.ID
+	}
+
+	if near != nil {
+		result["possible_duplicate"] = map[string]any{
+			"id":    near.ID,
+			"title": near.Title,
+		}
+	}
+
+	return result
+}
+
+// dryRunUpdateByID is the WithDryRun form of updateByID. Like it, it takes
+// an ID prefix and reports the full ID.
+func (s *Service) dryRunUpdateByID(itemID string, raw models.RawItemInput, today string, redactions map[string]int) (map[string]any, error) {
+	item, err := s.existingItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	target := models.SearchResult{
+		ID:       item.ID,
+		Tags:     item.Tags,
+		Project:  item.Project,
+		FilePath: item.FilePath,
+	}
+
+	return s.dryRunUpdate(target, raw, today, redactions)
+}
+
+// errDryRun rolls back the transaction dryRunUpdate merges in.
+var errDryRun = errors.New("dry run")
+
+// dryRunUpdate is Store's result for a WithDryRun call that merges raw into
+// target. The merge is run for real, in a transaction that is then rolled
+// back, so the preview shows exactly what Store would leave.
+func (s *Service) dryRunUpdate(target models.SearchResult, raw models.RawItemInput, today string, redactions map[string]int) (map[string]any, error) {
+	var result map[string]any
+
+	err := s.db.WithTx(func(tx db.Store) error {
+		var err error
+
+		result, err = mergeItem(tx, target, raw, today)
+		if err != nil {
+			return err
+		}
+
+		item, _, err := tx.GetItem(target.ID)
+		if err != nil {
+			return err
+		}
+
+		result["item"] = itemSummary(item, len(redactions) > 0)
+
+		return errDryRun
+	})
+	if !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	result["dry_run"] = true
+	result["redactions"] = redactions
+
+	return result, nil
+}
+
+// embedItem stores the vector for a newly inserted item, when
+// embedding.on_store says to (see onStore). Without a working embedding
+// provider, or when ctx is cancelled first, the item is left to keyword
+// search.
+func (s *Service) embedItem(ctx context.Context, rowid int64, item models.Item) {
+	s.onStore(ctx, func(ctx context.Context) {
+		provider, err := s.GetEmbeddingProvider()
+		if err != nil {
+			return
+		}
+
+		embedding, model, err := embedText(ctx, provider, s.embeddingModel(), itemEmbedText(item))
+		if err != nil {
+			return
+		}
+
+		if err := s.db.EnsureVecTable(len(embedding), s.vecMetric()); err != nil {
+			warnUnindexed(item.ID, err)
+
+			return
+		}
+
+		if s.db.InsertVector(rowid, embedding) == nil && model != "" {
+			_ = s.db.SetVectorModel(item.ID, model)
+		}
+	})
+}
+
+// warnUnindexed reports a note whose vector the index refused because the
+// index's dimension or metric no longer matches the configuration. Unlike a
+// provider outage this does not clear up by itself, so it is not left silently
+// to keyword search.
+func warnUnindexed(itemID string, err error) {
+	fmt.Fprintf(os.Stderr, "warning: note %s was not added to the vector index: %v\n", itemID, err)
+}
+
+// onStore runs embed as embedding.on_store says: right away, queued in the
+// background, or not at all. Queued embeddings run one at a time and Close
+// waits for them. A note left without a vector, because of off or an
+// interrupted queue, is embedded by the next reindex (--resume embeds only
+// those). A queued embedding runs under ctx without its cancellation, since
+// the caller has moved on by then.
+func (s *Service) onStore(ctx context.Context, embed func(context.Context)) {
+	s.embeddingMu.RLock()
+	mode := s.config.Embedding.OnStore
+	s.embeddingMu.RUnlock()
+
+	switch mode {
+	case config.OnStoreOff:
+	case config.OnStoreAsync:
+		ctx = context.WithoutCancel(ctx)
+
+		s.embeds.Go(func() {
+			s.embedQueue.Lock()
+			defer s.embedQueue.Unlock()
+
+			embed(ctx)
+			// Searches cached before the vector landed would miss it.
+			s.searchCache.invalidate()
+		})
+	default:
+		embed(ctx)
+	}
+}
+
+// updateByID merges raw into the note with itemID, as Store does for a dedup
+// match, and re-embeds it so vector search sees the new text. itemID may be a
+// prefix; the note is resolved once and its full ID used from then on.
+func (s *Service) updateByID(ctx context.Context, itemID string, raw models.RawItemInput, today string, redactions map[string]int) (map[string]any, error) {
+	item, err := s.existingItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	target := models.SearchResult{
+		ID:       item.ID,
+		Tags:     item.Tags,
+		Project:  item.Project,
+		FilePath: item.FilePath,
+	}
+
+	result, err := s.mergeInto(target, raw, today)
+	if err != nil {
+		return nil, err
+	}
+
+	s.reembed(ctx, item.ID)
+
+	result["item"] = s.storedItemSummary(item.ID, len(redactions) > 0)
+	result["redactions"] = redactions
+
+	return result, nil
+}
+
+// reembed replaces the vector of itemID with one for its current text,
+// following embedding.on_store like embedItem. With off the old vector stays
+// until the next full reindex. Like Store, it does nothing when no embedding
+// provider is available.
+func (s *Service) reembed(ctx context.Context, itemID string) {
+	s.onStore(ctx, func(ctx context.Context) {
+		provider, err := s.GetEmbeddingProvider()
+		if err != nil {
+			return
+		}
+
+		item, _, err := s.db.GetItem(itemID)
+		if err != nil || item == nil {
+			return
+		}
+
+		embedding, model, err := embedText(ctx, provider, s.embeddingModel(), itemEmbedText(*item))
+		if err != nil {
+			return
+		}
+
+		if err := s.db.EnsureVecTable(len(embedding), s.vecMetric()); err != nil {
+			warnUnindexed(itemID, err)
+
+			return
+		}
+
+		if s.db.ReplaceVector(itemID, embedding) == nil && model != "" {
+			_ = s.db.SetVectorModel(itemID, model)
+		}
+	})
+}
+
+// topupWithRecent appends recent items not already in results until limit is reached.
+func (s *Service) topupWithRecent(results []models.SearchResult, limit int, project *string, source *string, opts ...db.QueryOption) []models.SearchResult {
+	recent, err := s.db.ListRecent(limit, project, source, opts...)
+	if err != nil {
+		return results
+	}
+
+	seen := make(map[string]bool, len(results))
+	for _, r := range results {
+		seen[r.ID] = true
+	}
+
+	for _, r := range recent {
+		if !seen[r.ID] {
+			results = append(results, r)
+			if len(results) >= limit {
+				break
+			}
+		}
+	}
+
+	return results
+}
+
+// Helper functions
+
+// getCurrentDir returns the current working directory, or "unknown" if it
+// cannot be determined. This prevents filepath.Base("") returning "." which
+// would silently be stored as a project name.
+func getCurrentDir() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "unknown"
+	}
+
+	return dir
+}
+
+func getString(s *string) string {
+	if s == nil {
+		return ""
 	}
 
 	return *s
@@ -442,6 +2364,19 @@ func getStringFromMap(m map[string]any, key string) string {
 	return ""
 }
 
+// categoryDefaultTags returns the categories.default_tags entry for category,
+// or nil when there is none.
+func (s *Service) categoryDefaultTags(category *string) []string {
+	if category == nil {
+		return nil
+	}
+
+	s.embeddingMu.RLock()
+	defer s.embeddingMu.RUnlock()
+
+	return s.config.Categories.DefaultTags[*category]
+}
+
 func mergeTags(existing []string, extra []string) []string {
 	combined := make([]string, len(existing))
 	copy(combined, existing)
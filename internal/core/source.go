@@ -0,0 +1,92 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// NormalizeSource returns the canonical form of an agent source name: trimmed,
+// lowercased, with runs of spaces and underscores turned into single dashes,
+// then mapped through aliases (config source_aliases). Alias keys are
+// normalized the same way before matching; when several keys normalize to
+// the same name, the first in sorted order wins.
+func NormalizeSource(src string, aliases map[string]string) string {
+	norm := normalizeSourceWords(src)
+
+	for _, from := range slices.Sorted(maps.Keys(aliases)) {
+		if normalizeSourceWords(from) == norm {
+			return normalizeSourceWords(aliases[from])
+		}
+	}
+
+	return norm
+}
+
+func normalizeSourceWords(src string) string {
+	fields := strings.FieldsFunc(strings.ToLower(src), func(r rune) bool {
+		return r == ' ' || r == '_' || r == '\t' || r == '-'
+	})
+
+	return strings.Join(fields, "-")
+}
+
+// canonicalSource normalizes an optional source filter with the configured
+// aliases, so filters match what Store recorded.
+func (s *Service) canonicalSource(source *string) *string {
+	if source == nil {
+		return nil
+	}
+
+	s.embeddingMu.RLock()
+	aliases := s.config.SourceAliases
+	s.embeddingMu.RUnlock()
+
+	norm := NormalizeSource(*source, aliases)
+
+	return &norm
+}
+
+// NormalizeStoredSources rewrites the sources of notes stored before sources
+// were normalized, or under an alias added since, to their canonical form, so
+// the normalized source filter finds them. It returns the number of notes
+// changed. Store normalizes new notes itself; this is for the ones already
+// there, and runs only when asked (pantry sources --normalize).
+func (s *Service) NormalizeStoredSources() (int64, error) {
+	defer s.searchCache.invalidate()
+
+	s.embeddingMu.RLock()
+	aliases := s.config.SourceAliases
+	s.embeddingMu.RUnlock()
+
+	n, err := s.db.RenameSources(func(src string) string { return NormalizeSource(src, aliases) })
+	if err != nil {
+		return 0, fmt.Errorf("failed to normalize note sources: %w", err)
+	}
+
+	return n, nil
+}
+
+// Interfaces a note can be stored through, for DefaultSource.
+const (
+	OriginCLI = "cli"
+	OriginMCP = "mcp"
+)
+
+// DefaultSource returns the configured source for notes stored through
+// origin without one: defaults.cli_source or defaults.mcp_source. It is empty
+// when none is configured.
+func (s *Service) DefaultSource(origin string) string {
+	s.embeddingMu.RLock()
+	defer s.embeddingMu.RUnlock()
+
+	switch origin {
+	case OriginCLI:
+		return s.config.Defaults.CLISource
+	case OriginMCP:
+		return s.config.Defaults.MCPSource
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"pantry/internal/db"
+	"pantry/internal/models"
+	"pantry/internal/storage"
+)
+
+// metaContentHash is the metadata key under which StoreDir records the hash
+// of the file a note was read from.
+const metaContentHash = "content_hash"
+
+// defaultStoreDirGlob is the file name pattern StoreDir uses without one.
+const defaultStoreDirGlob = "*.md"
+
+// StoreDirOptions controls which files StoreDir reads.
+type StoreDirOptions struct {
+	// Recursive also reads files in subdirectories.
+	Recursive bool
+	// Glob filters file names, e.g. "ADR-*.md". Empty means "*.md".
+	Glob string
+	// Progress, if set, is called after each file with whether it was
+	// stored or skipped as already imported.
+	Progress func(path string, stored bool)
+}
+
+// StoreDirResult counts the files StoreDir stored and the ones it skipped
+// because a note from the same content is already in the project.
+type StoreDirResult struct {
+	Stored  int
+	Skipped int
+}
+
+// StoreDir stores every markdown file in dir as a note in project, read with
+// storage.ParseMarkdownNote: the title comes from the first heading or the
+// file name and the document becomes the details. base supplies the other
+// fields, such as tags, category and source. Each note records the hash of
+// its file, and a file whose content is already stored in the project is
+// skipped, so running it again only adds new or changed files.
+func (s *Service) StoreDir(dir, project string, base models.RawItemInput, o StoreDirOptions, opts ...StoreOption) (StoreDirResult, error) {
+	var result StoreDirResult
+
+	glob := o.Glob
+	if glob == "" {
+		glob = defaultStoreDirGlob
+	}
+
+	if _, err := filepath.Match(glob, ""); err != nil {
+		return result, fmt.Errorf("invalid glob %q: %w", glob, err)
+	}
+
+	if project == "" {
+		project = filepath.Base(getCurrentDir())
+	}
+
+	var paths []string
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path != dir && !o.Recursive {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if ok, _ := filepath.Match(glob, d.Name()); ok && d.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for _, path := range paths {
+		stored, err := s.storeFile(path, project, base, opts)
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", path, err)
+		}
+
+		if stored {
+			result.Stored++
+		} else {
+			result.Skipped++
+		}
+
+		if o.Progress != nil {
+			o.Progress(path, stored)
+		}
+	}
+
+	return result, nil
+}
+
+// storeFile stores one markdown file for StoreDir. It returns false when the
+// file's content is already stored in project.
+func (s *Service) storeFile(path, project string, base models.RawItemInput, opts []StoreOption) (bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	existing, err := s.db.CountItems(&project, nil, db.WithMeta(metaContentHash, hash))
+	if err != nil {
+		return false, err
+	}
+
+	if existing > 0 {
+		return false, nil
+	}
+
+	note := storage.ParseMarkdownNote(path, string(content))
+
+	raw := base
+	raw.Title = note.Title
+	raw.What = note.What
+
+	if raw.What == "" {
+		raw.What = note.Title
+	}
+
+	if note.Body != "" {
+		raw.Details = &note.Body
+	}
+
+	raw.Metadata = maps.Clone(base.Metadata)
+	if raw.Metadata == nil {
+		raw.Metadata = make(map[string]string, 1)
+	}
+
+	raw.Metadata[metaContentHash] = hash
+
+	if _, err := s.Store(raw, project, opts...); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"pantry/internal/audit"
+	"pantry/internal/storage"
+)
+
+// RenameTag renames tag to newTag on every note carrying it, in the database
+// and in the tags frontmatter of the notes files. A note that already has
+// newTag keeps one copy. Returns the number of notes changed.
+func (s *Service) RenameTag(tag, newTag string) (int, error) {
+	newTag = strings.TrimSpace(newTag)
+	if newTag == "" {
+		return 0, &ValidationError{Field: "tag", Message: "new tag must not be empty"}
+	}
+
+	if strings.ContainsAny(newTag, ",[]") {
+		return 0, &ValidationError{Field: "tag", Message: "new tag must not contain ',', '[' or ']'"}
+	}
+
+	return s.replaceTag(tag, newTag)
+}
+
+// DeleteTag removes tag from every note carrying it, in the database and in
+// the tags frontmatter of the notes files. Returns the number of notes changed.
+func (s *Service) DeleteTag(tag string) (int, error) {
+	return s.replaceTag(tag, "")
+}
+
+// replaceTag backs RenameTag and DeleteTag. The changed notes are re-embedded,
+// since their tags are part of the embedded text.
+func (s *Service) replaceTag(tag, replacement string) (int, error) {
+	defer s.searchCache.invalidate()
+
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return 0, &ValidationError{Field: "tag", Message: "must not be empty"}
+	}
+
+	ids, err := s.db.ReplaceTag(tag, replacement)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update tags: %w", err)
+	}
+
+	fileMode, _ := s.modes()
+	files := make(map[string]bool)
+
+	var errs []error
+
+	for _, id := range ids {
+		item, _, err := s.db.GetItem(id)
+		if err != nil || item == nil {
+			continue
+		}
+
+		if item.FilePath != "" && !files[item.FilePath] {
+			files[item.FilePath] = true
+
+			err := storage.ReplaceFrontmatterTag(item.FilePath, tag, replacement, fileMode)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+
+		s.reembed(context.Background(), id)
+
+		if s.auditEnabled() {
+			s.auditItem(audit.ActionUpdated, id, item)
+		}
+	}
+
+	return len(ids), errors.Join(errs...)
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"pantry/internal/config"
+	"pantry/internal/search"
+)
+
+// titleStopwords are dropped by normalizeTitle.
+var titleStopwords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "of": true,
+	"to": true, "in": true, "on": true, "for": true, "with": true, "is": true,
+}
+
+// titlesMatch reports whether two titles name the same note under mode (see
+// config.DedupConfig.TitleMatch). threshold applies to fuzzy matching.
+func titlesMatch(a, b, mode string, threshold float64) bool {
+	switch mode {
+	case config.TitleMatchNormalized:
+		return normalizeTitle(a) == normalizeTitle(b)
+	case config.TitleMatchFuzzy:
+		return titleSimilarity(a, b) >= threshold
+	default:
+		return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+	}
+}
+
+// normalizeTitle lowercases a title and keeps only its words, without
+// punctuation or stopwords. A title of nothing but stopwords keeps them.
+func normalizeTitle(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	kept := make([]string, 0, len(words))
+
+	for _, w := range words {
+		if !titleStopwords[w] {
+			kept = append(kept, w)
+		}
+	}
+
+	if len(kept) == 0 {
+		kept = words
+	}
+
+	return strings.Join(kept, " ")
+}
+
+// titleSimilarity returns the Levenshtein ratio of two titles, compared
+// case-insensitively with surrounding space trimmed: 1 for identical titles,
+// falling towards 0 as more edits are needed.
+func titleSimilarity(a, b string) float64 {
+	a = strings.ToLower(strings.TrimSpace(a))
+	b = strings.ToLower(strings.TrimSpace(b))
+
+	longest := max(utf8.RuneCountInString(a), utf8.RuneCountInString(b))
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(search.EditDistance(a, b))/float64(longest)
+}
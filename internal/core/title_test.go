@@ -0,0 +1,35 @@
+package core
+
+import "testing"
+
+func TestTitleSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"Fix auth bug", "fix auth bug ", 1},
+		{"", "", 1},
+		{"abcd", "abce", 0.75},
+		{"abc", "xyz", 0},
+	}
+
+	for _, tt := range tests {
+		if got := titleSimilarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("titleSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := map[string]string{
+		"Fix the auth bug.":   "fix auth bug",
+		"  OAuth2: refresh! ": "oauth2 refresh",
+		"The":                 "the",
+	}
+
+	for in, want := range tests {
+		if got := normalizeTitle(in); got != want {
+			t.Errorf("normalizeTitle(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -5,10 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"math/bits"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	// used to import sqlite vec bindings.
 	_ "github.com/asg017/sqlite-vec-go-bindings/ncruces"
@@ -17,6 +21,7 @@ import (
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 
 	"pantry/internal/gormlite"
@@ -48,7 +53,9 @@ type DB struct {
 
 // NewDB creates a new database connection.
 func NewDB(dbPath string) (*DB, error) {
-	dsn := "file:" + dbPath + "?_pragma=foreign_keys(1)"
+	// Setting any pragma drops the driver's default busy timeout; keep one so
+	// a background writer (embedding.on_store async) waits instead of failing.
+	dsn := "file:" + dbPath + "?_pragma=busy_timeout(10000)&_pragma=foreign_keys(1)"
 
 	gormDB, err := gorm.Open(gormlite.Open(dsn), &gorm.Config{
 		Logger: logger.Discard,
@@ -77,9 +84,38 @@ func (d *DB) HasVecTable() bool {
 	return count > 0
 }
 
-// DropVecTable drops the vector table.
+// DropVecTable drops the vector table and forgets its recorded dimension and
+// metric, and the model recorded for each vector, so the next EnsureVecTable
+// recreates it.
 func (d *DB) DropVecTable() error {
-	return d.db.Exec("DROP TABLE IF EXISTS items_vec").Error
+	if err := d.db.Exec("DROP TABLE IF EXISTS items_vec").Error; err != nil {
+		return err
+	}
+
+	if err := d.db.Exec("UPDATE items SET vector_model = NULL WHERE vector_model IS NOT NULL").Error; err != nil {
+		return err
+	}
+
+	return d.db.Where("key IN ?", []string{"embedding_dim", metaVecMetric}).Delete(&MetaModel{}).Error
+}
+
+// GetMeta returns the meta value for key and whether it is set.
+func (d *DB) GetMeta(key string) (string, bool, error) {
+	var meta MetaModel
+	if err := d.db.Where("key = ?", key).First(&meta).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", false, nil
+		}
+
+		return "", false, err
+	}
+
+	return meta.Value, true, nil
+}
+
+// SetMeta stores a meta value, replacing any previous value for key.
+func (d *DB) SetMeta(key, value string) error {
+	return d.db.Save(&MetaModel{Key: key, Value: value}).Error
 }
 
 // SetEmbeddingDim stores the embedding dimension in meta table.
@@ -92,22 +128,46 @@ func (d *DB) SetEmbeddingDim(dim int) error {
 	return d.db.Save(&meta).Error
 }
 
-// EnsureVecTable ensures the vector table exists with the correct dimension.
-func (d *DB) EnsureVecTable(dim int) error {
+// EnsureVecTable ensures the vector table exists with the correct dimension
+// and distance metric. An empty metric means MetricL2, vec0's default.
+func (d *DB) EnsureVecTable(dim int, metric string) error {
+	metric, err := normalizeMetric(metric)
+	if err != nil {
+		return err
+	}
+
 	storedDim := d.getEmbeddingDim()
 	if storedDim == nil {
 		if err := d.SetEmbeddingDim(dim); err != nil {
 			return err
 		}
 
-		return d.createVecTable(dim)
+		if err := d.SetMeta(metaVecMetric, metric); err != nil {
+			return err
+		}
+
+		return d.createVecTable(dim, metric)
 	} else if *storedDim != dim {
 		return fmt.Errorf("%w: database has %d, provider returned %d. Run 'pantry reindex' to rebuild", ErrDimensionMismatch, *storedDim, dim)
 	}
 
+	if stored := d.VecMetric(); stored != metric {
+		return fmt.Errorf("%w: database uses %s, config asks for %s. Run 'pantry reindex' to rebuild", ErrMetricMismatch, stored, metric)
+	}
+
 	return nil
 }
 
+// VecMetric returns the distance metric the vector table was created with.
+// Tables created before the metric was recorded use vec0's default, l2.
+func (d *DB) VecMetric() string {
+	if metric, ok, err := d.GetMeta(metaVecMetric); err == nil && ok {
+		return metric
+	}
+
+	return MetricL2
+}
+
 // InsertItem inserts an item into the database using GORM.
 func (d *DB) InsertItem(item models.Item, details *string) (int64, error) {
 	tagsJSON, err := json.Marshal(item.Tags)
@@ -120,8 +180,13 @@ func (d *DB) InsertItem(item models.Item, details *string) (int64, error) {
 		return 0, fmt.Errorf("failed to marshal related_files: %w", err)
 	}
 
+	metadataJSON, err := marshalMetadata(item.Metadata)
+	if err != nil {
+		return 0, err
+	}
+
 	itemModel := ItemModel{}
-	itemModel.FromItem(item, string(tagsJSON), string(relatedFilesJSON))
+	itemModel.FromItem(item, string(tagsJSON), string(relatedFilesJSON), metadataJSON)
 
 	if err := d.db.Create(&itemModel).Error; err != nil {
 		return 0, err
@@ -147,7 +212,205 @@ func (d *DB) InsertItem(item models.Item, details *string) (int64, error) {
 	return rowid, nil
 }
 
-// InsertVector inserts an embedding vector for an item.
+// insertBatchSize is how many rows InsertItems writes per INSERT statement,
+// and how many IDs it looks up per rowid query.
+const insertBatchSize = 200
+
+// InsertItems inserts items and their details in one transaction, in batches,
+// and returns their rowids in the order of items. details is either empty or
+// parallel to items, with nil for an item without details. Nothing is
+// inserted if any row fails.
+func (d *DB) InsertItems(items []models.Item, details []*string) ([]int64, error) {
+	if len(details) != 0 && len(details) != len(items) {
+		return nil, fmt.Errorf("InsertItems: %d details for %d items", len(details), len(items))
+	}
+
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	itemModels := make([]ItemModel, len(items))
+
+	var detailModels []ItemDetailModel
+
+	for i, item := range items {
+		tagsJSON, err := json.Marshal(item.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal tags: %w", err)
+		}
+
+		relatedFilesJSON, err := json.Marshal(item.RelatedFiles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal related_files: %w", err)
+		}
+
+		metadataJSON, err := marshalMetadata(item.Metadata)
+		if err != nil {
+			return nil, err
+		}
+
+		itemModels[i].FromItem(item, string(tagsJSON), string(relatedFilesJSON), metadataJSON)
+
+		if len(details) > 0 && details[i] != nil {
+			detailModels = append(detailModels, ItemDetailModel{ItemID: item.ID, Body: *details[i]})
+		}
+	}
+
+	rowids := make([]int64, len(items))
+
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(&itemModels, insertBatchSize).Error; err != nil {
+			return err
+		}
+
+		if len(detailModels) > 0 {
+			if err := tx.CreateInBatches(&detailModels, insertBatchSize).Error; err != nil {
+				return err
+			}
+		}
+
+		byID := make(map[string]int64, len(items))
+
+		for start := 0; start < len(items); start += insertBatchSize {
+			end := min(start+insertBatchSize, len(items))
+
+			ids := make([]string, 0, end-start)
+			for _, item := range items[start:end] {
+				ids = append(ids, item.ID)
+			}
+
+			var rows []struct {
+				ID    string
+				Rowid int64
+			}
+
+			if err := tx.Raw("SELECT id, rowid FROM items WHERE id IN ?", ids).Scan(&rows).Error; err != nil {
+				return err
+			}
+
+			for _, row := range rows {
+				byID[row.ID] = row.Rowid
+			}
+		}
+
+		for i, item := range items {
+			rowids[i] = byID[item.ID]
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rowids, nil
+}
+
+// SetMetadata adds metadata to an item's existing metadata, overwriting
+// keys present in both.
+func (d *DB) SetMetadata(itemID string, metadata map[string]string) error {
+	var itemModel ItemModel
+	if err := d.db.Where("id = ?", itemID).First(&itemModel).Error; err != nil {
+		return fmt.Errorf("%w: %s", ErrNotFound, itemID)
+	}
+
+	merged := unmarshalMetadata(itemModel.Metadata)
+	if merged == nil {
+		merged = make(map[string]string, len(metadata))
+	}
+
+	maps.Copy(merged, metadata)
+
+	metadataJSON, err := marshalMetadata(merged)
+	if err != nil {
+		return err
+	}
+
+	return d.db.Model(&ItemModel{}).Where("id = ?", itemID).Update("metadata", metadataJSON).Error
+}
+
+// marshalMetadata encodes metadata as JSON; empty metadata is stored as "".
+func marshalMetadata(metadata map[string]string) (string, error) {
+	if len(metadata) == 0 {
+		return "", nil
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// unmarshalMetadata decodes stored metadata, returning nil when it is empty
+// or malformed.
+func unmarshalMetadata(data string) map[string]string {
+	if data == "" {
+		return nil
+	}
+
+	var metadata map[string]string
+	_ = json.Unmarshal([]byte(data), &metadata)
+
+	return metadata
+}
+
+// ReplaceItem overwrites the fields of the existing item with item.ID, except
+// its project, file path and section anchor, and sets its details body to
+// details, removing it when details is nil. Both are written in one
+// transaction. The updated count is incremented.
+func (d *DB) ReplaceItem(item models.Item, details *string) error {
+	tagsJSON, err := json.Marshal(item.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
+	relatedFilesJSON, err := json.Marshal(item.RelatedFiles)
+	if err != nil {
+		return fmt.Errorf("failed to marshal related_files: %w", err)
+	}
+
+	metadataJSON, err := marshalMetadata(item.Metadata)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]any{
+		"title":         item.Title,
+		"what":          item.What,
+		"why":           item.Why,
+		"impact":        item.Impact,
+		"tags":          string(tagsJSON),
+		"category":      item.Category,
+		"source":        item.Source,
+		"related_files": string(relatedFilesJSON),
+		"metadata":      metadataJSON,
+		"created_at":    item.CreatedAt,
+		"updated_at":    item.UpdatedAt,
+		"updated_count": gorm.Expr("updated_count + 1"),
+	}
+
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&ItemModel{}).Where("id = ?", item.ID).Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("%w: %s", ErrNotFound, item.ID)
+		}
+
+		if details == nil {
+			return tx.Where("item_id = ?", item.ID).Delete(&ItemDetailModel{}).Error
+		}
+
+		return tx.Save(&ItemDetailModel{ItemID: item.ID, Body: *details}).Error
+	})
+}
+
+// InsertVector inserts an embedding vector for an item, replacing the one it
+// has, if any.
 func (d *DB) InsertVector(rowid int64, embedding []float32) error {
 	if !d.HasVecTable() {
 		return nil
@@ -158,14 +421,51 @@ func (d *DB) InsertVector(rowid int64, embedding []float32) error {
 		return fmt.Errorf("failed to marshal embedding: %w", err)
 	}
 
+	// vec0 tables only support deleting by rowid equality.
+	if err := d.db.Exec("DELETE FROM items_vec WHERE rowid = ?", rowid).Error; err != nil {
+		return err
+	}
+
 	return d.db.Exec(`
 		INSERT INTO items_vec (rowid, embedding)
 		VALUES (?, ?)
-	`, rowid, embeddingBytes).Error
+	`, rowid, string(embeddingBytes)).Error
 }
 
-// GetItem gets an item by ID using GORM.
+// ReplaceVector sets the embedding of the item with itemID, replacing the
+// one it has. It does nothing when there is no vector table.
+func (d *DB) ReplaceVector(itemID string, embedding []float32) error {
+	if !d.HasVecTable() {
+		return nil
+	}
+
+	var rowids []int64
+	if err := d.db.Raw("SELECT rowid FROM items WHERE id = ?", itemID).Scan(&rowids).Error; err != nil {
+		return err
+	}
+
+	if len(rowids) == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, itemID)
+	}
+
+	return d.InsertVector(rowids[0], embedding)
+}
+
+// GetItem gets an item by ID or prefix using GORM. The returned item carries
+// the full ID. A prefix matching nothing returns a nil item; one that is too
+// short or ambiguous returns the resolveID error.
 func (d *DB) GetItem(itemID string) (*models.Item, bool, error) {
+	fullID, err := d.resolveID(itemID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, false, nil
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	itemID = fullID
+
 	var itemModel ItemModel
 	if err := d.db.Where("id = ?", itemID).First(&itemModel).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -185,14 +485,72 @@ func (d *DB) GetItem(itemID string) (*models.Item, bool, error) {
 	// Parse tags and related files; ignore errors on malformed JSON (fields stay nil)
 	_ = json.Unmarshal([]byte(itemModel.Tags), &item.Tags)
 	_ = json.Unmarshal([]byte(itemModel.RelatedFiles), &item.RelatedFiles)
+	item.Metadata = unmarshalMetadata(itemModel.Metadata)
 
 	return &item, hasDetails, nil
 }
 
-// GetDetails gets full details for an item using GORM.
+// MinIDPrefix is the shortest ID prefix resolveID accepts; shorter ones would
+// match too many notes by accident. A complete ID of any length still resolves.
+const MinIDPrefix = 4
+
+// resolveID returns the full ID of the item whose ID is or starts with
+// prefix. It returns ErrNotFound when nothing matches, ErrIDPrefixTooShort for
+// a prefix under MinIDPrefix characters that is not a full ID, and
+// ErrAmbiguousIDPrefix when several items match.
+func (d *DB) resolveID(prefix string) (string, error) {
+	var ids []string
+
+	if err := d.db.Model(&ItemModel{}).Where("id = ?", prefix).Pluck("id", &ids).Error; err != nil {
+		return "", err
+	}
+
+	if len(ids) == 1 {
+		return ids[0], nil
+	}
+
+	if utf8.RuneCountInString(prefix) < MinIDPrefix {
+		return "", fmt.Errorf("%w: %q (use at least %d characters)", ErrIDPrefixTooShort, prefix, MinIDPrefix)
+	}
+
+	// A range on id, unlike LIKE, is answered from the primary key index.
+	if err := d.db.Model(&ItemModel{}).Where("id >= ? AND id < ?", prefix, prefixUpperBound(prefix)).
+		Order("id").Limit(2).Pluck("id", &ids).Error; err != nil {
+		return "", err
+	}
+
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("%w: %s", ErrNotFound, prefix)
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("%w: %q matches %s, %s, ...", ErrAmbiguousIDPrefix, prefix, ids[0], ids[1])
+	}
+}
+
+// prefixUpperBound returns the smallest string greater than every string
+// starting with prefix. prefix is valid UTF-8, so its last byte is below 0xff.
+func prefixUpperBound(prefix string) string {
+	b := []byte(prefix)
+	b[len(b)-1]++
+
+	return string(b)
+}
+
+// GetDetails gets full details for an item by ID or prefix using GORM.
 func (d *DB) GetDetails(itemID string) (*models.ItemDetail, error) {
+	fullID, err := d.resolveID(itemID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil //nolint:nilnil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
 	var detailModel ItemDetailModel
-	if err := d.db.Where("item_id LIKE ?", itemID+"%").First(&detailModel).Error; err != nil {
+	if err := d.db.Where("item_id = ?", fullID).First(&detailModel).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil //nolint:nilnil
 		}
@@ -206,16 +564,13 @@ func (d *DB) GetDetails(itemID string) (*models.ItemDetail, error) {
 	}, nil
 }
 
-// UpdateItem updates an existing item's fields using GORM.
-func (d *DB) UpdateItem(itemID string, what *string, why *string, impact *string, tags []string, detailsAppend *string) error {
-	// Resolve full ID from prefix
-	var itemModel ItemModel
-	if err := d.db.Where("id LIKE ?", itemID+"%").First(&itemModel).Error; err != nil {
-		return fmt.Errorf("%w: %s", ErrNotFound, itemID)
+// UpdateItem updates an existing item's fields by ID or prefix using GORM.
+func (d *DB) UpdateItem(itemID string, what *string, why *string, impact *string, tags []string, details *string, replaceDetails bool) error {
+	fullID, err := d.resolveID(itemID)
+	if err != nil {
+		return err
 	}
 
-	fullID := itemModel.ID
-
 	// Build updates
 	updates := map[string]any{
 		"updated_count": gorm.Expr("updated_count + 1"),
@@ -247,16 +602,21 @@ func (d *DB) UpdateItem(itemID string, what *string, why *string, impact *string
 		return err
 	}
 
-	// Handle details append
-	if detailsAppend != nil {
+	// Append to the details body, or replace it outright
+	if details != nil {
 		var detailModel ItemDetailModel
 		if err := d.db.Where("item_id = ?", fullID).First(&detailModel).Error; err == nil {
-			detailModel.Body = detailModel.Body + "\n\n" + *detailsAppend
+			if replaceDetails {
+				detailModel.Body = *details
+			} else {
+				detailModel.Body = detailModel.Body + "\n\n" + *details
+			}
+
 			d.db.Save(&detailModel)
 		} else {
 			detailModel = ItemDetailModel{
 				ItemID: fullID,
-				Body:   *detailsAppend,
+				Body:   *details,
 			}
 			d.db.Create(&detailModel)
 		}
@@ -267,90 +627,324 @@ func (d *DB) UpdateItem(itemID string, what *string, why *string, impact *string
 
 // DeleteItem deletes an item by ID or prefix using GORM.
 func (d *DB) DeleteItem(itemID string) (bool, error) {
-	// Resolve full ID from prefix
-	var itemModel ItemModel
-	if err := d.db.Where("id LIKE ?", itemID+"%").First(&itemModel).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return false, nil
+	fullID, err := d.resolveID(itemID)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	deleted := false
+
+	// Remove the item and everything hanging off it atomically, so a failure
+	// cannot leave an embedding behind that still surfaces in VectorSearch.
+	err = d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("item_id = ?", fullID).Delete(&ItemDetailModel{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("from_id = ? OR to_id = ?", fullID, fullID).Delete(&NoteLinkModel{}).Error; err != nil {
+			return err
+		}
+
+		if (&DB{db: tx}).HasVecTable() {
+			var rowid int64
+			if err := tx.Raw("SELECT rowid FROM items WHERE id = ?", fullID).Scan(&rowid).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Exec("DELETE FROM items_vec WHERE rowid = ?", rowid).Error; err != nil {
+				return err
+			}
 		}
 
+		result := tx.Where("id = ?", fullID).Delete(&ItemModel{})
+		deleted = result.RowsAffected > 0
+
+		return result.Error
+	})
+	if err != nil {
 		return false, err
 	}
 
-	fullID := itemModel.ID
+	return deleted, nil
+}
+
+// OrphanVectors returns the rowids in items_vec that have no items row.
+func (d *DB) OrphanVectors() ([]int64, error) {
+	if !d.HasVecTable() {
+		return nil, nil
+	}
+
+	var rowids []int64
+
+	err := d.db.Raw(`
+		SELECT rowid FROM items_vec
+		WHERE rowid NOT IN (SELECT rowid FROM items)
+		ORDER BY rowid
+	`).Scan(&rowids).Error
+
+	return rowids, err
+}
+
+// DeleteOrphanVectors removes items_vec rows that have no items row and
+// returns how many were removed.
+func (d *DB) DeleteOrphanVectors() (int64, error) {
+	orphans, err := d.OrphanVectors()
+	if err != nil || len(orphans) == 0 {
+		return 0, err
+	}
+
+	// vec0 tables only support deleting by rowid equality.
+	for _, rowid := range orphans {
+		if err := d.db.Exec("DELETE FROM items_vec WHERE rowid = ?", rowid).Error; err != nil {
+			return 0, err
+		}
+	}
 
-	// Delete details first
-	d.db.Where("item_id = ?", fullID).Delete(&ItemDetailModel{})
+	return int64(len(orphans)), nil
+}
 
-	// Delete item
-	result := d.db.Where("id = ?", fullID).Delete(&ItemModel{})
+// RewriteFilePaths replaces the oldDir prefix of every item's file_path with
+// newDir, e.g. after the notes directory was moved. Returns the number of
+// items changed.
+func (d *DB) RewriteFilePaths(oldDir, newDir string) (int64, error) {
+	oldDir = strings.TrimSuffix(oldDir, string(filepath.Separator)) + string(filepath.Separator)
+	newDir = strings.TrimSuffix(newDir, string(filepath.Separator)) + string(filepath.Separator)
 
-	return result.RowsAffected > 0, result.Error
+	// substr counts characters, not bytes.
+	n := utf8.RuneCountInString(oldDir)
+
+	res := d.db.Model(&ItemModel{}).
+		Where("substr(file_path, 1, ?) = ?", n, oldDir).
+		Update("file_path", gorm.Expr("? || substr(file_path, ?)", newDir, n+1))
+
+	return res.RowsAffected, res.Error
 }
 
-// FTSSearch searches items using FTS5 (must use raw SQL for FTS).
-func (d *DB) FTSSearch(query string, limit int, project *string, source *string) ([]models.SearchResult, error) {
-	// Build prefix matching query
-	terms := splitQuery(query)
-	ftsQuery := ""
+// RenameSources rewrites every item's source through rename, e.g. to bring
+// sources recorded before they were normalized into canonical form. Each
+// distinct source is renamed with one update, in a single transaction.
+// Returns the number of items changed.
+func (d *DB) RenameSources(rename func(source string) string) (int64, error) {
+	var changed int64
 
-	var ftsQuerySb315 strings.Builder
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		var sources []string
+		if err := tx.Model(&ItemModel{}).Where("source IS NOT NULL").Distinct().Pluck("source", &sources).Error; err != nil {
+			return err
+		}
 
-	for i, term := range terms {
-		if i > 0 {
-			ftsQuerySb315.WriteString(" OR ")
+		for _, from := range sources {
+			to := rename(from)
+			if to == from {
+				continue
+			}
+
+			res := tx.Model(&ItemModel{}).Where("source = ?", from).UpdateColumn("source", to)
+			if res.Error != nil {
+				return res.Error
+			}
+
+			changed += res.RowsAffected
+		}
+
+		return nil
+	})
+
+	return changed, err
+}
+
+// ReplaceTag replaces tag (matched case-insensitively) with replacement in the
+// tags of every item carrying it, or removes it when replacement is empty. An
+// item that already has replacement keeps a single copy. All items are
+// rewritten in one transaction; the update trigger re-indexes them for
+// keyword search. Returns the IDs of the changed items.
+func (d *DB) ReplaceTag(tag, replacement string) ([]string, error) {
+	var changed []string
+
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		var rows []struct {
+			ID   string
+			Tags string
+		}
+
+		// Every tagged item is read: tags are stored JSON-escaped and compared
+		// with Unicode case folding, which no SQL pre-filter matches reliably.
+		if err := tx.Model(&ItemModel{}).Select("id, tags").
+			Where("tags NOT IN ('', '[]', 'null')").Order("id").Scan(&rows).Error; err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			var tags []string
+			if err := json.Unmarshal([]byte(row.Tags), &tags); err != nil {
+				continue
+			}
+
+			updated, ok := replaceTag(tags, tag, replacement)
+			if !ok {
+				continue
+			}
+
+			tagsJSON, err := json.Marshal(updated)
+			if err != nil {
+				return fmt.Errorf("failed to marshal tags: %w", err)
+			}
+
+			if err := tx.Model(&ItemModel{}).Where("id = ?", row.ID).Update("tags", string(tagsJSON)).Error; err != nil {
+				return err
+			}
+
+			changed = append(changed, row.ID)
 		}
 
-		ftsQuerySb315.WriteString(fmt.Sprintf(`"%s"*`, term))
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	ftsQuery += ftsQuerySb315.String()
+	return changed, nil
+}
 
-	whereClause := ""
-	args := []any{ftsQuery}
+// replaceTag returns tags with tag replaced by replacement, or dropped when
+// replacement is empty, and whether tag was present. The replaced tag is
+// dropped instead when the note already has replacement; other tags are kept
+// as they are, duplicates included.
+func replaceTag(tags []string, tag, replacement string) ([]string, bool) {
+	found, has := false, false
+
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			found = true
+		} else if strings.EqualFold(t, replacement) {
+			has = true
+		}
+	}
 
-	if project != nil {
-		whereClause += " AND m.project = ?" //nolint:goconst
+	if !found {
+		return tags, false
+	}
 
-		args = append(args, *project)
+	out := make([]string, 0, len(tags))
+
+	for _, t := range tags {
+		if !strings.EqualFold(t, tag) {
+			out = append(out, t)
+
+			continue
+		}
+
+		if replacement != "" && !has {
+			out = append(out, replacement)
+			has = true
+		}
 	}
 
-	if source != nil {
-		whereClause += " AND m.source = ?" //nolint:goconst
+	return out, true
+}
 
-		args = append(args, *source)
+// RebuildFTS rebuilds the items_fts keyword index from the items table, for
+// when it has drifted out of sync. Returns the number of items indexed.
+func (d *DB) RebuildFTS() (int64, error) {
+	if err := d.db.Exec("INSERT INTO items_fts(items_fts) VALUES('rebuild')").Error; err != nil {
+		return 0, fmt.Errorf("failed to rebuild keyword index: %w", err)
 	}
 
+	var count int64
+	err := d.db.Model(&ItemModel{}).Count(&count).Error
+
+	return count, err
+}
+
+// InsertLink records a typed link from one item to another. Inserting an
+// existing link is a no-op.
+func (d *DB) InsertLink(fromID, toID, linkType string) error {
+	link := NoteLinkModel{
+		FromID:    fromID,
+		ToID:      toID,
+		Type:      linkType,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	return d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&link).Error
+}
+
+// ListLinks returns every link from or to itemID, oldest first.
+func (d *DB) ListLinks(itemID string) ([]models.NoteLink, error) {
+	var links []models.NoteLink
+
+	err := d.db.Raw(`
+		SELECT l.from_id, f.title AS from_title, l.to_id, t.title AS to_title,
+		       l.type, l.created_at
+		FROM note_links l
+		JOIN items f ON f.id = l.from_id
+		JOIN items t ON t.id = l.to_id
+		WHERE l.from_id = ? OR l.to_id = ?
+		ORDER BY l.created_at, l.from_id, l.to_id
+	`, itemID, itemID).Scan(&links).Error
+
+	return links, err
+}
+
+// SnippetStart and SnippetEnd wrap matched terms in SearchResult.Snippet.
+const (
+	SnippetStart = "**"
+	SnippetEnd   = "**"
+)
+
+// snippetTokens is the approximate length, in tokens, of an FTS snippet.
+const snippetTokens = 16
+
+// FTSSearch searches items using FTS5 (must use raw SQL for FTS), ranked by
+// bm25 with the WithColumnWeights weights.
+func (d *DB) FTSSearch(query string, limit int, project *string, source *string, opts ...QueryOption) ([]models.SearchResult, error) {
+	filter, err := newQueryFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ftsQuery := buildFTSQuery(query)
+
+	whereClause, filterArgs := filter.whereClause(project, source)
+	args := append([]any{SnippetStart, SnippetEnd, ftsQuery}, filterArgs...)
 	args = append(args, limit)
 
 	var rows []struct {
-		ID         string
-		Title      string
-		What       string
-		Why        sql.NullString
-		Impact     sql.NullString
-		Category   sql.NullString
-		Tags       string
-		Project    string
-		Source     sql.NullString
-		FilePath   string
-		CreatedAt  string
-		Score      float64
-		HasDetails bool
-	}
-
-	err := d.db.Raw(fmt.Sprintf(`
+		ID           string
+		Title        string
+		What         string
+		Why          sql.NullString
+		Impact       sql.NullString
+		Category     sql.NullString
+		Tags         string
+		Project      string
+		Source       sql.NullString
+		FilePath     string
+		CreatedAt    string
+		UpdatedAt    string
+		UpdatedCount int
+		Score        float64
+		HasDetails   bool
+		Snippet      string
+	}
+
+	err = d.db.Raw(fmt.Sprintf(`
 		SELECT m.id, m.title, m.what, m.why, m.impact, m.category, m.tags,
-		       m.project, m.source, m.file_path, m.created_at,
-		       -fts.rank as score,
-		       EXISTS(SELECT 1 FROM item_details WHERE item_id = m.id) as has_details
+		       m.project, m.source, m.file_path, m.created_at, m.updated_at, m.updated_count,
+		       -%[1]s as score,
+		       EXISTS(SELECT 1 FROM item_details WHERE item_id = m.id) as has_details,
+		       snippet(items_fts, -1, ?, ?, '...', %[2]d) as snippet
 		FROM items_fts fts
 		JOIN items m ON m.rowid = fts.rowid
 		WHERE fts.items_fts MATCH ?
-		%s
-		ORDER BY fts.rank
+		%[3]s
+		ORDER BY %[1]s
 		LIMIT ?
-	`, whereClause), args...).Scan(&rows).Error
+	`, filter.rankExpr(), snippetTokens, whereClause), args...).Scan(&rows).Error
 	if err != nil {
 		return nil, err
 	}
@@ -359,16 +953,22 @@ func (d *DB) FTSSearch(query string, limit int, project *string, source *string)
 
 	for i, row := range rows {
 		result := models.SearchResult{
-			ID:         row.ID,
-			Title:      row.Title,
-			What:       row.What,
-			Project:    row.Project,
-			FilePath:   row.FilePath,
-			CreatedAt:  row.CreatedAt,
-			Score:      row.Score,
-			HasDetails: row.HasDetails,
+			ID:           row.ID,
+			Title:        row.Title,
+			What:         row.What,
+			Project:      row.Project,
+			FilePath:     row.FilePath,
+			CreatedAt:    row.CreatedAt,
+			UpdatedAt:    row.UpdatedAt,
+			UpdatedCount: row.UpdatedCount,
+			Score:        row.Score,
+			HasDetails:   row.HasDetails,
+			Snippet:      row.Snippet,
 		}
 
+		rank := -row.Score
+		result.FTSRank = &rank
+
 		if row.Why.Valid {
 			result.Why = &row.Why.String
 		}
@@ -395,8 +995,17 @@ func (d *DB) FTSSearch(query string, limit int, project *string, source *string)
 	return results, nil
 }
 
-// VectorSearch searches items using vector similarity (must use raw SQL for vec).
-func (d *DB) VectorSearch(queryEmbedding []float32, limit int, project *string, source *string) ([]models.SearchResult, error) {
+// maxVecK is the largest k sqlite-vec accepts in a KNN query.
+const maxVecK = 4096
+
+// VectorSearch searches items using vector similarity (must use raw SQL for
+// vec). At most maxVecK nearest items are returned.
+func (d *DB) VectorSearch(queryEmbedding []float32, limit int, project *string, source *string, opts ...QueryOption) ([]models.SearchResult, error) {
+	filter, err := newQueryFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	if !d.HasVecTable() {
 		return []models.SearchResult{}, nil
 	}
@@ -407,39 +1016,29 @@ func (d *DB) VectorSearch(queryEmbedding []float32, limit int, project *string,
 	}
 
 	var rows []struct {
-		ID         string
-		Title      string
-		What       string
-		Why        sql.NullString
-		Impact     sql.NullString
-		Category   sql.NullString
-		Tags       string
-		Project    string
-		Source     sql.NullString
-		FilePath   string
-		CreatedAt  string
-		Distance   float64
-		HasDetails bool
-	}
-
-	whereClause := ""
-	args := []any{embeddingBytes, limit}
-
-	if project != nil {
-		whereClause += " AND m.project = ?"
-
-		args = append(args, *project)
+		ID           string
+		Title        string
+		What         string
+		Why          sql.NullString
+		Impact       sql.NullString
+		Category     sql.NullString
+		Tags         string
+		Project      string
+		Source       sql.NullString
+		FilePath     string
+		CreatedAt    string
+		UpdatedAt    string
+		UpdatedCount int
+		Distance     float64
+		HasDetails   bool
 	}
 
-	if source != nil {
-		whereClause += " AND m.source = ?"
-
-		args = append(args, *source)
-	}
+	whereClause, filterArgs := filter.whereClause(project, source)
+	args := append([]any{string(embeddingBytes), min(limit, maxVecK)}, filterArgs...)
 
 	err = d.db.Raw(fmt.Sprintf(`
 		SELECT m.id, m.title, m.what, m.why, m.impact, m.category, m.tags,
-		       m.project, m.source, m.file_path, m.created_at,
+		       m.project, m.source, m.file_path, m.created_at, m.updated_at, m.updated_count,
 		       v.distance,
 		       EXISTS(SELECT 1 FROM item_details WHERE item_id = m.id) as has_details
 		FROM items_vec v
@@ -457,14 +1056,17 @@ func (d *DB) VectorSearch(queryEmbedding []float32, limit int, project *string,
 
 	for i, row := range rows {
 		result := models.SearchResult{
-			ID:         row.ID,
-			Title:      row.Title,
-			What:       row.What,
-			Project:    row.Project,
-			FilePath:   row.FilePath,
-			CreatedAt:  row.CreatedAt,
-			Score:      1.0 - row.Distance,
-			HasDetails: row.HasDetails,
+			ID:           row.ID,
+			Title:        row.Title,
+			What:         row.What,
+			Project:      row.Project,
+			FilePath:     row.FilePath,
+			CreatedAt:    row.CreatedAt,
+			UpdatedAt:    row.UpdatedAt,
+			UpdatedCount: row.UpdatedCount,
+			Score:        1.0 - row.Distance,
+			Distance:     &row.Distance,
+			HasDetails:   row.HasDetails,
 		}
 
 		if row.Why.Valid {
@@ -493,50 +1095,58 @@ func (d *DB) VectorSearch(queryEmbedding []float32, limit int, project *string,
 	return results, nil
 }
 
-// ListRecent lists recent items ordered by creation date descending.
+// ListRecent lists recent items ordered by creation date descending, by
+// last change with WithOrderUpdated, or by distance from the WithOrderNear
+// time. Ties are broken by ID.
 // Uses a single raw SQL query with an EXISTS subquery to avoid N+1 queries.
-func (d *DB) ListRecent(limit int, project *string, source *string) ([]models.SearchResult, error) {
-	whereClause := "1=1"
-	args := []any{}
+func (d *DB) ListRecent(limit int, project *string, source *string, opts ...QueryOption) ([]models.SearchResult, error) {
+	filter, err := newQueryFilter(opts)
+	if err != nil {
+		return nil, err
+	}
 
-	if project != nil {
-		whereClause += " AND m.project = ?"
+	filterClause, args := filter.whereClause(project, source)
+	whereClause := "1=1" + filterClause
 
-		args = append(args, *project)
-	}
+	orderBy := "m.created_at DESC, m.id"
 
-	if source != nil {
-		whereClause += " AND m.source = ?"
+	switch {
+	case filter.orderNear != nil:
+		orderBy = "abs(julianday(m.created_at) - julianday(?)), m.created_at DESC, m.id"
 
-		args = append(args, *source)
+		args = append(args, *filter.orderNear)
+	case filter.orderUpdated:
+		orderBy = "m.updated_at DESC, m.id"
 	}
 
 	args = append(args, limit)
 
 	var rows []struct {
-		ID         string
-		Title      string
-		What       string
-		Why        sql.NullString
-		Impact     sql.NullString
-		Category   sql.NullString
-		Tags       string
-		Project    string
-		Source     sql.NullString
-		FilePath   string
-		CreatedAt  string
-		HasDetails bool
-	}
-
-	err := d.db.Raw(fmt.Sprintf(`
+		ID           string
+		Title        string
+		What         string
+		Why          sql.NullString
+		Impact       sql.NullString
+		Category     sql.NullString
+		Tags         string
+		Project      string
+		Source       sql.NullString
+		FilePath     string
+		CreatedAt    string
+		UpdatedAt    string
+		UpdatedCount int
+		HasDetails   bool
+	}
+
+	err = d.db.Raw(fmt.Sprintf(`
 		SELECT m.id, m.title, m.what, m.why, m.impact, m.category, m.tags,
-		       m.project, m.source, m.file_path, m.created_at,
+		       m.project, m.source, m.file_path, m.created_at, m.updated_at, m.updated_count,
 		       EXISTS(SELECT 1 FROM item_details WHERE item_id = m.id) AS has_details
 		FROM items m
 		WHERE %s
-		ORDER BY m.created_at DESC
+		ORDER BY %s
 		LIMIT ?
-	`, whereClause), args...).Scan(&rows).Error
+	`, whereClause, orderBy), args...).Scan(&rows).Error
 	if err != nil {
 		return nil, err
 	}
@@ -545,13 +1155,15 @@ func (d *DB) ListRecent(limit int, project *string, source *string) ([]models.Se
 
 	for i, row := range rows {
 		result := models.SearchResult{
-			ID:         row.ID,
-			Title:      row.Title,
-			What:       row.What,
-			Project:    row.Project,
-			FilePath:   row.FilePath,
-			CreatedAt:  row.CreatedAt,
-			HasDetails: row.HasDetails,
+			ID:           row.ID,
+			Title:        row.Title,
+			What:         row.What,
+			Project:      row.Project,
+			FilePath:     row.FilePath,
+			CreatedAt:    row.CreatedAt,
+			UpdatedAt:    row.UpdatedAt,
+			UpdatedCount: row.UpdatedCount,
+			HasDetails:   row.HasDetails,
 		}
 		if row.Why.Valid {
 			result.Why = &row.Why.String
@@ -579,10 +1191,81 @@ func (d *DB) ListRecent(limit int, project *string, source *string) ([]models.Se
 	return results, nil
 }
 
+// ListItems returns full items in creation order, optionally limited to a project.
+func (d *DB) ListItems(project *string) ([]models.Item, error) {
+	query := d.db.Order("created_at").Order("rowid")
+	if project != nil {
+		query = query.Where("project = ?", *project)
+	}
+
+	var itemModels []ItemModel
+	if err := query.Find(&itemModels).Error; err != nil {
+		return nil, err
+	}
+
+	return toItems(itemModels), nil
+}
+
+// ListByFilePath returns the items stored in the notes file at filePath, in
+// creation order.
+func (d *DB) ListByFilePath(filePath string) ([]models.Item, error) {
+	var itemModels []ItemModel
+	if err := d.db.Where("file_path = ?", filePath).Order("created_at").Order("rowid").Find(&itemModels).Error; err != nil {
+		return nil, err
+	}
+
+	return toItems(itemModels), nil
+}
+
+// toItems converts rows to items, decoding their JSON columns.
+func toItems(itemModels []ItemModel) []models.Item {
+	items := make([]models.Item, len(itemModels))
+
+	for i, im := range itemModels {
+		items[i] = im.ToItem()
+
+		// Ignore malformed JSON, as GetItem does (fields stay nil)
+		_ = json.Unmarshal([]byte(im.Tags), &items[i].Tags)
+		_ = json.Unmarshal([]byte(im.RelatedFiles), &items[i].RelatedFiles)
+		items[i].Metadata = unmarshalMetadata(im.Metadata)
+	}
+
+	return items
+}
+
 // ListAllForReindex lists all items with fields needed for re-embedding using GORM.
 func (d *DB) ListAllForReindex() ([]map[string]any, error) {
+	return d.listForReindex(d.db)
+}
+
+// ListMissingVectors returns items in ListAllForReindex form that have no row
+// in items_vec, so an interrupted reindex can embed only what is left. When
+// model is not empty, items whose vector SetVectorModel recorded as made by
+// another model, such as a fallback provider's, are returned too. If the
+// vector table does not exist, every item is returned.
+func (d *DB) ListMissingVectors(model string) ([]map[string]any, error) {
+	if !d.HasVecTable() {
+		return d.listForReindex(d.db)
+	}
+
+	query := d.db.Where("rowid NOT IN (SELECT rowid FROM items_vec)")
+	if model != "" {
+		query = query.Or("vector_model IS NOT NULL AND vector_model <> ?", model)
+	}
+
+	return d.listForReindex(query)
+}
+
+// SetVectorModel records model as the one that embedded the vector of the
+// item with itemID.
+func (d *DB) SetVectorModel(itemID, model string) error {
+	return d.db.Model(&ItemModel{}).Where("id = ?", itemID).UpdateColumn("vector_model", model).Error
+}
+
+// listForReindex loads the items matched by query, ordered by rowid.
+func (d *DB) listForReindex(query *gorm.DB) ([]map[string]any, error) {
 	var itemModels []ItemModel
-	if err := d.db.Order("rowid").Find(&itemModels).Error; err != nil {
+	if err := query.Order("rowid").Find(&itemModels).Error; err != nil {
 		return nil, err
 	}
 
@@ -596,6 +1279,7 @@ func (d *DB) ListAllForReindex() ([]map[string]any, error) {
 
 		result := map[string]any{
 			"rowid": rowid,
+			"id":    im.ID,
 			"title": im.Title,
 			"what":  im.What,
 		}
@@ -620,7 +1304,12 @@ func (d *DB) ListAllForReindex() ([]map[string]any, error) {
 }
 
 // CountItems counts total items with optional filters using GORM.
-func (d *DB) CountItems(project *string, source *string) (int64, error) {
+func (d *DB) CountItems(project *string, source *string, opts ...QueryOption) (int64, error) {
+	filter, err := newQueryFilter(opts)
+	if err != nil {
+		return 0, err
+	}
+
 	var count int64
 
 	query := d.db.Model(&ItemModel{})
@@ -629,10 +1318,26 @@ func (d *DB) CountItems(project *string, source *string) (int64, error) {
 		query = query.Where("project = ?", *project)
 	}
 
+	if filter.projectGlob != nil {
+		query = query.Where("project GLOB ?", *filter.projectGlob)
+	}
+
 	if source != nil {
 		query = query.Where("source = ?", *source)
 	}
 
+	for _, kv := range filter.meta {
+		query = query.Where(metaPredicate("metadata"), metaPath(kv[0]), kv[1])
+	}
+
+	if filter.createdFrom != nil {
+		query = query.Where("created_at >= ? AND created_at <= ?", *filter.createdFrom, *filter.createdTo)
+	}
+
+	if filter.changedFrom != nil {
+		query = query.Where("updated_at >= ?", *filter.changedFrom)
+	}
+
 	if err := query.Count(&count).Error; err != nil {
 		return 0, err
 	}
@@ -640,6 +1345,80 @@ func (d *DB) CountItems(project *string, source *string) (int64, error) {
 	return count, nil
 }
 
+// DumpSchema returns the table, index, trigger and view definitions from
+// sqlite_master (excluding SQLite internals) and all rows of the meta table.
+func (d *DB) DumpSchema() (*SchemaDump, error) {
+	var rows []struct {
+		Type    string
+		Name    string
+		TblName string
+		SQL     sql.NullString
+	}
+
+	if err := d.db.Raw(`
+		SELECT type, name, tbl_name, sql FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite_%'
+		ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 WHEN 'trigger' THEN 2 ELSE 3 END, name
+	`).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	dump := &SchemaDump{
+		Objects: make([]SchemaObject, len(rows)),
+		Meta:    map[string]string{},
+	}
+
+	for i, row := range rows {
+		dump.Objects[i] = SchemaObject{
+			Type:      row.Type,
+			Name:      row.Name,
+			TableName: row.TblName,
+			SQL:       row.SQL.String,
+		}
+	}
+
+	var metas []MetaModel
+	if err := d.db.Order("key").Find(&metas).Error; err != nil {
+		return nil, err
+	}
+
+	for _, m := range metas {
+		dump.Meta[m.Key] = m.Value
+	}
+
+	return dump, nil
+}
+
+// WithTx runs fn inside a database transaction, committing if fn returns nil
+// and rolling back otherwise.
+func (d *DB) WithTx(fn func(Store) error) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&DB{db: tx})
+	})
+}
+
+// Checkpoint copies the write-ahead log into the database file and truncates
+// it, as PRAGMA wal_checkpoint(TRUNCATE). It is a no-op for a database not in
+// WAL mode. A reader or writer in another process can keep the log from being
+// emptied; that is reported as an error.
+func (d *DB) Checkpoint() error {
+	var row struct {
+		Busy         int
+		Log          int
+		Checkpointed int
+	}
+
+	if err := d.db.Raw("PRAGMA wal_checkpoint(TRUNCATE)").Row().Scan(&row.Busy, &row.Log, &row.Checkpointed); err != nil {
+		return fmt.Errorf("failed to checkpoint the write-ahead log: %w", err)
+	}
+
+	if row.Busy != 0 {
+		return errors.New("failed to checkpoint the write-ahead log: the database is in use by another process")
+	}
+
+	return nil
+}
+
 // Close closes the database connection.
 func (d *DB) Close() error {
 	sqlDB, err := d.db.DB()
@@ -653,7 +1432,7 @@ func (d *DB) Close() error {
 // migrate runs database migrations using GORM AutoMigrate.
 func (d *DB) migrate() error {
 	// Auto-migrate GORM models
-	if err := d.db.AutoMigrate(&ItemModel{}, &ItemDetailModel{}, &MetaModel{}); err != nil {
+	if err := d.db.AutoMigrate(&ItemModel{}, &ItemDetailModel{}, &MetaModel{}, &NoteLinkModel{}); err != nil {
 		return fmt.Errorf("failed to auto-migrate: %w", err)
 	}
 
@@ -692,7 +1471,7 @@ func (d *DB) migrate() error {
 	// Create vec table if dimension is known
 	dim := d.getEmbeddingDim()
 	if dim != nil {
-		if err := d.createVecTable(*dim); err != nil {
+		if err := d.createVecTable(*dim, d.VecMetric()); err != nil {
 			return err
 		}
 	}
@@ -700,18 +1479,27 @@ func (d *DB) migrate() error {
 	return nil
 }
 
-// createVecTable creates the vector table with the given dimension.
-func (d *DB) createVecTable(dim int) error {
+// createVecTable creates the vector table with the given dimension and metric.
+func (d *DB) createVecTable(dim int, metric string) error {
 	query := fmt.Sprintf(`
 		CREATE VIRTUAL TABLE IF NOT EXISTS items_vec USING vec0(
 			rowid INTEGER PRIMARY KEY,
-			embedding float[%d]
+			embedding float[%d] distance_metric=%s
 		)
-	`, dim)
+	`, dim, metric)
 
 	return d.db.Exec(query).Error
 }
 
+// EmbeddingDim returns the dimension of the indexed vectors, or 0 if none is recorded.
+func (d *DB) EmbeddingDim() int {
+	if dim := d.getEmbeddingDim(); dim != nil {
+		return *dim
+	}
+
+	return 0
+}
+
 // getEmbeddingDim gets the stored embedding dimension from meta table.
 func (d *DB) getEmbeddingDim() *int {
 	var meta MetaModel
@@ -727,7 +1515,219 @@ func (d *DB) getEmbeddingDim() *int {
 	return &dim
 }
 
+// FacetFields are the item columns FTSFacets groups by.
+var FacetFields = []string{"category", "source", "project"}
+
+// FTSFacets counts every FTS match (not just one page of results) grouped by
+// each of FacetFields. NULL values are reported under the empty string.
+func (d *DB) FTSFacets(query string, project *string, source *string, opts ...QueryOption) (Facets, error) {
+	filter, err := newQueryFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	whereClause, filterArgs := filter.whereClause(project, source)
+	args := append([]any{buildFTSQuery(query)}, filterArgs...)
+
+	facets := make(Facets, len(FacetFields))
+
+	for _, field := range FacetFields {
+		var rows []FacetCount
+
+		err := d.db.Raw(fmt.Sprintf(`
+			SELECT COALESCE(m.%[1]s, '') AS value, COUNT(*) AS count
+			FROM items_fts fts
+			JOIN items m ON m.rowid = fts.rowid
+			WHERE fts.items_fts MATCH ?
+			%[2]s
+			GROUP BY COALESCE(m.%[1]s, '')
+			ORDER BY count DESC, value
+		`, field, whereClause), args...).Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+
+		facets[field] = rows
+	}
+
+	return facets, nil
+}
+
+// CountFTS counts every FTS match for query under the same filters as
+// FTSSearch, without fetching the rows.
+func (d *DB) CountFTS(query string, project *string, source *string, opts ...QueryOption) (int64, error) {
+	filter, err := newQueryFilter(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	whereClause, filterArgs := filter.whereClause(project, source)
+	args := append([]any{buildFTSQuery(query)}, filterArgs...)
+
+	var count int64
+
+	err = d.db.Raw(fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM items_fts fts
+		JOIN items m ON m.rowid = fts.rowid
+		WHERE fts.items_fts MATCH ?
+		%s
+	`, whereClause), args...).Scan(&count).Error
+
+	return count, err
+}
+
+// ListSources counts notes per distinct non-empty source, most used first.
+func (d *DB) ListSources() ([]FacetCount, error) {
+	var rows []FacetCount
+
+	err := d.db.Raw(`
+		SELECT source AS value, COUNT(*) AS count
+		FROM items
+		WHERE source IS NOT NULL AND source != ''
+		GROUP BY source
+		ORDER BY count DESC, value
+	`).Scan(&rows).Error
+
+	return rows, err
+}
+
+// Granularities accepted by CountByPeriod.
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// periodExprs bucket created_at by granularity. A week is labelled by its
+// Monday: 'weekday 0' moves to the coming Sunday (or stays on one).
+var periodExprs = map[string]string{
+	PeriodDay:   "date(created_at)",
+	PeriodWeek:  "date(created_at, 'weekday 0', '-6 days')",
+	PeriodMonth: "strftime('%Y-%m', created_at)",
+}
+
+// CountByPeriod counts the items created in each day, week or month, for
+// project (all projects when nil), oldest period first. Periods without
+// items are left out.
+func (d *DB) CountByPeriod(granularity string, project *string) ([]PeriodCount, error) {
+	expr, ok := periodExprs[granularity]
+	if !ok {
+		return nil, fmt.Errorf("invalid period %q: must be one of day, week, month", granularity)
+	}
+
+	query := d.db.Model(&ItemModel{}).Select(expr + " AS period, COUNT(*) AS count")
+	if project != nil {
+		query = query.Where("project = ?", *project)
+	}
+
+	var rows []PeriodCount
+
+	err := query.Group("period").Order("period").Scan(&rows).Error
+
+	return rows, err
+}
+
+// Stats returns the item, project, source, category and vector counts for
+// project (all projects when nil) and the size of the whole database. Empty
+// sources and categories are not counted.
+func (d *DB) Stats(project *string) (StoreStats, error) {
+	var stats StoreStats
+
+	query := d.db.Model(&ItemModel{}).Select(`
+		COUNT(*) AS items,
+		COUNT(DISTINCT project) AS projects,
+		COUNT(DISTINCT NULLIF(source, '')) AS sources,
+		COUNT(DISTINCT NULLIF(category, '')) AS categories,
+		COUNT(CASE WHEN EXISTS (SELECT 1 FROM item_details WHERE item_id = items.id) THEN 1 END) AS with_details,
+		(SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()) AS size_bytes
+	`)
+	if project != nil {
+		query = query.Where("project = ?", *project)
+	}
+
+	if err := query.Scan(&stats).Error; err != nil {
+		return StoreStats{}, err
+	}
+
+	if d.HasVecTable() {
+		vectors := d.db.Table("items_vec")
+		if project != nil {
+			vectors = vectors.Where("rowid IN (SELECT rowid FROM items WHERE project = ?)", *project)
+		}
+
+		if err := vectors.Count(&stats.Vectors).Error; err != nil {
+			return StoreStats{}, err
+		}
+	}
+
+	return stats, nil
+}
+
+// Terms counts the words in the titles, what text and tags of the items in
+// project (all projects when nil), lowercased. Words shorter than
+// minTermLength are left out. It is the vocabulary for spelling suggestions.
+func (d *DB) Terms(project *string) (map[string]int, error) {
+	var rows []struct {
+		Title string
+		What  string
+		Tags  string
+	}
+
+	query := d.db.Model(&ItemModel{}).Select("title, what, tags")
+	if project != nil {
+		query = query.Where("project = ?", *project)
+	}
+
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	terms := make(map[string]int)
+
+	for _, row := range rows {
+		var tags []string
+		_ = json.Unmarshal([]byte(row.Tags), &tags)
+
+		for _, text := range append(tags, row.Title, row.What) {
+			for _, word := range SplitWords(text) {
+				if utf8.RuneCountInString(word) >= minTermLength {
+					terms[word]++
+				}
+			}
+		}
+	}
+
+	return terms, nil
+}
+
+// minTermLength is the shortest word Terms counts.
+const minTermLength = 3
+
+// SplitWords lowercases text and splits it into runs of letters and digits.
+func SplitWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
 // Helper functions.
+
+// buildFTSQuery turns free text into an FTS5 query that prefix-matches any term.
+func buildFTSQuery(query string) string {
+	var sb strings.Builder
+
+	for i, term := range splitQuery(query) {
+		if i > 0 {
+			sb.WriteString(" OR ")
+		}
+
+		sb.WriteString(fmt.Sprintf(`"%s"*`, term))
+	}
+
+	return sb.String()
+}
+
 func splitQuery(query string) []string {
 	terms := []string{}
 	current := ""
@@ -1,7 +1,13 @@
 package db
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -173,6 +179,30 @@ func TestFTSSearch_Match(t *testing.T) {
 	}
 }
 
+func TestFTSSearch_SnippetMarksMatch(t *testing.T) {
+	d := newTestDB(t)
+	item := makeItem("Snippet Test", "proj")
+	item.What = "the cache layer was rewritten around a plugh index for faster lookups"
+
+	if _, err := d.InsertItem(item, nil); err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+
+	results, err := d.FTSSearch("plugh", 5, nil, nil)
+	if err != nil {
+		t.Fatalf("FTSSearch() error = %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("FTSSearch() returned %d results, want 1", len(results))
+	}
+
+	want := SnippetStart + "plugh" + SnippetEnd
+	if !strings.Contains(results[0].Snippet, want) {
+		t.Errorf("Snippet = %q, want it to contain %q", results[0].Snippet, want)
+	}
+}
+
 func TestFTSSearch_NoMatch(t *testing.T) {
 	d := newTestDB(t)
 	item := makeItem("No Match Test", "proj")
@@ -224,6 +254,38 @@ func TestFTSSearch_ProjectFilter(t *testing.T) {
 	}
 }
 
+func TestFTSSearch_ColumnWeights(t *testing.T) {
+	d := newTestDB(t)
+
+	titled := makeItem("Frobnicate the cache", "proj")
+	why := "frobnicate, frobnicate, frobnicate"
+	body := makeItem("Cache notes", "proj")
+	body.Why = &why
+
+	for _, item := range []models.Item{body, titled} {
+		if _, err := d.InsertItem(item, nil); err != nil {
+			t.Fatalf("InsertItem() error = %v", err)
+		}
+	}
+
+	results, err := d.FTSSearch("frobnicate", 10, nil, nil, WithColumnWeights(map[string]float64{"title": 10, "why": 1}))
+	if err != nil {
+		t.Fatalf("FTSSearch() error = %v", err)
+	}
+
+	if len(results) != 2 || results[0].ID != titled.ID {
+		t.Fatalf("FTSSearch() = %v, want the title match first", results)
+	}
+
+	if results[0].Score <= results[1].Score {
+		t.Errorf("FTSSearch() scores = %v, %v; want the title match higher", results[0].Score, results[1].Score)
+	}
+
+	if _, err := d.FTSSearch("frobnicate", 10, nil, nil, WithColumnWeights(map[string]float64{"details": 2})); err == nil {
+		t.Error("FTSSearch() with an unknown column weight: error = nil")
+	}
+}
+
 // --- UpdateItem ---
 
 func TestUpdateItem(t *testing.T) {
@@ -238,7 +300,7 @@ func TestUpdateItem(t *testing.T) {
 	newWhat := "updated what field"
 	newTags := []string{"newtag"}
 
-	err = d.UpdateItem(item.ID, &newWhat, nil, nil, newTags, nil)
+	err = d.UpdateItem(item.ID, &newWhat, nil, nil, newTags, nil, false)
 	if err != nil {
 		t.Fatalf("UpdateItem() error = %v", err)
 	}
@@ -265,7 +327,7 @@ func TestUpdateItem_DetailsAppend(t *testing.T) {
 
 	appended := "new appended content"
 
-	err = d.UpdateItem(item.ID, nil, nil, nil, nil, &appended)
+	err = d.UpdateItem(item.ID, nil, nil, nil, nil, &appended, false)
 	if err != nil {
 		t.Fatalf("UpdateItem() error = %v", err)
 	}
@@ -279,15 +341,40 @@ func TestUpdateItem_DetailsAppend(t *testing.T) {
 		t.Fatal("GetDetails() returned nil after append")
 	}
 
-	if detail.Body == original {
-		t.Error("Details body should have been appended to")
+	if want := original + "\n\n" + appended; detail.Body != want {
+		t.Errorf("Details body = %q, want %q", detail.Body, want)
+	}
+}
+
+func TestUpdateItem_DetailsReplace(t *testing.T) {
+	d := newTestDB(t)
+	item := makeItem("Details Replace Test", "proj")
+	original := "original details"
+
+	if _, err := d.InsertItem(item, &original); err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+
+	replacement := "corrected details"
+
+	if err := d.UpdateItem(item.ID, nil, nil, nil, nil, &replacement, true); err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+
+	detail, err := d.GetDetails(item.ID)
+	if err != nil {
+		t.Fatalf("GetDetails() error = %v", err)
+	}
+
+	if detail == nil || detail.Body != replacement {
+		t.Errorf("Details after replace = %+v, want body %q", detail, replacement)
 	}
 }
 
 func TestUpdateItem_NotFound(t *testing.T) {
 	d := newTestDB(t)
 
-	err := d.UpdateItem("nonexistent", nil, nil, nil, nil, nil)
+	err := d.UpdateItem("nonexistent", nil, nil, nil, nil, nil, false)
 	if err == nil {
 		t.Error("UpdateItem() should return error for nonexistent item")
 	}
@@ -337,8 +424,293 @@ func TestDeleteItem_NonExistent(t *testing.T) {
 	}
 }
 
+// --- ID prefixes ---
+
+func TestResolveID(t *testing.T) {
+	d := newTestDB(t)
+
+	for _, id := range []string{"abcd1111", "abcd2222", "ef01aaaa", "x"} {
+		item := makeItem("Note "+id, "proj")
+		item.ID = id
+
+		if _, err := d.InsertItem(item, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		prefix  string
+		want    string
+		wantErr error
+	}{
+		{"ef01", "ef01aaaa", nil},
+		{"abcd1", "abcd1111", nil},
+		{"abcd2222", "abcd2222", nil},
+		{"x", "x", nil}, // a complete ID needs no minimum length
+		{"ef0", "", ErrIDPrefixTooShort},
+		{"", "", ErrIDPrefixTooShort},
+		{"abcd", "", ErrAmbiguousIDPrefix},
+		{"ffff", "", ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		got, err := d.resolveID(tt.prefix)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("resolveID(%q) = %q, %v, want %q, %v", tt.prefix, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestResolveID_UsesIndex(t *testing.T) {
+	d := newTestDB(t)
+
+	var plan []struct{ Detail string }
+
+	if err := d.db.Raw("EXPLAIN QUERY PLAN SELECT id FROM items WHERE id >= ? AND id < ?", "abcd", "abce").Scan(&plan).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan) == 0 || !strings.Contains(plan[0].Detail, "INDEX") {
+		t.Errorf("prefix lookup plan = %+v, want an index search", plan)
+	}
+}
+
+func TestPrefixErrors_SurfaceFromCallers(t *testing.T) {
+	d := newTestDB(t)
+
+	for _, id := range []string{"abcd1111", "abcd2222"} {
+		item := makeItem("Note "+id, "proj")
+		item.ID = id
+
+		if _, err := d.InsertItem(item, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := d.DeleteItem("abcd"); !errors.Is(err, ErrAmbiguousIDPrefix) {
+		t.Errorf("DeleteItem(ambiguous) error = %v, want ErrAmbiguousIDPrefix", err)
+	}
+
+	what := "changed"
+	if err := d.UpdateItem("ab", &what, nil, nil, nil, nil, false); !errors.Is(err, ErrIDPrefixTooShort) {
+		t.Errorf("UpdateItem(short) error = %v, want ErrIDPrefixTooShort", err)
+	}
+
+	if _, err := d.GetDetails("abcd"); !errors.Is(err, ErrAmbiguousIDPrefix) {
+		t.Errorf("GetDetails(ambiguous) error = %v, want ErrAmbiguousIDPrefix", err)
+	}
+
+	if _, _, err := d.GetItem("abcd"); !errors.Is(err, ErrAmbiguousIDPrefix) {
+		t.Errorf("GetItem(ambiguous) error = %v, want ErrAmbiguousIDPrefix", err)
+	}
+
+	item, _, err := d.GetItem("abcd1")
+	if err != nil || item == nil || item.ID != "abcd1111" {
+		t.Errorf("GetItem(unique prefix) = %+v, %v, want abcd1111", item, err)
+	}
+
+	if item, _, err := d.GetItem("ffff"); item != nil || err != nil {
+		t.Errorf("GetItem(no match) = %+v, %v, want nil, nil", item, err)
+	}
+
+	deleted, err := d.DeleteItem("abcd2")
+	if err != nil || !deleted {
+		t.Errorf("DeleteItem(unique prefix) = %v, %v, want deleted", deleted, err)
+	}
+}
+
 // --- ListRecent ---
 
+func TestInsertItems(t *testing.T) {
+	d := newTestDB(t)
+
+	const n = insertBatchSize + 50 // spans two batches
+
+	items := make([]models.Item, n)
+	details := make([]*string, n)
+
+	for i := range items {
+		items[i] = makeItem(fmt.Sprintf("Batch note %d", i), "proj")
+		items[i].ID = fmt.Sprintf("batch-%03d", i)
+
+		if i%2 == 0 {
+			body := fmt.Sprintf("details %d", i)
+			details[i] = &body
+		}
+	}
+
+	rowids, err := d.InsertItems(items, details)
+	if err != nil {
+		t.Fatalf("InsertItems() error = %v", err)
+	}
+
+	if len(rowids) != n {
+		t.Fatalf("InsertItems() returned %d rowids, want %d", len(rowids), n)
+	}
+
+	for i, item := range items {
+		var rowid int64
+		if err := d.db.Raw("SELECT rowid FROM items WHERE id = ?", item.ID).Scan(&rowid).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		if rowids[i] != rowid {
+			t.Errorf("rowid of %s = %d, want %d", item.ID, rowids[i], rowid)
+		}
+
+		detail, err := d.GetDetails(item.ID)
+		if err != nil {
+			t.Fatalf("GetDetails(%s) error = %v", item.ID, err)
+		}
+
+		switch {
+		case details[i] == nil && detail != nil:
+			t.Errorf("%s has details %q, want none", item.ID, detail.Body)
+		case details[i] != nil && (detail == nil || detail.Body != *details[i]):
+			t.Errorf("%s details = %v, want %q", item.ID, detail, *details[i])
+		}
+	}
+
+	if count, _ := d.CountItems(nil, nil); count != n {
+		t.Errorf("CountItems() = %d, want %d", count, n)
+	}
+
+	// The batch is indexed for keyword search like single inserts.
+	if results, _ := d.FTSSearch("Batch", 5, nil, nil); len(results) == 0 {
+		t.Error("FTSSearch() found none of the batch-inserted notes")
+	}
+}
+
+func TestInsertItems_RollsBackOnError(t *testing.T) {
+	d := newTestDB(t)
+
+	items := []models.Item{makeItem("One", "proj"), makeItem("Two", "proj")}
+	items[0].ID, items[1].ID = "dup-id", "dup-id"
+
+	if _, err := d.InsertItems(items, nil); err == nil {
+		t.Fatal("InsertItems() with a duplicate ID should fail")
+	}
+
+	if count, _ := d.CountItems(nil, nil); count != 0 {
+		t.Errorf("CountItems() after a failed batch = %d, want 0", count)
+	}
+}
+
+func TestReplaceTag(t *testing.T) {
+	d := newTestDB(t)
+
+	tagged := map[string][]string{
+		"a": {"Auth", "api"},
+		"b": {"api", "auth", "login"},
+		"c": {"authz", "api"},
+	}
+
+	for id, tags := range tagged {
+		item := makeItem("Note "+id, "proj")
+		item.ID = id
+		item.Tags = tags
+
+		if _, err := d.InsertItem(item, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tagsOf := func(id string) []string {
+		item, _, err := d.GetItem(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return item.Tags
+	}
+
+	changed, err := d.ReplaceTag("auth", "login")
+	if err != nil {
+		t.Fatalf("ReplaceTag() error = %v", err)
+	}
+
+	if !slices.Equal(changed, []string{"a", "b"}) {
+		t.Errorf("ReplaceTag() changed = %v, want [a b]", changed)
+	}
+
+	for id, want := range map[string][]string{
+		"a": {"login", "api"},
+		"b": {"api", "login"},
+		"c": {"authz", "api"},
+	} {
+		if got := tagsOf(id); !slices.Equal(got, want) {
+			t.Errorf("tags of %s = %v, want %v", id, got, want)
+		}
+	}
+
+	results, err := d.FTSSearch("login", 10, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 {
+		t.Errorf("FTSSearch(login) = %d results, want the 2 renamed notes", len(results))
+	}
+
+	changed, err = d.ReplaceTag("api", "")
+	if err != nil {
+		t.Fatalf("ReplaceTag(delete) error = %v", err)
+	}
+
+	if len(changed) != 3 {
+		t.Errorf("ReplaceTag(delete) changed = %v, want all 3 notes", changed)
+	}
+
+	if got := tagsOf("c"); !slices.Equal(got, []string{"authz"}) {
+		t.Errorf("tags of c = %v, want [authz]", got)
+	}
+}
+
+func TestReplaceTag_EscapedAndCaseVariants(t *testing.T) {
+	d := newTestDB(t)
+
+	tagged := map[string][]string{
+		"a": {"R&D", "<ops>"},
+		"b": {"Ärger", "go", "Go"},
+	}
+
+	for id, tags := range tagged {
+		item := makeItem("Note "+id, "proj")
+		item.ID = id
+		item.Tags = tags
+
+		if _, err := d.InsertItem(item, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if changed, err := d.ReplaceTag("r&d", "research"); err != nil || !slices.Equal(changed, []string{"a"}) {
+		t.Errorf("ReplaceTag(r&d) = %v, %v, want [a]", changed, err)
+	}
+
+	if changed, err := d.ReplaceTag("<OPS>", ""); err != nil || !slices.Equal(changed, []string{"a"}) {
+		t.Errorf("ReplaceTag(<OPS>) = %v, %v, want [a]", changed, err)
+	}
+
+	if changed, err := d.ReplaceTag("ärger", "trouble"); err != nil || !slices.Equal(changed, []string{"b"}) {
+		t.Errorf("ReplaceTag(ärger) = %v, %v, want [b]", changed, err)
+	}
+
+	for id, want := range map[string][]string{
+		"a": {"research"},
+		"b": {"trouble", "go", "Go"}, // duplicates of other tags are left alone
+	} {
+		item, _, err := d.GetItem(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(item.Tags, want) {
+			t.Errorf("tags of %s = %v, want %v", id, item.Tags, want)
+		}
+	}
+}
+
 func TestListRecent_OrderByCreatedAtDesc(t *testing.T) {
 	d := newTestDB(t)
 
@@ -363,6 +735,49 @@ func TestListRecent_OrderByCreatedAtDesc(t *testing.T) {
 	}
 }
 
+func TestListRecent_OrderUpdated(t *testing.T) {
+	d := newTestDB(t)
+
+	for i, title := range []string{"Old", "Middle", "New"} {
+		item := makeItem(title, "proj")
+		item.ID = title + "-uuid"
+		item.CreatedAt = fmt.Sprintf("2024-01-0%dT00:00:00Z", i+1)
+		item.UpdatedAt = item.CreatedAt
+
+		if _, err := d.InsertItem(item, nil); err != nil {
+			t.Fatalf("InsertItem(%s) error = %v", title, err)
+		}
+	}
+
+	what := "revised"
+	if err := d.UpdateItem("Old-uuid", &what, nil, nil, nil, nil, false); err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+
+	byCreated, err := d.ListRecent(10, nil, nil)
+	if err != nil {
+		t.Fatalf("ListRecent() error = %v", err)
+	}
+
+	if got := byCreated[0].ID; got != "New-uuid" {
+		t.Errorf("ListRecent() first = %s, want New-uuid", got)
+	}
+
+	byUpdated, err := d.ListRecent(10, nil, nil, WithOrderUpdated())
+	if err != nil {
+		t.Fatalf("ListRecent(WithOrderUpdated) error = %v", err)
+	}
+
+	var ids []string
+	for _, r := range byUpdated {
+		ids = append(ids, r.ID)
+	}
+
+	if want := []string{"Old-uuid", "New-uuid", "Middle-uuid"}; !slices.Equal(ids, want) {
+		t.Errorf("ListRecent(WithOrderUpdated) = %v, want %v", ids, want)
+	}
+}
+
 func TestListRecent_LimitRespected(t *testing.T) {
 	d := newTestDB(t)
 
@@ -489,9 +904,9 @@ func TestHasVecTable_FalseByDefault(t *testing.T) {
 func TestEnsureVecTable_CreatesTable(t *testing.T) {
 	d := newTestDB(t)
 
-	err := d.EnsureVecTable(384)
+	err := d.EnsureVecTable(384, MetricL2)
 	if err != nil {
-		t.Fatalf("EnsureVecTable(384) error = %v", err)
+		t.Fatalf("EnsureVecTable(384, MetricL2) error = %v", err)
 	}
 
 	if !d.HasVecTable() {
@@ -503,13 +918,911 @@ func TestEnsureVecTable_DimensionMismatch(t *testing.T) {
 	d := newTestDB(t)
 
 	// First call establishes dimension
-	if err := d.EnsureVecTable(384); err != nil {
-		t.Fatalf("EnsureVecTable(384) error = %v", err)
+	if err := d.EnsureVecTable(384, MetricL2); err != nil {
+		t.Fatalf("EnsureVecTable(384, MetricL2) error = %v", err)
 	}
 
 	// Second call with different dimension should fail
-	err := d.EnsureVecTable(768)
+	err := d.EnsureVecTable(768, MetricL2)
 	if err == nil {
-		t.Fatal("EnsureVecTable() should fail on dimension mismatch")
+		t.Fatal("EnsureVecTable(768, MetricL2) should fail on dimension mismatch")
+	}
+}
+
+// --- WithTx ---
+
+func TestWithTx_Commit(t *testing.T) {
+	d := newTestDB(t)
+
+	err := d.WithTx(func(tx Store) error {
+		for _, title := range []string{"Tx A", "Tx B"} {
+			if _, err := tx.InsertItem(makeItem(title, "proj"), nil); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+
+	count, err := d.CountItems(nil, nil)
+	if err != nil {
+		t.Fatalf("CountItems() error = %v", err)
+	}
+
+	if count != 2 {
+		t.Errorf("CountItems() after commit = %d, want 2", count)
+	}
+}
+
+func TestWithTx_RollbackOnError(t *testing.T) {
+	d := newTestDB(t)
+
+	existing := makeItem("Existing", "proj")
+	if _, err := d.InsertItem(existing, nil); err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+
+	errBoom := errors.New("boom")
+	newWhat := "changed inside tx"
+
+	err := d.WithTx(func(tx Store) error {
+		if _, err := tx.InsertItem(makeItem("Rolled Back", "proj"), nil); err != nil {
+			return err
+		}
+
+		if err := tx.UpdateItem(existing.ID, &newWhat, nil, nil, nil, nil, false); err != nil {
+			return err
+		}
+
+		if _, err := tx.DeleteItem(existing.ID); err != nil {
+			return err
+		}
+
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("WithTx() error = %v, want %v", err, errBoom)
+	}
+
+	count, err := d.CountItems(nil, nil)
+	if err != nil {
+		t.Fatalf("CountItems() error = %v", err)
+	}
+
+	if count != 1 {
+		t.Errorf("CountItems() after rollback = %d, want 1", count)
+	}
+
+	got, _, err := d.GetItem(existing.ID)
+	if err != nil {
+		t.Fatalf("GetItem() error = %v", err)
+	}
+
+	if got == nil || got.What != existing.What {
+		t.Errorf("GetItem() after rollback = %v, want original item", got)
+	}
+}
+
+// --- Project glob ---
+
+func TestFTSSearch_ProjectGlob(t *testing.T) {
+	d := newTestDB(t)
+
+	for _, project := range []string{"acme-api", "acme-web", "acme-worker", "other-api"} {
+		item := makeItem("Glob "+project, project)
+		item.What = "shared globtoken content"
+
+		if _, err := d.InsertItem(item, nil); err != nil {
+			t.Fatalf("InsertItem() error = %v", err)
+		}
+	}
+
+	results, err := d.FTSSearch("globtoken", 10, nil, nil, WithProjectGlob("acme-*"))
+	if err != nil {
+		t.Fatalf("FTSSearch() error = %v", err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("FTSSearch() with glob returned %d results, want 3", len(results))
+	}
+
+	for _, r := range results {
+		if r.Project == "other-api" {
+			t.Errorf("FTSSearch() with glob acme-* returned project %q", r.Project)
+		}
+	}
+
+	count, err := d.CountItems(nil, nil, WithProjectGlob("*-api"))
+	if err != nil {
+		t.Fatalf("CountItems() error = %v", err)
+	}
+
+	if count != 2 {
+		t.Errorf("CountItems(*-api) = %d, want 2", count)
+	}
+
+	recent, err := d.ListRecent(10, nil, nil, WithProjectGlob("acme-w*"))
+	if err != nil {
+		t.Fatalf("ListRecent() error = %v", err)
+	}
+
+	if len(recent) != 2 {
+		t.Errorf("ListRecent(acme-w*) len = %d, want 2", len(recent))
+	}
+}
+
+func TestProjectGlob_RejectsInvalid(t *testing.T) {
+	d := newTestDB(t)
+
+	for _, pattern := range []string{"", "acme' OR 1=1 --", "acme/*", "a b"} {
+		if _, err := d.FTSSearch("x", 5, nil, nil, WithProjectGlob(pattern)); !errors.Is(err, ErrInvalidProjectGlob) {
+			t.Errorf("FTSSearch() with glob %q error = %v, want ErrInvalidProjectGlob", pattern, err)
+		}
+	}
+}
+
+// --- DumpSchema ---
+
+func TestDumpSchema(t *testing.T) {
+	d := newTestDB(t)
+
+	if err := d.EnsureVecTable(8, MetricL2); err != nil {
+		t.Fatalf("EnsureVecTable(8, MetricL2) error = %v", err)
+	}
+
+	dump, err := d.DumpSchema()
+	if err != nil {
+		t.Fatalf("DumpSchema() error = %v", err)
+	}
+
+	found := map[string]string{}
+	for _, o := range dump.Objects {
+		found[o.Name] = o.Type
+	}
+
+	want := map[string]string{
+		"items":        "table",
+		"item_details": "table",
+		"meta":         "table",
+		"items_fts":    "table",
+		"items_vec":    "table",
+		"items_ai":     "trigger",
+		"items_au":     "trigger",
+	}
+
+	for name, typ := range want {
+		if found[name] != typ {
+			t.Errorf("DumpSchema() %s type = %q, want %q", name, found[name], typ)
+		}
+	}
+
+	if dump.Meta["embedding_dim"] != "8" {
+		t.Errorf("DumpSchema() meta embedding_dim = %q, want 8", dump.Meta["embedding_dim"])
+	}
+}
+
+// --- FTSFacets ---
+
+func TestFTSFacets_CountsAllMatches(t *testing.T) {
+	d := newTestDB(t)
+
+	bug, decision := "bug", "decision"
+	claude := "claude-code"
+
+	fixtures := []struct {
+		project  string
+		category *string
+		source   *string
+	}{
+		{"api", &bug, &claude},
+		{"api", &bug, nil},
+		{"api", &decision, &claude},
+		{"web", &bug, &claude},
+		{"web", nil, nil},
+	}
+
+	for i, f := range fixtures {
+		item := makeItem(fmt.Sprintf("Facet %d", i), f.project)
+		item.What = "facetword appears here"
+		item.Category = f.category
+		item.Source = f.source
+
+		if _, err := d.InsertItem(item, nil); err != nil {
+			t.Fatalf("InsertItem() error = %v", err)
+		}
+	}
+
+	// A non-matching item must not be counted.
+	if _, err := d.InsertItem(makeItem("Unrelated", "api"), nil); err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+
+	// Even with a page size of 1 the facets cover every match.
+	page, err := d.FTSSearch("facetword", 1, nil, nil)
+	if err != nil || len(page) != 1 {
+		t.Fatalf("FTSSearch() = %d results, err %v; want 1", len(page), err)
+	}
+
+	facets, err := d.FTSFacets("facetword", nil, nil)
+	if err != nil {
+		t.Fatalf("FTSFacets() error = %v", err)
+	}
+
+	want := map[string][]FacetCount{
+		"category": {{"bug", 3}, {"", 1}, {"decision", 1}},
+		"source":   {{"claude-code", 3}, {"", 2}},
+		"project":  {{"api", 3}, {"web", 2}},
+	}
+
+	for field, counts := range want {
+		got := facets[field]
+		if len(got) != len(counts) {
+			t.Errorf("facets[%s] = %v, want %v", field, got, counts)
+
+			continue
+		}
+
+		for i := range counts {
+			if got[i] != counts[i] {
+				t.Errorf("facets[%s][%d] = %v, want %v", field, i, got[i], counts[i])
+			}
+		}
+	}
+
+	proj := "web"
+
+	scoped, err := d.FTSFacets("facetword", &proj, nil)
+	if err != nil {
+		t.Fatalf("FTSFacets(web) error = %v", err)
+	}
+
+	if len(scoped["project"]) != 1 || scoped["project"][0].Count != 2 {
+		t.Errorf("FTSFacets(web) project = %v, want [{web 2}]", scoped["project"])
+	}
+}
+
+// --- Note links ---
+
+func TestLinks_CreateQueryAndCascade(t *testing.T) {
+	d := newTestDB(t)
+
+	for _, title := range []string{"old", "new", "bug"} {
+		if _, err := d.InsertItem(makeItem(title, "proj"), nil); err != nil {
+			t.Fatalf("InsertItem(%s) error = %v", title, err)
+		}
+	}
+
+	if err := d.InsertLink("new-id", "old-id", models.LinkSupersedes); err != nil {
+		t.Fatalf("InsertLink() error = %v", err)
+	}
+
+	if err := d.InsertLink("bug-id", "new-id", models.LinkRelatedTo); err != nil {
+		t.Fatalf("InsertLink() error = %v", err)
+	}
+
+	// Re-inserting the same link is a no-op.
+	if err := d.InsertLink("new-id", "old-id", models.LinkSupersedes); err != nil {
+		t.Fatalf("InsertLink() duplicate error = %v", err)
+	}
+
+	links, err := d.ListLinks("new-id")
+	if err != nil {
+		t.Fatalf("ListLinks() error = %v", err)
+	}
+
+	if len(links) != 2 {
+		t.Fatalf("ListLinks(new) = %d links, want 2: %v", len(links), links)
+	}
+
+	for _, l := range links {
+		if l.FromTitle == "" || l.ToTitle == "" {
+			t.Errorf("link %v should carry both titles", l)
+		}
+	}
+
+	if old, _ := d.ListLinks("old-id"); len(old) != 1 || old[0].Type != models.LinkSupersedes {
+		t.Errorf("ListLinks(old) = %v, want one supersedes link", old)
+	}
+
+	// Deleting a note removes links in both directions.
+	if _, err := d.DeleteItem("new-id"); err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+
+	var remaining int64
+
+	d.db.Model(&NoteLinkModel{}).Count(&remaining)
+
+	if remaining != 0 {
+		t.Errorf("note_links rows after delete = %d, want 0", remaining)
+	}
+}
+
+// --- Vectors ---
+
+func TestListMissingVectors(t *testing.T) {
+	d := newTestDB(t)
+
+	rowid, err := d.InsertItem(makeItem("embedded", "proj"), nil)
+	if err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+
+	if _, err := d.InsertItem(makeItem("pending", "proj"), nil); err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+
+	// Without a vector table every item is missing.
+	if missing, _ := d.ListMissingVectors(""); len(missing) != 2 {
+		t.Errorf("ListMissingVectors() without table = %d, want 2", len(missing))
+	}
+
+	if err := d.EnsureVecTable(3, MetricL2); err != nil {
+		t.Fatalf("EnsureVecTable(3, MetricL2) error = %v", err)
+	}
+
+	// Embeddings are bound as JSON text; vec0 rejects them as a raw BLOB.
+	if err := d.InsertVector(rowid, []float32{0.1, 0.2, 0.3}); err != nil {
+		t.Fatalf("InsertVector() error = %v", err)
+	}
+
+	missing, err := d.ListMissingVectors("")
+	if err != nil {
+		t.Fatalf("ListMissingVectors() error = %v", err)
+	}
+
+	if len(missing) != 1 || missing[0]["title"] != "pending" {
+		t.Errorf("ListMissingVectors() = %v, want only the pending item", missing)
+	}
+
+	results, err := d.VectorSearch([]float32{0.1, 0.2, 0.3}, 5, nil, nil)
+	if err != nil || len(results) != 1 || results[0].ID != "embedded-id" {
+		t.Errorf("VectorSearch() = %v, %v; want the embedded item", results, err)
+	}
+
+	// A vector made by another model, such as a fallback, is due again.
+	if err := d.SetVectorModel("embedded-id", "backup"); err != nil {
+		t.Fatalf("SetVectorModel() error = %v", err)
+	}
+
+	if missing, _ := d.ListMissingVectors("primary"); len(missing) != 2 {
+		t.Errorf("ListMissingVectors(primary) = %d, want 2 with the backup's vector", len(missing))
+	}
+
+	if missing, _ := d.ListMissingVectors("backup"); len(missing) != 1 {
+		t.Errorf("ListMissingVectors(backup) = %d, want only the pending item", len(missing))
+	}
+}
+
+func TestDeleteItem_RemovesVector(t *testing.T) {
+	d := newTestDB(t)
+
+	rowid, err := d.InsertItem(makeItem("doomed", "proj"), nil)
+	if err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+
+	if err := d.EnsureVecTable(3, MetricL2); err != nil {
+		t.Fatalf("EnsureVecTable(3, MetricL2) error = %v", err)
+	}
+
+	if err := d.InsertVector(rowid, []float32{0.1, 0.2, 0.3}); err != nil {
+		t.Fatalf("InsertVector() error = %v", err)
+	}
+
+	if _, err := d.DeleteItem("doomed-id"); err != nil {
+		t.Fatalf("DeleteItem() error = %v", err)
+	}
+
+	var remaining int64
+
+	d.db.Raw("SELECT COUNT(*) FROM items_vec").Scan(&remaining)
+
+	if remaining != 0 {
+		t.Errorf("items_vec rows after delete = %d, want 0", remaining)
+	}
+}
+
+func TestOrphanVectors_DetectAndFix(t *testing.T) {
+	d := newTestDB(t)
+
+	rowid, err := d.InsertItem(makeItem("kept", "proj"), nil)
+	if err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+
+	if err := d.EnsureVecTable(3, MetricL2); err != nil {
+		t.Fatalf("EnsureVecTable(3, MetricL2) error = %v", err)
+	}
+
+	for _, id := range []int64{rowid, 9001} { // 9001 has no items row
+		if err := d.InsertVector(id, []float32{0.1, 0.2, 0.3}); err != nil {
+			t.Fatalf("InsertVector(%d) error = %v", id, err)
+		}
+	}
+
+	orphans, err := d.OrphanVectors()
+	if err != nil {
+		t.Fatalf("OrphanVectors() error = %v", err)
+	}
+
+	if len(orphans) != 1 || orphans[0] != 9001 {
+		t.Fatalf("OrphanVectors() = %v, want [9001]", orphans)
+	}
+
+	removed, err := d.DeleteOrphanVectors()
+	if err != nil || removed != 1 {
+		t.Fatalf("DeleteOrphanVectors() = %d, %v; want 1", removed, err)
+	}
+
+	if orphans, _ := d.OrphanVectors(); len(orphans) != 0 {
+		t.Errorf("OrphanVectors() after fix = %v, want none", orphans)
+	}
+
+	var remaining int64
+
+	d.db.Raw("SELECT COUNT(*) FROM items_vec").Scan(&remaining)
+
+	if remaining != 1 {
+		t.Errorf("items_vec rows after fix = %d, want 1 (the kept item)", remaining)
+	}
+}
+
+func TestDeleteItem_VectorSearchNoLongerReturnsIt(t *testing.T) {
+	d := newTestDB(t)
+
+	if err := d.EnsureVecTable(3, MetricL2); err != nil {
+		t.Fatalf("EnsureVecTable(3, MetricL2) error = %v", err)
+	}
+
+	for title, vec := range map[string][]float32{
+		"gone":  {1, 0, 0},
+		"stays": {0, 1, 0},
+	} {
+		rowid, err := d.InsertItem(makeItem(title, "proj"), nil)
+		if err != nil {
+			t.Fatalf("InsertItem(%s) error = %v", title, err)
+		}
+
+		if err := d.InsertVector(rowid, vec); err != nil {
+			t.Fatalf("InsertVector(%s) error = %v", title, err)
+		}
+	}
+
+	if deleted, err := d.DeleteItem("gone-id"); err != nil || !deleted {
+		t.Fatalf("DeleteItem() = %v, %v; want true", deleted, err)
+	}
+
+	results, err := d.VectorSearch([]float32{1, 0, 0}, 5, nil, nil)
+	if err != nil {
+		t.Fatalf("VectorSearch() error = %v", err)
+	}
+
+	for _, r := range results {
+		if r.ID == "gone-id" {
+			t.Error("VectorSearch() returned a deleted item")
+		}
+	}
+
+	if len(results) != 1 {
+		t.Errorf("VectorSearch() = %d results, want 1", len(results))
+	}
+}
+
+// --- Distance metric ---
+
+func TestEnsureVecTable_HonorsMetric(t *testing.T) {
+	for _, metric := range []string{MetricCosine, MetricL1} {
+		t.Run(metric, func(t *testing.T) {
+			d := newTestDB(t)
+
+			if err := d.EnsureVecTable(2, metric); err != nil {
+				t.Fatalf("EnsureVecTable(%s) error = %v", metric, err)
+			}
+
+			if got := d.VecMetric(); got != metric {
+				t.Errorf("VecMetric() = %q, want %q", got, metric)
+			}
+
+			var ddl string
+
+			d.db.Raw("SELECT sql FROM sqlite_master WHERE name = 'items_vec'").Scan(&ddl)
+
+			if !strings.Contains(ddl, "distance_metric="+metric) {
+				t.Errorf("items_vec DDL = %q, want distance_metric=%s", ddl, metric)
+			}
+
+			if err := d.EnsureVecTable(2, MetricL2); !errors.Is(err, ErrMetricMismatch) {
+				t.Errorf("EnsureVecTable(l2) error = %v, want ErrMetricMismatch", err)
+			}
+		})
+	}
+}
+
+func TestEnsureVecTable_RejectsUnknownMetric(t *testing.T) {
+	d := newTestDB(t)
+
+	if err := d.EnsureVecTable(2, "hamming"); !errors.Is(err, ErrInvalidMetric) {
+		t.Errorf("EnsureVecTable(hamming) error = %v, want ErrInvalidMetric", err)
+	}
+}
+
+func TestVectorSearch_PopulatesDistance(t *testing.T) {
+	d := newTestDB(t)
+
+	if err := d.EnsureVecTable(2, MetricCosine); err != nil {
+		t.Fatalf("EnsureVecTable() error = %v", err)
+	}
+
+	rowid, err := d.InsertItem(makeItem("vec", "proj"), nil)
+	if err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+
+	if err := d.InsertVector(rowid, []float32{1, 0}); err != nil {
+		t.Fatalf("InsertVector() error = %v", err)
+	}
+
+	// Orthogonal query: cosine distance 1, so score 0.
+	results, err := d.VectorSearch([]float32{0, 1}, 5, nil, nil)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("VectorSearch() = %v, %v; want 1 result", results, err)
+	}
+
+	r := results[0]
+	if r.Distance == nil {
+		t.Fatal("VectorSearch() result Distance should be set")
+	}
+
+	if math.Abs(*r.Distance-1) > 1e-6 || math.Abs(r.Score) > 1e-6 {
+		t.Errorf("Distance = %f, Score = %f; want 1 and 0", *r.Distance, r.Score)
+	}
+}
+
+func TestRestoreVecTable_BringsBackBackup(t *testing.T) {
+	d := newTestDB(t)
+
+	if err := d.EnsureVecTable(2, MetricCosine); err != nil {
+		t.Fatalf("EnsureVecTable() error = %v", err)
+	}
+
+	rowid, err := d.InsertItem(makeItem("vec", "proj"), nil)
+	if err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+
+	if err := d.InsertVector(rowid, []float32{1, 0}); err != nil {
+		t.Fatalf("InsertVector() error = %v", err)
+	}
+
+	if err := d.BackupVecTable(); err != nil {
+		t.Fatalf("BackupVecTable() error = %v", err)
+	}
+
+	// A rebuild for another dimension that is abandoned part-way.
+	if err := d.DropVecTable(); err != nil {
+		t.Fatalf("DropVecTable() error = %v", err)
+	}
+
+	if err := d.EnsureVecTable(3, MetricL2); err != nil {
+		t.Fatalf("EnsureVecTable(3) error = %v", err)
+	}
+
+	if err := d.RestoreVecTable(); err != nil {
+		t.Fatalf("RestoreVecTable() error = %v", err)
+	}
+
+	if d.EmbeddingDim() != 2 || d.VecMetric() != MetricCosine {
+		t.Errorf("restored table = %d dims, %s; want 2, cosine", d.EmbeddingDim(), d.VecMetric())
+	}
+
+	results, err := d.VectorSearch([]float32{1, 0}, 5, nil, nil)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("VectorSearch() = %v, %v; want the restored vector", results, err)
+	}
+
+	if _, ok, _ := d.GetMeta(metaBackupDim); ok {
+		t.Error("RestoreVecTable() left the backup behind")
+	}
+
+	// With the backup gone, a second restore does nothing.
+	if err := d.RestoreVecTable(); err != nil || d.EmbeddingDim() != 2 {
+		t.Errorf("second RestoreVecTable() = %v, dim %d; want no change", err, d.EmbeddingDim())
+	}
+}
+
+func TestRebuildFTS_RestoresSearch(t *testing.T) {
+	d := newTestDB(t)
+	item := makeItem("FTS Rebuild Test", "proj")
+	item.What = "needle plugh"
+
+	if _, err := d.InsertItem(item, nil); err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+
+	// Drop every index entry, as a broken trigger or manual edit might.
+	if err := d.db.Exec("INSERT INTO items_fts(items_fts) VALUES('delete-all')").Error; err != nil {
+		t.Fatalf("clearing items_fts: %v", err)
+	}
+
+	results, err := d.FTSSearch("plugh", 5, nil, nil)
+	if err != nil {
+		t.Fatalf("FTSSearch() error = %v", err)
+	}
+
+	if len(results) != 0 {
+		t.Fatalf("FTSSearch() after clearing = %d results, want 0", len(results))
+	}
+
+	count, err := d.RebuildFTS()
+	if err != nil {
+		t.Fatalf("RebuildFTS() error = %v", err)
+	}
+
+	if count != 1 {
+		t.Errorf("RebuildFTS() = %d, want 1", count)
+	}
+
+	results, err = d.FTSSearch("plugh", 5, nil, nil)
+	if err != nil {
+		t.Fatalf("FTSSearch() error = %v", err)
+	}
+
+	if len(results) != 1 || results[0].ID != item.ID {
+		t.Errorf("FTSSearch() after rebuild = %v, want %s", results, item.ID)
+	}
+}
+
+func TestSearchResults_ReportUpdatedAt(t *testing.T) {
+	d := newTestDB(t)
+
+	if err := d.EnsureVecTable(2, MetricCosine); err != nil {
+		t.Fatalf("EnsureVecTable() error = %v", err)
+	}
+
+	item := makeItem("Revised", "proj")
+	item.CreatedAt = "2024-03-02T10:00:00Z"
+	item.UpdatedAt = item.CreatedAt
+
+	rowid, err := d.InsertItem(item, nil)
+	if err != nil {
+		t.Fatalf("InsertItem() error = %v", err)
+	}
+
+	if err := d.InsertVector(rowid, []float32{1, 0}); err != nil {
+		t.Fatalf("InsertVector() error = %v", err)
+	}
+
+	fresh, _ := d.ListRecent(5, nil, nil)
+	if len(fresh) != 1 || fresh[0].UpdatedAt != item.CreatedAt || fresh[0].Revised() {
+		t.Fatalf("ListRecent() before update = %+v, want UpdatedAt = CreatedAt", fresh)
+	}
+
+	what := "revised what"
+	if err := d.UpdateItem(item.ID, &what, nil, nil, nil, nil, false); err != nil {
+		t.Fatalf("UpdateItem() error = %v", err)
+	}
+
+	fts, err := d.FTSSearch("revised", 5, nil, nil)
+	if err != nil {
+		t.Fatalf("FTSSearch() error = %v", err)
+	}
+
+	vec, err := d.VectorSearch([]float32{1, 0}, 5, nil, nil)
+	if err != nil {
+		t.Fatalf("VectorSearch() error = %v", err)
+	}
+
+	recent, err := d.ListRecent(5, nil, nil)
+	if err != nil {
+		t.Fatalf("ListRecent() error = %v", err)
+	}
+
+	for name, results := range map[string][]models.SearchResult{"FTSSearch": fts, "VectorSearch": vec, "ListRecent": recent} {
+		if len(results) != 1 {
+			t.Fatalf("%s() returned %d results, want 1", name, len(results))
+		}
+
+		r := results[0]
+		if r.CreatedAt != item.CreatedAt || r.UpdatedAt <= r.CreatedAt || !r.Revised() {
+			t.Errorf("%s() created %q updated %q, want a later updated_at", name, r.CreatedAt, r.UpdatedAt)
+		}
+	}
+}
+
+func TestCheckpoint_TruncatesWAL(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.db")
+
+	d, err := NewDB(path)
+	if err != nil {
+		t.Fatalf("NewDB() error = %v", err)
+	}
+
+	t.Cleanup(func() { _ = d.Close() })
+
+	// A fresh database uses a rollback journal; checkpointing is a no-op.
+	if err := d.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint() without WAL error = %v", err)
+	}
+
+	if err := d.db.Exec("PRAGMA journal_mode=WAL").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 20 {
+		item := makeItem(fmt.Sprintf("WAL note %d", i), "proj")
+		if _, err := d.InsertItem(item, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(path + "-wal")
+	if err != nil || info.Size() == 0 {
+		t.Fatalf("WAL after writes = %v, %v; want a non-empty log", info, err)
+	}
+
+	if err := d.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint() error = %v", err)
+	}
+
+	info, err = os.Stat(path + "-wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Size() != 0 {
+		t.Errorf("WAL after Checkpoint() = %d bytes, want 0", info.Size())
+	}
+
+	if n, _ := d.CountItems(nil, nil); n != 20 {
+		t.Errorf("CountItems() after Checkpoint() = %d, want 20", n)
+	}
+}
+
+func TestCountByPeriod(t *testing.T) {
+	d := newTestDB(t)
+
+	for i, created := range []string{
+		"2025-03-03T09:00:00Z", // Monday
+		"2025-03-09T23:00:00Z", // Sunday, same week
+		"2025-03-10T08:00:00Z", // next Monday
+		"2025-03-31T12:00:00Z", // Monday
+		"2025-04-01T12:00:00Z", // Tuesday, same week, next month
+	} {
+		project := "api"
+		if i == 2 {
+			project = "web"
+		}
+
+		item := makeItem(fmt.Sprintf("Note %d", i), project)
+		item.CreatedAt, item.UpdatedAt = created, created
+
+		if _, err := d.InsertItem(item, nil); err != nil {
+			t.Fatalf("InsertItem() error = %v", err)
+		}
+	}
+
+	api := "api"
+
+	tests := []struct {
+		granularity string
+		project     *string
+		want        []PeriodCount
+	}{
+		{PeriodWeek, nil, []PeriodCount{{"2025-03-03", 2}, {"2025-03-10", 1}, {"2025-03-31", 2}}},
+		{PeriodMonth, nil, []PeriodCount{{"2025-03", 4}, {"2025-04", 1}}},
+		{PeriodDay, &api, []PeriodCount{{"2025-03-03", 1}, {"2025-03-09", 1}, {"2025-03-31", 1}, {"2025-04-01", 1}}},
+	}
+
+	for _, tt := range tests {
+		got, err := d.CountByPeriod(tt.granularity, tt.project)
+		if err != nil {
+			t.Fatalf("CountByPeriod(%s) error = %v", tt.granularity, err)
+		}
+
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("CountByPeriod(%s) = %v, want %v", tt.granularity, got, tt.want)
+		}
+	}
+
+	if _, err := d.CountByPeriod("year", nil); err == nil {
+		t.Error("CountByPeriod(year) error = nil, want an invalid period error")
+	}
+}
+
+func TestStats(t *testing.T) {
+	d := newTestDB(t)
+
+	empty, err := d.Stats(nil)
+	if err != nil {
+		t.Fatalf("Stats(nil) on an empty database error = %v", err)
+	}
+
+	if empty.Items != 0 || empty.Vectors != 0 || empty.SizeBytes <= 0 {
+		t.Errorf("Stats(nil) on an empty database = %+v, want no items and a non-zero size", empty)
+	}
+
+	if err := d.EnsureVecTable(3, MetricL2); err != nil {
+		t.Fatalf("EnsureVecTable() error = %v", err)
+	}
+
+	cat, src := "bug", "cli"
+	details := "longer body"
+
+	for i, f := range []struct {
+		project  string
+		category *string
+		source   *string
+		details  *string
+	}{
+		{"api", &cat, &src, &details},
+		{"api", nil, &src, nil},
+		{"web", &cat, nil, &details},
+		{"web", nil, nil, nil},
+	} {
+		item := makeItem(fmt.Sprintf("Stats %d", i), f.project)
+		item.Category, item.Source = f.category, f.source
+
+		rowid, err := d.InsertItem(item, f.details)
+		if err != nil {
+			t.Fatalf("InsertItem() error = %v", err)
+		}
+
+		if i < 3 {
+			if err := d.InsertVector(rowid, []float32{0.1, 0.2, 0.3}); err != nil {
+				t.Fatalf("InsertVector() error = %v", err)
+			}
+		}
+	}
+
+	stats, err := d.Stats(nil)
+	if err != nil {
+		t.Fatalf("Stats(nil) error = %v", err)
+	}
+
+	items, _ := d.CountItems(nil, nil)
+	sources, _ := d.ListSources()
+	missing, _ := d.ListMissingVectors("")
+
+	var withDetails int64
+
+	for _, id := range []string{"Stats 0-id", "Stats 1-id", "Stats 2-id", "Stats 3-id"} {
+		if detail, _ := d.GetDetails(id); detail != nil {
+			withDetails++
+		}
+	}
+
+	want := StoreStats{
+		Items:       items,
+		WithDetails: withDetails,
+		Projects:    2,
+		Sources:     int64(len(sources)),
+		Categories:  1,
+		Vectors:     items - int64(len(missing)),
+		SizeBytes:   stats.SizeBytes,
+	}
+
+	if stats != want {
+		t.Errorf("Stats(nil) = %+v, want %+v", stats, want)
+	}
+
+	if stats.Items != 4 || stats.WithDetails != 2 || stats.Vectors != 3 {
+		t.Errorf("Stats(nil) = %+v, want 4 items, 2 with details and 3 vectors", stats)
+	}
+
+	project := "web"
+
+	scoped, err := d.Stats(&project)
+	if err != nil {
+		t.Fatalf("Stats(web) error = %v", err)
+	}
+
+	want = StoreStats{Items: 2, WithDetails: 1, Projects: 1, Categories: 1, Vectors: 1, SizeBytes: stats.SizeBytes}
+	if scoped != want {
+		t.Errorf("Stats(web) = %+v, want %+v", scoped, want)
 	}
 }
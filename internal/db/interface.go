@@ -9,28 +9,73 @@ import (
 // ErrNotFound is returned when a requested item does not exist in the database.
 var ErrNotFound = errors.New("item not found")
 
+// ErrIDPrefixTooShort is returned when an ID prefix is too short to look up.
+var ErrIDPrefixTooShort = errors.New("ID prefix too short")
+
+// ErrAmbiguousIDPrefix is returned when an ID prefix matches several items.
+var ErrAmbiguousIDPrefix = errors.New("ambiguous ID prefix")
+
 // ErrDimensionMismatch is returned when the embedding dimension stored in the
 // database does not match the dimension returned by the current provider.
 // The caller should advise the user to run 'pantry reindex'.
 var ErrDimensionMismatch = errors.New("embedding dimension mismatch")
 
+// ErrMetricMismatch is returned when the vector table was created with a
+// different distance metric than the configured one.
+var ErrMetricMismatch = errors.New("distance metric mismatch")
+
 // Store is the persistence interface for pantry operations.
 // *DB implements this interface; test code can inject a stub.
 type Store interface {
 	InsertItem(item models.Item, details *string) (int64, error)
+	InsertItems(items []models.Item, details []*string) ([]int64, error)
+	ReplaceItem(item models.Item, details *string) error
 	InsertVector(rowid int64, embedding []float32) error
+	ReplaceVector(itemID string, embedding []float32) error
 	GetItem(itemID string) (*models.Item, bool, error)
 	GetDetails(itemID string) (*models.ItemDetail, error)
-	UpdateItem(itemID string, what *string, why *string, impact *string, tags []string, detailsAppend *string) error
+	UpdateItem(itemID string, what *string, why *string, impact *string, tags []string, details *string, replaceDetails bool) error
+	SetMetadata(itemID string, metadata map[string]string) error
 	DeleteItem(itemID string) (bool, error)
-	FTSSearch(query string, limit int, project *string, source *string) ([]models.SearchResult, error)
-	VectorSearch(queryEmbedding []float32, limit int, project *string, source *string) ([]models.SearchResult, error)
-	ListRecent(limit int, project *string, source *string) ([]models.SearchResult, error)
+	InsertLink(fromID, toID, linkType string) error
+	ListLinks(itemID string) ([]models.NoteLink, error)
+	FTSSearch(query string, limit int, project *string, source *string, opts ...QueryOption) ([]models.SearchResult, error)
+	VectorSearch(queryEmbedding []float32, limit int, project *string, source *string, opts ...QueryOption) ([]models.SearchResult, error)
+	FTSFacets(query string, project *string, source *string, opts ...QueryOption) (Facets, error)
+	CountFTS(query string, project *string, source *string, opts ...QueryOption) (int64, error)
+	ListRecent(limit int, project *string, source *string, opts ...QueryOption) ([]models.SearchResult, error)
+	ListItems(project *string) ([]models.Item, error)
+	ListByFilePath(filePath string) ([]models.Item, error)
+	ListSources() ([]FacetCount, error)
+	CountByPeriod(granularity string, project *string) ([]PeriodCount, error)
+	Stats(project *string) (StoreStats, error)
+	Terms(project *string) (map[string]int, error)
 	ListAllForReindex() ([]map[string]any, error)
-	CountItems(project *string, source *string) (int64, error)
+	ListMissingVectors(model string) ([]map[string]any, error)
+	SetVectorModel(itemID, model string) error
+	CountItems(project *string, source *string, opts ...QueryOption) (int64, error)
 	HasVecTable() bool
-	EnsureVecTable(dim int) error
+	EnsureVecTable(dim int, metric string) error
+	VecMetric() string
 	SetEmbeddingDim(dim int) error
+	EmbeddingDim() int
+	GetMeta(key string) (string, bool, error)
+	SetMeta(key, value string) error
 	DropVecTable() error
+	BackupVecTable() error
+	RestoreVecTable() error
+	DropVecBackup() error
+	OrphanVectors() ([]int64, error)
+	DeleteOrphanVectors() (int64, error)
+	RewriteFilePaths(oldDir, newDir string) (int64, error)
+	RenameSources(rename func(source string) string) (int64, error)
+	ReplaceTag(tag, replacement string) ([]string, error)
+	RebuildFTS() (int64, error)
+	Checkpoint() error
+	DumpSchema() (*SchemaDump, error)
+	// WithTx runs fn against a Store bound to a single transaction. If fn
+	// returns an error (or panics) every write made through it is rolled back.
+	// The Store passed to fn must not be closed or retained after fn returns.
+	WithTx(fn func(Store) error) error
 	Close() error
 }
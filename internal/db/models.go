@@ -18,11 +18,14 @@ type ItemModel struct {
 	Project       string  `gorm:"type:text;not null"`
 	Source        *string `gorm:"type:text"`
 	RelatedFiles  string  `gorm:"type:text"` // JSON encoded
+	Metadata      string  `gorm:"type:text"` // JSON encoded; empty when unset
 	FilePath      string  `gorm:"type:text;not null"`
 	SectionAnchor string  `gorm:"type:text"`
 	CreatedAt     string  `gorm:"type:text;not null"`
 	UpdatedAt     string  `gorm:"type:text;not null"`
 	UpdatedCount  int     `gorm:"default:0"`
+	// VectorModel is the model that embedded the item's vector, when known.
+	VectorModel *string `gorm:"type:text"`
 }
 
 // TableName specifies the table name for GORM.
@@ -52,6 +55,20 @@ func (MetaModel) TableName() string {
 	return "meta"
 }
 
+// NoteLinkModel represents the note_links table: a typed, directed edge
+// between two items.
+type NoteLinkModel struct {
+	FromID    string `gorm:"primaryKey;type:text"`
+	ToID      string `gorm:"primaryKey;type:text;index"`
+	Type      string `gorm:"primaryKey;type:text"`
+	CreatedAt string `gorm:"type:text;not null"`
+}
+
+// TableName specifies the table name for GORM.
+func (NoteLinkModel) TableName() string {
+	return "note_links"
+}
+
 // ToItem converts ItemModel to models.Item.
 func (im *ItemModel) ToItem() models.Item {
 	item := models.Item{
@@ -85,7 +102,7 @@ func (im *ItemModel) ToItem() models.Item {
 }
 
 // FromItem converts models.Item to ItemModel.
-func (im *ItemModel) FromItem(item models.Item, tagsJSON, relatedFilesJSON string) {
+func (im *ItemModel) FromItem(item models.Item, tagsJSON, relatedFilesJSON, metadataJSON string) {
 	im.ID = item.ID
 	im.Title = item.Title
 	im.What = item.What
@@ -96,8 +113,53 @@ func (im *ItemModel) FromItem(item models.Item, tagsJSON, relatedFilesJSON strin
 	im.Project = item.Project
 	im.Source = item.Source
 	im.RelatedFiles = relatedFilesJSON
+	im.Metadata = metadataJSON
 	im.FilePath = item.FilePath
 	im.SectionAnchor = item.SectionAnchor
 	im.CreatedAt = item.CreatedAt
 	im.UpdatedAt = item.UpdatedAt
 }
+
+// SchemaObject describes one entry of sqlite_master.
+type SchemaObject struct {
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	TableName string `json:"table"`
+	SQL       string `json:"sql"`
+}
+
+// SchemaDump is the database schema together with the stored meta values.
+type SchemaDump struct {
+	Objects []SchemaObject    `json:"objects"`
+	Meta    map[string]string `json:"meta"`
+}
+
+// FacetCount is the number of matching items sharing one facet value.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Facets maps a facet field name to its value counts, highest first.
+type Facets map[string][]FacetCount
+
+// PeriodCount is the number of items created in one period, labelled by its
+// first day (YYYY-MM-DD) or month (YYYY-MM).
+type PeriodCount struct {
+	Period string `json:"period"`
+	Count  int64  `json:"count"`
+}
+
+// StoreStats are aggregate counts over the database, or one project's notes,
+// as returned by Stats.
+type StoreStats struct {
+	Items       int64 `json:"items"`
+	WithDetails int64 `json:"with_details"`
+	Projects    int64 `json:"projects"`
+	Sources     int64 `json:"sources"`
+	Categories  int64 `json:"categories"`
+	Vectors     int64 `json:"vectors"`
+	// SizeBytes is the size of the database file, not counting the
+	// write-ahead log.
+	SizeBytes int64 `json:"size_bytes"`
+}
@@ -0,0 +1,223 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidProjectGlob is returned when a project glob contains characters
+// outside the allowed set.
+var ErrInvalidProjectGlob = errors.New("invalid project glob")
+
+// ErrInvalidMetaKey is returned when a metadata filter key contains
+// characters outside the allowed set.
+var ErrInvalidMetaKey = errors.New("invalid metadata key")
+
+// metaKeyRe limits metadata filter keys to characters that need no quoting in
+// a JSON path.
+var metaKeyRe = regexp.MustCompile(`^[A-Za-z0-9_\-]+$`)
+
+// projectGlobRe limits globs to the characters used in project names plus the
+// GLOB wildcards * ? and [...] classes.
+var projectGlobRe = regexp.MustCompile(`^[A-Za-z0-9._\-*?\[\]^]+$`)
+
+// QueryOption narrows FTSSearch, VectorSearch, ListRecent and CountItems
+// beyond the positional project/source filters.
+type QueryOption func(*queryFilter)
+
+// queryFilter collects the optional predicates set by QueryOption values.
+type queryFilter struct {
+	projectGlob *string
+	meta        [][2]string // key, value pairs that must all match
+	createdFrom *string     // RFC3339, inclusive
+	createdTo   *string     // RFC3339, inclusive
+	changedFrom *string     // RFC3339, inclusive; matched against updated_at
+	orderNear   *string     // RFC3339; ListRecent orders by distance from it
+	// orderUpdated makes ListRecent order by last change instead of creation.
+	orderUpdated bool
+	// columnWeights are FTSSearch's bm25 weights by FTSColumns name.
+	columnWeights map[string]float64
+}
+
+// FTSColumns are the columns of the keyword index, in index order.
+var FTSColumns = []string{"title", "what", "why", "impact", "tags", "category", "project", "source"}
+
+// WithProjectGlob restricts results to projects matching a shell-style glob
+// such as "acme-*". Matching is case-sensitive (SQLite GLOB).
+func WithProjectGlob(pattern string) QueryOption {
+	return func(f *queryFilter) { f.projectGlob = &pattern }
+}
+
+// WithMeta restricts results to items whose metadata has key set to value.
+// Several WithMeta options must all match.
+func WithMeta(key, value string) QueryOption {
+	return func(f *queryFilter) { f.meta = append(f.meta, [2]string{key, value}) }
+}
+
+// WithCreatedBetween restricts results to items created from from to to,
+// inclusive.
+func WithCreatedBetween(from, to time.Time) QueryOption {
+	fromStr := from.UTC().Format(time.RFC3339)
+	toStr := to.UTC().Format(time.RFC3339)
+
+	return func(f *queryFilter) {
+		f.createdFrom = &fromStr
+		f.createdTo = &toStr
+	}
+}
+
+// WithChangedSince restricts results to items created or updated at or after
+// t. A new item's updated_at equals its created_at, so one check covers both.
+func WithChangedSince(t time.Time) QueryOption {
+	since := t.UTC().Format(time.RFC3339)
+
+	return func(f *queryFilter) { f.changedFrom = &since }
+}
+
+// WithColumnWeights ranks FTSSearch matches with bm25 using these per-column
+// weights, keyed by FTSColumns name; a match in a column weighted 10 counts
+// ten times one weighted 1. Columns left out weigh 1. Other queries ignore it.
+func WithColumnWeights(weights map[string]float64) QueryOption {
+	return func(f *queryFilter) { f.columnWeights = weights }
+}
+
+// WithOrderNear makes ListRecent return the items created closest to t
+// instead of the newest. Other queries ignore it.
+func WithOrderNear(t time.Time) QueryOption {
+	near := t.UTC().Format(time.RFC3339)
+
+	return func(f *queryFilter) { f.orderNear = &near }
+}
+
+// WithOrderUpdated makes ListRecent return the most recently changed items
+// first, ordering by updated_at instead of created_at. Other queries ignore
+// it.
+func WithOrderUpdated() QueryOption {
+	return func(f *queryFilter) { f.orderUpdated = true }
+}
+
+// ValidateMetaKey returns an error if key is empty or contains characters
+// other than letters, digits, '_' and '-'.
+func ValidateMetaKey(key string) error {
+	if !metaKeyRe.MatchString(key) {
+		return fmt.Errorf("%w %q: only letters, digits, '_' and '-' are allowed", ErrInvalidMetaKey, key)
+	}
+
+	return nil
+}
+
+// ValidateProjectGlob returns an error if pattern is empty or contains
+// characters that are not allowed in a project glob.
+func ValidateProjectGlob(pattern string) error {
+	if !projectGlobRe.MatchString(pattern) {
+		return fmt.Errorf("%w %q: only letters, digits, '.', '_', '-' and the wildcards * ? [ ] are allowed", ErrInvalidProjectGlob, pattern)
+	}
+
+	return nil
+}
+
+// newQueryFilter applies opts and validates the result.
+func newQueryFilter(opts []QueryOption) (*queryFilter, error) {
+	f := &queryFilter{}
+	for _, o := range opts {
+		o(f)
+	}
+
+	if f.projectGlob != nil {
+		if err := ValidateProjectGlob(*f.projectGlob); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, kv := range f.meta {
+		if err := ValidateMetaKey(kv[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	for col, w := range f.columnWeights {
+		if !slices.Contains(FTSColumns, col) || w < 0 {
+			return nil, fmt.Errorf("invalid column weight %s=%v: column must be one of %s and the weight not negative", col, w, strings.Join(FTSColumns, ", "))
+		}
+	}
+
+	return f, nil
+}
+
+// rankExpr returns the bm25 call FTSSearch ranks by. Lower is better, as
+// with FTS5's rank.
+func (f *queryFilter) rankExpr() string {
+	weights := make([]string, len(FTSColumns))
+	for i, col := range FTSColumns {
+		w, ok := f.columnWeights[col]
+		if !ok {
+			w = 1
+		}
+
+		weights[i] = strconv.FormatFloat(w, 'g', -1, 64)
+	}
+
+	return "bm25(items_fts, " + strings.Join(weights, ", ") + ")"
+}
+
+// whereClause builds the " AND ..." predicates (against the items alias m)
+// shared by the raw-SQL search queries, along with their bind arguments.
+func (f *queryFilter) whereClause(project *string, source *string) (string, []any) {
+	clause := ""
+	args := []any{}
+
+	if project != nil {
+		clause += " AND m.project = ?"
+
+		args = append(args, *project)
+	}
+
+	if f.projectGlob != nil {
+		clause += " AND m.project GLOB ?"
+
+		args = append(args, *f.projectGlob)
+	}
+
+	if source != nil {
+		clause += " AND m.source = ?"
+
+		args = append(args, *source)
+	}
+
+	for _, kv := range f.meta {
+		clause += " AND " + metaPredicate("m.metadata")
+
+		args = append(args, metaPath(kv[0]), kv[1])
+	}
+
+	// created_at is RFC3339 in UTC, so text order is time order.
+	if f.createdFrom != nil {
+		clause += " AND m.created_at >= ? AND m.created_at <= ?"
+
+		args = append(args, *f.createdFrom, *f.createdTo)
+	}
+
+	if f.changedFrom != nil {
+		clause += " AND m.updated_at >= ?"
+
+		args = append(args, *f.changedFrom)
+	}
+
+	return clause, args
+}
+
+// metaPredicate matches one metadata pair in the JSON column col. Items
+// without metadata store "", which json_extract would reject, hence the CASE.
+func metaPredicate(col string) string {
+	return fmt.Sprintf("CASE WHEN json_valid(%[1]s) THEN json_extract(%[1]s, ?) END = ?", col)
+}
+
+// metaPath returns the JSON path of a validated metadata key.
+func metaPath(key string) string {
+	return `$."` + key + `"`
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Vector distance metrics supported by vec0.
+const (
+	MetricL2     = "l2"
+	MetricCosine = "cosine"
+	MetricL1     = "l1"
+)
+
+// Metrics lists the supported distance metrics, MetricL2 (the default)
+// first.
+var Metrics = []string{MetricL2, MetricCosine, MetricL1}
+
+// metaVecMetric is the meta key recording the vector table's distance metric.
+const metaVecMetric = "vec_metric"
+
+// ErrInvalidMetric is returned for a distance metric vec0 does not support.
+var ErrInvalidMetric = errors.New("invalid distance metric")
+
+// normalizeMetric maps "" to MetricL2 and rejects unknown metrics.
+func normalizeMetric(metric string) (string, error) {
+	switch {
+	case metric == "":
+		return MetricL2, nil
+	case slices.Contains(Metrics, metric):
+		return metric, nil
+	default:
+		return "", fmt.Errorf("%w %q: must be one of %s", ErrInvalidMetric, metric, strings.Join(Metrics, ", "))
+	}
+}
+
+// Meta keys recording the dimension and metric of the backed-up vector table.
+const (
+	metaBackupDim    = "vec_backup_dim"
+	metaBackupMetric = "vec_backup_metric"
+)
+
+// BackupVecTable copies the vector table, with its dimension and metric, into
+// items_vec_backup, replacing an earlier backup, so RestoreVecTable can bring
+// it back if a rebuild fails. It does nothing when there is no vector table.
+func (d *DB) BackupVecTable() error {
+	if !d.HasVecTable() {
+		return d.DropVecBackup()
+	}
+
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		t := &DB{db: tx}
+		if err := t.DropVecBackup(); err != nil {
+			return err
+		}
+
+		if err := tx.Exec("CREATE TABLE items_vec_backup AS SELECT rowid AS item_rowid, embedding FROM items_vec").Error; err != nil {
+			return err
+		}
+
+		if err := t.SetMeta(metaBackupDim, strconv.Itoa(t.EmbeddingDim())); err != nil {
+			return err
+		}
+
+		return t.SetMeta(metaBackupMetric, t.VecMetric())
+	})
+}
+
+// RestoreVecTable replaces the vector table with the one saved by
+// BackupVecTable and drops the backup. Without a backup it does nothing.
+func (d *DB) RestoreVecTable() error {
+	value, ok, err := d.GetMeta(metaBackupDim)
+	if err != nil || !ok {
+		return err
+	}
+
+	dim, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", metaBackupDim, value, err)
+	}
+
+	metric, _, err := d.GetMeta(metaBackupMetric)
+	if err != nil {
+		return err
+	}
+
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		t := &DB{db: tx}
+		if err := t.DropVecTable(); err != nil {
+			return err
+		}
+
+		if err := t.EnsureVecTable(dim, metric); err != nil {
+			return err
+		}
+
+		if err := tx.Exec("INSERT INTO items_vec (rowid, embedding) SELECT item_rowid, embedding FROM items_vec_backup").Error; err != nil {
+			return err
+		}
+
+		return t.DropVecBackup()
+	})
+}
+
+// DropVecBackup removes the backup made by BackupVecTable, if any.
+func (d *DB) DropVecBackup() error {
+	if err := d.db.Exec("DROP TABLE IF EXISTS items_vec_backup").Error; err != nil {
+		return err
+	}
+
+	return d.db.Where("key IN ?", []string{metaBackupDim, metaBackupMetric}).Delete(&MetaModel{}).Error
+}
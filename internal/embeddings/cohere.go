@@ -0,0 +1,106 @@
+package embeddings
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultCohereBaseURL is used when no base_url is configured.
+const DefaultCohereBaseURL = "https://api.cohere.com"
+
+// CohereProvider implements embedding generation using the Cohere embed API.
+// Notes are embedded as search_document and queries as search_query, as
+// selected by WithInputType on the context passed to Embed.
+type CohereProvider struct {
+	model   string
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// NewCohereProvider creates a new Cohere embedding provider.
+// baseURL is optional; defaults to DefaultCohereBaseURL.
+func NewCohereProvider(model string, apiKey string, baseURL string) *CohereProvider {
+	if baseURL == "" {
+		baseURL = DefaultCohereBaseURL
+	}
+
+	return &CohereProvider{
+		model:   model,
+		apiKey:  apiKey,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{},
+	}
+}
+
+// CloseIdleConnections releases idle keep-alive connections to the API.
+func (p *CohereProvider) CloseIdleConnections() {
+	p.client.CloseIdleConnections()
+}
+
+type cohereEmbedRequest struct {
+	Texts     []string `json:"texts"`
+	Model     string   `json:"model"`
+	InputType string   `json:"input_type"`
+}
+
+type cohereEmbedResponse struct {
+	Embeddings [][]float64 `json:"embeddings"`
+}
+
+// Embed generates an embedding vector using Cohere.
+func (p *CohereProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	url := p.baseURL + "/v1/embed"
+
+	jsonData, err := json.Marshal(cohereEmbedRequest{
+		Texts:     []string{text},
+		Model:     p.model,
+		InputType: string(InputTypeFrom(ctx)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call Cohere API: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+
+		return nil, fmt.Errorf("cohere API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response cohereEmbedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(response.Embeddings) == 0 {
+		return nil, errors.New("no embedding data in response")
+	}
+
+	embedding := make([]float32, len(response.Embeddings[0]))
+	for i, v := range response.Embeddings[0] {
+		embedding[i] = float32(v)
+	}
+
+	return embedding, nil
+}
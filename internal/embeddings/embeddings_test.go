@@ -3,9 +3,13 @@ package embeddings
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"pantry/internal/config"
 )
@@ -75,6 +79,48 @@ func TestOllamaProvider_Embed_HTTPError(t *testing.T) {
 	}
 }
 
+func TestOllamaProvider_Embed_CancelledContext(t *testing.T) {
+	arrived := make(chan struct{})
+	release := make(chan struct{})
+
+	//nolint:revive
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(arrived)
+
+		// Hang like a stalled provider until the client gives up.
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		<-arrived
+		cancel()
+	}()
+
+	p := NewOllamaProvider("model", srv.URL)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Embed(ctx, "text")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Embed() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Embed() did not return after its context was cancelled")
+	}
+}
+
 func TestOllamaProvider_Embed_ConnectionRefused(t *testing.T) {
 	// Point at a port that isn't listening
 	p := NewOllamaProvider("model", "http://127.0.0.1:1")
@@ -153,6 +199,77 @@ func TestOpenAIProvider_Embed_EmptyDataArray(t *testing.T) {
 	}
 }
 
+// --- CohereProvider tests ---
+
+func TestCohereProvider_Embed_InputType(t *testing.T) {
+	var got []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embed" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+
+		if auth := r.Header.Get("Authorization"); auth != "Bearer co-test" {
+			t.Errorf("Authorization = %q, want Bearer co-test", auth)
+		}
+
+		var body struct {
+			Texts     []string `json:"texts"`
+			Model     string   `json:"model"`
+			InputType string   `json:"input_type"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+
+		if len(body.Texts) != 1 || body.Model != "embed-english-v3.0" {
+			t.Errorf("request = %+v, want one text and model embed-english-v3.0", body)
+		}
+
+		got = append(got, body.InputType)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"embeddings": [][]float64{{0.1, 0.2, 0.3}},
+		})
+	}))
+	defer srv.Close()
+
+	p := NewCohereProvider("embed-english-v3.0", "co-test", srv.URL)
+
+	embedding, err := p.Embed(context.Background(), "a note")
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	if len(embedding) != 3 {
+		t.Errorf("embedding len = %d, want 3", len(embedding))
+	}
+
+	if _, err := p.Embed(WithInputType(context.Background(), InputQuery), "a query"); err != nil {
+		t.Fatalf("Embed(query) error = %v", err)
+	}
+
+	want := []string{"search_document", "search_query"}
+	if !slices.Equal(got, want) {
+		t.Errorf("input_type = %v, want %v", got, want)
+	}
+}
+
+func TestCohereProvider_Embed_EmptyEmbeddings(t *testing.T) {
+	//nolint:revive
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"embeddings": [][]float64{}})
+	}))
+	defer srv.Close()
+
+	p := NewCohereProvider("model", "key", srv.URL)
+
+	if _, err := p.Embed(context.Background(), "text"); err == nil {
+		t.Fatal("Embed() should return error when embeddings are empty")
+	}
+}
+
 // --- Factory tests ---
 
 func TestNewProvider_Ollama(t *testing.T) {
@@ -213,6 +330,18 @@ func TestNewProvider_OpenRouter_RequiresAPIKey(t *testing.T) {
 	}
 }
 
+func TestNewProvider_Cohere_RequiresAPIKey(t *testing.T) {
+	cfg := config.EmbeddingConfig{
+		Provider: "cohere",
+		Model:    "embed-english-v3.0",
+	}
+
+	_, err := NewProvider(cfg)
+	if err == nil {
+		t.Fatal("NewProvider(cohere) without API key should return error")
+	}
+}
+
 func TestNewProvider_UnknownProvider(t *testing.T) {
 	cfg := config.EmbeddingConfig{
 		Provider: "bogus",
@@ -224,3 +353,242 @@ func TestNewProvider_UnknownProvider(t *testing.T) {
 		t.Fatal("NewProvider(bogus) should return error for unknown provider")
 	}
 }
+
+func TestNewProvider_OpenAI_ResolvesEnvKey(t *testing.T) {
+	t.Setenv("PANTRY_TEST_OPENAI_KEY", "sk-env")
+
+	key := "env:PANTRY_TEST_OPENAI_KEY"
+	cfg := config.EmbeddingConfig{
+		Provider: "openai",
+		Model:    "text-embedding-3-small",
+		APIKey:   &key,
+	}
+
+	if _, err := NewProvider(cfg); err != nil {
+		t.Fatalf("NewProvider(openai) with env: key error = %v", err)
+	}
+
+	missing := "env:PANTRY_TEST_OPENAI_KEY_UNSET"
+	cfg.APIKey = &missing
+
+	if _, err := NewProvider(cfg); err == nil {
+		t.Fatal("NewProvider(openai) should fail when env: key is unset")
+	}
+}
+
+func TestProviders_ImplementIdleCloser(t *testing.T) {
+	providers := map[string]Provider{
+		"ollama": NewOllamaProvider("nomic-embed-text", "http://localhost:11434"),
+		"openai": NewOpenAIProvider("text-embedding-3-small", "sk-test", ""),
+	}
+
+	for name, p := range providers {
+		if _, ok := p.(IdleCloser); !ok {
+			t.Errorf("%s provider should implement IdleCloser", name)
+		}
+
+		CloseIdleConnections(p) // must not panic on an unused client
+	}
+}
+
+// --- FallbackProvider tests ---
+
+// stubProvider returns vec, or err when set, counting its calls.
+type stubProvider struct {
+	vec   []float32
+	err   error
+	calls int
+}
+
+func (p *stubProvider) Embed(context.Context, string) ([]float32, error) {
+	p.calls++
+
+	return p.vec, p.err
+}
+
+func TestNewProvider_Fallback_UsesBackupWhenPrimaryFails(t *testing.T) {
+	//nolint:revive
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	p, err := NewProvider(config.EmbeddingConfig{
+		Provider: "ollama",
+		Model:    "nomic-embed-text",
+		BaseURL:  &srv.URL,
+		Fallback: []config.EmbeddingConfig{{Provider: "mock", Model: "mock"}},
+	})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+
+	if _, ok := p.(*FallbackProvider); !ok {
+		t.Fatalf("NewProvider() with fallback = %T, want *FallbackProvider", p)
+	}
+
+	got, err := p.Embed(context.Background(), "cache invalidation")
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	want, _ := NewMockProvider().Embed(context.Background(), "cache invalidation")
+	if !slices.Equal(got, want) {
+		t.Error("Embed() did not return the fallback's vector")
+	}
+}
+
+func TestFallbackProvider_Embed(t *testing.T) {
+	primary := &stubProvider{vec: []float32{1, 2, 3}}
+	backup := &stubProvider{vec: []float32{4, 5, 6}}
+	p := NewFallbackProvider(primary, backup)
+
+	got, err := p.Embed(context.Background(), "text")
+	if err != nil || !slices.Equal(got, primary.vec) {
+		t.Fatalf("Embed() = %v, %v; want the primary's vector", got, err)
+	}
+
+	if backup.calls != 0 {
+		t.Error("backup was called although the primary succeeded")
+	}
+
+	primary.err = errors.New("primary down")
+
+	got, err = p.Embed(context.Background(), "text")
+	if err != nil || !slices.Equal(got, backup.vec) {
+		t.Fatalf("Embed() = %v, %v; want the backup's vector", got, err)
+	}
+
+	// A backup of another dimension could not be stored next to the
+	// primary's vectors, so it counts as failing too.
+	backup.vec = []float32{4, 5}
+
+	if _, err := p.Embed(context.Background(), "text"); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Embed() with a mismatched backup error = %v, want ErrDimensionMismatch", err)
+	}
+
+	// Cancellation ends the chain instead of trying the next provider.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	primary.err = context.Canceled
+	backup.calls = 0
+
+	if _, err := p.Embed(ctx, "text"); !errors.Is(err, context.Canceled) || backup.calls != 0 {
+		t.Errorf("Embed() with a cancelled context error = %v, backup calls = %d; want context.Canceled and no calls", err, backup.calls)
+	}
+
+	primary.err = errors.New("primary down")
+	backup.err = errors.New("backup down")
+
+	_, err = p.Embed(context.Background(), "text")
+	if err == nil || !strings.Contains(err.Error(), "primary down") || !strings.Contains(err.Error(), "backup down") {
+		t.Errorf("Embed() with every provider down error = %v, want both failures", err)
+	}
+}
+
+func TestFallbackProvider_Probe(t *testing.T) {
+	primary := &stubProvider{vec: []float32{1, 2, 3}}
+	backup := &stubProvider{vec: []float32{4, 5}}
+
+	// A backup of another dimension is caught before anything is embedded.
+	if err := NewFallbackProvider(primary, backup).Probe(context.Background(), 0); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Probe() with a mismatched backup error = %v, want ErrDimensionMismatch", err)
+	}
+
+	// With no provider answering, the index's dimension is what later vectors
+	// are held to.
+	primary.err = errors.New("primary down")
+	backup.err = errors.New("backup down")
+
+	p := NewFallbackProvider(primary, backup)
+	if err := p.Probe(context.Background(), 3); err != nil {
+		t.Fatalf("Probe() with every provider down error = %v", err)
+	}
+
+	backup.err = nil
+
+	if _, err := p.Embed(context.Background(), "text"); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Embed() from a backup unlike the index error = %v, want ErrDimensionMismatch", err)
+	}
+}
+
+func TestNewProvider_Fallback_EmbedModel(t *testing.T) {
+	//nolint:revive
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	p, err := NewProvider(config.EmbeddingConfig{
+		Provider: "ollama",
+		Model:    "nomic-embed-text",
+		BaseURL:  &srv.URL,
+		Fallback: []config.EmbeddingConfig{{Provider: "mock", Model: "mock"}},
+	})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+
+	_, model, err := p.(*FallbackProvider).EmbedModel(context.Background(), "text")
+	if err != nil || model != "mock" {
+		t.Errorf("EmbedModel() = %q, %v; want the backup's model", model, err)
+	}
+}
+
+// --- MockProvider tests ---
+
+func TestMockProvider_Deterministic(t *testing.T) {
+	p, err := NewProvider(config.EmbeddingConfig{Provider: "mock", Model: "mock"})
+	if err != nil {
+		t.Fatalf("NewProvider(mock) error = %v", err)
+	}
+
+	embed := func(text string) []float32 {
+		t.Helper()
+
+		v, err := p.Embed(context.Background(), text)
+		if err != nil {
+			t.Fatalf("Embed(%q) error = %v", text, err)
+		}
+
+		if len(v) != MockDimension {
+			t.Fatalf("Embed(%q) len = %d, want %d", text, len(v), MockDimension)
+		}
+
+		return v
+	}
+
+	a := embed("Use JWT for auth tokens")
+	b := embed("Use JWT for auth tokens")
+
+	if !slices.Equal(a, b) {
+		t.Error("identical text gave different vectors")
+	}
+
+	if slices.Equal(a, embed("Retry webhooks with backoff")) {
+		t.Error("different text gave the same vector")
+	}
+
+	// Sharing words brings vectors closer than sharing none.
+	dot := func(x, y []float32) float32 {
+		var sum float32
+		for i := range x {
+			sum += x[i] * y[i]
+		}
+
+		return sum
+	}
+
+	if near, far := dot(a, embed("JWT auth tokens expire")), dot(a, embed("Retry webhooks with backoff")); near <= far {
+		t.Errorf("similarity with shared words = %v, without = %v; want shared higher", near, far)
+	}
+
+	if got := dot(a, a); got < 0.999 || got > 1.001 {
+		t.Errorf("|v|^2 = %v, want 1", got)
+	}
+
+	if empty := embed(""); empty[0] != 1 {
+		t.Error("empty text should still give a non-zero vector")
+	}
+}
@@ -8,7 +8,50 @@ import (
 )
 
 // NewProvider creates a new embedding provider based on configuration.
+// An api_key of the form env:, exec: or keychain: is resolved here, so the
+// secret itself never needs to live in config.yaml. With fallback providers
+// configured, the result is a FallbackProvider trying cfg first.
 func NewProvider(cfg config.EmbeddingConfig) (Provider, error) {
+	primary, err := newProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Fallback) == 0 {
+		return primary, nil
+	}
+
+	chain := []Provider{primary}
+	models := []string{cfg.Model}
+
+	for i, fb := range cfg.Fallback {
+		p, err := newProvider(fb)
+		if err != nil {
+			return nil, fmt.Errorf("embedding.fallback[%d]: %w", i, err)
+		}
+
+		chain = append(chain, p)
+		models = append(models, fb.Model)
+	}
+
+	fallback := NewFallbackProvider(chain...)
+	fallback.models = models
+
+	return fallback, nil
+}
+
+// newProvider creates the single provider described by cfg, ignoring
+// cfg.Fallback.
+func newProvider(cfg config.EmbeddingConfig) (Provider, error) {
+	if cfg.APIKey != nil && *cfg.APIKey != "" {
+		key, err := config.ResolveSecret(*cfg.APIKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve embedding.api_key: %w", err)
+		}
+
+		cfg.APIKey = &key
+	}
+
 	switch cfg.Provider {
 	case "ollama":
 		baseURL := "http://localhost:11434"
@@ -43,6 +86,21 @@ func NewProvider(cfg config.EmbeddingConfig) (Provider, error) {
 
 		return NewOpenAIProvider(cfg.Model, *cfg.APIKey, baseURL), nil
 
+	case "cohere":
+		if cfg.APIKey == nil || *cfg.APIKey == "" {
+			return nil, errors.New("API key required for Cohere provider")
+		}
+
+		baseURL := ""
+		if cfg.BaseURL != nil {
+			baseURL = *cfg.BaseURL
+		}
+
+		return NewCohereProvider(cfg.Model, *cfg.APIKey, baseURL), nil
+
+	case "mock":
+		return NewMockProvider(), nil
+
 	default:
 		return nil, fmt.Errorf("unknown embedding provider: %s", cfg.Provider)
 	}
@@ -0,0 +1,146 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrDimensionMismatch is returned by FallbackProvider when a provider in the
+// chain returns a vector of a different dimension than the chain's. Such a
+// vector could not be stored or compared against the index.
+var ErrDimensionMismatch = errors.New("embedding dimension mismatch")
+
+// FallbackProvider tries each provider in order and returns the first vector
+// one of them produces, so a backup can stand in while the primary is down.
+//
+// The chain's dimension is set by Probe, or else by the first vector
+// returned; a later vector of another dimension, from whichever provider, is
+// treated as a failure of that provider and the chain moves on.
+type FallbackProvider struct {
+	providers []Provider
+	// models names the model of each provider, for EmbedModel.
+	models []string
+
+	mu  sync.Mutex
+	dim int
+}
+
+// NewFallbackProvider returns a provider trying providers in the given order.
+func NewFallbackProvider(providers ...Provider) *FallbackProvider {
+	return &FallbackProvider{providers: providers}
+}
+
+// CloseIdleConnections releases idle connections held by every provider in
+// the chain.
+func (p *FallbackProvider) CloseIdleConnections() {
+	for _, provider := range p.providers {
+		CloseIdleConnections(provider)
+	}
+}
+
+// Probe embeds a short text with every provider in the chain and checks that
+// those answering agree on one dimension, which Embed then holds every
+// vector to. Probing stops once ctx is done. When no provider answers,
+// indexDim (the dimension of the vectors already stored, 0 for none) is used
+// instead, so a provider coming up later cannot add vectors of another size.
+func (p *FallbackProvider) Probe(ctx context.Context, indexDim int) error {
+	dim, first := 0, 0
+
+	for i, provider := range p.providers {
+		embedding, err := provider.Embed(ctx, "dimension probe")
+		if ctx.Err() != nil {
+			break
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if dim == 0 {
+			dim, first = len(embedding), i
+		} else if len(embedding) != dim {
+			return fmt.Errorf("%w: provider %d returns %d, provider %d returns %d", ErrDimensionMismatch, first+1, dim, i+1, len(embedding))
+		}
+	}
+
+	if dim == 0 {
+		dim = indexDim
+	}
+
+	p.mu.Lock()
+	p.dim = dim
+	p.mu.Unlock()
+
+	return nil
+}
+
+// Embed returns the first vector produced by a provider in the chain. When
+// every provider fails, the error wraps each of their errors.
+func (p *FallbackProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	embedding, _, err := p.embed(ctx, text)
+
+	return embedding, err
+}
+
+// EmbedModel is Embed, also returning the model of the provider that produced
+// the vector, so a vector made by a backup can be told apart and re-embedded
+// once the primary is back. The model is empty for a chain built with
+// NewFallbackProvider rather than NewProvider.
+func (p *FallbackProvider) EmbedModel(ctx context.Context, text string) ([]float32, string, error) {
+	embedding, i, err := p.embed(ctx, text)
+	if err != nil || i >= len(p.models) {
+		return embedding, "", err
+	}
+
+	return embedding, p.models[i], nil
+}
+
+// embed implements Embed, returning the index of the provider that answered.
+func (p *FallbackProvider) embed(ctx context.Context, text string) ([]float32, int, error) {
+	var errs []error
+
+	for i, provider := range p.providers {
+		embedding, err := provider.Embed(ctx, text)
+		if err == nil {
+			err = p.checkDim(len(embedding))
+		}
+
+		if err == nil {
+			return embedding, i, nil
+		}
+
+		errs = append(errs, fmt.Errorf("provider %d: %w", i+1, err))
+
+		// Cancellation applies to the whole chain, not just this provider.
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil, 0, errors.New("no embedding providers configured")
+	}
+
+	return nil, 0, fmt.Errorf("all embedding providers failed: %w", errors.Join(errs...))
+}
+
+// checkDim records dim as the chain's dimension when none is set yet and
+// reports a mismatch afterwards.
+func (p *FallbackProvider) checkDim(dim int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.dim == 0 {
+		p.dim = dim
+
+		return nil
+	}
+
+	if dim != p.dim {
+		return fmt.Errorf("%w: expected %d, got %d", ErrDimensionMismatch, p.dim, dim)
+	}
+
+	return nil
+}
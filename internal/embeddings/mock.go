@@ -0,0 +1,73 @@
+package embeddings
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// MockDimension is the length of every MockProvider vector.
+const MockDimension = 64
+
+// MockProvider returns deterministic pseudo-embeddings computed locally by
+// hashing the words of the text into MockDimension buckets. Identical text
+// always yields the identical vector and texts sharing words end up close,
+// so vector search can be exercised in tests, demos and CI without a network
+// or Ollama. It has no notion of meaning beyond shared words.
+type MockProvider struct{}
+
+// NewMockProvider creates a new mock embedding provider.
+func NewMockProvider() *MockProvider {
+	return &MockProvider{}
+}
+
+// Embed returns the unit-length hashed bag-of-words vector for text.
+func (p *MockProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	vec := make([]float64, MockDimension)
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, word := range words {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(word))
+		sum := h.Sum64()
+
+		// The low bits pick the bucket, one high bit the sign, which keeps
+		// unrelated words from all pushing the vector the same way.
+		sign := 1.0
+		if sum>>63 == 1 {
+			sign = -1
+		}
+
+		vec[sum%MockDimension] += sign
+	}
+
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+
+	out := make([]float32, MockDimension)
+
+	// Text without words still needs a valid, non-zero vector.
+	if norm == 0 {
+		out[0] = 1
+
+		return out, nil
+	}
+
+	norm = math.Sqrt(norm)
+	for i, v := range vec {
+		out[i] = float32(v / norm)
+	}
+
+	return out, nil
+}
@@ -26,6 +26,11 @@ func NewOllamaProvider(model string, baseURL string) *OllamaProvider {
 	}
 }
 
+// CloseIdleConnections releases idle keep-alive connections to Ollama.
+func (p *OllamaProvider) CloseIdleConnections() {
+	p.client.CloseIdleConnections()
+}
+
 type ollamaEmbedRequest struct {
 	Model  string `json:"model"`
 	Prompt string `json:"prompt"`
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/openai/openai-go"
@@ -13,26 +14,36 @@ import (
 // OpenAIProvider implements embedding generation using the OpenAI SDK.
 // Also works with OpenRouter and other OpenAI-compatible APIs via base_url.
 type OpenAIProvider struct {
-	model  string
-	client openai.Client
+	model      string
+	client     openai.Client
+	httpClient *http.Client
 }
 
 // NewOpenAIProvider creates a new OpenAI embedding provider.
 // baseURL is optional; defaults to https://api.openai.com/v1.
 func NewOpenAIProvider(model string, apiKey string, baseURL string) *OpenAIProvider {
+	httpClient := &http.Client{}
+
 	opts := []option.RequestOption{
 		option.WithAPIKey(apiKey),
+		option.WithHTTPClient(httpClient),
 	}
 	if baseURL != "" {
 		opts = append(opts, option.WithBaseURL(strings.TrimSuffix(baseURL, "/")))
 	}
 
 	return &OpenAIProvider{
-		model:  model,
-		client: openai.NewClient(opts...),
+		model:      model,
+		client:     openai.NewClient(opts...),
+		httpClient: httpClient,
 	}
 }
 
+// CloseIdleConnections releases idle keep-alive connections to the API.
+func (p *OpenAIProvider) CloseIdleConnections() {
+	p.httpClient.CloseIdleConnections()
+}
+
 // Embed generates an embedding vector using the OpenAI embeddings API.
 func (p *OpenAIProvider) Embed(ctx context.Context, text string) ([]float32, error) {
 	resp, err := p.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
@@ -7,3 +7,43 @@ type Provider interface {
 	// Embed generates an embedding vector for the given text
 	Embed(ctx context.Context, text string) ([]float32, error)
 }
+
+// IdleCloser is implemented by providers that keep pooled HTTP connections.
+type IdleCloser interface {
+	// CloseIdleConnections releases idle keep-alive connections.
+	CloseIdleConnections()
+}
+
+// CloseIdleConnections releases idle connections held by p, if it has any.
+func CloseIdleConnections(p Provider) {
+	if c, ok := p.(IdleCloser); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// InputType says whether text is being embedded for storage or as a search
+// query. Providers such as Cohere embed the two differently; the rest ignore it.
+type InputType string
+
+// Input types understood by WithInputType.
+const (
+	InputDocument InputType = "search_document"
+	InputQuery    InputType = "search_query"
+)
+
+type inputTypeKey struct{}
+
+// WithInputType returns a copy of ctx that tells Embed what the text is for.
+func WithInputType(ctx context.Context, t InputType) context.Context {
+	return context.WithValue(ctx, inputTypeKey{}, t)
+}
+
+// InputTypeFrom returns the input type carried by ctx, defaulting to
+// InputDocument so callers that never set one keep embedding notes as before.
+func InputTypeFrom(ctx context.Context) InputType {
+	if t, ok := ctx.Value(inputTypeKey{}).(InputType); ok && t != "" {
+		return t
+	}
+
+	return InputDocument
+}
@@ -0,0 +1,63 @@
+// Package fsutil writes pantry's files and directories with the configured
+// permissions.
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Default modes, used when no permissions are configured.
+const (
+	DefaultFileMode os.FileMode = 0644
+	DefaultDirMode  os.FileMode = 0755
+)
+
+// WriteFile writes data to path, creating it with perm (less the umask).
+// Existing files keep their mode, so a mode tightened by hand is never
+// loosened again by the defaults.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(path, data, perm)
+}
+
+// MkdirAll creates dir and any missing parents with perm (less the umask).
+// Existing directories keep their mode.
+func MkdirAll(dir string, perm os.FileMode) error {
+	return os.MkdirAll(dir, perm)
+}
+
+// Touch creates an empty file at path with perm (less the umask) unless it
+// already exists. Call it before handing the path to code that creates the
+// file itself with its own mode, such as SQLite.
+func Touch(path string, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	return f.Close()
+}
+
+// ParseMode parses an octal mode such as "0600". An empty string yields def.
+func ParseMode(s string, def os.FileMode) (os.FileMode, error) {
+	if s == "" {
+		return def, nil
+	}
+
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("invalid mode %q: want octal permissions such as 0600", s)
+	}
+
+	return os.FileMode(mode), nil
+}
+
+// WorldReadable reports whether mode lets other users read the file.
+func WorldReadable(mode os.FileMode) bool {
+	return mode.Perm()&0o004 != 0
+}
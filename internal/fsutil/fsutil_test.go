@@ -0,0 +1,69 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{"", DefaultFileMode, false},
+		{"0600", 0600, false},
+		{"700", 0700, false},
+		{"0800", 0, true},
+		{"01777", 0, true},
+		{"rw-------", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMode(tt.in, DefaultFileMode)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMode(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("ParseMode(%q) = %o, want %o", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTouch_CreatesWithModeAndKeepsExisting(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions")
+	}
+
+	path := filepath.Join(t.TempDir(), "index.db")
+
+	if err := Touch(path, 0600); err != nil {
+		t.Fatalf("Touch() error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %o, want 600", info.Mode().Perm())
+	}
+
+	if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if err := Touch(path, 0644); err != nil {
+		t.Fatalf("second Touch() error = %v", err)
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != "data" {
+		t.Errorf("Touch() changed an existing file's content to %q", data)
+	}
+}
@@ -3,27 +3,47 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"math"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	mcpsdk "github.com/modelcontextprotocol/go-sdk/mcp"
 
 	"pantry/internal/core"
+	"pantry/internal/db"
 	"pantry/internal/models"
 )
 
 // pantryService is the subset of core.Service used by MCP tool handlers.
 // Defining it here allows tests to inject stubs without depending on core.Service.
 type pantryService interface {
-	Store(raw models.RawItemInput, project string) (map[string]any, error)
-	Search(query string, limit int, project *string, source *string, useVectors bool) ([]models.SearchResult, error)
-	GetContext(limit int, project *string, source *string, query *string, semanticMode string, topupRecent bool) ([]models.SearchResult, int64, error)
+	Store(raw models.RawItemInput, project string, opts ...core.StoreOption) (map[string]any, error)
+	Search(query string, limit int, project *string, source *string, useVectors bool, opts ...core.SearchOption) ([]models.SearchResult, error)
+	GetContext(limit int, project *string, source *string, query *string, semanticMode string, topupRecent bool, opts ...core.SearchOption) ([]models.SearchResult, int64, error)
+	Links(itemID string) ([]models.NoteLink, error)
+	SinceTime(since string) (time.Time, error)
+	SetCheckpoint(name string) (time.Time, error)
+	DefaultSource(origin string) string
 	Close() error
 }
 
+// configReloader is implemented by services that can re-read config.yaml.
+type configReloader interface {
+	ReloadConfig() error
+}
+
 // RunServer starts the MCP server with stdio transport.
+// Sending SIGHUP reloads config.yaml without restarting the server.
 func RunServer() error {
 	svc, err := core.NewService("")
 	if err != nil {
@@ -32,6 +52,16 @@ func RunServer() error {
 
 	defer func() { _ = svc.Close() }()
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	defer signal.Stop(hup)
+
+	go watchReload(ctx, hup, svc, os.Stderr)
+
 	// Create MCP server
 	mcpServer := mcpsdk.NewServer(&mcpsdk.Implementation{
 		Name:    "pantry",
@@ -44,7 +74,28 @@ func RunServer() error {
 	}
 
 	// Run server with stdio transport
-	return mcpServer.Run(context.Background(), &mcpsdk.StdioTransport{})
+	return mcpServer.Run(ctx, &mcpsdk.StdioTransport{})
+}
+
+// watchReload reloads svc's configuration each time a signal arrives on sig,
+// until ctx is done. An invalid config is logged to logw and the previous
+// configuration stays in effect. Stdout is reserved for the MCP protocol, so
+// logw should be stderr.
+func watchReload(ctx context.Context, sig <-chan os.Signal, svc configReloader, logw io.Writer) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			if err := svc.ReloadConfig(); err != nil {
+				fmt.Fprintf(logw, "pantry: config reload failed, keeping previous config: %v\n", err)
+
+				continue
+			}
+
+			fmt.Fprintln(logw, "pantry: config reloaded")
+		}
+	}
 }
 
 // registerTools registers all pantry tools with the MCP server.
@@ -54,7 +105,7 @@ func registerTools(s *mcpsdk.Server, svc pantryService) error {
 	// Register pantry_store tool
 	//nolint:revive
 	storeHandler := func(ctx context.Context, req *mcpsdk.CallToolRequest, input map[string]any) (*mcpsdk.CallToolResult, map[string]any, error) {
-		result, err := HandlePantryStore(svc, input)
+		result, err := handlePantryStore(ctx, svc, input, clientName(req))
 		if err != nil {
 			return &mcpsdk.CallToolResult{
 				Content: []mcpsdk.Content{
@@ -68,7 +119,7 @@ func registerTools(s *mcpsdk.Server, svc pantryService) error {
 	}
 	mcpsdk.AddTool(s, &mcpsdk.Tool{
 		Name:        "pantry_store",
-		Description: "Store a note for future sessions. You MUST call this before ending any session where you made changes, fixed bugs, made decisions, or learned something.",
+		Description: "Store a note for future sessions. You MUST call this before ending any session where you made changes, fixed bugs, made decisions, or learned something. The response echoes the stored item after redaction and any merge with an existing note.",
 		InputSchema: map[string]any{
 			"type": "object",
 			"properties": map[string]any{
@@ -82,6 +133,9 @@ func registerTools(s *mcpsdk.Server, svc pantryService) error {
 				"details":       map[string]any{"type": "string", "description": "Full context with all important details"},
 				"source":        map[string]any{"type": "string", "description": "Source agent name"},
 				"project":       map[string]any{"type": "string", "description": "Project name (defaults to current directory)"},
+				"metadata":      map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}, "description": "Extra key-value context such as model, task_id or session_id; keys may use letters, digits, '_' and '-'"},
+				"force_create":  map[string]any{"type": "boolean", "description": "Always create a new note instead of updating a note with the same title; the similar note is linked as related_to"},
+				"update_id":     map[string]any{"type": "string", "description": "ID of an existing note to update (what/why/impact replaced, tags merged, details appended) instead of matching by title; errors if it does not exist"},
 			},
 			"required": []string{"title", "what"},
 		},
@@ -90,7 +144,7 @@ func registerTools(s *mcpsdk.Server, svc pantryService) error {
 	// Register pantry_search tool
 	//nolint:revive
 	searchHandler := func(ctx context.Context, req *mcpsdk.CallToolRequest, input map[string]any) (*mcpsdk.CallToolResult, map[string]any, error) {
-		results, err := HandlePantrySearch(svc, input)
+		results, err := handlePantrySearch(ctx, svc, input)
 		if err != nil {
 			return &mcpsdk.CallToolResult{
 				Content: []mcpsdk.Content{
@@ -108,10 +162,12 @@ func registerTools(s *mcpsdk.Server, svc pantryService) error {
 		InputSchema: map[string]any{
 			"type": "object",
 			"properties": map[string]any{
-				"query":   map[string]any{"type": "string", "description": "Search query"},
-				"limit":   map[string]any{"type": "integer", "description": "Maximum number of notes", "default": 5},
-				"project": map[string]any{"type": "string", "description": "Filter by project"},
-				"source":  map[string]any{"type": "string", "description": "Filter by source"},
+				"query":        map[string]any{"type": "string", "description": "Search query"},
+				"limit":        map[string]any{"type": "integer", "description": "Maximum number of notes", "default": 5, "minimum": 1},
+				"project":      map[string]any{"type": "string", "description": "Filter by project"},
+				"project_glob": map[string]any{"type": "string", "description": "Filter by project name glob, e.g. acme-*"},
+				"source":       map[string]any{"type": "string", "description": "Filter by source"},
+				"fields":       map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": "Return only these fields of each note, e.g. [\"id\", \"title\", \"score\"]; all when omitted"},
 			},
 			"required": []string{"query"},
 		},
@@ -120,7 +176,7 @@ func registerTools(s *mcpsdk.Server, svc pantryService) error {
 	// Register pantry_context tool
 	//nolint:revive
 	contextHandler := func(ctx context.Context, req *mcpsdk.CallToolRequest, input map[string]any) (*mcpsdk.CallToolResult, map[string]any, error) {
-		result, err := HandlePantryContext(svc, input)
+		result, err := handlePantryContext(ctx, svc, input)
 		if err != nil {
 			return &mcpsdk.CallToolResult{
 				Content: []mcpsdk.Content{
@@ -134,22 +190,59 @@ func registerTools(s *mcpsdk.Server, svc pantryService) error {
 	}
 	mcpsdk.AddTool(s, &mcpsdk.Tool{
 		Name:        "pantry_context",
-		Description: "Get notes for the current project. Returns prior decisions, bugs, and context.",
+		Description: "Get notes for the current project. Returns prior decisions, bugs, and context. Pass query to get the notes most relevant to the current task instead of the most recent ones. On a follow-up call, pass since to get only what changed after an earlier call that set checkpoint.",
 		InputSchema: map[string]any{
 			"type": "object",
 			"properties": map[string]any{
-				"limit":   map[string]any{"type": "integer", "description": "Maximum number of notes", "default": 10},
-				"project": map[string]any{"type": "string", "description": "Project name (defaults to current directory)"},
-				"source":  map[string]any{"type": "string", "description": "Filter by source"},
+				"query":      map[string]any{"type": "string", "description": "What you are working on; ranks notes by relevance, topped up with recent ones"},
+				"limit":      map[string]any{"type": "integer", "description": "Maximum number of notes", "default": 10, "minimum": 1},
+				"project":    map[string]any{"type": "string", "description": "Project name (defaults to current directory)"},
+				"source":     map[string]any{"type": "string", "description": "Filter by source"},
+				"since":      map[string]any{"type": "string", "description": "Only notes created or updated since this checkpoint name or date (YYYY-MM-DD or RFC3339)"},
+				"checkpoint": map[string]any{"type": "string", "description": "Record a checkpoint of this name once the notes are returned, for a later call's since"},
 			},
 		},
 	}, contextHandler)
 
+	// Register pantry_links tool
+	//nolint:revive
+	linksHandler := func(ctx context.Context, req *mcpsdk.CallToolRequest, input map[string]any) (*mcpsdk.CallToolResult, map[string]any, error) {
+		result, err := HandlePantryLinks(svc, input)
+		if err != nil {
+			return &mcpsdk.CallToolResult{
+				Content: []mcpsdk.Content{
+					&mcpsdk.TextContent{Text: fmt.Sprintf("Error: %v", err)},
+				},
+				IsError: true,
+			}, nil, nil
+		}
+
+		return nil, result, nil
+	}
+	mcpsdk.AddTool(s, &mcpsdk.Tool{
+		Name:        "pantry_links",
+		Description: "List notes linked to or from a note (supersedes, related_to). Use it to follow how decisions evolved.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"id": map[string]any{"type": "string", "description": "Note ID"},
+			},
+			"required": []string{"id"},
+		},
+	}, linksHandler)
+
 	return nil
 }
 
 // HandlePantryStore handles the pantry_store tool call.
 func HandlePantryStore(svc pantryService, params map[string]any) (map[string]any, error) {
+	return handlePantryStore(context.Background(), svc, params, "")
+}
+
+// handlePantryStore stores the note in params. A note without a source gets
+// defaults.mcp_source, or else agent, the connected client's name. The note
+// is embedded under ctx, the tool call's context.
+func handlePantryStore(ctx context.Context, svc pantryService, params map[string]any, agent string) (map[string]any, error) {
 	title, _ := params["title"].(string)
 	what, _ := params["what"].(string)
 	why, _ := getStringFromMap(params, "why")
@@ -182,6 +275,14 @@ func HandlePantryStore(svc pantryService, params map[string]any) (map[string]any
 		raw.Category = &category
 	}
 
+	if source == "" {
+		source = svc.DefaultSource(core.OriginMCP)
+	}
+
+	if source == "" {
+		source = agent
+	}
+
 	if source != "" {
 		raw.Source = &source
 	}
@@ -193,7 +294,28 @@ func HandlePantryStore(svc pantryService, params map[string]any) (map[string]any
 	raw.Tags = tags
 	raw.RelatedFiles = relatedFiles
 
-	result, err := svc.Store(raw, project)
+	metadata, err := getMetadataFromMap(params, "metadata")
+	if err != nil {
+		return nil, err
+	}
+
+	raw.Metadata = metadata
+
+	var opts []core.StoreOption
+	if forceCreate, _ := params["force_create"].(bool); forceCreate {
+		opts = append(opts, core.WithForceCreate())
+	}
+
+	if updateID, _ := getStringFromMap(params, "update_id"); updateID != "" {
+		opts = append(opts, core.WithUpdateID(updateID))
+	}
+
+	// Only a context that can be cancelled changes anything.
+	if ctx.Done() != nil {
+		opts = append(opts, core.WithStoreContext(ctx))
+	}
+
+	result, err := svc.Store(raw, project, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -201,13 +323,42 @@ func HandlePantryStore(svc pantryService, params map[string]any) (map[string]any
 	return result, nil
 }
 
-// HandlePantrySearch handles the pantry_search tool call.
+// clientName returns the name the MCP client reported when it connected, or
+// "" if unknown.
+func clientName(req *mcpsdk.CallToolRequest) string {
+	if req == nil || req.Session == nil {
+		return ""
+	}
+
+	params := req.Session.InitializeParams()
+	if params == nil || params.ClientInfo == nil {
+		return ""
+	}
+
+	return params.ClientInfo.Name
+}
+
+// searchResultFields are the keys a pantry_search result can carry, as
+// accepted by its fields parameter.
+var searchResultFields = []string{
+	"id", "title", "what", "why", "impact", "category", "tags", "project", "source",
+	"created_at", "updated_at", "score", "has_details", "snippet",
+}
+
+// HandlePantrySearch handles the pantry_search tool call. With fields, each
+// result is trimmed to those keys.
 func HandlePantrySearch(svc pantryService, params map[string]any) ([]map[string]any, error) {
+	return handlePantrySearch(context.Background(), svc, params)
+}
+
+// handlePantrySearch is HandlePantrySearch embedding the query under ctx, the
+// tool call's context, so a cancelled call stops waiting on the provider.
+func handlePantrySearch(ctx context.Context, svc pantryService, params map[string]any) ([]map[string]any, error) {
 	query, _ := params["query"].(string)
 
-	limit := 5
-	if l, ok := params["limit"].(float64); ok {
-		limit = int(l)
+	limit, err := getLimitFromMap(params, "limit", 5)
+	if err != nil {
+		return nil, err
 	}
 
 	var project *string
@@ -215,37 +366,84 @@ func HandlePantrySearch(svc pantryService, params map[string]any) ([]map[string]
 		project = &p
 	}
 
-	results, err := svc.Search(query, limit, project, nil, true)
+	fields, err := getFieldsFromMap(params, "fields")
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []core.SearchOption
+	if g, ok := params["project_glob"].(string); ok && g != "" {
+		opts = append(opts, core.WithProjectGlob(g))
+	}
+
+	if ctx.Done() != nil {
+		opts = append(opts, core.WithSearchContext(ctx))
+	}
+
+	results, err := svc.Search(query, limit, project, nil, true, opts...)
 	if err != nil {
 		return nil, err
 	}
 
 	clean := make([]map[string]any, len(results))
 	for i, r := range results {
-		clean[i] = map[string]any{
-			"id":          r.ID,
-			"title":       r.Title,
-			"what":        r.What,
-			"why":         r.Why,
-			"impact":      r.Impact,
-			"category":    r.Category,
-			"tags":        r.Tags,
-			"project":     r.Project,
-			"source":      r.Source,
-			"created_at":  r.CreatedAt[:10],
-			"score":       r.Score,
-			"has_details": r.HasDetails,
-		}
+		clean[i] = SearchResultMap(r, fields)
 	}
 
 	return clean, nil
 }
 
-// HandlePantryContext handles the pantry_context tool call.
+// SearchResultMap returns r in the form pantry_search returns it, trimmed to
+// fields when any are given. Dates are cut to YYYY-MM-DD, and updated_at is
+// only present for a revised note. pantry search --format jsonl-mcp prints
+// the same maps, so the two cannot drift apart.
+func SearchResultMap(r models.SearchResult, fields []string) map[string]any {
+	m := map[string]any{
+		"id":          r.ID,
+		"title":       r.Title,
+		"what":        r.What,
+		"why":         r.Why,
+		"impact":      r.Impact,
+		"category":    r.Category,
+		"tags":        r.Tags,
+		"project":     r.Project,
+		"source":      r.Source,
+		"created_at":  r.CreatedAt[:10],
+		"score":       r.Score,
+		"has_details": r.HasDetails,
+	}
+
+	if r.Snippet != "" {
+		m["snippet"] = r.Snippet
+	}
+
+	if r.Revised() {
+		m["updated_at"] = r.UpdatedAt[:10]
+	}
+
+	if len(fields) > 0 {
+		maps.DeleteFunc(m, func(k string, _ any) bool {
+			return !slices.Contains(fields, k)
+		})
+	}
+
+	return m
+}
+
+// HandlePantryContext handles the pantry_context tool call. Without a query
+// it returns the most recent notes; with one it searches (semantically when
+// vectors are available) and tops up with recent notes. since limits either
+// to notes changed after a checkpoint or date, and checkpoint records one.
 func HandlePantryContext(svc pantryService, params map[string]any) (map[string]any, error) {
-	limit := 10
-	if l, ok := params["limit"].(float64); ok {
-		limit = int(l)
+	return handlePantryContext(context.Background(), svc, params)
+}
+
+// handlePantryContext is HandlePantryContext embedding any query under ctx,
+// the tool call's context.
+func handlePantryContext(ctx context.Context, svc pantryService, params map[string]any) (map[string]any, error) {
+	limit, err := getLimitFromMap(params, "limit", 10)
+	if err != nil {
+		return nil, err
 	}
 
 	var project *string
@@ -256,11 +454,38 @@ func HandlePantryContext(svc pantryService, params map[string]any) (map[string]a
 		project = &proj
 	}
 
-	results, total, err := svc.GetContext(limit, project, nil, nil, "never", false)
+	var query *string
+	if q, _ := getStringFromMap(params, "query"); strings.TrimSpace(q) != "" {
+		query = &q
+	}
+
+	var opts []core.SearchOption
+	if ctx.Done() != nil {
+		opts = append(opts, core.WithSearchContext(ctx))
+	}
+
+	if since, _ := getStringFromMap(params, "since"); strings.TrimSpace(since) != "" {
+		t, err := svc.SinceTime(since)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, core.WithChangedSince(t))
+	}
+
+	results, total, err := svc.GetContext(limit, project, nil, query, "auto", query != nil, opts...)
 	if err != nil {
 		return nil, err
 	}
 
+	// The checkpoint is only moved once the notes are in hand, so a failed
+	// call does not skip them on the next one.
+	if name, _ := getStringFromMap(params, "checkpoint"); strings.TrimSpace(name) != "" {
+		if _, err := svc.SetCheckpoint(name); err != nil {
+			return nil, err
+		}
+	}
+
 	notes := make([]map[string]any, len(results))
 
 	for i, r := range results {
@@ -281,6 +506,42 @@ func HandlePantryContext(svc pantryService, params map[string]any) (map[string]a
 	}, nil
 }
 
+// HandlePantryLinks handles the pantry_links tool call.
+func HandlePantryLinks(svc pantryService, params map[string]any) (map[string]any, error) {
+	id, _ := getStringFromMap(params, "id")
+	if id == "" {
+		return nil, errors.New("id is required")
+	}
+
+	links, err := svc.Links(id)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]map[string]any, len(links))
+	for i, l := range links {
+		direction := "outgoing"
+		if l.ToID == id {
+			direction = "incoming"
+		}
+
+		out[i] = map[string]any{
+			"type":       l.Type,
+			"direction":  direction,
+			"from_id":    l.FromID,
+			"from_title": l.FromTitle,
+			"to_id":      l.ToID,
+			"to_title":   l.ToTitle,
+			"created_at": l.CreatedAt,
+		}
+	}
+
+	return map[string]any{
+		"id":    id,
+		"links": out,
+	}, nil
+}
+
 // Helper functions.
 //
 //nolint:unparam
@@ -294,6 +555,96 @@ func getStringFromMap(m map[string]any, key string) (string, bool) {
 	return "", false
 }
 
+// getLimitFromMap reads a result limit from m[key], returning def when it is
+// absent. Unlike the CLI's --limit, 0 does not mean "no limit": an agent
+// passing it would load every note into its context.
+func getLimitFromMap(m map[string]any, key string, def int) (int, error) {
+	val, ok := m[key]
+	if !ok {
+		return def, nil
+	}
+
+	l, ok := val.(float64)
+	if !ok || l < 1 || l != math.Trunc(l) {
+		return 0, fmt.Errorf("%s must be a positive integer", key)
+	}
+
+	return int(l), nil
+}
+
+// getFieldsFromMap reads the result fields named in m[key], returning nil when
+// it is absent. Unlike tags, a string is not accepted: a comma-separated list
+// would be taken for one unknown field, so anything but an array of known
+// field names is an error.
+func getFieldsFromMap(m map[string]any, key string) ([]string, error) {
+	val, ok := m[key]
+	if !ok || val == nil {
+		return nil, nil
+	}
+
+	arr, ok := val.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array of field names", key)
+	}
+
+	fields := make([]string, len(arr))
+
+	for i, v := range arr {
+		f, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be an array of field names", key)
+		}
+
+		if !slices.Contains(searchResultFields, f) {
+			return nil, fmt.Errorf("unknown field %q: must be one of %s", f, strings.Join(searchResultFields, ", "))
+		}
+
+		fields[i] = f
+	}
+
+	return fields, nil
+}
+
+// getMetadataFromMap reads an object of metadata from m[key]. Scalar values
+// are converted to strings, numbers in plain decimal (1e21 is written out);
+// keys must pass db.ValidateMetaKey. Anything but an object is an error.
+func getMetadataFromMap(m map[string]any, key string) (map[string]string, error) {
+	val, ok := m[key]
+	if !ok || val == nil {
+		return nil, nil //nolint:nilnil
+	}
+
+	obj, ok := val.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object of key/value pairs", key)
+	}
+
+	if len(obj) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	metadata := make(map[string]string, len(obj))
+
+	for k, v := range obj {
+		if err := db.ValidateMetaKey(k); err != nil {
+			return nil, err
+		}
+
+		switch v := v.(type) {
+		case string:
+			metadata[k] = v
+		case float64:
+			metadata[k] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			metadata[k] = strconv.FormatBool(v)
+		default:
+			return nil, fmt.Errorf("metadata %q: value must be a string, number or boolean", k)
+		}
+	}
+
+	return metadata, nil
+}
+
 func getStringSliceFromMap(m map[string]any, key string) ([]string, bool) {
 	//nolint:nestif
 	if val, ok := m[key]; ok {
@@ -1,9 +1,22 @@
 package mcp
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
+	"pantry/internal/core"
 	"pantry/internal/models"
 )
 
@@ -14,26 +27,41 @@ type stubService struct {
 	storeErr       error
 	searchResults  []models.SearchResult
 	searchErr      error
+	searchOpts     []core.SearchOption
 	contextResults []models.SearchResult
 	contextTotal   int64
 	contextErr     error
+	links          []models.NoteLink
+	linksErr       error
 }
 
 //nolint:revive
-func (s *stubService) Store(raw models.RawItemInput, project string) (map[string]any, error) {
+func (s *stubService) Store(raw models.RawItemInput, project string, opts ...core.StoreOption) (map[string]any, error) {
 	return s.storeResult, s.storeErr
 }
 
 //nolint:revive
-func (s *stubService) Search(query string, limit int, project *string, source *string, useVectors bool) ([]models.SearchResult, error) {
+func (s *stubService) Search(query string, limit int, project *string, source *string, useVectors bool, opts ...core.SearchOption) ([]models.SearchResult, error) {
+	s.searchOpts = opts
+
 	return s.searchResults, s.searchErr
 }
 
 //nolint:revive
-func (s *stubService) GetContext(limit int, project *string, source *string, query *string, semanticMode string, topupRecent bool) ([]models.SearchResult, int64, error) {
+func (s *stubService) GetContext(limit int, project *string, source *string, query *string, semanticMode string, topupRecent bool, _ ...core.SearchOption) ([]models.SearchResult, int64, error) {
 	return s.contextResults, s.contextTotal, s.contextErr
 }
 
+func (s *stubService) Links(_ string) ([]models.NoteLink, error) {
+	return s.links, s.linksErr
+}
+
+func (s *stubService) SinceTime(_ string) (time.Time, error) { return time.Time{}, nil }
+
+func (s *stubService) SetCheckpoint(_ string) (time.Time, error) { return time.Time{}, nil }
+
+func (s *stubService) DefaultSource(_ string) string { return "" }
+
 func (s *stubService) Close() error { return nil }
 
 // --- HandlePantryStore tests ---
@@ -66,6 +94,36 @@ func TestHandlePantryStore_Success(t *testing.T) {
 	}
 }
 
+func TestHandlePantryStore_EchoesStoredItem(t *testing.T) {
+	svc := &stubService{
+		storeResult: map[string]any{
+			"id":        "abc-123",
+			"file_path": "/tmp/session.md",
+			"action":    "updated",
+			"item": map[string]any{
+				"title":    "My Title",
+				"what":     "key [REDACTED]",
+				"tags":     []string{"a", "b"},
+				"redacted": true,
+			},
+		},
+	}
+
+	result, err := HandlePantryStore(svc, map[string]any{"title": "My Title", "what": "key sk_live_x"})
+	if err != nil {
+		t.Fatalf("HandlePantryStore() error = %v", err)
+	}
+
+	item, ok := result["item"].(map[string]any)
+	if !ok {
+		t.Fatalf("item = %T, want map[string]any", result["item"])
+	}
+
+	if item["what"] != "key [REDACTED]" || item["redacted"] != true {
+		t.Errorf("item = %v, want post-redaction fields", item)
+	}
+}
+
 func TestHandlePantryStore_PropagatesError(t *testing.T) {
 	svc := &stubService{
 		storeErr: errors.New("storage failure"),
@@ -143,25 +201,130 @@ func TestHandlePantryStore_TagsFromNativeArray(t *testing.T) {
 	}
 }
 
+func TestHandlePantryStore_ForceCreate(t *testing.T) {
+	captureSvc := &capturingStub{}
+
+	if _, err := HandlePantryStore(captureSvc, map[string]any{"title": "T", "what": "W"}); err != nil {
+		t.Fatalf("HandlePantryStore() error = %v", err)
+	}
+
+	if len(captureSvc.lastOpts) != 0 {
+		t.Errorf("Store() got %d options without force_create, want 0", len(captureSvc.lastOpts))
+	}
+
+	if _, err := HandlePantryStore(captureSvc, map[string]any{"title": "T", "what": "W", "force_create": true}); err != nil {
+		t.Fatalf("HandlePantryStore() error = %v", err)
+	}
+
+	if len(captureSvc.lastOpts) != 1 {
+		t.Errorf("Store() got %d options with force_create, want 1", len(captureSvc.lastOpts))
+	}
+}
+
+func TestHandlePantryStore_UpdateID(t *testing.T) {
+	captureSvc := &capturingStub{}
+
+	if _, err := HandlePantryStore(captureSvc, map[string]any{"title": "T", "what": "W", "update_id": "abc-123"}); err != nil {
+		t.Fatalf("HandlePantryStore() error = %v", err)
+	}
+
+	if len(captureSvc.lastOpts) != 1 {
+		t.Errorf("Store() got %d options with update_id, want 1", len(captureSvc.lastOpts))
+	}
+}
+
+func TestHandlePantryStore_Metadata(t *testing.T) {
+	captureSvc := &capturingStub{}
+
+	params := map[string]any{
+		"title":    "T",
+		"what":     "W",
+		"metadata": map[string]any{"model": "opus", "turn": float64(3), "tokens": float64(1e21)},
+	}
+	if _, err := HandlePantryStore(captureSvc, params); err != nil {
+		t.Fatalf("HandlePantryStore() error = %v", err)
+	}
+
+	if got := captureSvc.lastRaw.Metadata; got["model"] != "opus" || got["turn"] != "3" || got["tokens"] != "1000000000000000000000" || len(got) != 3 {
+		t.Errorf("Metadata = %v, want model=opus turn=3 tokens=1000000000000000000000", got)
+	}
+
+	params["metadata"] = map[string]any{"bad key": "x"}
+	if _, err := HandlePantryStore(captureSvc, params); err == nil {
+		t.Error("HandlePantryStore() with invalid metadata key: error = nil")
+	}
+
+	for _, bad := range []any{"model=opus", []any{"model"}, float64(1)} {
+		params["metadata"] = bad
+		if _, err := HandlePantryStore(captureSvc, params); err == nil {
+			t.Errorf("HandlePantryStore() with metadata %v: error = nil, want an object required", bad)
+		}
+	}
+}
+
+func TestHandlePantryStore_DefaultSource(t *testing.T) {
+	params := map[string]any{"title": "T", "what": "W"}
+
+	tests := []struct {
+		name       string
+		configured string
+		agent      string
+		source     string
+		want       string
+	}{
+		{"configured default", "agent-x", "claude-code", "", "agent-x"},
+		{"client name", "", "claude-code", "", "claude-code"},
+		{"explicit source wins", "agent-x", "claude-code", "cursor", "cursor"},
+		{"nothing known", "", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureSvc := &capturingStub{defaultSource: tt.configured}
+
+			params["source"] = tt.source
+			if _, err := handlePantryStore(context.Background(), captureSvc, params, tt.agent); err != nil {
+				t.Fatalf("handlePantryStore() error = %v", err)
+			}
+
+			got := ""
+			if captureSvc.lastRaw.Source != nil {
+				got = *captureSvc.lastRaw.Source
+			}
+
+			if got != tt.want {
+				t.Errorf("Source = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 // capturingStub records the last Store() call for inspection.
 type capturingStub struct {
-	lastRaw     models.RawItemInput
-	lastProject string
+	lastRaw       models.RawItemInput
+	lastProject   string
+	lastOpts      []core.StoreOption
+	defaultSource string
 }
 
-func (c *capturingStub) Store(raw models.RawItemInput, project string) (map[string]any, error) {
+func (c *capturingStub) Store(raw models.RawItemInput, project string, opts ...core.StoreOption) (map[string]any, error) {
 	c.lastRaw = raw
 	c.lastProject = project
+	c.lastOpts = opts
 
 	return map[string]any{"id": "x", "file_path": "/f", "action": "created"}, nil
 }
-func (c *capturingStub) Search(_ string, _ int, _ *string, _ *string, _ bool) ([]models.SearchResult, error) {
+func (c *capturingStub) Search(_ string, _ int, _ *string, _ *string, _ bool, _ ...core.SearchOption) ([]models.SearchResult, error) {
 	return nil, nil
 }
-func (c *capturingStub) GetContext(_ int, _ *string, _ *string, _ *string, _ string, _ bool) ([]models.SearchResult, int64, error) {
+func (c *capturingStub) GetContext(_ int, _ *string, _ *string, _ *string, _ string, _ bool, _ ...core.SearchOption) ([]models.SearchResult, int64, error) {
 	return nil, 0, nil
 }
-func (c *capturingStub) Close() error { return nil }
+func (c *capturingStub) Links(_ string) ([]models.NoteLink, error) { return nil, nil }
+func (c *capturingStub) SinceTime(_ string) (time.Time, error)     { return time.Time{}, nil }
+func (c *capturingStub) SetCheckpoint(_ string) (time.Time, error) { return time.Time{}, nil }
+func (c *capturingStub) DefaultSource(_ string) string             { return c.defaultSource }
+func (c *capturingStub) Close() error                              { return nil }
 
 // --- HandlePantrySearch tests ---
 
@@ -197,6 +360,7 @@ func TestHandlePantrySearch_WithResults(t *testing.T) {
 				Project:   "myproject",
 				Score:     0.95,
 				CreatedAt: "2024-01-01T00:00:00Z",
+				Snippet:   "We **decided** X",
 			},
 		},
 	}
@@ -226,6 +390,58 @@ func TestHandlePantrySearch_WithResults(t *testing.T) {
 	if results[0]["score"] != 0.95 {
 		t.Errorf("score = %v, want 0.95", results[0]["score"])
 	}
+
+	if results[0]["snippet"] != "We **decided** X" {
+		t.Errorf("snippet = %v, want We **decided** X", results[0]["snippet"])
+	}
+}
+
+func TestHandlePantrySearch_ProjectGlob(t *testing.T) {
+	svc := &stubService{}
+
+	if _, err := HandlePantrySearch(svc, map[string]any{"query": "x"}); err != nil {
+		t.Fatalf("HandlePantrySearch() error = %v", err)
+	}
+
+	if len(svc.searchOpts) != 0 {
+		t.Errorf("search options = %d, want 0 without project_glob", len(svc.searchOpts))
+	}
+
+	if _, err := HandlePantrySearch(svc, map[string]any{"query": "x", "project_glob": "acme-*"}); err != nil {
+		t.Fatalf("HandlePantrySearch() error = %v", err)
+	}
+
+	if len(svc.searchOpts) != 1 {
+		t.Errorf("search options = %d, want 1 with project_glob", len(svc.searchOpts))
+	}
+}
+
+func TestHandlePantrySearch_Fields(t *testing.T) {
+	svc := &stubService{
+		searchResults: []models.SearchResult{
+			{ID: "item-1", Title: "Some Decision", What: "We decided X", Score: 0.95, CreatedAt: "2024-01-01T00:00:00Z"},
+		},
+	}
+
+	results, err := HandlePantrySearch(svc, map[string]any{"query": "x", "fields": []any{"id", "title"}})
+	if err != nil {
+		t.Fatalf("HandlePantrySearch() error = %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+
+	want := map[string]any{"id": "item-1", "title": "Some Decision"}
+	if !maps.Equal(results[0], want) {
+		t.Errorf("result = %v, want %v", results[0], want)
+	}
+
+	for _, fields := range []any{[]any{"id", "body"}, []any{"id", 3.0}, "id,title", map[string]any{"id": true}} {
+		if _, err := HandlePantrySearch(svc, map[string]any{"query": "x", "fields": fields}); err == nil {
+			t.Errorf("HandlePantrySearch(fields %v) should error", fields)
+		}
+	}
 }
 
 func TestHandlePantrySearch_PropagatesError(t *testing.T) {
@@ -281,6 +497,86 @@ func TestHandlePantryContext_LimitParam(t *testing.T) {
 	}
 }
 
+func TestHandlePantryContext_SinceAndCheckpoint(t *testing.T) {
+	svc, err := core.NewService(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	defer svc.Close()
+
+	if _, err := svc.Store(models.RawItemInput{Title: "Earlier note", What: "w"}, "api"); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	result, err := HandlePantryContext(svc, map[string]any{"project": "api", "checkpoint": "session"})
+	if err != nil {
+		t.Fatalf("HandlePantryContext(checkpoint) error = %v", err)
+	}
+
+	if result["showing"] != 1 {
+		t.Errorf("HandlePantryContext(checkpoint) showing = %v, want 1", result["showing"])
+	}
+
+	if _, ok, err := svc.CheckpointTime("session"); !ok || err != nil {
+		t.Fatalf("CheckpointTime(session) = %v, %v; want the checkpoint the call recorded", ok, err)
+	}
+
+	for since, want := range map[string]int{"session": 1, "2000-01-01": 1, "2999-01-01": 0} {
+		result, err := HandlePantryContext(svc, map[string]any{"project": "api", "since": since})
+		if err != nil {
+			t.Fatalf("HandlePantryContext(since %s) error = %v", since, err)
+		}
+
+		if result["showing"] != want {
+			t.Errorf("HandlePantryContext(since %s) showing = %v, want %d", since, result["showing"], want)
+		}
+	}
+
+	if _, err := HandlePantryContext(svc, map[string]any{"project": "api", "since": "yesterday"}); err == nil {
+		t.Error("HandlePantryContext(since yesterday) should fail: no such checkpoint or date")
+	}
+}
+
+func TestHandlers_RejectNonPositiveLimit(t *testing.T) {
+	for _, limit := range []any{float64(0), float64(-3), float64(2.5), "10"} {
+		params := map[string]any{"query": "q", "limit": limit}
+
+		if _, err := HandlePantrySearch(&stubService{}, params); err == nil {
+			t.Errorf("HandlePantrySearch(limit %v) should fail", limit)
+		}
+
+		if _, err := HandlePantryContext(&stubService{}, params); err == nil {
+			t.Errorf("HandlePantryContext(limit %v) should fail", limit)
+		}
+	}
+}
+
+func TestHandlePantryContext_PassesQuery(t *testing.T) {
+	capSvc := &contextCapturingStub{}
+
+	if _, err := HandlePantryContext(capSvc, map[string]any{"query": "retry backoff"}); err != nil {
+		t.Fatalf("HandlePantryContext() error = %v", err)
+	}
+
+	if capSvc.lastQuery == nil || *capSvc.lastQuery != "retry backoff" {
+		t.Fatalf("query passed to GetContext = %v, want %q", capSvc.lastQuery, "retry backoff")
+	}
+
+	if capSvc.lastMode != "auto" || !capSvc.lastTopup {
+		t.Errorf("GetContext(mode %q, topup %v), want auto with topup", capSvc.lastMode, capSvc.lastTopup)
+	}
+
+	// Without a query the recent-notes path is kept.
+	if _, err := HandlePantryContext(capSvc, map[string]any{"query": "  "}); err != nil {
+		t.Fatalf("HandlePantryContext() error = %v", err)
+	}
+
+	if capSvc.lastQuery != nil {
+		t.Errorf("blank query passed to GetContext as %q, want nil", *capSvc.lastQuery)
+	}
+}
+
 func TestHandlePantryContext_PropagatesError(t *testing.T) {
 	svc := &stubService{contextErr: errors.New("context failed")}
 
@@ -292,25 +588,35 @@ func TestHandlePantryContext_PropagatesError(t *testing.T) {
 
 type contextCapturingStub struct {
 	lastLimit int
+	lastQuery *string
+	lastMode  string
+	lastTopup bool
 	onContext func(int)
 }
 
 //nolint:revive
-func (c *contextCapturingStub) Store(raw models.RawItemInput, project string) (map[string]any, error) {
+func (c *contextCapturingStub) Store(raw models.RawItemInput, project string, opts ...core.StoreOption) (map[string]any, error) {
 	return map[string]any{"id": "x", "file_path": "/f", "action": "created"}, nil
 }
-func (c *contextCapturingStub) Search(_ string, _ int, _ *string, _ *string, _ bool) ([]models.SearchResult, error) {
+func (c *contextCapturingStub) Search(_ string, _ int, _ *string, _ *string, _ bool, _ ...core.SearchOption) ([]models.SearchResult, error) {
 	return nil, nil
 }
-func (c *contextCapturingStub) GetContext(limit int, _ *string, _ *string, _ *string, _ string, _ bool) ([]models.SearchResult, int64, error) {
+func (c *contextCapturingStub) GetContext(limit int, _ *string, _ *string, query *string, semanticMode string, topupRecent bool, _ ...core.SearchOption) ([]models.SearchResult, int64, error) {
 	c.lastLimit = limit
+	c.lastQuery = query
+	c.lastMode = semanticMode
+	c.lastTopup = topupRecent
 	if c.onContext != nil {
 		c.onContext(limit)
 	}
 
 	return []models.SearchResult{}, 0, nil
 }
-func (c *contextCapturingStub) Close() error { return nil }
+func (c *contextCapturingStub) Links(_ string) ([]models.NoteLink, error) { return nil, nil }
+func (c *contextCapturingStub) SinceTime(_ string) (time.Time, error)     { return time.Time{}, nil }
+func (c *contextCapturingStub) SetCheckpoint(_ string) (time.Time, error) { return time.Time{}, nil }
+func (c *contextCapturingStub) DefaultSource(_ string) string             { return "" }
+func (c *contextCapturingStub) Close() error                              { return nil }
 
 // --- getStringSliceFromMap tests ---
 
@@ -370,3 +676,161 @@ func TestGetStringSliceFromMap_EmptyCommaString(t *testing.T) {
 		t.Error("getStringSliceFromMap() should return ok=false for blank string")
 	}
 }
+
+// --- SIGHUP reload ---
+
+// syncBuffer is a bytes.Buffer safe for the watcher goroutine and the test to share.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// waitForLog polls w until it contains want or the deadline passes.
+func waitForLog(t *testing.T, w *syncBuffer, want string) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(w.String(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %q in log %q", want, w.String())
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWatchReload_SIGHUPSwitchesModel(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		seenModels []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		seenModels = append(seenModels, body.Model)
+		mu.Unlock()
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"embedding": []float64{0.1, 0.2, 0.3}})
+	}))
+	defer srv.Close()
+
+	home := t.TempDir()
+	configPath := filepath.Join(home, "config.yaml")
+
+	writeConfig := func(provider, model string) {
+		t.Helper()
+
+		content := "embedding:\n  provider: " + provider + "\n  model: " + model + "\n  base_url: " + srv.URL + "\n"
+		if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	writeConfig("ollama", "model-a")
+
+	svc, err := core.NewService(home)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	defer svc.Close()
+
+	embed := func() {
+		t.Helper()
+
+		p, err := svc.GetEmbeddingProvider()
+		if err != nil {
+			t.Fatalf("GetEmbeddingProvider() error = %v", err)
+		}
+
+		if _, err := p.Embed(context.Background(), "probe"); err != nil {
+			t.Fatalf("Embed() error = %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sig := make(chan os.Signal, 1)
+	logw := &syncBuffer{}
+
+	go watchReload(ctx, sig, svc, logw)
+
+	embed()
+
+	writeConfig("ollama", "model-b")
+
+	sig <- syscall.SIGHUP
+
+	waitForLog(t, logw, "config reloaded")
+	embed()
+
+	// An invalid config is logged and the previous one keeps serving.
+	writeConfig("bogus", "model-c")
+
+	sig <- syscall.SIGHUP
+
+	waitForLog(t, logw, "config reload failed")
+	embed()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	want := []string{"model-a", "model-b", "model-b"}
+	if strings.Join(seenModels, ",") != strings.Join(want, ",") {
+		t.Errorf("embed models = %v, want %v", seenModels, want)
+	}
+}
+
+// --- HandlePantryLinks tests ---
+
+func TestHandlePantryLinks_Directions(t *testing.T) {
+	svc := &stubService{links: []models.NoteLink{
+		{FromID: "new", FromTitle: "New decision", ToID: "old", ToTitle: "Old decision", Type: models.LinkSupersedes},
+		{FromID: "bug", FromTitle: "Bug", ToID: "new", ToTitle: "New decision", Type: models.LinkRelatedTo},
+	}}
+
+	result, err := HandlePantryLinks(svc, map[string]any{"id": "new"})
+	if err != nil {
+		t.Fatalf("HandlePantryLinks() error = %v", err)
+	}
+
+	links, _ := result["links"].([]map[string]any)
+	if len(links) != 2 {
+		t.Fatalf("links = %d, want 2", len(links))
+	}
+
+	if links[0]["direction"] != "outgoing" || links[0]["type"] != models.LinkSupersedes {
+		t.Errorf("links[0] = %v, want outgoing supersedes", links[0])
+	}
+
+	if links[1]["direction"] != "incoming" || links[1]["from_id"] != "bug" {
+		t.Errorf("links[1] = %v, want incoming from bug", links[1])
+	}
+}
+
+func TestHandlePantryLinks_RequiresID(t *testing.T) {
+	if _, err := HandlePantryLinks(&stubService{}, map[string]any{}); err == nil {
+		t.Error("HandlePantryLinks() should fail without an id")
+	}
+}
@@ -11,6 +11,15 @@ import (
 // ValidCategories defines the allowed categories for items.
 var ValidCategories = []string{"decision", "pattern", "bug", "context", "learning"}
 
+// Link types recorded between notes.
+const (
+	LinkRelatedTo  = "related_to"
+	LinkSupersedes = "supersedes"
+)
+
+// ValidLinkTypes defines the allowed note link types.
+var ValidLinkTypes = []string{LinkSupersedes, LinkRelatedTo}
+
 // CategoryHeadings maps category values to display headings.
 var CategoryHeadings = map[string]string{
 	"decision": "Decisions",
@@ -31,6 +40,9 @@ type RawItemInput struct {
 	RelatedFiles []string
 	Details      *string
 	Source       *string
+	// Metadata is free-form key-value context from the caller, such as the
+	// agent's model or session ID.
+	Metadata map[string]string
 }
 
 // Item represents a stored item in the pantry.
@@ -45,6 +57,7 @@ type Item struct {
 	Project       string
 	Source        *string
 	RelatedFiles  []string
+	Metadata      map[string]string
 	FilePath      string
 	SectionAnchor string
 	CreatedAt     string
@@ -67,6 +80,7 @@ func FromRaw(raw RawItemInput, project string, filePath string) Item {
 		Project:       project,
 		Source:        raw.Source,
 		RelatedFiles:  raw.RelatedFiles,
+		Metadata:      raw.Metadata,
 		FilePath:      filePath,
 		SectionAnchor: anchor,
 		CreatedAt:     now,
@@ -91,19 +105,51 @@ type ItemDetail struct {
 	Body   string
 }
 
+// NoteLink is a typed, directed link between two notes, with both titles
+// resolved for display.
+type NoteLink struct {
+	FromID    string
+	FromTitle string
+	ToID      string
+	ToTitle   string
+	Type      string
+	CreatedAt string
+}
+
 // SearchResult represents a search result with score and metadata.
 type SearchResult struct {
-	ID         string
-	Title      string
-	What       string
-	Why        *string
-	Impact     *string
-	Category   *string
-	Tags       []string
-	Project    string
-	Source     *string
-	Score      float64
+	ID       string
+	Title    string
+	What     string
+	Why      *string
+	Impact   *string
+	Category *string
+	Tags     []string
+	Project  string
+	Source   *string
+	Score    float64
+	Distance *float64 // raw vector distance; nil for keyword-only matches
+	// FTSRank is the raw FTS5 bm25 rank (more negative is better); nil for
+	// vector-only matches.
+	FTSRank    *float64
 	HasDetails bool
 	FilePath   string
 	CreatedAt  string
+	// UpdatedAt is when the note was last changed; it equals CreatedAt for a
+	// note never updated.
+	UpdatedAt string
+	Snippet   string // FTS excerpt around the match; empty for vector-only matches
+	// UpdatedCount is how many times the note was updated after creation.
+	UpdatedCount int
+	// CrossProject marks a result from outside the searched project, added
+	// by a fallback search.
+	CrossProject bool
+	// Similar holds near-duplicates folded into this result by a grouped
+	// search, in rank order.
+	Similar []SearchResult
+}
+
+// Revised reports whether the note was changed after it was created.
+func (r SearchResult) Revised() bool {
+	return r.UpdatedAt != "" && r.UpdatedAt != r.CreatedAt
 }
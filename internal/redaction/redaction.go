@@ -22,6 +22,9 @@ var SensitivePatterns = []string{
 	`api[_-]?key\s*[:=]\s*["']?.+`,         // API key fields
 }
 
+// Placeholder replaces every redacted span.
+const Placeholder = "[REDACTED]"
+
 // compiledBuiltins holds pre-compiled versions of SensitivePatterns.
 // Compiled once at package init; zero runtime cost per Redact call.
 var (
@@ -69,7 +72,7 @@ func RedactCompiled(text string, extra []*regexp.Regexp) string {
 	for {
 		prev := text
 
-		text = redactedTagRe.ReplaceAllString(text, "[REDACTED]")
+		text = redactedTagRe.ReplaceAllString(text, Placeholder)
 
 		if prev == text {
 			break
@@ -81,17 +84,28 @@ func RedactCompiled(text string, extra []*regexp.Regexp) string {
 
 	// Layer 2: Built-in patterns (pre-compiled, zero cost)
 	for _, re := range compiledBuiltins {
-		text = re.ReplaceAllString(text, "[REDACTED]")
+		text = re.ReplaceAllString(text, Placeholder)
 	}
 
 	// Layer 3: Custom patterns
 	for _, re := range extra {
-		text = re.ReplaceAllString(text, "[REDACTED]")
+		text = re.ReplaceAllString(text, Placeholder)
 	}
 
 	return text
 }
 
+// Count returns how many spans were redacted to turn before into after. Input
+// that was altered without gaining a placeholder, such as a bare <redacted>
+// tag being stripped, counts as one.
+func Count(before, after string) int {
+	if before == after {
+		return 0
+	}
+
+	return max(strings.Count(after, Placeholder)-strings.Count(before, Placeholder), 1)
+}
+
 // LoadPantryIgnore loads custom redaction patterns from a .pantryignore file.
 func LoadPantryIgnore(path string) ([]string, error) {
 	file, err := os.Open(path)
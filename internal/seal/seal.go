@@ -0,0 +1,112 @@
+// Package seal encrypts pantry exports with a passphrase, using AES-256-GCM
+// with a key derived by PBKDF2-SHA256.
+package seal
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// magic starts every sealed file and is authenticated along with the data.
+const magic = "PANTRYSEAL1\n"
+
+const (
+	saltSize   = 16
+	keySize    = 32
+	iterations = 600_000
+)
+
+// ErrNotSealed is returned by Open for data that was not produced by Seal.
+var ErrNotSealed = errors.New("not an encrypted pantry export")
+
+// ErrWrongPassphrase is returned by Open when the passphrase does not match
+// or the data was modified after sealing.
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted data")
+
+// IsSealed reports whether data looks like the output of Seal.
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(magic))
+}
+
+// Seal encrypts plaintext with passphrase. The output holds the format
+// marker, a random salt and nonce, and the authenticated ciphertext.
+func Seal(plaintext []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase must not be empty")
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := make([]byte, 0, len(magic)+saltSize+len(nonce)+len(plaintext)+gcm.Overhead())
+	out = append(out, magic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+
+	return gcm.Seal(out, nonce, plaintext, []byte(magic)), nil
+}
+
+// Open decrypts data produced by Seal.
+func Open(data []byte, passphrase string) ([]byte, error) {
+	if !IsSealed(data) {
+		return nil, ErrNotSealed
+	}
+
+	rest := data[len(magic):]
+	if len(rest) < saltSize {
+		return nil, ErrWrongPassphrase
+	}
+
+	salt, rest := rest[:saltSize], rest[saltSize:]
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) < gcm.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+
+	nonce, ciphertext := rest[:gcm.NonceSize()], rest[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(magic))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	return plaintext, nil
+}
+
+// newGCM derives the key for passphrase and salt and returns its AEAD.
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
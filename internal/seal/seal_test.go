@@ -0,0 +1,64 @@
+package seal
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSealOpen_RoundTrip(t *testing.T) {
+	plaintext := []byte(`[{"id":"abc","title":"Use JWT auth"}]`)
+
+	sealed, err := Seal(plaintext, "correct horse")
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	if !IsSealed(sealed) {
+		t.Error("IsSealed() = false for sealed data")
+	}
+
+	if bytes.Contains(sealed, []byte("JWT")) {
+		t.Error("sealed data contains the plaintext")
+	}
+
+	opened, err := Open(sealed, "correct horse")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open() = %q, want %q", opened, plaintext)
+	}
+}
+
+func TestOpen_WrongPassphrase(t *testing.T) {
+	sealed, err := Seal([]byte("secret notes"), "correct horse")
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	if _, err := Open(sealed, "battery staple"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Open(wrong passphrase) error = %v, want ErrWrongPassphrase", err)
+	}
+
+	// A flipped byte is caught the same way.
+	sealed[len(sealed)-1] ^= 1
+	if _, err := Open(sealed, "correct horse"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Open(tampered) error = %v, want ErrWrongPassphrase", err)
+	}
+
+	if _, err := Open([]byte(`[{"id":"abc"}]`), "correct horse"); !errors.Is(err, ErrNotSealed) {
+		t.Errorf("Open(plain JSON) error = %v, want ErrNotSealed", err)
+	}
+
+	if _, err := Open([]byte(magic+"short"), "correct horse"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Open(truncated) error = %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestSeal_EmptyPassphrase(t *testing.T) {
+	if _, err := Seal([]byte("x"), ""); err == nil {
+		t.Error("Seal() with empty passphrase: error = nil")
+	}
+}
@@ -0,0 +1,111 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"pantry/internal/models"
+)
+
+// CollapseSimilar folds near-duplicate results into the highest-ranked one:
+// walking in rank order, a result whose similarity to an earlier kept result
+// reaches threshold is appended to that result's Similar instead of being
+// kept. Similarity is the overlap (Jaccard index) of the words in title and
+// what, so reworded copies of one note collapse while distinct notes on the
+// same topic stay apart. A threshold of 0 or less returns results unchanged.
+func CollapseSimilar(results []models.SearchResult, threshold float64) []models.SearchResult {
+	if threshold <= 0 {
+		return results
+	}
+
+	kept := make([]models.SearchResult, 0, len(results))
+	words := make([]map[string]bool, 0, len(results))
+
+	for _, r := range results {
+		w := resultWords(r)
+		folded := false
+
+		for i := range kept {
+			if jaccard(words[i], w) >= threshold {
+				kept[i].Similar = append(kept[i].Similar, r)
+				folded = true
+
+				break
+			}
+		}
+
+		if !folded {
+			kept = append(kept, r)
+			words = append(words, w)
+		}
+	}
+
+	return kept
+}
+
+// DedupFields are the fields DeduplicateBy can compare.
+var DedupFields = []string{"title", "what"}
+
+// DeduplicateBy keeps only the highest-ranked of the results that share field
+// ("title" or "what"), compared case-insensitively and ignoring surrounding
+// and repeated whitespace. Unlike CollapseSimilar, the dropped results are
+// not kept in Similar. An empty or unknown field returns results unchanged.
+func DeduplicateBy(results []models.SearchResult, field string) []models.SearchResult {
+	var value func(models.SearchResult) string
+
+	switch field {
+	case "title":
+		value = func(r models.SearchResult) string { return r.Title }
+	case "what":
+		value = func(r models.SearchResult) string { return r.What }
+	default:
+		return results
+	}
+
+	seen := make(map[string]bool, len(results))
+	kept := make([]models.SearchResult, 0, len(results))
+
+	for _, r := range results {
+		key := strings.ToLower(strings.Join(strings.Fields(value(r)), " "))
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		kept = append(kept, r)
+	}
+
+	return kept
+}
+
+// resultWords returns the lowercased words of a result's title and what.
+func resultWords(r models.SearchResult) map[string]bool {
+	fields := strings.FieldsFunc(strings.ToLower(r.Title+" "+r.What), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+
+	words := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		words[f] = true
+	}
+
+	return words
+}
+
+// jaccard returns |a ∩ b| / |a ∪ b|, or 0 when both are empty.
+func jaccard(a, b map[string]bool) float64 {
+	shared := 0
+
+	for w := range a {
+		if b[w] {
+			shared++
+		}
+	}
+
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+
+	return float64(shared) / float64(union)
+}
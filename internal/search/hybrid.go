@@ -2,7 +2,9 @@ package search
 
 import (
 	"context"
+	"math"
 	"sort"
+	"time"
 
 	"pantry/internal/db"
 	"pantry/internal/embeddings"
@@ -57,6 +59,7 @@ func MergeResults(ftsResults []models.SearchResult, vecResults []models.SearchRe
 	for _, r := range vecResults {
 		if existing, ok := scores[r.ID]; ok {
 			existing.Score += vecWeight * r.Score
+			existing.Distance = r.Distance
 		} else {
 			result := r
 			result.Score = vecWeight * r.Score
@@ -80,9 +83,56 @@ func MergeResults(ftsResults []models.SearchResult, vecResults []models.SearchRe
 	return ranked
 }
 
+// RecencyBoost multiplies each score by 0.5^(age/halfLifeDays), where age is
+// the time since CreatedAt, and re-sorts by score. Results whose CreatedAt
+// cannot be parsed keep their score. A halfLifeDays of 0 or less is a no-op.
+func RecencyBoost(results []models.SearchResult, halfLifeDays float64, now time.Time) {
+	if halfLifeDays <= 0 {
+		return
+	}
+
+	for i := range results {
+		created, err := time.Parse(time.RFC3339, results[i].CreatedAt)
+		if err != nil {
+			continue
+		}
+
+		ageDays := max(now.Sub(created).Hours()/24, 0)
+		results[i].Score *= math.Pow(0.5, ageDays/halfLifeDays)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+}
+
+// NearBoost multiplies each score by 0.5^(d/window), where d is the time
+// between CreatedAt and target, and re-sorts by score, so notes from around
+// target rank higher. Results whose CreatedAt cannot be parsed keep their
+// score. A window of 0 or less is a no-op.
+func NearBoost(results []models.SearchResult, target time.Time, window time.Duration) {
+	if window <= 0 {
+		return
+	}
+
+	for i := range results {
+		created, err := time.Parse(time.RFC3339, results[i].CreatedAt)
+		if err != nil {
+			continue
+		}
+
+		d := created.Sub(target).Abs()
+		results[i].Score *= math.Pow(0.5, float64(d)/float64(window))
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+}
+
 // TieredSearch performs FTS-first tiered search that only calls embed when FTS results are sparse.
-func TieredSearch(ctx context.Context, store db.Store, embeddingProvider embeddings.Provider, query string, limit int, minFTSResults int, project *string, source *string) ([]models.SearchResult, error) {
-	ftsResults, err := store.FTSSearch(query, limit*2, project, source)
+func TieredSearch(ctx context.Context, store db.Store, embeddingProvider embeddings.Provider, query string, limit int, minFTSResults int, project *string, source *string, opts ...db.QueryOption) ([]models.SearchResult, error) {
+	ftsResults, err := store.FTSSearch(query, limit*2, project, source, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -108,7 +158,7 @@ func TieredSearch(ctx context.Context, store db.Store, embeddingProvider embeddi
 	}
 
 	// FTS results are sparse — fall back to hybrid (embed + vector search + merge)
-	queryVec, err := embeddingProvider.Embed(ctx, query)
+	queryVec, err := embeddingProvider.Embed(embeddings.WithInputType(ctx, embeddings.InputQuery), query)
 	if err != nil {
 		// On any embedding error, return whatever FTS found
 		if len(ftsResults) > limit {
@@ -118,7 +168,7 @@ func TieredSearch(ctx context.Context, store db.Store, embeddingProvider embeddi
 		return ftsResults, nil
 	}
 
-	vecResults, err := store.VectorSearch(queryVec, limit*2, project, source)
+	vecResults, err := store.VectorSearch(queryVec, limit*2, project, source, opts...)
 	if err != nil {
 		// On vector search error, return FTS results
 		if len(ftsResults) > limit {
@@ -132,8 +182,8 @@ func TieredSearch(ctx context.Context, store db.Store, embeddingProvider embeddi
 }
 
 // HybridSearch runs FTS5 and optionally vector search, merges results.
-func HybridSearch(ctx context.Context, store db.Store, embeddingProvider embeddings.Provider, query string, limit int, project *string, source *string) ([]models.SearchResult, error) {
-	ftsResults, err := store.FTSSearch(query, limit*2, project, source)
+func HybridSearch(ctx context.Context, store db.Store, embeddingProvider embeddings.Provider, query string, limit int, project *string, source *string, opts ...db.QueryOption) ([]models.SearchResult, error) {
+	ftsResults, err := store.FTSSearch(query, limit*2, project, source, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -149,7 +199,7 @@ func HybridSearch(ctx context.Context, store db.Store, embeddingProvider embeddi
 		return ftsResults, nil
 	}
 
-	queryVec, err := embeddingProvider.Embed(ctx, query)
+	queryVec, err := embeddingProvider.Embed(embeddings.WithInputType(ctx, embeddings.InputQuery), query)
 	if err != nil {
 		// On embedding error, return FTS results
 		if len(ftsResults) > limit {
@@ -159,7 +209,7 @@ func HybridSearch(ctx context.Context, store db.Store, embeddingProvider embeddi
 		return ftsResults, nil
 	}
 
-	vecResults, err := store.VectorSearch(queryVec, limit*2, project, source)
+	vecResults, err := store.VectorSearch(queryVec, limit*2, project, source, opts...)
 	if err != nil {
 		// On vector search error, return FTS results
 		if len(ftsResults) > limit {
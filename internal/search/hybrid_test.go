@@ -3,8 +3,11 @@ package search
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
+	"time"
 
+	"pantry/internal/db"
 	"pantry/internal/models"
 )
 
@@ -19,12 +22,12 @@ type fakeStore struct {
 	vecCalled  int
 }
 
-func (f *fakeStore) FTSSearch(_ string, _ int, _ *string, _ *string) ([]models.SearchResult, error) {
+func (f *fakeStore) FTSSearch(_ string, _ int, _ *string, _ *string, _ ...db.QueryOption) ([]models.SearchResult, error) {
 	f.ftsCalled++
 
 	return f.ftsResults, f.ftsErr
 }
-func (f *fakeStore) VectorSearch(_ []float32, _ int, _ *string, _ *string) ([]models.SearchResult, error) {
+func (f *fakeStore) VectorSearch(_ []float32, _ int, _ *string, _ *string, _ ...db.QueryOption) ([]models.SearchResult, error) {
 	f.vecCalled++
 
 	return f.vecResults, f.vecErr
@@ -32,23 +35,65 @@ func (f *fakeStore) VectorSearch(_ []float32, _ int, _ *string, _ *string) ([]mo
 
 // Unused interface methods — zero-value implementations.
 func (f *fakeStore) InsertItem(_ models.Item, _ *string) (int64, error) { return 0, nil }
-func (f *fakeStore) InsertVector(_ int64, _ []float32) error            { return nil }
-func (f *fakeStore) GetItem(_ string) (*models.Item, bool, error)       { return nil, false, nil }
-func (f *fakeStore) GetDetails(_ string) (*models.ItemDetail, error)    { return nil, nil } //nolint:nilnil
-func (f *fakeStore) UpdateItem(_ string, _ *string, _ *string, _ *string, _ []string, _ *string) error {
+func (f *fakeStore) InsertItems(_ []models.Item, _ []*string) ([]int64, error) {
+	return nil, nil
+}
+func (f *fakeStore) ReplaceItem(_ models.Item, _ *string) error      { return nil }
+func (f *fakeStore) InsertVector(_ int64, _ []float32) error         { return nil }
+func (f *fakeStore) ReplaceVector(_ string, _ []float32) error       { return nil }
+func (f *fakeStore) GetItem(_ string) (*models.Item, bool, error)    { return nil, false, nil }
+func (f *fakeStore) GetDetails(_ string) (*models.ItemDetail, error) { return nil, nil } //nolint:nilnil
+func (f *fakeStore) UpdateItem(_ string, _ *string, _ *string, _ *string, _ []string, _ *string, _ bool) error {
 	return nil
 }
-func (f *fakeStore) DeleteItem(_ string) (bool, error) { return false, nil }
-func (f *fakeStore) ListRecent(_ int, _ *string, _ *string) ([]models.SearchResult, error) {
+func (f *fakeStore) DeleteItem(_ string) (bool, error)               { return false, nil }
+func (f *fakeStore) SetMetadata(_ string, _ map[string]string) error { return nil }
+func (f *fakeStore) ListRecent(_ int, _ *string, _ *string, _ ...db.QueryOption) ([]models.SearchResult, error) {
 	return nil, nil
 }
-func (f *fakeStore) ListAllForReindex() ([]map[string]any, error)   { return nil, nil }
-func (f *fakeStore) CountItems(_ *string, _ *string) (int64, error) { return 0, nil }
-func (f *fakeStore) HasVecTable() bool                              { return false }
-func (f *fakeStore) EnsureVecTable(_ int) error                     { return nil }
-func (f *fakeStore) SetEmbeddingDim(_ int) error                    { return nil }
-func (f *fakeStore) DropVecTable() error                            { return nil }
-func (f *fakeStore) Close() error                                   { return nil }
+func (f *fakeStore) ListAllForReindex() ([]map[string]any, error) { return nil, nil }
+func (f *fakeStore) CountItems(_ *string, _ *string, _ ...db.QueryOption) (int64, error) {
+	return 0, nil
+}
+func (f *fakeStore) HasVecTable() bool                    { return false }
+func (f *fakeStore) EnsureVecTable(_ int, _ string) error { return nil }
+func (f *fakeStore) SetEmbeddingDim(_ int) error          { return nil }
+func (f *fakeStore) DropVecTable() error                  { return nil }
+func (f *fakeStore) BackupVecTable() error                { return nil }
+func (f *fakeStore) RestoreVecTable() error               { return nil }
+func (f *fakeStore) DropVecBackup() error                 { return nil }
+func (f *fakeStore) WithTx(fn func(db.Store) error) error { return fn(f) }
+func (f *fakeStore) DumpSchema() (*db.SchemaDump, error)  { return &db.SchemaDump{}, nil }
+func (f *fakeStore) CountFTS(_ string, _ *string, _ *string, _ ...db.QueryOption) (int64, error) {
+	return int64(len(f.ftsResults)), nil
+}
+
+func (f *fakeStore) FTSFacets(_ string, _ *string, _ *string, _ ...db.QueryOption) (db.Facets, error) {
+	return db.Facets{}, nil
+}
+func (f *fakeStore) ListItems(_ *string) ([]models.Item, error)            { return nil, nil }
+func (f *fakeStore) ListByFilePath(_ string) ([]models.Item, error)        { return nil, nil }
+func (f *fakeStore) EmbeddingDim() int                                     { return 0 }
+func (f *fakeStore) InsertLink(_, _, _ string) error                       { return nil }
+func (f *fakeStore) ListLinks(_ string) ([]models.NoteLink, error)         { return nil, nil }
+func (f *fakeStore) ListSources() ([]db.FacetCount, error)                 { return nil, nil }
+func (f *fakeStore) Terms(*string) (map[string]int, error)                 { return nil, nil }
+func (f *fakeStore) ListMissingVectors(_ string) ([]map[string]any, error) { return nil, nil }
+func (f *fakeStore) SetVectorModel(_, _ string) error                      { return nil }
+func (f *fakeStore) GetMeta(_ string) (string, bool, error)                { return "", false, nil }
+func (f *fakeStore) SetMeta(_, _ string) error                             { return nil }
+func (f *fakeStore) OrphanVectors() ([]int64, error)                       { return nil, nil }
+func (f *fakeStore) DeleteOrphanVectors() (int64, error)                   { return 0, nil }
+func (f *fakeStore) RewriteFilePaths(string, string) (int64, error)        { return 0, nil }
+func (f *fakeStore) RenameSources(func(string) string) (int64, error)      { return 0, nil }
+func (f *fakeStore) ReplaceTag(string, string) ([]string, error)           { return nil, nil }
+func (f *fakeStore) RebuildFTS() (int64, error)                            { return 0, nil }
+func (f *fakeStore) Checkpoint() error                                     { return nil }
+func (f *fakeStore) VecMetric() string                                     { return db.MetricL2 }
+func (f *fakeStore) Close() error                                          { return nil }
+
+func (f *fakeStore) CountByPeriod(string, *string) ([]db.PeriodCount, error) { return nil, nil }
+func (f *fakeStore) Stats(*string) (db.StoreStats, error)                    { return db.StoreStats{}, nil }
 
 // fakeEmbedder always returns a fixed 3-float vector.
 type fakeEmbedder struct {
@@ -150,6 +195,25 @@ func TestMergeResults_DeduplicationCombinesScores(t *testing.T) {
 	}
 }
 
+func TestMergeResults_KeepsRawScores(t *testing.T) {
+	rank, dist := -3.5, 0.25
+
+	fts := []models.SearchResult{makeResult("shared", 2.0)}
+	fts[0].FTSRank = &rank
+	vec := []models.SearchResult{makeResult("shared", 4.0)}
+	vec[0].Distance = &dist
+
+	result := MergeResults(fts, vec, 0.3, 0.7, 10)
+
+	if len(result) != 1 || result[0].FTSRank == nil || result[0].Distance == nil {
+		t.Fatalf("merged result = %+v, want both the FTS rank and the distance", result)
+	}
+
+	if *result[0].FTSRank != rank || *result[0].Distance != dist {
+		t.Errorf("raw scores = %v, %v, want %v, %v", *result[0].FTSRank, *result[0].Distance, rank, dist)
+	}
+}
+
 func TestMergeResults_OrderedByScoreDesc(t *testing.T) {
 	fts := []models.SearchResult{
 		makeResult("low", 1.0),
@@ -266,3 +330,88 @@ func TestTieredSearch_EmbedError_ReturnsFTSResults(t *testing.T) {
 		t.Error("TieredSearch() should return FTS results as fallback on embed error")
 	}
 }
+
+func TestRecencyBoost_NewerOvertakesOlder(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	older := makeResult("older", 1.0)
+	older.CreatedAt = now.AddDate(0, 0, -60).Format(time.RFC3339)
+	newer := makeResult("newer", 0.9)
+	newer.CreatedAt = now.AddDate(0, 0, -1).Format(time.RFC3339)
+
+	results := []models.SearchResult{older, newer}
+
+	RecencyBoost(results, 0, now)
+
+	if results[0].ID != "older" {
+		t.Fatalf("half-life 0 should keep the order, got %s first", results[0].ID)
+	}
+
+	RecencyBoost(results, 7, now)
+
+	if results[0].ID != "newer" {
+		t.Errorf("with a 7-day half-life the newer note should rank first, got %s", results[0].ID)
+	}
+
+	if results[0].Score <= results[1].Score {
+		t.Errorf("scores not re-sorted: %v <= %v", results[0].Score, results[1].Score)
+	}
+}
+
+func TestCollapseSimilar_FoldsNearDuplicates(t *testing.T) {
+	results := []models.SearchResult{
+		{ID: "a", Title: "Fix auth token refresh", What: "Refresh tokens before expiry", Score: 0.9},
+		{ID: "b", Title: "Fix auth token refresh bug", What: "Refresh tokens before expiry", Score: 0.8},
+		{ID: "c", Title: "Use Postgres for storage", What: "Relational data fits Postgres", Score: 0.7},
+		{ID: "d", Title: "Fixed auth token refresh", What: "Refresh tokens before expiry", Score: 0.6},
+		{ID: "e", Title: "Fix auth token refresh", What: "Refresh the tokens before expiry", Score: 0.5},
+	}
+
+	got := CollapseSimilar(results, 0.6)
+
+	if len(got) != 2 || got[0].ID != "a" || got[1].ID != "c" {
+		t.Fatalf("CollapseSimilar() kept %v, want [a c]", ids(got))
+	}
+
+	if sim := ids(got[0].Similar); len(sim) != 3 || sim[0] != "b" || sim[1] != "d" || sim[2] != "e" {
+		t.Errorf("representative Similar = %v, want [b d e]", sim)
+	}
+
+	if len(got[1].Similar) != 0 {
+		t.Errorf("distinct note Similar = %v, want none", ids(got[1].Similar))
+	}
+
+	if len(CollapseSimilar(results, 0)) != len(results) {
+		t.Error("CollapseSimilar(threshold 0) should return results unchanged")
+	}
+}
+
+func TestDeduplicateBy(t *testing.T) {
+	results := []models.SearchResult{
+		{ID: "a", Title: "Cache TTL", What: "Five minutes", Score: 0.9},
+		{ID: "b", Title: "Retry policy", What: "Five minutes", Score: 0.8},
+		{ID: "c", Title: "cache  ttl ", What: "Ten minutes", Score: 0.7},
+		{ID: "d", Title: "Cache TTL", What: "One hour", Score: 0.6},
+	}
+
+	if got := ids(DeduplicateBy(results, "title")); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("DeduplicateBy(title) = %v, want [a b]", got)
+	}
+
+	if got := ids(DeduplicateBy(results, "what")); !slices.Equal(got, []string{"a", "c", "d"}) {
+		t.Errorf("DeduplicateBy(what) = %v, want [a c d]", got)
+	}
+
+	if got := DeduplicateBy(results, ""); len(got) != len(results) {
+		t.Errorf("DeduplicateBy(\"\") kept %d results, want all %d", len(got), len(results))
+	}
+}
+
+func ids(results []models.SearchResult) []string {
+	out := make([]string, len(results))
+	for i, r := range results {
+		out[i] = r.ID
+	}
+
+	return out
+}
@@ -0,0 +1,118 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"pantry/internal/models"
+)
+
+// MatchCase keeps the results whose text (title, what, why, impact and tags)
+// holds the query terms in the case they were typed, for searches where API
+// and api mean different things. The keyword index ignores case, so this
+// refines its matches: a result is dropped when a term it contains appears
+// only in another case, or when no term appears in its text at all (a
+// vector-only match). Text and terms are split into words and stemmed as the
+// index's tokenizer does, so a term matches the same words there as in the
+// index; a trailing * makes a term a prefix, as in the index.
+func MatchCase(results []models.SearchResult, query string) []models.SearchResult {
+	var terms []caseTerm
+
+	for _, field := range strings.Fields(query) {
+		prefix := strings.HasSuffix(field, "*")
+
+		words := ftsWords(field)
+		for i, w := range words {
+			terms = append(terms, caseTerm{word: w, prefix: prefix && i == len(words)-1})
+		}
+	}
+
+	if len(terms) == 0 {
+		return results
+	}
+
+	kept := make([]models.SearchResult, 0, len(results))
+
+	for _, r := range results {
+		if matchesCase(ftsWords(resultText(r)), terms) {
+			kept = append(kept, r)
+		}
+	}
+
+	return kept
+}
+
+// caseTerm is a query word MatchCase looks for.
+type caseTerm struct {
+	word   string
+	prefix bool
+}
+
+// matches reports whether word is one the index would match t on: the same
+// stem, or for a prefix term a stem starting with it.
+func (t caseTerm) matches(word string) bool {
+	stem := porterStem(strings.ToLower(word))
+	if t.prefix {
+		return strings.HasPrefix(stem, strings.ToLower(t.word))
+	}
+
+	return stem == porterStem(strings.ToLower(t.word))
+}
+
+// ftsWords splits text into words as the index's unicode61 tokenizer does:
+// runs of letters and digits, everything else separating them.
+func ftsWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// matchesCase reports whether words hold at least one of terms in its case
+// and none of them only in another case. A word and a term agree in case when
+// they are spelled the same over their common length, so API matches APIs
+// while api does not.
+func matchesCase(words []string, terms []caseTerm) bool {
+	found := false
+
+	for _, t := range terms {
+		seen, agreed := false, false
+
+		for _, w := range words {
+			if !t.matches(w) {
+				continue
+			}
+
+			seen = true
+
+			if n := min(len(w), len(t.word)); w[:n] == t.word[:n] {
+				agreed = true
+
+				break
+			}
+		}
+
+		switch {
+		case agreed:
+			found = true
+		case seen:
+			return false
+		}
+	}
+
+	return found
+}
+
+// resultText joins the text fields of r that MatchCase checks.
+func resultText(r models.SearchResult) string {
+	parts := []string{r.Title, r.What, strings.Join(r.Tags, " ")}
+
+	if r.Why != nil {
+		parts = append(parts, *r.Why)
+	}
+
+	if r.Impact != nil {
+		parts = append(parts, *r.Impact)
+	}
+
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,69 @@
+package search
+
+import (
+	"slices"
+	"testing"
+
+	"pantry/internal/models"
+)
+
+func TestPorterStem(t *testing.T) {
+	tests := map[string]string{
+		"caresses":    "caress",
+		"ponies":      "poni",
+		"running":     "run",
+		"hopping":     "hop",
+		"agreed":      "agre",
+		"happy":       "happi",
+		"relational":  "relat",
+		"conditional": "condit",
+		"generalize":  "gener",
+		"adjustment":  "adjust",
+		"controlling": "control",
+		"apis":        "api",
+		"go":          "go",
+		"v2":          "v2",
+	}
+
+	for word, want := range tests {
+		if got := porterStem(word); got != want {
+			t.Errorf("porterStem(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestMatchCase(t *testing.T) {
+	results := []models.SearchResult{
+		{ID: "exact", Title: "Rate limits on the API gateway"},
+		{ID: "plural", Title: "Documented the public APIs"},
+		{ID: "lower", Title: "Renamed the api package"},
+		{ID: "inside", Title: "Fixed the rapid retry loop"},
+		{ID: "other", Title: "Unrelated note"},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// APIs stems to api, as the index does; rapid holds "api" but is
+		// another word.
+		{"API", []string{"exact", "plural"}},
+		{"api", []string{"lower"}},
+		{"AP*", []string{"exact", "plural"}},
+		// exact has gateway only in another case, so it is dropped.
+		{`"API Gateway"`, []string{"plural"}},
+	}
+
+	for _, tt := range tests {
+		got := MatchCase(results, tt.query)
+
+		ids := make([]string, len(got))
+		for i, r := range got {
+			ids[i] = r.ID
+		}
+
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("MatchCase(%q) = %v, want %v", tt.query, ids, tt.want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package search
+
+import "strings"
+
+// porterStem reduces a lower-case word to its stem the way the FTS5 porter
+// tokenizer does, so a word can be compared with the terms the keyword index
+// matched it on. Words shorter than three letters, and words that are not
+// plain ASCII letters, are returned as they are, as FTS5 leaves them.
+func porterStem(word string) string {
+	if len(word) < 3 || len(word) > 64 {
+		return word
+	}
+
+	for i := range len(word) {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+
+	w := []byte(word)
+	w = porterStep1ab(w)
+	w = porterStep1c(w)
+	w = porterStep2(w)
+	w = porterStep3(w)
+	w = porterStep4(w)
+	w = porterStep5(w)
+
+	return string(w)
+}
+
+// isConsonant reports whether w[i] is a consonant: not a vowel, and not a y
+// following a consonant.
+func isConsonant(w []byte, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(w, i-1)
+	default:
+		return true
+	}
+}
+
+// measure returns the number of vowel-consonant sequences in w.
+func measure(w []byte) int {
+	m, i := 0, 0
+
+	for i < len(w) && isConsonant(w, i) {
+		i++
+	}
+
+	for i < len(w) {
+		for i < len(w) && !isConsonant(w, i) {
+			i++
+		}
+
+		if i == len(w) {
+			break
+		}
+
+		for i < len(w) && isConsonant(w, i) {
+			i++
+		}
+
+		m++
+	}
+
+	return m
+}
+
+// hasVowel reports whether w contains a vowel.
+func hasVowel(w []byte) bool {
+	for i := range w {
+		if !isConsonant(w, i) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// endsDouble reports whether w ends with a double consonant.
+func endsDouble(w []byte) bool {
+	n := len(w)
+
+	return n >= 2 && w[n-1] == w[n-2] && isConsonant(w, n-1)
+}
+
+// endsCVC reports whether w ends consonant-vowel-consonant, the last not w, x
+// or y.
+func endsCVC(w []byte) bool {
+	n := len(w)
+	if n < 3 || !isConsonant(w, n-1) || isConsonant(w, n-2) || !isConsonant(w, n-3) {
+		return false
+	}
+
+	return w[n-1] != 'w' && w[n-1] != 'x' && w[n-1] != 'y'
+}
+
+// porterRule replaces suffix with repl when the stem left without it passes
+// cond.
+type porterRule struct {
+	suffix, repl string
+	cond         func(stem []byte) bool
+}
+
+// applyRules applies the first rule whose suffix w ends with, if its
+// condition holds; later rules are not tried either way.
+func applyRules(w []byte, rules []porterRule) []byte {
+	for _, r := range rules {
+		if !strings.HasSuffix(string(w), r.suffix) {
+			continue
+		}
+
+		stem := w[:len(w)-len(r.suffix)]
+		if r.cond == nil || r.cond(stem) {
+			return append(stem, r.repl...)
+		}
+
+		return w
+	}
+
+	return w
+}
+
+func measureAbove(n int) func([]byte) bool {
+	return func(stem []byte) bool { return measure(stem) > n }
+}
+
+func porterStep1ab(w []byte) []byte {
+	w = applyRules(w, []porterRule{
+		{"sses", "ss", nil},
+		{"ies", "i", nil},
+		{"ss", "ss", nil},
+		{"s", "", nil},
+	})
+
+	s := string(w)
+
+	var stem []byte
+
+	switch {
+	case strings.HasSuffix(s, "eed"):
+		if measure(w[:len(w)-3]) > 0 {
+			return w[:len(w)-1]
+		}
+
+		return w
+	case strings.HasSuffix(s, "ed") && hasVowel(w[:len(w)-2]):
+		stem = w[:len(w)-2]
+	case strings.HasSuffix(s, "ing") && hasVowel(w[:len(w)-3]):
+		stem = w[:len(w)-3]
+	default:
+		return w
+	}
+
+	s = string(stem)
+
+	switch {
+	case strings.HasSuffix(s, "at"), strings.HasSuffix(s, "bl"), strings.HasSuffix(s, "iz"):
+		return append(stem, 'e')
+	case endsDouble(stem) && !strings.ContainsRune("lsz", rune(stem[len(stem)-1])):
+		return stem[:len(stem)-1]
+	case measure(stem) == 1 && endsCVC(stem):
+		return append(stem, 'e')
+	}
+
+	return stem
+}
+
+func porterStep1c(w []byte) []byte {
+	if n := len(w); n > 1 && w[n-1] == 'y' && hasVowel(w[:n-1]) {
+		w[n-1] = 'i'
+	}
+
+	return w
+}
+
+func porterStep2(w []byte) []byte {
+	m0 := measureAbove(0)
+
+	return applyRules(w, []porterRule{
+		{"ational", "ate", m0},
+		{"tional", "tion", m0},
+		{"enci", "ence", m0},
+		{"anci", "ance", m0},
+		{"izer", "ize", m0},
+		{"logi", "log", m0},
+		{"bli", "ble", m0},
+		{"alli", "al", m0},
+		{"entli", "ent", m0},
+		{"eli", "e", m0},
+		{"ousli", "ous", m0},
+		{"ization", "ize", m0},
+		{"ation", "ate", m0},
+		{"ator", "ate", m0},
+		{"alism", "al", m0},
+		{"iveness", "ive", m0},
+		{"fulness", "ful", m0},
+		{"ousness", "ous", m0},
+		{"aliti", "al", m0},
+		{"iviti", "ive", m0},
+		{"biliti", "ble", m0},
+	})
+}
+
+func porterStep3(w []byte) []byte {
+	m0 := measureAbove(0)
+
+	return applyRules(w, []porterRule{
+		{"icate", "ic", m0},
+		{"ative", "", m0},
+		{"alize", "al", m0},
+		{"iciti", "ic", m0},
+		{"ical", "ic", m0},
+		{"ful", "", m0},
+		{"ness", "", m0},
+	})
+}
+
+func porterStep4(w []byte) []byte {
+	m1 := measureAbove(1)
+
+	return applyRules(w, []porterRule{
+		{"al", "", m1},
+		{"ance", "", m1},
+		{"ence", "", m1},
+		{"er", "", m1},
+		{"ic", "", m1},
+		{"able", "", m1},
+		{"ible", "", m1},
+		{"ant", "", m1},
+		{"ement", "", m1},
+		{"ment", "", m1},
+		{"ent", "", m1},
+		{"ion", "", func(stem []byte) bool {
+			n := len(stem)
+
+			return measure(stem) > 1 && n > 0 && (stem[n-1] == 's' || stem[n-1] == 't')
+		}},
+		{"ou", "", m1},
+		{"ism", "", m1},
+		{"ate", "", m1},
+		{"iti", "", m1},
+		{"ous", "", m1},
+		{"ive", "", m1},
+		{"ize", "", m1},
+	})
+}
+
+func porterStep5(w []byte) []byte {
+	if n := len(w); n > 1 && w[n-1] == 'e' {
+		stem := w[:n-1]
+		if m := measure(stem); m > 1 || (m == 1 && !endsCVC(stem)) {
+			w = stem
+		}
+	}
+
+	if n := len(w); n > 1 && w[n-1] == 'l' && endsDouble(w) && measure(w) > 1 {
+		w = w[:n-1]
+	}
+
+	return w
+}
@@ -0,0 +1,134 @@
+package search
+
+import (
+	"sort"
+	"strings"
+
+	"pantry/internal/db"
+)
+
+// Suggest returns up to n spellings of query that use words from vocab, a
+// word count such as db.Store.Terms returns, for a search that found nothing.
+// Each query word missing from vocab is replaced by the closest vocab word by
+// edit distance, within one edit for words up to five letters and two edits
+// for longer ones; ties go to the more frequent word. The first suggestion
+// uses the best replacement for every word, the others the next best ones.
+// It returns nil when every word is known or none has a close match.
+func Suggest(vocab map[string]int, query string, n int) []string {
+	words := db.SplitWords(query)
+	candidates := make([][]string, len(words))
+	misspelled := false
+
+	for i, w := range words {
+		if vocab[w] > 0 {
+			continue
+		}
+
+		if candidates[i] = closeTerms(vocab, w); len(candidates[i]) > 0 {
+			misspelled = true
+		}
+	}
+
+	if !misspelled {
+		return nil
+	}
+
+	var suggestions []string
+
+	for rank := 0; len(suggestions) < n; rank++ {
+		out := make([]string, len(words))
+		varied := false
+
+		for i, w := range words {
+			switch c := candidates[i]; {
+			case rank < len(c):
+				out[i] = c[rank]
+				varied = true
+			case len(c) > 0:
+				out[i] = c[0]
+			default:
+				out[i] = w
+			}
+		}
+
+		if !varied {
+			break
+		}
+
+		suggestions = append(suggestions, strings.Join(out, " "))
+	}
+
+	return suggestions
+}
+
+// closeTerms returns the vocab words within editing reach of w, closest and
+// then most frequent first.
+func closeTerms(vocab map[string]int, w string) []string {
+	maxDist := 1
+	if len([]rune(w)) > 5 {
+		maxDist = 2
+	}
+
+	type match struct {
+		term  string
+		dist  int
+		count int
+	}
+
+	var matches []match
+
+	for term, count := range vocab {
+		if d := EditDistance(w, term); d <= maxDist {
+			matches = append(matches, match{term, d, count})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.dist != b.dist {
+			return a.dist < b.dist
+		}
+
+		if a.count != b.count {
+			return a.count > b.count
+		}
+
+		return a.term < b.term
+	})
+
+	terms := make([]string, len(matches))
+	for i, m := range matches {
+		terms[i] = m.term
+	}
+
+	return terms
+}
+
+// EditDistance returns the Levenshtein distance between a and b in runes.
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
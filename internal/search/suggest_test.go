@@ -0,0 +1,44 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	vocab := map[string]int{"authentication": 3, "middleware": 2, "jwt": 4, "cache": 5, "catch": 1}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"authentcation", []string{"authentication"}},
+		{"jwt midleware", []string{"jwt middleware"}},
+		{"cach", []string{"cache", "catch"}},
+		{"jwt middleware", nil},
+		{"kubernetes", nil},
+	}
+
+	for _, tt := range tests {
+		if got := Suggest(vocab, tt.query, 3); !slices.Equal(got, tt.want) {
+			t.Errorf("Suggest(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"cache", "cache", 0},
+		{"authentcation", "authentication", 1},
+		{"café", "cafe", 1},
+	} {
+		if got := EditDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("EditDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"pantry/internal/fsutil"
+)
+
+// LegacyShelfDir is the notes directory name used by older versions, which
+// wrote to shelf/ instead of shelves/.
+const LegacyShelfDir = "shelf"
+
+// MigrationResult describes what MigrateLegacyShelf did. Paths are relative
+// to the shelf directories.
+type MigrationResult struct {
+	Moved     []string
+	Conflicts []string // present in both with different content; left in place
+}
+
+// MigrateLegacyShelf moves every file under legacyDir into the same relative
+// path under shelvesDir, creating directories with dirMode. A file that
+// already exists in shelvesDir with identical content is dropped from
+// legacyDir; one with different content is reported as a conflict and left
+// alone. Directories emptied by the move, legacyDir included, are removed.
+// A missing legacyDir is not an error.
+func MigrateLegacyShelf(legacyDir, shelvesDir string, dirMode os.FileMode) (MigrationResult, error) {
+	var result MigrationResult
+
+	if _, err := os.Stat(legacyDir); os.IsNotExist(err) {
+		return result, nil
+	}
+
+	var dirs []string
+
+	err := filepath.WalkDir(legacyDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			dirs = append(dirs, path)
+
+			return nil
+		}
+
+		rel, err := filepath.Rel(legacyDir, path)
+		if err != nil {
+			return err
+		}
+
+		dst := filepath.Join(shelvesDir, rel)
+
+		if _, err := os.Stat(dst); err == nil {
+			same, err := sameContent(path, dst)
+			if err != nil {
+				return err
+			}
+
+			if !same {
+				result.Conflicts = append(result.Conflicts, rel)
+
+				return nil
+			}
+
+			return os.Remove(path)
+		}
+
+		if err := fsutil.MkdirAll(filepath.Dir(dst), dirMode); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(dst), err)
+		}
+
+		if err := os.Rename(path, dst); err != nil {
+			return fmt.Errorf("failed to move %s: %w", rel, err)
+		}
+
+		result.Moved = append(result.Moved, rel)
+
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// Deepest first, so parents are empty by the time they are reached.
+	// Directories still holding conflicts fail to remove and are kept.
+	slices.Reverse(dirs)
+
+	for _, dir := range dirs {
+		_ = os.Remove(dir)
+	}
+
+	return result, nil
+}
+
+// sameContent reports whether two files have identical bytes.
+func sameContent(a, b string) (bool, error) {
+	dataA, err := os.ReadFile(a)
+	if err != nil {
+		return false, err
+	}
+
+	dataB, err := os.ReadFile(b)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(dataA, dataB), nil
+}
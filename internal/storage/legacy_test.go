@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateLegacyShelf(t *testing.T) {
+	home := t.TempDir()
+	legacy := filepath.Join(home, LegacyShelfDir)
+	shelves := filepath.Join(home, "shelves")
+
+	files := map[string]string{
+		"api/2026-01-01-notes.md": "moved",
+		"api/2026-01-02-notes.md": "same",
+		"web/2026-01-03-notes.md": "legacy version",
+	}
+	for rel, content := range files {
+		writeFile(t, filepath.Join(legacy, rel), content)
+	}
+
+	writeFile(t, filepath.Join(shelves, "api/2026-01-02-notes.md"), "same")
+	writeFile(t, filepath.Join(shelves, "web/2026-01-03-notes.md"), "current version")
+
+	result, err := MigrateLegacyShelf(legacy, shelves, 0755)
+	if err != nil {
+		t.Fatalf("MigrateLegacyShelf() error = %v", err)
+	}
+
+	if len(result.Moved) != 1 || result.Moved[0] != filepath.Join("api", "2026-01-01-notes.md") {
+		t.Errorf("Moved = %v, want [api/2026-01-01-notes.md]", result.Moved)
+	}
+
+	if len(result.Conflicts) != 1 || result.Conflicts[0] != filepath.Join("web", "2026-01-03-notes.md") {
+		t.Errorf("Conflicts = %v, want [web/2026-01-03-notes.md]", result.Conflicts)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(shelves, "api", "2026-01-01-notes.md")); string(data) != "moved" {
+		t.Errorf("moved file content = %q, want %q", data, "moved")
+	}
+
+	// The identical copy and the emptied api/ directory are gone; the
+	// conflicting file stays where it was.
+	if _, err := os.Stat(filepath.Join(legacy, "api")); !os.IsNotExist(err) {
+		t.Errorf("legacy api/ still present: %v", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(legacy, "web", "2026-01-03-notes.md")); string(data) != "legacy version" {
+		t.Errorf("conflicting legacy file content = %q", data)
+	}
+
+	// No legacy dir at all is not an error.
+	if _, err := MigrateLegacyShelf(filepath.Join(home, "missing"), shelves, 0755); err != nil {
+		t.Errorf("MigrateLegacyShelf(missing) error = %v", err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
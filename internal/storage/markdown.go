@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxWhatLength caps the What of a note read by ParseMarkdownNote; the full
+// text is kept as its details.
+const maxWhatLength = 300
+
+// MarkdownNote is a note read from a standalone markdown document.
+type MarkdownNote struct {
+	Title string
+	What  string
+	Body  string
+}
+
+// ParseMarkdownNote reads a standalone markdown document, such as a design
+// record, as a note. The title is the first "# " heading, or else the file
+// name without extension with dashes and underscores as spaces. What is the
+// first paragraph after the heading; Body is the whole document without
+// frontmatter and the title heading.
+func ParseMarkdownNote(name, content string) MarkdownNote {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	if strings.HasPrefix(content, "---\n") {
+		_, content = splitFrontmatter(content)
+	}
+
+	var (
+		title string
+		lines []string
+	)
+
+	for _, line := range strings.Split(content, "\n") {
+		if title == "" && strings.HasPrefix(line, "# ") {
+			title = strings.TrimSpace(strings.TrimPrefix(line, "# "))
+
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	if title == "" {
+		stem := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+		title = strings.TrimSpace(strings.NewReplacer("-", " ", "_", " ").Replace(stem))
+	}
+
+	body := strings.TrimSpace(strings.Join(lines, "\n"))
+
+	return MarkdownNote{Title: title, What: firstParagraph(body), Body: body}
+}
+
+// firstParagraph returns the first block of text in body that is not a
+// heading, joined onto one line and cut to maxWhatLength runes.
+func firstParagraph(body string) string {
+	for _, block := range strings.Split(body, "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" || strings.HasPrefix(block, "#") {
+			continue
+		}
+
+		what := []rune(strings.Join(strings.Fields(block), " "))
+		if len(what) > maxWhatLength {
+			return string(what[:maxWhatLength-3]) + "..."
+		}
+
+		return string(what)
+	}
+
+	return ""
+}
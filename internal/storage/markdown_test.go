@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMarkdownNote(t *testing.T) {
+	doc := "---\nstatus: accepted\n---\n# Use Postgres\n\nWe need\nrelational data.\n\n## Consequences\n\nMore ops work.\n"
+
+	note := ParseMarkdownNote("decisions/adr-001.md", doc)
+
+	if note.Title != "Use Postgres" {
+		t.Errorf("Title = %q, want the H1", note.Title)
+	}
+
+	if note.What != "We need relational data." {
+		t.Errorf("What = %q, want the first paragraph on one line", note.What)
+	}
+
+	if strings.Contains(note.Body, "status: accepted") || strings.Contains(note.Body, "# Use Postgres") {
+		t.Errorf("Body = %q, want no frontmatter or title heading", note.Body)
+	}
+
+	if !strings.Contains(note.Body, "More ops work.") {
+		t.Errorf("Body = %q, want the rest of the document", note.Body)
+	}
+}
+
+func TestParseMarkdownNote_TitleFromFileName(t *testing.T) {
+	note := ParseMarkdownNote("notes/cache_invalidation-rules.md", "Keys expire after 5 minutes.\n\n---\n\nSee runbook.")
+
+	if note.Title != "cache invalidation rules" {
+		t.Errorf("Title = %q, want the file name", note.Title)
+	}
+
+	if note.What != "Keys expire after 5 minutes." {
+		t.Errorf("What = %q", note.What)
+	}
+
+	long := ParseMarkdownNote("long.md", strings.Repeat("word ", 100))
+	if n := len([]rune(long.What)); n != maxWhatLength {
+		t.Errorf("long What has %d runes, want %d", n, maxWhatLength)
+	}
+}
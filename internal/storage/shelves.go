@@ -9,18 +9,20 @@ import (
 	"strings"
 	"time"
 
+	"pantry/internal/fsutil"
 	"pantry/internal/models"
 )
 
-// WriteNoteItem writes an item to a daily notes file.
-func WriteNoteItem(projectDir string, item models.Item, dateStr string, details *string) (string, error) {
+// WriteNoteItem writes an item to a daily notes file. A new file is created
+// with mode perm.
+func WriteNoteItem(projectDir string, item models.Item, dateStr string, details *string, perm os.FileMode) (string, error) {
 	filePath := filepath.Join(projectDir, dateStr+"-notes.md")
 	sectionContent := renderSection(item, details)
 
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		// Create new file
 		content := createNewNotesFile(item, dateStr, sectionContent)
-		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		if err := fsutil.WriteFile(filePath, []byte(content), perm); err != nil {
 			return "", fmt.Errorf("failed to write notes file: %w", err)
 		}
 	} else {
@@ -31,7 +33,7 @@ func WriteNoteItem(projectDir string, item models.Item, dateStr string, details
 		}
 
 		updatedContent := appendToNotesFile(string(existingContent), item, sectionContent)
-		if err := os.WriteFile(filePath, []byte(updatedContent), 0644); err != nil {
+		if err := fsutil.WriteFile(filePath, []byte(updatedContent), perm); err != nil {
 			return "", fmt.Errorf("failed to update notes file: %w", err)
 		}
 	}
@@ -69,8 +71,12 @@ func renderSection(item models.Item, details *string) string {
 }
 
 // createNewNotesFile creates a new notes file with frontmatter and initial content.
+// The created timestamp comes from the item so that replayed files match the originals.
 func createNewNotesFile(item models.Item, dateStr string, sectionContent string) string {
-	now := time.Now().UTC().Format(time.RFC3339)
+	now := item.CreatedAt
+	if now == "" {
+		now = time.Now().UTC().Format(time.RFC3339)
+	}
 
 	sources := []string{}
 	if item.Source != nil {
@@ -233,6 +239,63 @@ func updateFrontmatter(frontmatter string, item models.Item) string {
 	return strings.Join(updatedLines, "\n")
 }
 
+// ReplaceFrontmatterTag replaces tag with replacement in the tags line of the
+// notes file at filePath, or removes it when replacement is empty. A file
+// without tag in its frontmatter is left untouched.
+func ReplaceFrontmatterTag(filePath, tag, replacement string, perm os.FileMode) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read notes file: %w", err)
+	}
+
+	frontmatter, body := splitFrontmatter(string(content))
+	if frontmatter == "" {
+		return nil
+	}
+
+	lines := strings.Split(frontmatter, "\n")
+	changed := false
+
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "tags:") {
+			continue
+		}
+
+		tags := parseBracketedList(line)
+		kept := make([]string, 0, len(tags))
+
+		for _, t := range tags {
+			if strings.EqualFold(t, tag) {
+				changed = true
+
+				if replacement == "" {
+					continue
+				}
+
+				t = strings.ToLower(replacement)
+			}
+
+			if !slices.Contains(kept, t) {
+				kept = append(kept, t)
+			}
+		}
+
+		sort.Strings(kept)
+		lines[i] = fmt.Sprintf("tags: [%s]", strings.Join(kept, ", "))
+	}
+
+	if !changed {
+		return nil
+	}
+
+	updated := strings.Join(lines, "\n") + "\n" + body
+	if err := fsutil.WriteFile(filePath, []byte(updated), perm); err != nil {
+		return fmt.Errorf("failed to update notes file: %w", err)
+	}
+
+	return nil
+}
+
 // insertSectionInBody inserts section in body at correct position based on category.
 func insertSectionInBody(body string, item models.Item, sectionContent string) string {
 	if item.Category == nil {
@@ -3,6 +3,7 @@ package storage
 import (
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 
 	"pantry/internal/models"
@@ -29,7 +30,7 @@ func TestWriteNoteItem(t *testing.T) {
 
 	details := "Full details here"
 
-	filePath, err := WriteNoteItem(projectDir, item, "2026-01-01", &details)
+	filePath, err := WriteNoteItem(projectDir, item, "2026-01-01", &details, 0600)
 	if err != nil {
 		t.Fatalf("WriteNoteItem() error = %v", err)
 	}
@@ -38,9 +39,12 @@ func TestWriteNoteItem(t *testing.T) {
 		t.Error("WriteNoteItem() should return file path")
 	}
 
-	// Verify file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+	// Verify file exists with the requested mode
+	info, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
 		t.Errorf("WriteNoteItem() file does not exist: %s", filePath)
+	} else if err == nil && runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("WriteNoteItem() file mode = %o, want 600", info.Mode().Perm())
 	}
 
 	// Verify file content
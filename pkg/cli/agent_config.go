@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// setupDryRun makes the agent config writers print what they would write
+	// instead of touching any file.
+	setupDryRun bool
+	// dryRunOut receives the dry-run output.
+	dryRunOut io.Writer = os.Stdout
+	// warnOut receives warnings about the agent configs being edited.
+	warnOut io.Writer = os.Stderr
+)
+
+// readJSONConfig reads an agent's JSON config file into a map. A missing file
+// yields an empty map. Parse errors name the file, line and column so a
+// malformed config can be fixed by hand before pantry edits it.
+func readJSONConfig(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]any), nil
+		}
+
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var config map[string]any
+	if err := json.Unmarshal(data, &config); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, col := lineCol(data, syntaxErr.Offset)
+
+			return nil, fmt.Errorf("%s:%d:%d: invalid JSON: %w (fix or move the file, then re-run)", path, line, col, err)
+		}
+
+		return nil, fmt.Errorf("%s: expected a JSON object: %w", path, err)
+	}
+
+	if config == nil {
+		config = make(map[string]any)
+	}
+
+	return config, nil
+}
+
+// writeJSONConfig writes config as indented JSON to path, creating its
+// directory. With setupDryRun it prints the result instead.
+func writeJSONConfig(path string, config map[string]any) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if setupDryRun {
+		fmt.Fprintf(dryRunOut, "--- %s (dry run, not written)\n%s\n", path, data)
+
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+
+	return nil
+}
+
+// checkTOML does a line-level sanity check of a TOML file: every line must be
+// blank, a comment, a [table] header or a key = value pair. Values spanning
+// lines (multi-line strings, arrays and inline tables) are followed to their
+// end, and brackets inside strings or comments are not counted. It catches
+// the damage a hand edit usually does, but is no full TOML parser, so its
+// errors are reported as warnings.
+func checkTOML(path string, data []byte) error {
+	open, depth := "", 0
+
+	for i, raw := range strings.Split(string(data), "\n") {
+		// Inside a multi-line value: only track where it ends.
+		if open != "" || depth > 0 {
+			open, depth, _ = scanTOML(raw, open, depth)
+
+			continue
+		}
+
+		_, _, end := scanTOML(raw, "", 0)
+		line := strings.TrimSpace(raw[:end])
+
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") || strings.Trim(line, "[] ") == "" {
+				return fmt.Errorf("%s:%d: invalid TOML table header %q", path, i+1, line)
+			}
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok || strings.TrimSpace(key) == "" || strings.TrimSpace(value) == "" {
+				return fmt.Errorf("%s:%d: invalid TOML, expected key = value: %q", path, i+1, line)
+			}
+
+			open, depth, _ = scanTOML(value, "", 0)
+		}
+	}
+
+	if depth > 0 || open != "" {
+		return fmt.Errorf("%s: invalid TOML, unterminated array or string", path)
+	}
+
+	return nil
+}
+
+// scanTOML reads one line of TOML from the state the previous line left:
+// open is the triple quote of a multi-line string still open (empty for
+// none) and depth the number of arrays and inline tables still open. It
+// returns the state after the line and where a comment starts on it (the
+// line length without one). Brackets and # inside strings are skipped.
+func scanTOML(line, open string, depth int) (string, int, int) {
+	for i := 0; i < len(line); i++ {
+		if open != "" {
+			switch {
+			case strings.HasPrefix(line[i:], open):
+				i += len(open) - 1
+				open = ""
+			case open == `"""` && line[i] == '\\':
+				i++
+			}
+
+			continue
+		}
+
+		switch c := line[i]; {
+		case strings.HasPrefix(line[i:], `"""`), strings.HasPrefix(line[i:], "'''"):
+			open = line[i : i+3]
+			i += 2
+		case c == '"' || c == '\'':
+			// A single-line string: skip to its closing quote.
+			for i++; i < len(line) && line[i] != c; i++ {
+				if c == '"' && line[i] == '\\' {
+					i++
+				}
+			}
+		case c == '#':
+			return open, depth, i
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+
+	return open, depth, len(line)
+}
+
+// lineCol converts a byte offset into 1-based line and column numbers.
+func lineCol(data []byte, offset int64) (int, int) {
+	offset = min(offset, int64(len(data)))
+	before := data[:offset]
+
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(offset) - bytes.LastIndexByte(before, '\n')
+
+	return line, col
+}
@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// agentEntry is a pantry MCP server entry found in an agent config file.
+type agentEntry struct {
+	Agent   string
+	Path    string
+	Command string
+}
+
+// agentConfigFile is a config file that `pantry setup` may write to.
+type agentConfigFile struct {
+	agent string
+	path  string
+}
+
+// agentConfigFiles lists the user- and project-scope config files written by
+// `pantry setup`, rooted at home and cwd.
+func agentConfigFiles(home, cwd string) []agentConfigFile {
+	return []agentConfigFile{
+		{"claude", filepath.Join(home, ".claude.json")},
+		{"claude", filepath.Join(cwd, ".mcp.json")},
+		{"cursor", filepath.Join(home, ".cursor", "mcp.json")},
+		{"cursor", filepath.Join(cwd, ".cursor", "mcp.json")},
+		{"codex", filepath.Join(home, ".codex", "config.toml")},
+		{"codex", filepath.Join(cwd, ".codex", "config.toml")},
+		{"opencode", filepath.Join(home, ".config", "opencode", "opencode.json")},
+		{"opencode", filepath.Join(cwd, "opencode.json")},
+		{"roocode", filepath.Join(cwd, ".roo", "mcp.json")},
+	}
+}
+
+// scanAgentConfigs returns the pantry entries in the known agent config files.
+// Missing files are skipped; unreadable or malformed ones are returned in errs.
+func scanAgentConfigs(home, cwd string) (entries []agentEntry, errs []error) {
+	seen := make(map[string]bool)
+
+	for _, f := range agentConfigFiles(home, cwd) {
+		// home and cwd can be the same directory.
+		if seen[f.path] {
+			continue
+		}
+
+		seen[f.path] = true
+
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+
+			continue
+		}
+
+		var (
+			command string
+			found   bool
+		)
+
+		if strings.HasSuffix(f.path, ".toml") {
+			command, found = pantryTOMLCommand(data)
+		} else {
+			command, found, err = pantryJSONCommand(data)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.path, err))
+
+				continue
+			}
+		}
+
+		if found {
+			entries = append(entries, agentEntry{Agent: f.agent, Path: f.path, Command: command})
+		}
+	}
+
+	return entries, errs
+}
+
+// pantryJSONCommand finds the pantry server under "mcpServers" (or OpenCode's
+// "mcp") and returns its command. OpenCode stores the command as an array.
+func pantryJSONCommand(data []byte) (string, bool, error) {
+	var config map[string]any
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", false, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	for _, key := range []string{"mcpServers", "mcp"} {
+		servers, _ := config[key].(map[string]any)
+
+		entry, ok := servers["pantry"].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		switch cmd := entry["command"].(type) {
+		case string:
+			return cmd, true, nil
+		case []any:
+			if len(cmd) > 0 {
+				first, _ := cmd[0].(string)
+
+				return first, true, nil
+			}
+		}
+
+		return "", true, nil
+	}
+
+	return "", false, nil
+}
+
+// pantryTOMLCommand finds the [mcp_servers.pantry] table in a Codex
+// config.toml and returns its command value.
+func pantryTOMLCommand(data []byte) (string, bool) {
+	if !bytes.Contains(data, []byte("[mcp_servers.pantry]")) {
+		return "", false
+	}
+
+	inTable := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "[") {
+			inTable = line == "[mcp_servers.pantry]"
+
+			continue
+		}
+
+		if key, value, ok := strings.Cut(line, "="); inTable && ok && strings.TrimSpace(key) == "command" {
+			return strings.Trim(strings.TrimSpace(value), `"'`), true
+		}
+	}
+
+	return "", true
+}
+
+// resolveAgentCommand returns the executable an agent would launch for
+// command: an absolute path must exist, anything else is looked up on PATH.
+func resolveAgentCommand(command string) (string, error) {
+	if command == "" {
+		return "", errors.New("entry has no command")
+	}
+
+	if filepath.IsAbs(command) {
+		if _, err := os.Stat(command); err != nil {
+			return "", fmt.Errorf("%s does not exist (stale path from an old install?)", command)
+		}
+
+		return command, nil
+	}
+
+	path, err := exec.LookPath(command)
+	if err != nil {
+		return "", fmt.Errorf("%q not found on PATH", command)
+	}
+
+	return path, nil
+}
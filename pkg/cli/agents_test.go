@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeAgentFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+func TestScanAgentConfigs_FindsPantryEntries(t *testing.T) {
+	home := t.TempDir()
+	cwd := t.TempDir()
+
+	writeAgentFile(t, filepath.Join(home, ".cursor", "mcp.json"),
+		`{"mcpServers": {"pantry": {"command": "/opt/old/bin/pantry", "args": ["mcp"]}, "other": {"command": "x"}}}`)
+	writeAgentFile(t, filepath.Join(home, ".config", "opencode", "opencode.json"),
+		`{"mcp": {"pantry": {"type": "local", "command": ["pantry", "mcp"]}}}`)
+	writeAgentFile(t, filepath.Join(cwd, ".codex", "config.toml"),
+		"model = \"o3\"\n\n[mcp_servers.pantry]\ncommand = \"pantry\"\nargs = [\"mcp\"]\n")
+	writeAgentFile(t, filepath.Join(home, ".claude.json"), `{"mcpServers": {}}`)
+	writeAgentFile(t, filepath.Join(cwd, ".roo", "mcp.json"), `{not json`)
+
+	entries, errs := scanAgentConfigs(home, cwd)
+
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), ".roo") {
+		t.Errorf("errs = %v, want one parse error for .roo/mcp.json", errs)
+	}
+
+	got := make(map[string]string)
+	for _, e := range entries {
+		got[e.Agent] = e.Command
+	}
+
+	want := map[string]string{"cursor": "/opt/old/bin/pantry", "opencode": "pantry", "codex": "pantry"}
+	if len(got) != len(want) {
+		t.Fatalf("entries = %+v, want agents %v", entries, want)
+	}
+
+	for agent, cmd := range want {
+		if got[agent] != cmd {
+			t.Errorf("%s command = %q, want %q", agent, got[agent], cmd)
+		}
+	}
+}
+
+func TestResolveAgentCommand_StaleAbsolutePath(t *testing.T) {
+	if _, err := resolveAgentCommand(filepath.Join(t.TempDir(), "missing", "pantry")); err == nil {
+		t.Error("resolveAgentCommand() should fail for a path that no longer exists")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip("no executable path")
+	}
+
+	if got, err := resolveAgentCommand(exe); err != nil || got != exe {
+		t.Errorf("resolveAgentCommand(%q) = %q, %v", exe, got, err)
+	}
+}
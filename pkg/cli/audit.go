@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"pantry/internal/audit"
+	"pantry/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditLimit  int
+	auditVerify bool
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show the audit log of note changes",
+	Long: `Show the most recent entries of the audit log: every store, update and
+removal, with its time, note ID and source. Enable it with audit.enabled in
+config.yaml. Each entry is chained to the one before it by hash; --verify
+reports the first entry that was edited or removed.`,
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		svc, err := core.NewService("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer func() { _ = svc.Close() }()
+
+		entries, err := svc.AuditLog()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if auditVerify {
+			if i := audit.Verify(entries); i >= 0 {
+				fmt.Fprintf(os.Stderr, "Error: audit log broken at entry %d (%s): it was edited, or an earlier entry was removed\n", i+1, entries[i].Time)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Audit log intact (%d entries)\n", len(entries))
+
+			return
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("No audit entries. Set audit.enabled: true in config.yaml to record changes.")
+
+			return
+		}
+
+		if auditLimit > 0 && len(entries) > auditLimit {
+			entries = entries[len(entries)-auditLimit:]
+		}
+
+		printAuditEntries(stdout, entries)
+	},
+}
+
+// printAuditEntries writes one line per entry, oldest first.
+func printAuditEntries(w io.Writer, entries []audit.Entry) {
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s  %-7s  %s  %s", e.Time, e.Action, e.ItemID, e.Title)
+
+		if e.Project != "" {
+			fmt.Fprintf(w, " [%s]", e.Project)
+		}
+
+		if e.Source != nil {
+			fmt.Fprintf(w, " (source: %s)", *e.Source)
+		}
+
+		fmt.Fprintln(w)
+	}
+}
+
+func init() {
+	auditCmd.Flags().IntVarP(&auditLimit, "limit", "n", 50, "Show at most this many recent entries; 0 shows all")
+	auditCmd.Flags().BoolVar(&auditVerify, "verify", false, "Check the hash chain instead of listing entries")
+}
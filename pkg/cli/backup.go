@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"pantry/internal/config"
+	"pantry/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupOutput string
+	restoreForce bool
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Archive the whole pantry into a .tar.gz",
+	Long: `Write index.db, config.yaml, .pantryignore and shelves/ to a gzipped tar
+archive, for restoring with 'pantry restore'. The database is checkpointed
+first so the archive holds every committed change.`,
+	Args: cobra.NoArgs,
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		output := backupOutput
+		if output == "" {
+			output = "pantry-" + time.Now().Format("20060102") + ".tar.gz"
+		}
+
+		svc, err := core.NewService("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer func() { _ = svc.Close() }()
+
+		f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) //nolint:gosec
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		n, err := svc.Backup(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+
+		if err != nil {
+			_ = os.Remove(output)
+
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Backed up %d files to %s\n", n, output)
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <archive>",
+	Short: "Recreate the pantry from a backup archive",
+	Long: `Unpack an archive written by 'pantry backup' into the pantry home
+(PANTRY_HOME or ~/.pantry). A pantry that already has notes is only
+replaced with --force.`,
+	Args: cobra.ExactArgs(1),
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := os.Open(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer func() { _ = f.Close() }()
+
+		home := config.GetPantryHome()
+
+		n, err := core.Restore(f, home, restoreForce)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Restored %d files into %s\n", n, home)
+	},
+}
+
+func init() {
+	backupCmd.Flags().StringVarP(&backupOutput, "output", "o", "", "Archive to write (default: pantry-YYYYMMDD.tar.gz); an existing file is not replaced")
+	restoreCmd.Flags().BoolVar(&restoreForce, "force", false, "Replace an existing pantry's database and files")
+}
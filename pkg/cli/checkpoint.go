@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"pantry/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+var checkpointCmd = &cobra.Command{
+	Use:   "checkpoint [name]",
+	Short: "Record the current time, for list --diff-since",
+	Long: `Record the current time under name (default "last"). A later
+'pantry list --diff-since <name>' shows only the notes created or updated
+since then, so repeated context pulls in a long session stay small.`,
+	Args: cobra.MaximumNArgs(1),
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		name := core.DefaultCheckpoint
+		if len(args) > 0 {
+			name = args[0]
+		}
+
+		svc, err := core.NewService("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer func() { _ = svc.Close() }()
+
+		at, err := svc.SetCheckpoint(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(stdout, "Checkpoint %q set at %s\n", name, at.Format(time.RFC3339))
+	},
+}
+
+// diffSinceOption builds the option for --diff-since, which takes a
+// checkpoint name or a date (see core.Service.SinceTime). It returns nil when
+// since is empty.
+func diffSinceOption(svc *core.Service, since string) (core.SearchOption, error) {
+	if since == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	t, err := svc.SinceTime(since)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --diff-since: %w", err)
+	}
+
+	return core.WithChangedSince(t), nil
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// colorModes are the values accepted by --color.
+var colorModes = []string{"auto", "always", "never"}
+
+// colorMode holds the persistent --color flag.
+var colorMode = "auto"
+
+// ANSI colors used by paint.
+const (
+	colorRed    = "31"
+	colorGreen  = "32"
+	colorYellow = "33"
+)
+
+// validateColorMode returns an error unless mode is one of colorModes.
+func validateColorMode(mode string) error {
+	if !slices.Contains(colorModes, mode) {
+		return fmt.Errorf("invalid --color %q: must be one of %s", mode, strings.Join(colorModes, ", "))
+	}
+
+	return nil
+}
+
+// useColor decides whether to emit ANSI codes. An explicit always or never
+// wins; auto colors a terminal unless NO_COLOR is set.
+func useColor(mode string, noColor, tty bool) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	default:
+		return tty && !noColor
+	}
+}
+
+// colorEnabled applies useColor to --color, NO_COLOR and stdout.
+func colorEnabled() bool {
+	return useColor(colorMode, os.Getenv("NO_COLOR") != "", isTerminal(os.Stdout))
+}
+
+// paint wraps s in the ANSI color code when color is enabled.
+func paint(code, s string) string {
+	if !colorEnabled() {
+		return s
+	}
+
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
@@ -0,0 +1,50 @@
+package cli
+
+import "testing"
+
+func TestUseColor(t *testing.T) {
+	tests := []struct {
+		mode         string
+		noColor, tty bool
+		want         bool
+	}{
+		{"auto", false, true, true},
+		{"auto", false, false, false},
+		{"auto", true, true, false},
+		{"never", false, true, false},
+		{"always", false, false, true},
+		{"always", true, false, true},
+	}
+
+	for _, tt := range tests {
+		if got := useColor(tt.mode, tt.noColor, tt.tty); got != tt.want {
+			t.Errorf("useColor(%q, NO_COLOR=%t, tty=%t) = %t, want %t", tt.mode, tt.noColor, tt.tty, got, tt.want)
+		}
+	}
+}
+
+func TestPaint(t *testing.T) {
+	defer func(mode string) { colorMode = mode }(colorMode)
+
+	// Test output is not a terminal, so only always adds codes.
+	colorMode = "always"
+	if got, want := paint(colorGreen, "ok"), "\x1b[32mok\x1b[0m"; got != want {
+		t.Errorf("paint() with --color always = %q, want %q", got, want)
+	}
+
+	colorMode = "never"
+	if got := paint(colorGreen, "ok"); got != "ok" {
+		t.Errorf("paint() with --color never = %q, want plain", got)
+	}
+
+	colorMode = "auto"
+	t.Setenv("NO_COLOR", "1")
+
+	if got := paint(colorGreen, "ok"); got != "ok" {
+		t.Errorf("paint() with NO_COLOR = %q, want plain", got)
+	}
+
+	if err := validateColorMode("rainbow"); err == nil {
+		t.Error("validateColorMode(rainbow) error = nil")
+	}
+}
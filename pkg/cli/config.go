@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"pantry/internal/config"
+	"pantry/internal/fsutil"
 
 	"github.com/spf13/cobra"
 	"go.yaml.in/yaml/v3"
@@ -27,6 +28,8 @@ var configCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		config.ApplyOverrides(cfg)
+
 		// Redact API keys
 		cfgCopy := *cfg
 		if cfgCopy.Embedding.APIKey != nil {
@@ -60,13 +63,13 @@ var configInitCmd = &cobra.Command{
 			return
 		}
 
-		if err := os.MkdirAll(home, 0755); err != nil {
+		if err := fsutil.MkdirAll(home, fsutil.DefaultDirMode); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: failed to create config directory: %v\n", err)
 			os.Exit(1)
 		}
 
 		template := config.GetDefaultConfigTemplate()
-		if err := os.WriteFile(configPath, []byte(template), 0644); err != nil {
+		if err := fsutil.WriteFile(configPath, []byte(template), fsutil.DefaultFileMode); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: failed to write config file: %v\n", err)
 			os.Exit(1)
 		}
@@ -91,7 +94,7 @@ Examples:
 		home := config.GetPantryHome()
 		configPath := filepath.Join(home, "config.yaml")
 
-		cfg, err := config.LoadConfig(configPath)
+		cfg, err := config.LoadFile(configPath)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -111,11 +114,18 @@ Examples:
 				case "openrouter":
 					cfg.Embedding.Model = "openai/text-embedding-3-small"
 					cfg.Embedding.BaseURL = nil
+				case "cohere":
+					cfg.Embedding.Model = "embed-english-v3.0"
+					cfg.Embedding.BaseURL = nil
 				case "ollama":
 					cfg.Embedding.Model = "nomic-embed-text"
 					base := "http://localhost:11434"
 					cfg.Embedding.BaseURL = &base
 					cfg.Embedding.APIKey = nil
+				case "mock":
+					cfg.Embedding.Model = "mock"
+					cfg.Embedding.BaseURL = nil
+					cfg.Embedding.APIKey = nil
 				}
 			}
 
@@ -161,6 +171,16 @@ Examples:
 	},
 }
 
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the config file path",
+	Args:  cobra.NoArgs,
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintln(cmd.OutOrStdout(), filepath.Join(config.GetPantryHome(), "config.yaml"))
+	},
+}
+
 var (
 	configSetProvider string
 	configSetModel    string
@@ -171,8 +191,9 @@ var (
 func init() {
 	configCmd.AddCommand(configInitCmd)
 	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configPathCmd)
 	configInitCmd.Flags().BoolVarP(&configInitForce, "force", "f", false, "Overwrite existing config")
-	configSetCmd.Flags().StringVar(&configSetProvider, "provider", "", "Embedding provider (ollama, openai, openrouter)")
+	configSetCmd.Flags().StringVar(&configSetProvider, "provider", "", "Embedding provider (ollama, openai, openrouter, cohere, mock)")
 	configSetCmd.Flags().StringVar(&configSetModel, "model", "", "Embedding model name")
 	configSetCmd.Flags().StringVar(&configSetAPIKey, "api-key", "", "API key for the embedding provider")
 	configSetCmd.Flags().StringVar(&configSetBaseURL, "base-url", "", "Base URL for the embedding API")
@@ -2,17 +2,30 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"pantry/internal/config"
 	"pantry/internal/core"
+	"pantry/internal/db"
+	"pantry/internal/fsutil"
 	"pantry/internal/redaction"
+	"pantry/internal/storage"
 
 	"github.com/spf13/cobra"
 )
 
+var doctorFix bool
+
+// walWarnSize is the write-ahead log size above which doctor suggests a
+// checkpoint. A log this large usually means a server crashed before its
+// last checkpoint, and it slows down the next open.
+const walWarnSize = 32 << 20
+
 var doctorCmd = &cobra.Command{
 	Use:   "doctor",
 	Short: "Check pantry health and capabilities",
@@ -20,15 +33,15 @@ var doctorCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		ok := true
 		pass := func(label, detail string) {
-			fmt.Printf("  \u2713 %-28s %s\n", label, detail)
+			fmt.Printf("  %s %-28s %s\n", paint(colorGreen, "\u2713"), label, detail)
 		}
 		fail := func(label, detail string) {
-			fmt.Printf("  \u2717 %-28s %s\n", label, detail)
+			fmt.Printf("  %s %-28s %s\n", paint(colorRed, "\u2717"), label, detail)
 
 			ok = false
 		}
 		warn := func(label, detail string) {
-			fmt.Printf("  ! %-28s %s\n", label, detail)
+			fmt.Printf("  %s %-28s %s\n", paint(colorYellow, "!"), label, detail)
 		}
 
 		home := config.GetPantryHome()
@@ -57,6 +70,11 @@ var doctorCmd = &cobra.Command{
 			pass("shelves/", shelvesDir)
 		}
 
+		// Older versions wrote notes to shelf/; `pantry init` moves them.
+		if _, err := os.Stat(filepath.Join(home, storage.LegacyShelfDir)); err == nil {
+			warn("legacy shelf/", "notes from an older version — run `pantry init` to move them into shelves/")
+		}
+
 		configPath := filepath.Join(home, "config.yaml")
 		if _, err := os.Stat(configPath); err != nil {
 			warn("config.yaml", "not found, using defaults")
@@ -71,6 +89,50 @@ var doctorCmd = &cobra.Command{
 			pass(".pantryignore", ignorePath)
 		}
 
+		// Notes may hold sensitive decisions; flag anything other users can read.
+		var exposed []string
+
+		for _, path := range []string{home, dbPath, shelvesDir, configPath} {
+			if info, err := os.Stat(path); err == nil && fsutil.WorldReadable(info.Mode()) {
+				exposed = append(exposed, filepath.Base(path))
+			}
+		}
+
+		if len(exposed) > 0 {
+			warn("permissions", fmt.Sprintf("readable by other users: %s — set permissions.file_mode/dir_mode and run `chmod -R go-rwx %s`", strings.Join(exposed, ", "), home))
+		} else {
+			pass("permissions", "not readable by other users")
+		}
+
+		// --- Agents ---
+		fmt.Println("\nAgents:")
+
+		if path, err := exec.LookPath("pantry"); err != nil {
+			warn("pantry on PATH", "not found — agents that run `pantry mcp` cannot start it")
+		} else {
+			pass("pantry on PATH", path)
+		}
+
+		userHome, _ := os.UserHomeDir()
+		cwd, _ := os.Getwd()
+
+		entries, scanErrs := scanAgentConfigs(userHome, cwd)
+		for _, err := range scanErrs {
+			warn("agent config", err.Error())
+		}
+
+		if len(entries) == 0 {
+			warn("MCP entries", "no agent configured — run `pantry setup <agent>`")
+		}
+
+		for _, e := range entries {
+			if resolved, err := resolveAgentCommand(e.Command); err != nil {
+				fail(e.Agent, fmt.Sprintf("%s: %v", e.Path, err))
+			} else {
+				pass(e.Agent, fmt.Sprintf("%s -> %s", e.Path, resolved))
+			}
+		}
+
 		// --- Configuration ---
 		fmt.Println("\nConfiguration:")
 
@@ -79,6 +141,7 @@ var doctorCmd = &cobra.Command{
 			fail("load config", err.Error())
 		} else {
 			pass("load config", "ok")
+			config.ApplyOverrides(cfg)
 
 			if err := cfg.Validate(); err != nil {
 				fail("validate config", err.Error())
@@ -119,11 +182,11 @@ var doctorCmd = &cobra.Command{
 
 		pass("database connection", "ok")
 
-		total, err := svc.CountItems(nil, nil)
+		stats, err := svc.Stats(nil)
 		if err != nil {
 			fail("note count", err.Error())
 		} else {
-			pass("note count", fmt.Sprintf("%d notes stored", total))
+			pass("note count", fmt.Sprintf("%d notes stored in %d projects, %d with vectors (%s)", stats.Items, stats.Projects, stats.Vectors, formatMiB(stats.SizeBytes)))
 		}
 
 		pass("FTS5 search", "always available")
@@ -134,21 +197,47 @@ var doctorCmd = &cobra.Command{
 			warn("vector search", "not available — run `pantry reindex` after configuring embeddings")
 		}
 
+		walPath := dbPath + "-wal"
+
+		switch info, err := os.Stat(walPath); {
+		case err != nil:
+			pass("write-ahead log", "none")
+		case doctorFix && info.Size() > 0:
+			if err := svc.Checkpoint(); err != nil {
+				fail("write-ahead log", err.Error())
+			} else {
+				pass("write-ahead log", fmt.Sprintf("checkpointed %s", formatMiB(info.Size())))
+			}
+		case info.Size() > walWarnSize:
+			warn("write-ahead log", fmt.Sprintf("%s — a server may have crashed; run `pantry doctor --fix` to checkpoint it", formatMiB(info.Size())))
+		default:
+			pass("write-ahead log", formatMiB(info.Size()))
+		}
+
 		// --- Embedding provider live test ---
 		fmt.Println("\nEmbedding provider:")
 
-		provider, err := svc.GetEmbeddingProvider()
-		if err != nil {
+		if _, err := svc.GetEmbeddingProvider(); err != nil {
 			fail("initialize provider", err.Error())
 		} else {
 			pass("initialize provider", "ok")
 
-			embedding, err := provider.Embed(context.Background(), "pantry doctor probe")
-			if err != nil {
+			// A model switch changes the dimension; stores then fail until a reindex.
+			indexed, probed, err := svc.CheckEmbeddingDimension(context.Background())
+
+			switch {
+			case errors.Is(err, db.ErrDimensionMismatch):
+				pass("live probe", fmt.Sprintf("ok — %d dimensions", probed))
+				fail("index dimension", fmt.Sprintf("dimension mismatch — index has %d, provider returns %d; run `pantry reindex`", indexed, probed))
+			case err != nil:
 				fail("live probe", err.Error())
 				warn("", "check that your embedding service is running and reachable")
-			} else {
-				pass("live probe", fmt.Sprintf("ok — %d dimensions", len(embedding)))
+			case indexed == 0:
+				pass("live probe", fmt.Sprintf("ok — %d dimensions", probed))
+				pass("index dimension", "no vectors indexed yet")
+			default:
+				pass("live probe", fmt.Sprintf("ok — %d dimensions", probed))
+				pass("index dimension", fmt.Sprintf("matches (%d)", indexed))
 			}
 		}
 
@@ -163,3 +252,12 @@ var doctorCmd = &cobra.Command{
 		}
 	},
 }
+
+// formatMiB formats a size in bytes as mebibytes.
+func formatMiB(size int64) string {
+	return fmt.Sprintf("%.1f MiB", float64(size)/(1<<20))
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "Checkpoint the database write-ahead log to reclaim its space")
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"pantry/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+var dumpSchemaJSON bool
+
+var dumpSchemaCmd = &cobra.Command{
+	Use:   "dump-schema",
+	Short: "Print database tables, indexes, triggers and meta values",
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		svc, err := core.NewService("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer func() { _ = svc.Close() }()
+
+		dump, err := svc.DumpSchema()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if dumpSchemaJSON {
+			data, err := json.MarshalIndent(dump, "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Println(string(data))
+
+			return
+		}
+
+		fmt.Println("-- Schema")
+
+		for _, o := range dump.Objects {
+			fmt.Printf("\n-- %s %s (on %s)\n", o.Type, o.Name, o.TableName)
+
+			if o.SQL != "" {
+				fmt.Printf("%s;\n", o.SQL)
+			}
+		}
+
+		fmt.Println("\n-- Meta")
+
+		keys := make([]string, 0, len(dump.Meta))
+		for k := range dump.Meta {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			fmt.Printf("%s = %s\n", k, dump.Meta[k])
+		}
+	},
+}
+
+func init() {
+	dumpSchemaCmd.Flags().BoolVar(&dumpSchemaJSON, "json", false, "Output as JSON")
+}
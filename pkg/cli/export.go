@@ -0,0 +1,260 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"pantry/internal/core"
+	"pantry/internal/seal"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	exportFormat      string
+	exportProject     bool
+	exportIncludeWhat bool
+	exportEncrypt     bool
+)
+
+// exportKeyEnv names the variable holding the passphrase for export
+// --encrypt and import --decrypt.
+const exportKeyEnv = "PANTRY_EXPORT_KEY"
+
+// exportRecord is the JSON form of a note written by pantry export.
+type exportRecord struct {
+	ID           string            `json:"id"`
+	CreatedAt    string            `json:"created_at"`
+	UpdatedAt    string            `json:"updated_at,omitempty"`
+	Project      string            `json:"project"`
+	Category     *string           `json:"category,omitempty"`
+	Source       *string           `json:"source,omitempty"`
+	Title        string            `json:"title"`
+	What         string            `json:"what"`
+	Why          *string           `json:"why,omitempty"`
+	Impact       *string           `json:"impact,omitempty"`
+	Tags         []string          `json:"tags,omitempty"`
+	RelatedFiles []string          `json:"related_files,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	Details      *string           `json:"details,omitempty"`
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export notes as JSON or CSV",
+	Args:  cobra.NoArgs,
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		if exportFormat != "json" && exportFormat != "csv" {
+			fmt.Fprintf(os.Stderr, "Error: invalid --format %q: must be json or csv\n", exportFormat)
+			os.Exit(1)
+		}
+
+		var passphrase string
+
+		if exportEncrypt {
+			if isTerminal(os.Stdout) {
+				fmt.Fprintf(os.Stderr, "Error: refusing to write encrypted output to a terminal; redirect it to a file\n")
+				os.Exit(1)
+			}
+
+			var err error
+
+			passphrase, err = readPassphrase(true)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		svc, err := core.NewService("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer func() { _ = svc.Close() }()
+
+		var project *string
+
+		if exportProject {
+			dir, _ := os.Getwd()
+			projectName := filepath.Base(dir)
+			project = &projectName
+		}
+
+		items, err := svc.Export(project)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		var out bytes.Buffer
+
+		if exportFormat == "csv" {
+			err = writeExportCSV(&out, items, exportIncludeWhat)
+		} else {
+			err = writeExportJSON(&out, items)
+		}
+
+		data := out.Bytes()
+
+		if err == nil && exportEncrypt {
+			data, err = seal.Seal(data, passphrase)
+		}
+
+		if err == nil {
+			_, err = os.Stdout.Write(data)
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// writeExportJSON writes items as an indented JSON array.
+func writeExportJSON(w io.Writer, items []core.ExportedItem) error {
+	records := make([]exportRecord, len(items))
+
+	for i, item := range items {
+		records[i] = exportRecord{
+			ID:           item.ID,
+			CreatedAt:    item.CreatedAt,
+			UpdatedAt:    item.UpdatedAt,
+			Project:      item.Project,
+			Category:     item.Category,
+			Source:       item.Source,
+			Title:        item.Title,
+			What:         item.What,
+			Why:          item.Why,
+			Impact:       item.Impact,
+			Tags:         item.Tags,
+			RelatedFiles: item.RelatedFiles,
+			Metadata:     item.Metadata,
+			Details:      item.Details,
+		}
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal notes: %w", err)
+	}
+
+	_, err = fmt.Fprintln(w, string(data))
+
+	return err
+}
+
+// writeExportCSV writes one row of note metadata per item. Bodies are left out
+// so the file stays usable in a spreadsheet; includeWhat adds the what column.
+func writeExportCSV(w io.Writer, items []core.ExportedItem, includeWhat bool) error {
+	header := []string{"id", "created_at", "project", "category", "source", "title", "tags", "has_details"}
+	if includeWhat {
+		header = append(header, "what")
+	}
+
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		row := []string{
+			item.ID,
+			item.CreatedAt,
+			item.Project,
+			deref(item.Category),
+			deref(item.Source),
+			item.Title,
+			strings.Join(item.Tags, ";"),
+			strconv.FormatBool(item.Details != nil),
+		}
+		if includeWhat {
+			row = append(row, item.What)
+		}
+
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// readPassphrase returns the export passphrase from PANTRY_EXPORT_KEY or,
+// when stdin is a terminal, asks for it on stderr without echoing it. With
+// confirm it is asked for twice.
+func readPassphrase(confirm bool) (string, error) {
+	if key := os.Getenv(exportKeyEnv); key != "" {
+		return key, nil
+	}
+
+	if !isTerminal(os.Stdin) {
+		return "", fmt.Errorf("no passphrase: set %s or run in a terminal", exportKeyEnv)
+	}
+
+	ask := func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+
+		line, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase: %w", err)
+		}
+
+		return string(line), nil
+	}
+
+	passphrase, err := ask("Passphrase (set " + exportKeyEnv + " to skip): ")
+	if err != nil {
+		return "", err
+	}
+
+	if passphrase == "" {
+		return "", errors.New("passphrase must not be empty")
+	}
+
+	if confirm {
+		again, err := ask("Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+
+		if again != passphrase {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+
+	return passphrase, nil
+}
+
+// deref returns *s, or "" when s is nil.
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFormat, "format", "json", "Output format: json or csv")
+	exportCmd.Flags().BoolVarP(&exportProject, "project", "p", false, "Export only the current project")
+	exportCmd.Flags().BoolVar(&exportIncludeWhat, "include-what", false, "Add the what column (csv only)")
+	exportCmd.Flags().BoolVar(&exportEncrypt, "encrypt", false, "Encrypt the output with a passphrase from "+exportKeyEnv+" or a prompt")
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"slices"
+	"testing"
+
+	"pantry/internal/core"
+	"pantry/internal/models"
+	"pantry/internal/seal"
+)
+
+func TestWriteExportCSV_HeaderAndQuoting(t *testing.T) {
+	cat := "decision"
+	details := "full body"
+
+	items := []core.ExportedItem{{
+		Item: models.Item{
+			ID:        "0123456789abcdef",
+			Title:     "Auth, sessions, and JWT",
+			What:      "Replaced sessions with JWT",
+			Category:  &cat,
+			Tags:      []string{"auth", "jwt"},
+			Project:   "api",
+			CreatedAt: "2026-01-02T03:04:05Z",
+		},
+		Details: &details,
+	}}
+
+	var buf bytes.Buffer
+	if err := writeExportCSV(&buf, items, true); err != nil {
+		t.Fatalf("writeExportCSV() error = %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+
+	wantHeader := []string{"id", "created_at", "project", "category", "source", "title", "tags", "has_details", "what"}
+	if len(rows) != 2 || !slices.Equal(rows[0], wantHeader) {
+		t.Fatalf("rows = %q, want header %q and one row", rows, wantHeader)
+	}
+
+	wantRow := []string{"0123456789abcdef", "2026-01-02T03:04:05Z", "api", "decision", "", "Auth, sessions, and JWT", "auth;jwt", "true", "Replaced sessions with JWT"}
+	if !slices.Equal(rows[1], wantRow) {
+		t.Errorf("row = %q, want %q", rows[1], wantRow)
+	}
+}
+
+func TestExportImport_EncryptedRoundTrip(t *testing.T) {
+	src, err := core.NewService(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	defer func() { _ = src.Close() }()
+
+	details := "Tokens live for 15 minutes"
+	if _, err := src.Store(models.RawItemInput{Title: "Use JWT auth", What: "Replaced sessions", Tags: []string{"auth"}, Details: &details}, "api"); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	if _, err := src.Store(models.RawItemInput{Title: "Retry webhooks", What: "Exponential backoff"}, "web"); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	exported, err := src.Export(nil)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeExportJSON(&buf, exported); err != nil {
+		t.Fatalf("writeExportJSON() error = %v", err)
+	}
+
+	sealed, err := seal.Seal(buf.Bytes(), "hunter2")
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	if _, err := parseExportJSON(sealed); err == nil {
+		t.Error("parseExportJSON() of encrypted data: error = nil, want a hint to use --decrypt")
+	}
+
+	if _, err := seal.Open(sealed, "hunter3"); !errors.Is(err, seal.ErrWrongPassphrase) {
+		t.Fatalf("Open(wrong passphrase) error = %v, want ErrWrongPassphrase", err)
+	}
+
+	plain, err := seal.Open(sealed, "hunter2")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	items, err := parseExportJSON(plain)
+	if err != nil {
+		t.Fatalf("parseExportJSON() error = %v", err)
+	}
+
+	dst, err := core.NewService(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	defer func() { _ = dst.Close() }()
+
+	result, err := dst.Import(items)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	if result.Imported != 2 || result.Skipped != 0 {
+		t.Errorf("Import() = %+v, want 2 imported", result)
+	}
+
+	reexported, err := dst.Export(nil)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	for i := range exported {
+		want, got := exported[i], reexported[i]
+		if got.ID != want.ID || got.Title != want.Title || got.CreatedAt != want.CreatedAt || got.Project != want.Project ||
+			!slices.Equal(got.Tags, want.Tags) || (want.Details != nil && (got.Details == nil || *got.Details != *want.Details)) {
+			t.Errorf("imported note %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	// Importing the same export again changes nothing.
+	result, err = dst.Import(items)
+	if err != nil || result.Imported != 0 || result.Skipped != 2 {
+		t.Errorf("second Import() = %+v, %v; want 2 skipped", result, err)
+	}
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"pantry/internal/config"
+	"pantry/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	gitSyncPush bool
+	gitSyncAuto bool
+)
+
+var gitSyncCmd = &cobra.Command{
+	Use:   "git-sync",
+	Short: "Commit changes to the notes files when the pantry home is a git repository",
+	Long: `Stage shelves/ in the pantry home and commit it with a message listing the
+notes changed, then push with --push. The pantry home must already be a git
+repository; other files in it are left alone.
+
+--auto sets hooks.post_store to run git-sync after every store instead.`,
+	Args: cobra.NoArgs,
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		if gitSyncAuto {
+			enableGitSyncHook(gitSyncPush)
+
+			return
+		}
+
+		svc, err := core.NewService("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer func() { _ = svc.Close() }()
+
+		result, err := svc.GitSync(gitSyncPush)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if !result.Committed {
+			fmt.Println("Nothing to commit.")
+
+			return
+		}
+
+		fmt.Printf("Committed %d notes files\n", len(result.Files))
+
+		if result.Pushed {
+			fmt.Println("Pushed")
+		}
+	},
+}
+
+// enableGitSyncHook sets hooks.post_store to run git-sync with this pantry
+// binary, refusing to replace a hook other than an earlier git-sync one.
+func enableGitSyncHook(push bool) {
+	configPath := filepath.Join(config.GetPantryHome(), "config.yaml")
+
+	cfg, err := config.LoadFile(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	exe, err := pantryExecutable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	hook := quoteHookArg(exe) + " git-sync"
+	if push {
+		hook += " --push"
+	}
+
+	if cfg.Hooks.PostStore != "" && !slices.Contains(strings.Fields(cfg.Hooks.PostStore), "git-sync") {
+		fmt.Fprintf(os.Stderr, "Error: hooks.post_store is already set to %q; edit %s to combine them\n", cfg.Hooks.PostStore, configPath)
+		os.Exit(1)
+	}
+
+	cfg.Hooks.PostStore = hook
+
+	if err := config.SaveConfig(configPath, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Updated %s\n  hooks.post_store: %s\n", configPath, hook)
+}
+
+// pantryExecutable returns the path of the running pantry binary, for a hook
+// that must not depend on the PATH of whatever process stores a note. The
+// path found on PATH is preferred when it is the same binary: it is often a
+// symlink that, unlike its target, survives an upgrade.
+func pantryExecutable() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the pantry binary: %w", err)
+	}
+
+	if onPath, err := exec.LookPath("pantry"); err == nil {
+		if abs, err := filepath.Abs(onPath); err == nil && sameFile(abs, exe) {
+			return abs, nil
+		}
+	}
+
+	return exe, nil
+}
+
+// sameFile reports whether a and b name the same existing file.
+func sameFile(a, b string) bool {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+
+	bi, err := os.Stat(b)
+
+	return err == nil && os.SameFile(ai, bi)
+}
+
+// quoteHookArg quotes s for the shell hooks run in: sh, or cmd on Windows.
+func quoteHookArg(s string) string {
+	if runtime.GOOS == "windows" {
+		return `"` + s + `"`
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func init() {
+	gitSyncCmd.Flags().BoolVar(&gitSyncPush, "push", false, "Push after committing")
+	gitSyncCmd.Flags().BoolVar(&gitSyncAuto, "auto", false, "Run git-sync after every store (sets hooks.post_store) instead of now")
+}
@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"os/exec"
+	"runtime"
+	"testing"
+)
+
+func TestQuoteHookArg(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook command uses sh")
+	}
+
+	for _, arg := range []string{"/usr/local/bin/pantry", "/Users/Jo Doe/bin/pantry", "/opt/it's/pantry", "/tmp/$HOME`x`"} {
+		out, err := exec.Command("sh", "-c", "printf %s "+quoteHookArg(arg)).Output()
+		if err != nil {
+			t.Fatalf("sh with %s error = %v", quoteHookArg(arg), err)
+		}
+
+		if string(out) != arg {
+			t.Errorf("quoteHookArg(%q) runs as %q", arg, out)
+		}
+	}
+}
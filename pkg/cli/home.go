@@ -0,0 +1,21 @@
+package cli
+
+import (
+	"fmt"
+
+	"pantry/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+var homeCmd = &cobra.Command{
+	Use:   "home",
+	Short: "Print the pantry home directory",
+	Long: `Print the pantry home directory ($PANTRY_HOME, or ~/.pantry), e.g. for
+cd "$(pantry home)". The directory is not created.`,
+	Args: cobra.NoArgs,
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintln(cmd.OutOrStdout(), config.GetPantryHome())
+	},
+}
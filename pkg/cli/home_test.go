@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestHomeAndConfigPath(t *testing.T) {
+	home := filepath.Join(t.TempDir(), "pantry")
+	t.Setenv("PANTRY_HOME", home)
+
+	for _, tt := range []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"home", []string{"home"}, home},
+		{"config path", []string{"config", "path"}, filepath.Join(home, "config.yaml")},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			rootCmd.SetOut(&buf)
+			rootCmd.SetArgs(tt.args)
+			t.Cleanup(func() {
+				rootCmd.SetOut(nil)
+				rootCmd.SetArgs(nil)
+			})
+
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			if got := buf.String(); got != tt.want+"\n" {
+				t.Errorf("output = %q, want %q", got, tt.want+"\n")
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"pantry/internal/core"
+	"pantry/internal/models"
+	"pantry/internal/seal"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	importDecrypt bool
+	importMerge   string
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import notes from a JSON export",
+	Long: `Import notes written by 'pantry export' (JSON format), from a file or stdin.
+Notes keep their IDs and dates. A note whose ID already exists is handled by
+--merge-strategy: skip keeps the existing note, overwrite replaces it, newest
+keeps whichever was updated last, and append-details appends the imported
+details to the existing note's.`,
+	Args: cobra.MaximumNArgs(1),
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		merge := core.MergeStrategy(importMerge)
+		if !slices.Contains(core.MergeStrategies, merge) {
+			fmt.Fprintf(os.Stderr, "Error: invalid --merge-strategy %q: must be skip, overwrite, newest or append-details\n", importMerge)
+			os.Exit(1)
+		}
+
+		var (
+			data []byte
+			err  error
+		)
+
+		if len(args) == 1 && args[0] != "-" {
+			data, err = os.ReadFile(args[0])
+		} else {
+			data, err = io.ReadAll(os.Stdin)
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if importDecrypt {
+			passphrase, err := readPassphrase(false)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			data, err = seal.Open(data, passphrase)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		items, err := parseExportJSON(data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		svc, err := core.NewService("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer func() { _ = svc.Close() }()
+
+		result, err := svc.Import(items, core.WithMergeStrategy(merge))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if result.Updated > 0 {
+			fmt.Printf("Imported %d notes, updated %d, skipped %d already present\n", result.Imported, result.Updated, result.Skipped)
+		} else {
+			fmt.Printf("Imported %d notes, skipped %d already present\n", result.Imported, result.Skipped)
+		}
+	},
+}
+
+// parseExportJSON decodes the output of writeExportJSON.
+func parseExportJSON(data []byte) ([]core.ExportedItem, error) {
+	if seal.IsSealed(data) {
+		return nil, errors.New("the export is encrypted; use --decrypt")
+	}
+
+	var records []exportRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("invalid export JSON: %w", err)
+	}
+
+	items := make([]core.ExportedItem, len(records))
+
+	for i, r := range records {
+		items[i] = core.ExportedItem{
+			Item: models.Item{
+				ID:           r.ID,
+				Title:        r.Title,
+				What:         r.What,
+				Why:          r.Why,
+				Impact:       r.Impact,
+				Tags:         r.Tags,
+				Category:     r.Category,
+				Project:      r.Project,
+				Source:       r.Source,
+				RelatedFiles: r.RelatedFiles,
+				Metadata:     r.Metadata,
+				CreatedAt:    r.CreatedAt,
+				UpdatedAt:    r.UpdatedAt,
+			},
+			Details: r.Details,
+		}
+	}
+
+	return items, nil
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importMerge, "merge-strategy", string(core.MergeSkip), "How to handle a note whose ID already exists: skip, overwrite, newest or append-details")
+	importCmd.Flags().BoolVar(&importDecrypt, "decrypt", false, "Decrypt an export made with --encrypt, using "+exportKeyEnv+" or a prompt")
+}
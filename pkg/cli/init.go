@@ -1,44 +1,131 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
 	"pantry/internal/config"
 	"pantry/internal/core"
+	"pantry/internal/fsutil"
 
 	"github.com/spf13/cobra"
 )
 
+var initSkipChecks bool
+
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize the pantry",
 	//nolint:revive
 	Run: func(cmd *cobra.Command, args []string) {
 		home := config.GetPantryHome()
+		configPath := filepath.Join(home, "config.yaml")
+
+		cfg, err := config.LoadFile(configPath) // returns defaults when file missing
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		_, dirMode, err := cfg.Permissions.Modes()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 
 		shelvesDir := filepath.Join(home, "shelves")
-		if err := os.MkdirAll(shelvesDir, 0755); err != nil {
+		if err := fsutil.MkdirAll(shelvesDir, dirMode); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: failed to create shelves directory: %v\n", err)
 			os.Exit(1)
 		}
 
 		// Create default config if missing
-		configPath := filepath.Join(home, "config.yaml")
 		if _, err := os.Stat(configPath); os.IsNotExist(err) {
-			cfg, _ := config.LoadConfig(configPath) // returns defaults when file missing
 			if err := config.SaveConfig(configPath, cfg); err != nil {
 				fmt.Fprintf(os.Stderr, "Warning: failed to create config: %v\n", err)
 			}
 		}
 
 		// Initialize database (creates index.db and runs migrations)
-		if _, err := core.NewService(home); err != nil {
+		svc, err := core.NewService(home)
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: failed to initialize database: %v\n", err)
 			os.Exit(1)
 		}
 
+		defer func() { _ = svc.Close() }()
+
 		fmt.Printf("Pantry initialized at %s\n", home)
+
+		migrateLegacyShelf(os.Stdout, svc)
+
+		if !initSkipChecks {
+			checkEmbeddings(os.Stdout, svc, cfg)
+		}
 	},
 }
+
+// migrateLegacyShelf moves notes left under shelf/ by older versions into
+// shelves/ and reports the outcome. It prints nothing when there is no
+// legacy directory.
+func migrateLegacyShelf(w io.Writer, svc *core.Service) {
+	result, err := svc.MigrateLegacyShelf()
+	if err != nil {
+		fmt.Fprintf(w, "! Legacy shelf/ migration failed: %v\n", err)
+
+		return
+	}
+
+	if len(result.Moved) > 0 {
+		fmt.Fprintf(w, "Moved %d files from legacy shelf/ into shelves/\n", len(result.Moved))
+	}
+
+	for _, rel := range result.Conflicts {
+		fmt.Fprintf(w, "! shelf/%s differs from shelves/%s; left in place, merge it by hand\n", rel, rel)
+	}
+}
+
+// checkEmbeddings probes the embedding provider like doctor does and prints
+// the outcome, with a hint on how to fix a failure. Keyword search works
+// either way, so a failure is a warning. Reports whether the probe succeeded.
+func checkEmbeddings(w io.Writer, svc *core.Service, cfg *config.Config) bool {
+	fail := func(err error) bool {
+		fmt.Fprintf(w, "! Semantic search unavailable: %v\n", err)
+
+		switch cfg.Embedding.Provider {
+		case "ollama":
+			fmt.Fprintf(w, "  Start Ollama and pull the model: ollama pull %s\n", cfg.Embedding.Model)
+		default:
+			fmt.Fprintf(w, "  Set an API key: pantry config set --api-key <key>\n")
+		}
+
+		fmt.Fprintln(w, "  Keyword search works in the meantime; re-check with `pantry doctor`.")
+
+		return false
+	}
+
+	provider, err := svc.GetEmbeddingProvider()
+	if err != nil {
+		return fail(err)
+	}
+
+	embedding, err := provider.Embed(context.Background(), "pantry init probe")
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Fprintf(w, "\u2713 Embeddings: %s / %s (%d dimensions)\n", cfg.Embedding.Provider, cfg.Embedding.Model, len(embedding))
+
+	if n, err := svc.CountItems(nil, nil); err == nil && n > 0 && !svc.VectorsAvailable() {
+		fmt.Fprintf(w, "  %d existing notes have no vectors yet; run `pantry reindex` to index them.\n", n)
+	}
+
+	return true
+}
+
+func init() {
+	initCmd.Flags().BoolVar(&initSkipChecks, "skip-checks", false, "Skip the embedding provider check")
+}
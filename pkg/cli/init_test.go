@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pantry/internal/config"
+	"pantry/internal/core"
+)
+
+// probeProvider returns a fixed 4-dim embedding, or err when set.
+type probeProvider struct {
+	err error
+}
+
+func (p probeProvider) Embed(_ context.Context, _ string) ([]float32, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	return []float32{0.1, 0.2, 0.3, 0.4}, nil
+}
+
+func newCheckService(t *testing.T, provider probeProvider) (*core.Service, *config.Config) {
+	t.Helper()
+
+	home := t.TempDir()
+
+	svc, err := core.NewService(home, core.WithEmbeddingProvider(provider))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	t.Cleanup(func() { _ = svc.Close() })
+
+	cfg, err := config.LoadConfig(filepath.Join(home, "config.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	return svc, cfg
+}
+
+func TestCheckEmbeddings_ReachableProvider(t *testing.T) {
+	svc, cfg := newCheckService(t, probeProvider{})
+
+	var buf bytes.Buffer
+	if !checkEmbeddings(&buf, svc, cfg) {
+		t.Fatalf("checkEmbeddings() = false, output:\n%s", buf.String())
+	}
+
+	if !strings.Contains(buf.String(), "4 dimensions") {
+		t.Errorf("output = %q, want it to report the probe dimension", buf.String())
+	}
+}
+
+func TestCheckEmbeddings_UnreachableProviderGivesGuidance(t *testing.T) {
+	svc, cfg := newCheckService(t, probeProvider{err: errors.New("connection refused")})
+
+	var buf bytes.Buffer
+	if checkEmbeddings(&buf, svc, cfg) {
+		t.Fatal("checkEmbeddings() = true for a failing provider")
+	}
+
+	if !strings.Contains(buf.String(), "ollama pull") {
+		t.Errorf("output = %q, want guidance to start Ollama", buf.String())
+	}
+}
@@ -0,0 +1,18 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+)
+
+// interruptContext returns a context cancelled by the first Ctrl-C, so a
+// command can abandon an embedding call in flight instead of waiting on a
+// slow provider. Once it is cancelled, Ctrl-C is handled as usual again, so a
+// second one ends the process. Call stop when the command is done.
+func interruptContext() (ctx context.Context, stop context.CancelFunc) {
+	ctx, stop = signal.NotifyContext(context.Background(), os.Interrupt)
+	context.AfterFunc(ctx, stop)
+
+	return ctx, stop
+}
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"pantry/internal/core"
+	"pantry/internal/models"
+
+	"github.com/spf13/cobra"
+)
+
+var linkType string
+
+var linkCmd = &cobra.Command{
+	Use:   "link [from-id] [to-id]",
+	Short: "Link one note to another",
+	Long: `Record a typed link from one note to another, for example when a new
+decision supersedes an old one:
+
+  pantry link <new-id> <old-id> --type supersedes`,
+	Args: cobra.ExactArgs(2),
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		svc, err := core.NewService("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer func() { _ = svc.Close() }()
+
+		if err := svc.Link(args[0], args[1], linkType); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Linked %s %s %s\n", args[0], linkType, args[1])
+	},
+}
+
+var linksCmd = &cobra.Command{
+	Use:   "links [id]",
+	Short: "Show notes linked to or from a note",
+	Args:  cobra.ExactArgs(1),
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		itemID := args[0]
+
+		svc, err := core.NewService("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer func() { _ = svc.Close() }()
+
+		// printLinks tells the direction by comparing full IDs.
+		item, err := svc.GetItem(itemID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if item == nil {
+			fmt.Fprintf(os.Stderr, "Error: note not found: %s\n", itemID)
+			os.Exit(1)
+		}
+
+		itemID = item.ID
+
+		links, err := svc.Links(itemID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(links) == 0 {
+			fmt.Printf("No links for note %s\n", itemID)
+
+			return
+		}
+
+		printLinks(os.Stdout, itemID, links)
+	},
+}
+
+// printLinks writes one line per link as seen from itemID: "->" for links
+// itemID points at, "<-" for links pointing at itemID.
+func printLinks(w io.Writer, itemID string, links []models.NoteLink) {
+	for _, l := range links {
+		arrow, otherID, otherTitle := "->", l.ToID, l.ToTitle
+		if l.ToID == itemID {
+			arrow, otherID, otherTitle = "<-", l.FromID, l.FromTitle
+		}
+
+		fmt.Fprintf(w, "  %-10s %s %s  %s\n", l.Type, arrow, shortID(otherID), otherTitle)
+	}
+}
+
+// shortID returns the first 8 characters of an ID, as shown in compact output.
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+
+	return id
+}
+
+func init() {
+	linkCmd.Flags().StringVar(&linkType, "type", models.LinkRelatedTo, "Link type ("+strings.Join(models.ValidLinkTypes, ", ")+")")
+}
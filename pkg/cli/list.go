@@ -2,28 +2,82 @@ package cli
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"pantry/internal/core"
+	"pantry/internal/models"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	listLimit   int
-	listProject bool
-	listSource  string
-	listQuery   string
+	listLimit    int
+	listProject  bool
+	listSource   string
+	listQuery    string
+	listFormat   string
+	listMeta     []string
+	listStream   bool
+	listNear     string
+	listWindow   string
+	listNearMode string
+	listUpdated  bool
+	listSince    string
+	listTree     bool
+	listDepth    int
 )
 
+// listFormats are the values accepted by list --format.
+var listFormats = []string{"oneline", "table", "wide"}
+
+// Levels of list --tree, as accepted by --depth.
+const (
+	treeProjects   = 1
+	treeCategories = 2
+	treeNotes      = 3
+)
+
+// uncategorized heads the --tree branch of notes without a category.
+const uncategorized = "(none)"
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List recent notes",
 	//nolint:revive
 	Run: func(cmd *cobra.Command, args []string) {
+		if listLimit < 0 {
+			fmt.Fprintf(os.Stderr, "Error: --limit must not be negative\n")
+			os.Exit(1)
+		}
+
+		if listFormat != "" && !slices.Contains(listFormats, listFormat) {
+			fmt.Fprintf(os.Stderr, "Error: invalid --format %q: must be one of %s\n", listFormat, strings.Join(listFormats, ", "))
+			os.Exit(1)
+		}
+
+		if cmd.Flags().Changed("depth") {
+			if !listTree {
+				fmt.Fprintf(os.Stderr, "Error: --depth needs --tree\n")
+				os.Exit(1)
+			}
+
+			if listDepth < treeProjects || listDepth > treeNotes {
+				fmt.Fprintf(os.Stderr, "Error: --depth must be 1 (projects), 2 (categories) or 3 (notes)\n")
+				os.Exit(1)
+			}
+		}
+
+		// A tree is an overview of everything unless a limit was asked for.
+		if listTree && !cmd.Flags().Changed("limit") {
+			listLimit = 0
+		}
+
 		svc, err := core.NewService("")
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -50,20 +104,108 @@ var listCmd = &cobra.Command{
 			query = &listQuery
 		}
 
-		results, total, err := svc.GetContext(listLimit, project, source, query, "never", false)
+		opts, err := metaSearchOptions(listMeta)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 
+		nearOpt, err := nearSearchOption(listNear, listWindow, listNearMode)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if nearOpt != nil {
+			opts = append(opts, nearOpt)
+		}
+
+		if listUpdated {
+			opts = append(opts, core.WithRecentlyUpdated())
+		}
+
+		sinceOpt, err := diffSinceOption(svc, listSince)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if sinceOpt != nil {
+			opts = append(opts, sinceOpt)
+		}
+
+		results, total, err := svc.GetContext(listLimit, project, source, query, "never", false, opts...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if listLimit == 0 {
+			warnUnlimited(len(results))
+		}
+
+		// A stream is empty rather than carrying a human-readable message.
+		if listStream {
+			if err := streamSearchJSON(stdout, results, nil); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			return
+		}
+
 		if len(results) == 0 {
-			fmt.Println("No notes found.")
+			fmt.Fprintln(stdout, "No notes found.")
+
+			return
+		}
+
+		if listTree {
+			printTree(stdout, results, listDepth)
+
+			return
+		}
+
+		// Formatted output is meant for scanning and scripts: no header or hint.
+		if listFormat != "" {
+			printList(stdout, results, listFormat)
 
 			return
 		}
 
-		fmt.Printf("Notes (%d total, showing %d):\n", total, len(results))
+		fmt.Fprintf(stdout, "Notes (%d total, showing %d):\n", total, len(results))
+		printList(stdout, results, "")
+		fmt.Fprintln(stdout, "\nUse `pantry search <query>` to search notes, `pantry retrieve <id>` for full details.")
+	},
+}
+
+// printList writes results in the given list format. An empty format is the
+// default bullet list; table and wide size their columns to the widest value.
+func printList(w io.Writer, results []models.SearchResult, format string) {
+	switch format {
+	case "oneline":
+		for _, r := range results {
+			fmt.Fprintf(w, "%s %s\n", shortID(r.ID), r.Title)
+		}
+	case "table", "wide":
+		header := []string{"ID", "DATE", "CATEGORY", "PROJECT", "TITLE"}
+		if format == "wide" {
+			header = append(header, "TAGS", "SOURCE", "UPDATES")
+		}
+
+		rows := [][]string{header}
+
+		for _, r := range results {
+			row := []string{shortID(r.ID), r.CreatedAt[:10], deref(r.Category), r.Project, r.Title}
+			if format == "wide" {
+				row = append(row, strings.Join(r.Tags, ","), deref(r.Source), strconv.Itoa(r.UpdatedCount))
+			}
+
+			rows = append(rows, row)
+		}
 
+		printColumns(w, rows)
+	default:
 		for _, r := range results {
 			dateStr := r.CreatedAt[:10]
 
@@ -82,16 +224,130 @@ var listCmd = &cobra.Command{
 				tags = fmt.Sprintf(" [[%s]]", strings.Join(r.Tags, " "))
 			}
 
-			fmt.Printf("- %s [%s] %s%s%s\n", r.ID[:8], dateDisplay, r.Title, cat, tags)
+			fmt.Fprintf(w, "- %s [%s] %s%s%s%s\n", r.ID[:8], dateDisplay, r.Title, cat, tags, updatedSuffix(r, "Jan 02"))
 		}
+	}
+}
 
-		fmt.Println("\nUse `pantry search <query>` to search notes, `pantry retrieve <id>` for full details.")
-	},
+// printColumns writes rows as left-aligned columns separated by two spaces,
+// each as wide as its longest cell. The last column is not padded.
+func printColumns(w io.Writer, rows [][]string) {
+	var widths []int
+
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(row)-1 {
+				fmt.Fprintln(w, cell)
+			} else {
+				fmt.Fprintf(w, "%-*s  ", widths[i], cell)
+			}
+		}
+	}
+}
+
+// treeBranch is a project or category in list --tree, with the notes under
+// it in list order.
+type treeBranch struct {
+	Name    string
+	Notes   []models.SearchResult
+	Entries []*treeBranch
+}
+
+// buildTree groups results by project, then category, each level sorted by
+// name. Notes keep their list order within a category; notes without a
+// category go under uncategorized.
+func buildTree(results []models.SearchResult) []*treeBranch {
+	var projects []*treeBranch
+
+	branch := func(list *[]*treeBranch, name string) *treeBranch {
+		for _, b := range *list {
+			if b.Name == name {
+				return b
+			}
+		}
+
+		b := &treeBranch{Name: name}
+		*list = append(*list, b)
+
+		return b
+	}
+
+	for _, r := range results {
+		cat := uncategorized
+		if r.Category != nil && *r.Category != "" {
+			cat = *r.Category
+		}
+
+		project := branch(&projects, r.Project)
+		project.Notes = append(project.Notes, r)
+
+		category := branch(&project.Entries, cat)
+		category.Notes = append(category.Notes, r)
+	}
+
+	byName := func(a, b *treeBranch) int { return strings.Compare(a.Name, b.Name) }
+
+	slices.SortFunc(projects, byName)
+
+	for _, p := range projects {
+		slices.SortFunc(p.Entries, byName)
+	}
+
+	return projects
+}
+
+// printTree writes results as a tree of projects, categories and note titles,
+// each branch with its note count. depth stops it at treeProjects or
+// treeCategories; anything else prints the notes too.
+func printTree(w io.Writer, results []models.SearchResult, depth int) {
+	for _, project := range buildTree(results) {
+		fmt.Fprintf(w, "%s (%d)\n", project.Name, len(project.Notes))
+
+		if depth == treeProjects {
+			continue
+		}
+
+		for _, category := range project.Entries {
+			fmt.Fprintf(w, "  %s (%d)\n", category.Name, len(category.Notes))
+
+			if depth == treeCategories {
+				continue
+			}
+
+			for _, r := range category.Notes {
+				fmt.Fprintf(w, "    - %s %s\n", shortID(r.ID), r.Title)
+			}
+		}
+	}
 }
 
 func init() {
-	listCmd.Flags().IntVarP(&listLimit, "limit", "n", 10, "Maximum number of notes")
+	listCmd.Flags().IntVarP(&listLimit, "limit", "n", 10, "Maximum number of notes; 0 lists all")
 	listCmd.Flags().BoolVarP(&listProject, "project", "p", false, "Filter to current project")
 	listCmd.Flags().StringVarP(&listSource, "source", "s", "", "Filter by source")
 	listCmd.Flags().StringVarP(&listQuery, "query", "q", "", "Search query for filtering")
+	listCmd.Flags().StringArrayVar(&listMeta, "meta", nil, "Filter by metadata key=value (repeatable; all must match)")
+	listCmd.Flags().BoolVar(&listStream, "json-stream", false, "Print notes as NDJSON, one JSON object per line")
+	listCmd.Flags().StringVar(&listNear, "near", "", "Show notes created around this date first (YYYY-MM, YYYY-MM-DD or RFC3339)")
+	listCmd.Flags().StringVar(&listWindow, "window", "14d", "Time window for --near, e.g. 14d, 2w or 36h")
+	listCmd.Flags().StringVar(&listNearMode, "near-mode", "boost", "How --near applies: boost (closest dates first) or restrict (only notes inside the window)")
+	listCmd.Flags().BoolVar(&listUpdated, "updated", false, "Show the most recently changed notes first instead of the newest")
+	listCmd.Flags().StringVar(&listSince, "diff-since", "", "Only notes created or updated since a checkpoint (see 'pantry checkpoint') or a date (YYYY-MM-DD or RFC3339)")
+	listCmd.Flags().BoolVar(&listTree, "tree", false, "Show notes as a tree of projects, categories and titles with counts (lists every note unless --limit is set)")
+	listCmd.Flags().IntVar(&listDepth, "depth", treeNotes, "With --tree, stop at 1 (projects) or 2 (categories); 3 shows the notes")
+	listCmd.Flags().StringVar(&listFormat, "format", "", "Output format: oneline, table or wide (default: bullet list)")
+
+	listCmd.MarkFlagsMutuallyExclusive("json-stream", "format", "tree")
+	listCmd.MarkFlagsMutuallyExclusive("updated", "near")
+	listCmd.MarkFlagsMutuallyExclusive("updated", "query")
 }
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"pantry/internal/models"
+)
+
+func testListResults() []models.SearchResult {
+	first := testSearchResult()
+	first.UpdatedCount = 2
+
+	src := "claude-code"
+	second := models.SearchResult{
+		ID:        "fedcba9876543210",
+		Title:     "Cache invalidation bug",
+		Project:   "frontend-app",
+		Source:    &src,
+		CreatedAt: "2026-01-03T10:00:00Z",
+	}
+
+	return []models.SearchResult{first, second}
+}
+
+func TestPrintList_Oneline(t *testing.T) {
+	var buf bytes.Buffer
+	printList(&buf, testListResults(), "oneline")
+
+	want := "01234567 Use JWT auth\nfedcba98 Cache invalidation bug\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestPrintList_Table(t *testing.T) {
+	var buf bytes.Buffer
+	printList(&buf, testListResults(), "table")
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want header + 2 rows:\n%s", len(lines), buf.String())
+	}
+
+	if got := strings.Fields(lines[0]); strings.Join(got, " ") != "ID DATE CATEGORY PROJECT TITLE" {
+		t.Errorf("header = %q", lines[0])
+	}
+
+	// Columns are aligned: the title starts at the same offset on every line.
+	col := strings.Index(lines[0], "TITLE")
+	if strings.Index(lines[1], "Use JWT auth") != col || strings.Index(lines[2], "Cache invalidation bug") != col {
+		t.Errorf("title column not aligned at %d:\n%s", col, buf.String())
+	}
+
+	if !strings.HasPrefix(lines[1], "01234567  2026-01-02  decision") {
+		t.Errorf("row = %q", lines[1])
+	}
+}
+
+func TestPrintList_Wide(t *testing.T) {
+	var buf bytes.Buffer
+	printList(&buf, testListResults(), "wide")
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want header + 2 rows:\n%s", len(lines), buf.String())
+	}
+
+	if got := strings.Fields(lines[0]); strings.Join(got, " ") != "ID DATE CATEGORY PROJECT TITLE TAGS SOURCE UPDATES" {
+		t.Errorf("header = %q", lines[0])
+	}
+
+	if !strings.Contains(lines[1], "auth,jwt") || !strings.HasSuffix(lines[1], "2") {
+		t.Errorf("row 1 = %q, want tags and update count", lines[1])
+	}
+
+	if !strings.Contains(lines[2], "claude-code") || !strings.HasSuffix(lines[2], "0") {
+		t.Errorf("row 2 = %q, want source and update count", lines[2])
+	}
+}
+
+func TestPrintList_ShowsUpdatedDate(t *testing.T) {
+	results := testListResults()
+	results[0].UpdatedAt = "2026-02-10T08:00:00Z"
+	results[1].UpdatedAt = results[1].CreatedAt
+
+	var buf bytes.Buffer
+	printList(&buf, results, "")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("printList() printed %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+
+	if !strings.HasSuffix(lines[0], " (updated Feb 10)") {
+		t.Errorf("revised note line = %q, want an (updated Feb 10) suffix", lines[0])
+	}
+
+	if strings.Contains(lines[1], "updated") {
+		t.Errorf("unrevised note line = %q, want no updated date", lines[1])
+	}
+
+	buf.Reset()
+	printSearchResult(&buf, 1, results[0], false, "decimal", nil)
+
+	if !strings.Contains(buf.String(), "2026-01-02 (updated 2026-02-10) | api") {
+		t.Errorf("printSearchResult() = %q, want the updated date after the created date", buf.String())
+	}
+}
+
+func TestPrintTree(t *testing.T) {
+	bug, decision := "bug", "decision"
+
+	note := func(id, title, project string, category *string) models.SearchResult {
+		return models.SearchResult{ID: id, Title: title, Project: project, Category: category, CreatedAt: "2026-01-02T03:04:05Z"}
+	}
+
+	results := []models.SearchResult{
+		note("aaaaaaaa11111111", "Fix login loop", "web", &bug),
+		note("bbbbbbbb22222222", "Use JWT auth", "api", &decision),
+		note("cccccccc33333333", "Retry on 503", "api", &bug),
+		note("dddddddd44444444", "Stale cache", "web", &bug),
+		note("eeeeeeee55555555", "Onboarding notes", "api", nil),
+	}
+
+	for _, tt := range []struct {
+		depth int
+		want  string
+	}{
+		{treeProjects, "api (3)\nweb (2)\n"},
+		{treeCategories, "api (3)\n  (none) (1)\n  bug (1)\n  decision (1)\nweb (2)\n  bug (2)\n"},
+		{treeNotes, "api (3)\n" +
+			"  (none) (1)\n    - eeeeeeee Onboarding notes\n" +
+			"  bug (1)\n    - cccccccc Retry on 503\n" +
+			"  decision (1)\n    - bbbbbbbb Use JWT auth\n" +
+			"web (2)\n" +
+			"  bug (2)\n    - aaaaaaaa Fix login loop\n    - dddddddd Stale cache\n"},
+	} {
+		var buf bytes.Buffer
+		printTree(&buf, results, tt.depth)
+
+		if buf.String() != tt.want {
+			t.Errorf("depth %d output = %q, want %q", tt.depth, buf.String(), tt.want)
+		}
+	}
+}
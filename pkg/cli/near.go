@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"pantry/internal/core"
+)
+
+// nearModes are the values accepted by --near-mode.
+var nearModes = []string{"boost", "restrict"}
+
+// nearSearchOption builds the option for --near, --window and --near-mode.
+// It returns nil when near is empty.
+func nearSearchOption(near, window, mode string) (core.SearchOption, error) {
+	if near == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	if !slices.Contains(nearModes, mode) {
+		return nil, fmt.Errorf("invalid --near-mode %q: must be one of %s", mode, strings.Join(nearModes, ", "))
+	}
+
+	target, err := parseNearDate(near)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := parseWindow(window)
+	if err != nil {
+		return nil, err
+	}
+
+	return core.WithNear(target, d, mode == "restrict"), nil
+}
+
+// parseNearDate parses a --near date: YYYY-MM (taken as the 15th, the middle
+// of the month), YYYY-MM-DD or an RFC3339 time.
+func parseNearDate(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01", s); err == nil {
+		return t.AddDate(0, 0, 14), nil
+	}
+
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid --near %q: use YYYY-MM, YYYY-MM-DD or RFC3339", s)
+}
+
+// parseWindow parses a --window such as 14d, 2w or any Go duration (36h).
+// It must be positive.
+func parseWindow(s string) (time.Duration, error) {
+	var (
+		d   time.Duration
+		err error
+	)
+
+	unit := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+
+	if n := len(s); n > 1 && unit[s[n-1]] != 0 {
+		var count float64
+
+		count, err = strconv.ParseFloat(s[:n-1], 64)
+		d = time.Duration(count * float64(unit[s[n-1]]))
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid --window %q: use a positive duration such as 14d, 2w or 36h", s)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseNearDate(t *testing.T) {
+	tests := map[string]time.Time{
+		"2024-03":              time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
+		"2024-03-02":           time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+		"2024-03-02T10:00:00Z": time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC),
+	}
+
+	for in, want := range tests {
+		got, err := parseNearDate(in)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseNearDate(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+
+	if _, err := parseNearDate("March 2024"); err == nil {
+		t.Error("parseNearDate(\"March 2024\") error = nil")
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := map[string]time.Duration{
+		"14d": 14 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+		"36h": 36 * time.Hour,
+	}
+
+	for in, want := range tests {
+		if got, err := parseWindow(in); err != nil || got != want {
+			t.Errorf("parseWindow(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"", "0d", "-3d", "soon"} {
+		if _, err := parseWindow(in); err == nil {
+			t.Errorf("parseWindow(%q) error = nil", in)
+		}
+	}
+}
+
+func TestNearSearchOption_InvalidMode(t *testing.T) {
+	if opt, err := nearSearchOption("", "14d", "bogus"); opt != nil || err != nil {
+		t.Errorf("nearSearchOption(empty) = %v, %v; want nil, nil", opt, err)
+	}
+
+	if _, err := nearSearchOption("2024-03", "14d", "bogus"); err == nil {
+		t.Error("nearSearchOption(mode bogus) error = nil")
+	}
+}
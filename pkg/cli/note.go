@@ -0,0 +1,303 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"pantry/internal/core"
+	"pantry/internal/models"
+
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+)
+
+var noteEditAppend bool
+
+var noteCmd = &cobra.Command{
+	Use:   "note",
+	Short: "Work with a single note",
+}
+
+var noteEditCmd = &cobra.Command{
+	Use:   "edit <id>",
+	Short: "Edit a note's fields and details in $EDITOR",
+	Long: `Open a note in $VISUAL or $EDITOR (vi if neither is set) as YAML fields
+followed by its details. Fields you change are saved and the note is
+re-embedded; the title is shown for reference and cannot be edited here.
+With --append-details the details start empty and what you write is appended.`,
+	Args: cobra.ExactArgs(1),
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		svc, err := core.NewService("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer func() { _ = svc.Close() }()
+
+		item, err := svc.GetItem(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if item == nil {
+			fmt.Fprintf(os.Stderr, "Error: note not found: %s\n", args[0])
+			os.Exit(1)
+		}
+
+		details := ""
+		if !noteEditAppend {
+			if d, err := svc.GetDetails(item.ID); err == nil && d != nil {
+				details = d.Body
+			}
+		}
+
+		before := noteEditFromItem(item, details)
+
+		buf, err := formatNoteEdit(item, before)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		edited, err := editInEditor(buf)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		after, err := parseNoteEdit(edited)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v (nothing was saved)\n", err)
+			os.Exit(1)
+		}
+
+		c := diffNoteEdit(before, after)
+		if noteEditAppend && c.details != nil && *c.details == "" {
+			c.details = nil
+		}
+
+		if c.empty() {
+			fmt.Println("No changes.")
+
+			return
+		}
+
+		if err := svc.Update(item.ID, c.what, c.why, c.impact, c.tags, c.details, !noteEditAppend); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Updated note %s (%s)\n", item.ID, strings.Join(c.fields(), ", "))
+	},
+}
+
+// noteEdit is the editable part of a note, as laid out in the edit buffer.
+type noteEdit struct {
+	What    string   `yaml:"what"`
+	Why     string   `yaml:"why"`
+	Impact  string   `yaml:"impact"`
+	Tags    []string `yaml:"tags,flow"`
+	Details string   `yaml:"-"`
+}
+
+func noteEditFromItem(item *models.Item, details string) noteEdit {
+	deref := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+
+		return *s
+	}
+
+	return noteEdit{
+		What:    item.What,
+		Why:     deref(item.Why),
+		Impact:  deref(item.Impact),
+		Tags:    item.Tags,
+		Details: details,
+	}
+}
+
+// formatNoteEdit lays out e for editing: comment lines naming the note, the
+// fields as YAML between "---" lines, then the details.
+func formatNoteEdit(item *models.Item, e noteEdit) (string, error) {
+	if e.Tags == nil {
+		e.Tags = []string{}
+	}
+
+	fields, err := yaml.Marshal(e)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode note: %w", err)
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Note %s: %s\n", item.ID, item.Title)
+	fmt.Fprintf(&b, "# Edit the fields below; the details follow the second ---.\n")
+	fmt.Fprintf(&b, "---\n%s---\n", fields)
+
+	if e.Details != "" {
+		b.WriteString(e.Details)
+		b.WriteString("\n")
+	}
+
+	return b.String(), nil
+}
+
+// parseNoteEdit reads back a buffer written by formatNoteEdit. Comment lines
+// before the fields are ignored.
+func parseNoteEdit(buf string) (noteEdit, error) {
+	var e noteEdit
+
+	buf = strings.ReplaceAll(buf, "\r\n", "\n")
+
+	for strings.HasPrefix(buf, "#") {
+		_, rest, _ := strings.Cut(buf, "\n")
+		buf = rest
+	}
+
+	rest, ok := strings.CutPrefix(buf, "---\n")
+	if !ok {
+		return e, errors.New("edited note must start with the --- fields block")
+	}
+
+	fields, details, ok := strings.Cut(rest, "\n---\n")
+	if !ok {
+		fields, ok = strings.CutSuffix(rest, "\n---")
+		if !ok {
+			return e, errors.New("edited note is missing the --- line that closes the fields")
+		}
+	}
+
+	if err := yaml.Unmarshal([]byte(fields), &e); err != nil {
+		return e, fmt.Errorf("invalid fields: %w", err)
+	}
+
+	if strings.TrimSpace(e.What) == "" {
+		return e, errors.New("what must not be empty")
+	}
+
+	e.Details = strings.TrimRight(details, "\n")
+
+	return e, nil
+}
+
+// noteEditChanges holds the fields an edit changed, in the form Update takes:
+// nil means unchanged.
+type noteEditChanges struct {
+	what, why, impact, details *string
+	tags                       []string
+}
+
+func (c noteEditChanges) empty() bool {
+	return len(c.fields()) == 0
+}
+
+// fields names the changed fields, for the confirmation message.
+func (c noteEditChanges) fields() []string {
+	var names []string
+
+	for _, f := range []struct {
+		name    string
+		changed bool
+	}{
+		{"what", c.what != nil},
+		{"why", c.why != nil},
+		{"impact", c.impact != nil},
+		{"tags", c.tags != nil},
+		{"details", c.details != nil},
+	} {
+		if f.changed {
+			names = append(names, f.name)
+		}
+	}
+
+	return names
+}
+
+// diffNoteEdit returns the fields of after that differ from before.
+func diffNoteEdit(before, after noteEdit) noteEditChanges {
+	var c noteEditChanges
+
+	changed := func(old, cur string) *string {
+		if old == cur {
+			return nil
+		}
+
+		return &cur
+	}
+
+	c.what = changed(before.What, after.What)
+	c.why = changed(before.Why, after.Why)
+	c.impact = changed(before.Impact, after.Impact)
+	c.details = changed(before.Details, after.Details)
+
+	if !slices.Equal(before.Tags, after.Tags) {
+		c.tags = after.Tags
+		if c.tags == nil {
+			c.tags = []string{}
+		}
+	}
+
+	return c
+}
+
+// editInEditor writes buf to a temporary file, opens it in the user's editor
+// and returns the saved contents.
+func editInEditor(buf string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+
+	if editor == "" {
+		editor = "vi"
+	}
+
+	f, err := os.CreateTemp("", "pantry-note-*.md")
+	if err != nil {
+		return "", err
+	}
+
+	path := f.Name()
+	defer func() { _ = os.Remove(path) }()
+
+	if _, err := f.WriteString(buf); err != nil {
+		_ = f.Close()
+
+		return "", err
+	}
+
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	// The editor may carry arguments, e.g. "code --wait".
+	parts := strings.Fields(editor)
+
+	c := exec.Command(parts[0], append(parts[1:], path)...) //nolint:gosec
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	if err := c.Run(); err != nil {
+		return "", fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func init() {
+	noteEditCmd.Flags().BoolVar(&noteEditAppend, "append-details", false, "Start with empty details and append what you write instead of replacing them")
+	noteCmd.AddCommand(noteEditCmd)
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"pantry/internal/models"
+)
+
+func testNoteItem() *models.Item {
+	why := "Sessions did not scale"
+
+	return &models.Item{
+		ID:    "0123456789abcdef",
+		Title: "Use JWT for auth",
+		What:  "Switched to JWT: short-lived tokens",
+		Why:   &why,
+		Tags:  []string{"auth", "security"},
+	}
+}
+
+func TestNoteEdit_RoundTrip(t *testing.T) {
+	item := testNoteItem()
+	before := noteEditFromItem(item, "## Notes\n\nmulti-line\ndetails")
+
+	buf, err := formatNoteEdit(item, before)
+	if err != nil {
+		t.Fatalf("formatNoteEdit() error = %v", err)
+	}
+
+	if !strings.HasPrefix(buf, "# Note 0123456789abcdef: Use JWT for auth\n") {
+		t.Errorf("buffer does not start with the note header:\n%s", buf)
+	}
+
+	after, err := parseNoteEdit(buf)
+	if err != nil {
+		t.Fatalf("parseNoteEdit() error = %v\n%s", err, buf)
+	}
+
+	if after.What != before.What || after.Why != before.Why || after.Impact != "" ||
+		!slices.Equal(after.Tags, before.Tags) || after.Details != before.Details {
+		t.Errorf("round trip = %+v, want %+v", after, before)
+	}
+
+	if c := diffNoteEdit(before, after); !c.empty() {
+		t.Errorf("unedited buffer changed %v", c.fields())
+	}
+}
+
+func TestNoteEdit_OnlyChangedFields(t *testing.T) {
+	item := testNoteItem()
+	before := noteEditFromItem(item, "old details")
+
+	buf, err := formatNoteEdit(item, before)
+	if err != nil {
+		t.Fatalf("formatNoteEdit() error = %v", err)
+	}
+
+	buf = strings.Replace(buf, "Sessions did not scale", "Sessions were slow", 1)
+	buf = strings.Replace(buf, "old details", "new details", 1)
+
+	after, err := parseNoteEdit(buf)
+	if err != nil {
+		t.Fatalf("parseNoteEdit() error = %v", err)
+	}
+
+	c := diffNoteEdit(before, after)
+
+	if got, want := c.fields(), []string{"why", "details"}; !slices.Equal(got, want) {
+		t.Fatalf("changed fields = %v, want %v", got, want)
+	}
+
+	if *c.why != "Sessions were slow" || *c.details != "new details" {
+		t.Errorf("changes = why %q, details %q", *c.why, *c.details)
+	}
+
+	if c.what != nil || c.impact != nil || c.tags != nil {
+		t.Error("unchanged fields should be nil")
+	}
+}
+
+func TestParseNoteEdit_Invalid(t *testing.T) {
+	for name, buf := range map[string]string{
+		"no fields block": "what: x\n",
+		"unclosed":        "---\nwhat: x\n",
+		"empty what":      "---\nwhat: \"\"\n---\n",
+		"bad yaml":        "---\nwhat: [\n---\n",
+	} {
+		if _, err := parseNoteEdit(buf); err == nil {
+			t.Errorf("parseNoteEdit(%s) = nil error, want one", name)
+		}
+	}
+}
@@ -35,7 +35,7 @@ var notesCmd = &cobra.Command{
 
 		entries, err := os.ReadDir(shelvesDir)
 		if err != nil {
-			fmt.Println("No notes found.")
+			fmt.Fprintln(stdout, "No notes found.")
 
 			return
 		}
@@ -63,7 +63,7 @@ var notesCmd = &cobra.Command{
 		}
 
 		if len(noteFiles) == 0 {
-			fmt.Println("No notes found.")
+			fmt.Fprintln(stdout, "No notes found.")
 
 			return
 		}
@@ -73,7 +73,7 @@ var notesCmd = &cobra.Command{
 			return noteFiles[i].fname > noteFiles[j].fname
 		})
 
-		fmt.Println("\nNotes:")
+		fmt.Fprintln(stdout, "\nNotes:")
 
 		maxProject := 0
 
@@ -94,7 +94,7 @@ var notesCmd = &cobra.Command{
 
 			dateStr := strings.Replace(nf.fname, "-notes.md", "", 1)
 			fullPath := filepath.Join(shelvesDir, nf.project, nf.fname)
-			fmt.Printf("  %s | %-*s | %s\n", dateStr, maxProject, nf.project, fullPath)
+			fmt.Fprintf(stdout, "  %s | %-*s | %s\n", dateStr, maxProject, nf.project, fullPath)
 		}
 	},
 }
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// stdout is where the print-heavy commands (search, list, notes) write. A
+// write to a closed pipe, as in `pantry list | head`, ends the process
+// quietly with status 0.
+var stdout io.Writer = brokenPipeWriter{w: os.Stdout, exit: os.Exit}
+
+// brokenPipeWriter calls exit(0) when a write to w fails with EPIPE.
+type brokenPipeWriter struct {
+	w    io.Writer
+	exit func(code int)
+}
+
+func (b brokenPipeWriter) Write(p []byte) (int, error) {
+	n, err := b.w.Write(p)
+	if isBrokenPipe(err) {
+		b.exit(0)
+	}
+
+	return n, err
+}
+
+// isBrokenPipe reports whether err comes from writing to a pipe whose reader
+// has gone away.
+func isBrokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE)
+}
+
+// ignoreSIGPIPE makes writes to a closed stdout return EPIPE instead of the
+// Go runtime killing the process with SIGPIPE, so brokenPipeWriter can exit
+// cleanly.
+func ignoreSIGPIPE() {
+	signal.Ignore(syscall.SIGPIPE)
+}
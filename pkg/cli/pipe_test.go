@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"os"
+	"testing"
+
+	"pantry/internal/models"
+)
+
+func TestBrokenPipeWriter_ExitsQuietly(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("os.Pipe() error = %v", err)
+	}
+
+	defer w.Close()
+
+	code := -1
+	out := brokenPipeWriter{w: w, exit: func(c int) { code = c }}
+
+	results := []models.SearchResult{{ID: "a", Title: "First"}, {ID: "b", Title: "Second"}}
+
+	if err := streamSearchJSON(out, results[:1], nil); err != nil {
+		t.Fatalf("streamSearchJSON() before close error = %v", err)
+	}
+
+	// The reader goes away mid-stream, as `head` does.
+	r.Close()
+
+	err = streamSearchJSON(out, results[1:], nil)
+	if !isBrokenPipe(err) {
+		t.Errorf("streamSearchJSON() after close error = %v, want EPIPE", err)
+	}
+
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0", code)
+	}
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"pantry/internal/config"
+	"pantry/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+var pruneVectorsCmd = &cobra.Command{
+	Use:   "prune-vectors",
+	Short: "Drop the vector index and search by keywords only",
+	Long: `Drop the vector table and its recorded dimension and metric, so search
+uses the keyword index alone. Notes are kept. Set embedding.on_store to off
+to keep new notes from building a new vector table; 'pantry reindex' brings
+semantic search back.`,
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		svc, err := core.NewService("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer func() { _ = svc.Close() }()
+
+		n, err := svc.PruneVectors()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(stdout, "Dropped %d vectors; search now uses keywords only.\n", n)
+
+		cfg, err := config.LoadConfig(filepath.Join(config.GetPantryHome(), "config.yaml"))
+		if err == nil && cfg.Embedding.OnStore != config.OnStoreOff {
+			fmt.Fprintln(stdout, "New notes will be embedded again; set embedding.on_store: off in config.yaml to stay keyword-only.")
+		}
+	},
+}
@@ -3,12 +3,22 @@ package cli
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
+	"pantry/internal/config"
 	"pantry/internal/core"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	reindexResume bool
+	reindexFTS    bool
+	reindexJobs   int
+	reindexModel  string
+	reindexProv   string
+)
+
 var reindexCmd = &cobra.Command{
 	Use:   "reindex",
 	Short: "Rebuild vector index with current embedding provider",
@@ -22,6 +32,28 @@ var reindexCmd = &cobra.Command{
 
 		defer func() { _ = svc.Close() }()
 
+		if reindexFTS {
+			count, err := svc.RebuildFTS()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Rebuilt keyword index for %d notes\n", count)
+
+			return
+		}
+
+		if reindexProv != "" && reindexModel == "" {
+			fmt.Fprintf(os.Stderr, "Error: --provider needs --model\n")
+			os.Exit(1)
+		}
+
+		if reindexJobs < 1 {
+			fmt.Fprintf(os.Stderr, "Error: --concurrency must be at least 1\n")
+			os.Exit(1)
+		}
+
 		// Check if there are any notes
 		// Simplified - would need to get count from service
 		fmt.Println("Reindexing notes...")
@@ -34,14 +66,47 @@ var reindexCmd = &cobra.Command{
 			}
 		}
 
-		result, err := svc.Reindex(progressCallback)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Reindex skipped: %v\n", err)
+		var opts []core.ReindexOption
+		if reindexResume {
+			opts = append(opts, core.WithResume())
+		}
 
-			return
+		if reindexJobs > 1 {
+			opts = append(opts, core.WithConcurrency(reindexJobs))
+		}
+
+		if reindexModel != "" {
+			opts = append(opts, core.WithModel(reindexProv, reindexModel))
+		}
+
+		// Ctrl-C stops the run like a failed embedding: what is done is kept.
+		ctx, stop := interruptContext()
+		defer stop()
+
+		opts = append(opts, core.WithReindexContext(ctx))
+
+		result, err := svc.Reindex(progressCallback, opts...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
 
 		fmt.Printf("Re-indexed %v notes with %v (%v dims)\n",
 			result["count"], result["model"], result["dim"])
+
+		if reindexModel != "" {
+			fmt.Printf("Updated %s to use %v\n", filepath.Join(config.GetPantryHome(), "config.yaml"), result["model"])
+		}
 	},
 }
+
+func init() {
+	reindexCmd.Flags().BoolVar(&reindexResume, "resume", false, "Continue an interrupted reindex, embedding only notes without a vector or with one from a fallback provider")
+	reindexCmd.Flags().BoolVar(&reindexFTS, "fts", false, "Rebuild the keyword (FTS) index from the notes table instead; no embeddings needed")
+	reindexCmd.Flags().IntVar(&reindexJobs, "concurrency", 1, "Embed up to this many notes at once, e.g. for a local Ollama with spare CPU")
+	reindexCmd.Flags().StringVar(&reindexModel, "model", "", "Switch to this embedding model: rebuild the index for it and save it to config.yaml once every note is embedded")
+	reindexCmd.Flags().StringVar(&reindexProv, "provider", "", "With --model, switch to this embedding provider too (ollama, openai, openrouter, cohere)")
+	reindexCmd.MarkFlagsMutuallyExclusive("fts", "resume")
+	reindexCmd.MarkFlagsMutuallyExclusive("fts", "concurrency")
+	reindexCmd.MarkFlagsMutuallyExclusive("fts", "model")
+}
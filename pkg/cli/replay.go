@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"pantry/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+var replayProject string
+
+var replayCmd = &cobra.Command{
+	Use:   "replay [id]",
+	Short: "Rebuild notes markdown from the database",
+	Long: `Regenerate the daily notes files under shelves/ from the database.
+
+With an item ID, the notes file containing that item is rebuilt. With --project,
+every notes file of that project is rebuilt. With neither, all projects are.
+Existing files are overwritten, since the database is the source of truth.`,
+	Args: cobra.MaximumNArgs(1),
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		itemID := ""
+		if len(args) == 1 {
+			itemID = args[0]
+		}
+
+		if itemID != "" && replayProject != "" {
+			fmt.Fprintf(os.Stderr, "Error: pass either an item ID or --project, not both\n")
+			os.Exit(1)
+		}
+
+		svc, err := core.NewService("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer func() { _ = svc.Close() }()
+
+		var project *string
+		if replayProject != "" {
+			project = &replayProject
+		}
+
+		files, err := svc.Replay(itemID, project)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(files) == 0 {
+			fmt.Println("No notes to replay.")
+
+			return
+		}
+
+		for _, f := range files {
+			fmt.Printf("Wrote %s\n", f)
+		}
+
+		fmt.Printf("Replayed %d notes file(s)\n", len(files))
+	},
+}
+
+func init() {
+	replayCmd.Flags().StringVar(&replayProject, "project", "", "Rebuild all notes files of this project")
+}
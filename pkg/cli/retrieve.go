@@ -2,13 +2,19 @@ package cli
 
 import (
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"strings"
 
 	"pantry/internal/core"
+	"pantry/internal/models"
 
 	"github.com/spf13/cobra"
 )
 
+var retrieveRender bool
+
 var retrieveCmd = &cobra.Command{
 	Use:   "retrieve [id]",
 	Short: "Retrieve full details for a note",
@@ -31,6 +37,12 @@ var retrieveCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if retrieveRender {
+			renderNote(svc, itemID, detail)
+
+			return
+		}
+
 		if detail == nil {
 			fmt.Printf("No details found for note %s\n", itemID)
 
@@ -40,3 +52,68 @@ var retrieveCmd = &cobra.Command{
 		fmt.Println(detail.Body)
 	},
 }
+
+// renderNote prints a note's fields, its details, and the notes linked to it.
+func renderNote(svc *core.Service, itemID string, detail *models.ItemDetail) {
+	// Details are looked up by ID prefix; reuse the full ID they resolved to.
+	if detail != nil {
+		itemID = detail.ItemID
+	}
+
+	item, err := svc.GetItem(itemID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if item == nil {
+		fmt.Printf("No note found for %s\n", itemID)
+
+		return
+	}
+
+	fmt.Printf("# %s\n", item.Title)
+
+	meta := []string{item.Project, item.CreatedAt[:10]}
+	if item.Category != nil {
+		meta = append(meta, *item.Category)
+	}
+
+	fmt.Printf("%s\n\n", strings.Join(meta, " | "))
+	fmt.Printf("What: %s\n", item.What)
+
+	if item.Why != nil {
+		fmt.Printf("Why: %s\n", *item.Why)
+	}
+
+	if item.Impact != nil {
+		fmt.Printf("Impact: %s\n", *item.Impact)
+	}
+
+	if len(item.Metadata) > 0 {
+		fmt.Println("Metadata:")
+
+		for _, key := range slices.Sorted(maps.Keys(item.Metadata)) {
+			fmt.Printf("  %s: %s\n", key, item.Metadata[key])
+		}
+	}
+
+	if detail != nil {
+		fmt.Printf("\n%s\n", detail.Body)
+	}
+
+	links, err := svc.Links(item.ID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(links) > 0 {
+		fmt.Println("\nRelated notes:")
+		printLinks(os.Stdout, item.ID, links)
+	}
+}
+
+func init() {
+	retrieveCmd.Flags().BoolVar(&retrieveRender, "render", false, "Show the note's fields and related notes, not just its details")
+}
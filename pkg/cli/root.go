@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 
+	"pantry/internal/config"
+
 	"github.com/spf13/cobra"
 )
 
@@ -14,10 +16,24 @@ var rootCmd = &cobra.Command{
 Store, search, and retrieve decisions, patterns, bugs,
 and context across sessions.`,
 	Version: Version,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if err := validateColorMode(colorMode); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		config.SetOverrides(embeddingOverrides)
+	},
 }
 
+// embeddingOverrides holds the --embedding-* flags, which override config.yaml
+// and the PANTRY_EMBEDDING_* variables for one invocation.
+var embeddingOverrides config.Overrides
+
 // Execute runs the root command.
 func Execute() {
+	ignoreSIGPIPE()
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -25,7 +41,13 @@ func Execute() {
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVar(&embeddingOverrides.EmbeddingProvider, "embedding-provider", "", "Embedding provider for this run (overrides config and env)")
+	rootCmd.PersistentFlags().StringVar(&embeddingOverrides.EmbeddingModel, "embedding-model", "", "Embedding model for this run (overrides config and env)")
+	rootCmd.PersistentFlags().StringVar(&embeddingOverrides.EmbeddingBaseURL, "embedding-base-url", "", "Embedding API base URL for this run (overrides config and env)")
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", "auto", "Color output: auto (terminal without NO_COLOR), always or never")
+
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(homeCmd)
 	rootCmd.AddCommand(doctorCmd)
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(storeCmd)
@@ -33,10 +55,28 @@ func init() {
 	rootCmd.AddCommand(retrieveCmd)
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(removeCmd)
+	rootCmd.AddCommand(updateCmd)
 	rootCmd.AddCommand(notesCmd)
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(setupCmd)
 	rootCmd.AddCommand(uninstallCmd)
 	rootCmd.AddCommand(reindexCmd)
 	rootCmd.AddCommand(mcpCmd)
+	rootCmd.AddCommand(dumpSchemaCmd)
+	rootCmd.AddCommand(replayCmd)
+	rootCmd.AddCommand(linkCmd)
+	rootCmd.AddCommand(linksCmd)
+	rootCmd.AddCommand(sourcesCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(noteCmd)
+	rootCmd.AddCommand(gitSyncCmd)
+	rootCmd.AddCommand(tagsCmd)
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(checkpointCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(pruneVectorsCmd)
 }
@@ -1,21 +1,111 @@
 package cli
 
 import (
+	"bufio"
+	"cmp"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
 	"pantry/internal/core"
+	"pantry/internal/db"
+	"pantry/internal/mcp"
+	"pantry/internal/models"
+	"pantry/internal/search"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	searchLimit   int
-	searchProject bool
-	searchSource  string
+	searchLimit       int
+	searchProject     bool
+	searchProjectGlob string
+	searchSource      string
+	searchInteractive bool
+	searchTemplate    string
+	searchFacets      bool
+	searchWithin      string
+	searchJSON        bool
+	searchRecentBoost float64
+	searchAgentCtx    bool
+	searchMaxTokens   int
+	searchCountOnly   bool
+	searchMeta        []string
+	searchJSONStream  bool
+	searchFallback    string
+	searchBySource    bool
+	searchTimeout     time.Duration
+	searchNear        string
+	searchWindow      string
+	searchNearMode    string
+	searchOutputIDs   bool
+	searchFullIDs     bool
+	searchGroup       float64
+	searchExpand      bool
+	searchMinFTS      int
+	searchFields      []string
+	searchScoreFormat string
+	searchFormat      string
+	searchDedupBy     string
+	searchPretty      bool
+	searchSiblings    bool
+	searchProjectDir  string
+	searchCase        bool
+	searchProjStats   bool
 )
 
+// scoreFormats are the values accepted by search --score-format.
+var scoreFormats = []string{"decimal", "percent", "raw"}
+
+// searchFormats are the values accepted by search --format.
+var searchFormats = []string{"jsonl-mcp"}
+
+// unknownSource heads the --all-sources group of notes stored without a
+// source.
+const unknownSource = "(unknown)"
+
+// charsPerToken is the rough ratio used to keep --agent-context output within
+// its token budget without a tokenizer.
+const charsPerToken = 4
+
+// searchJSONResult is one result as printed by search --json.
+type searchJSONResult struct {
+	ID           string   `json:"id"`
+	Title        string   `json:"title"`
+	What         string   `json:"what"`
+	Why          *string  `json:"why,omitempty"`
+	Impact       *string  `json:"impact,omitempty"`
+	Category     *string  `json:"category,omitempty"`
+	Tags         []string `json:"tags"`
+	Project      string   `json:"project"`
+	Source       *string  `json:"source,omitempty"`
+	CreatedAt    string   `json:"created_at"`
+	UpdatedAt    string   `json:"updated_at,omitempty"`
+	Score        float64  `json:"score"`
+	Distance     *float64 `json:"distance,omitempty"`
+	FTSRank      *float64 `json:"fts_rank,omitempty"`
+	HasDetails   bool     `json:"has_details"`
+	Snippet      string   `json:"snippet,omitempty"`
+	CrossProject bool     `json:"cross_project,omitempty"`
+	// Similar lists the near-duplicates folded into this result by
+	// --group-threshold.
+	Similar []similarJSONResult `json:"similar,omitempty"`
+}
+
+// similarJSONResult is a near-duplicate folded into a search --json result.
+type similarJSONResult struct {
+	ID    string  `json:"id"`
+	Title string  `json:"title"`
+	Score float64 `json:"score"`
+}
+
 var searchCmd = &cobra.Command{
 	Use:   "search [query]",
 	Short: "Search pantry items",
@@ -24,6 +114,23 @@ var searchCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		query := args[0]
 
+		if searchLimit < 0 {
+			fmt.Fprintf(os.Stderr, "Error: --limit must not be negative\n")
+			os.Exit(1)
+		}
+
+		var tmpl *template.Template
+
+		if searchTemplate != "" {
+			var err error
+
+			tmpl, err = parseSearchTemplate(searchTemplate)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
 		svc, err := core.NewService("")
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -40,66 +147,920 @@ var searchCmd = &cobra.Command{
 			project = &projectName
 		}
 
+		if searchProjectDir != "" {
+			projectName := projectFromDir(searchProjectDir)
+			project = &projectName
+		}
+
 		var source *string
 		if searchSource != "" {
 			source = &searchSource
 		}
 
-		results, err := svc.Search(query, searchLimit, project, source, true)
+		var opts []core.SearchOption
+
+		if searchProjectGlob != "" {
+			if err := db.ValidateProjectGlob(searchProjectGlob); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			opts = append(opts, core.WithProjectGlob(searchProjectGlob))
+		}
+
+		metaOpts, err := metaSearchOptions(searchMeta)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 
-		if len(results) == 0 {
-			fmt.Println("No results found.")
+		opts = append(opts, metaOpts...)
+
+		// The global --embedding-model already switches the configured model;
+		// passing it on as well makes a dimension mismatch an error instead
+		// of a silent keyword-only search.
+		if model := embeddingOverrides.EmbeddingModel; model != "" {
+			if searchWithin != "" {
+				fmt.Fprintf(os.Stderr, "Error: --within cannot be combined with --embedding-model\n")
+				os.Exit(1)
+			}
+
+			opts = append(opts, core.WithEmbeddingModel(model))
+		}
+
+		if searchWithin != "" {
+			opts = append(opts, core.WithinAnchor(searchWithin))
+		}
+
+		switch searchFallback {
+		case "":
+		case "all":
+			if project == nil {
+				fmt.Fprintf(os.Stderr, "Error: --fallback all needs --project or --project-from\n")
+				os.Exit(1)
+			}
+
+			opts = append(opts, core.WithFallbackAll())
+		default:
+			fmt.Fprintf(os.Stderr, "Error: invalid --fallback %q: must be all\n", searchFallback)
+			os.Exit(1)
+		}
+
+		if cmd.Flags().Changed("recent-boost") {
+			if searchRecentBoost < 0 {
+				fmt.Fprintf(os.Stderr, "Error: --recent-boost must not be negative\n")
+				os.Exit(1)
+			}
+
+			opts = append(opts, core.WithRecencyHalfLife(searchRecentBoost))
+		}
+
+		nearOpt, err := nearSearchOption(searchNear, searchWindow, searchNearMode)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if nearOpt != nil {
+			opts = append(opts, nearOpt)
+		}
+
+		if cmd.Flags().Changed("min-fts") {
+			if searchMinFTS < 0 {
+				fmt.Fprintf(os.Stderr, "Error: --min-fts must not be negative\n")
+				os.Exit(1)
+			}
+
+			opts = append(opts, core.WithMinFTSResults(searchMinFTS))
+		}
+
+		if searchGroup < 0 || searchGroup > 1 {
+			fmt.Fprintf(os.Stderr, "Error: --group-threshold must be between 0 and 1\n")
+			os.Exit(1)
+		}
+
+		if searchGroup > 0 {
+			opts = append(opts, core.WithGroupThreshold(searchGroup))
+		}
+
+		if searchCase {
+			opts = append(opts, core.WithCaseSensitive())
+		}
+
+		if searchDedupBy != "" {
+			if !slices.Contains(search.DedupFields, searchDedupBy) {
+				fmt.Fprintf(os.Stderr, "Error: invalid --deduplicate-by %q: must be one of %s\n", searchDedupBy, strings.Join(search.DedupFields, ", "))
+				os.Exit(1)
+			}
+
+			opts = append(opts, core.WithDeduplicateBy(searchDedupBy))
+		}
+
+		if cmd.Flags().Changed("timeout") {
+			if searchTimeout < 0 {
+				fmt.Fprintf(os.Stderr, "Error: --timeout must not be negative\n")
+				os.Exit(1)
+			}
+
+			opts = append(opts, core.WithTimeout(searchTimeout))
+		}
+
+		if len(searchFields) > 0 {
+			if !searchJSON && !searchJSONStream {
+				fmt.Fprintf(os.Stderr, "Error: --fields-return needs --json or --json-stream\n")
+				os.Exit(1)
+			}
+
+			if err := validateSearchFields(searchFields); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if searchPretty && !searchJSON {
+			fmt.Fprintf(os.Stderr, "Error: --pretty needs --json\n")
+			os.Exit(1)
+		}
+
+		if !slices.Contains(scoreFormats, searchScoreFormat) {
+			fmt.Fprintf(os.Stderr, "Error: invalid --score-format %q: must be one of %s\n", searchScoreFormat, strings.Join(scoreFormats, ", "))
+			os.Exit(1)
+		}
+
+		if searchFormat != "" && !slices.Contains(searchFormats, searchFormat) {
+			fmt.Fprintf(os.Stderr, "Error: invalid --format %q: must be one of %s\n", searchFormat, strings.Join(searchFormats, ", "))
+			os.Exit(1)
+		}
+
+		if searchCountOnly {
+			n, err := svc.CountMatches(query, project, source, opts...)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Fprintln(stdout, n)
 
 			return
 		}
 
-		fmt.Printf("\n Results (%d found) \n\n", len(results))
+		ctx, stop := interruptContext()
+		defer stop()
+
+		results, err := svc.Search(query, searchLimit, project, source, true, append(opts, core.WithSearchContext(ctx))...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if searchLimit == 0 {
+			warnUnlimited(len(results))
+		}
+
+		var facets db.Facets
+
+		if searchFacets || searchBySource || searchProjStats {
+			facets, err = svc.Facets(query, project, source, opts...)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
 
-		for i, r := range results {
-			cat := ""
-			if r.Category != nil {
-				cat = *r.Category
+		if searchFormat == "jsonl-mcp" {
+			if err := streamSearchMCP(stdout, results); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
 			}
 
-			src := ""
-			if r.Source != nil {
-				src = *r.Source
+			return
+		}
+
+		if searchJSONStream {
+			if err := streamSearchJSON(stdout, results, searchFields); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
 			}
 
-			fmt.Printf(" [%d] %s (score: %.2f)\n", i+1, r.Title, r.Score)
-			fmt.Printf("     id: %s\n", r.ID)
-			fmt.Printf("     %s | %s | %s", cat, r.CreatedAt[:10], r.Project)
+			return
+		}
+
+		if searchOutputIDs {
+			printIDs(stdout, results, searchFullIDs)
+
+			return
+		}
+
+		if searchJSON {
+			if !searchFacets {
+				facets = nil
+			}
 
-			if src != "" {
-				fmt.Printf(" | %s", src)
+			if err := printSearchJSON(stdout, results, facets, searchFields, searchPretty); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
 			}
 
-			fmt.Println()
-			fmt.Printf("     What: %s\n", r.What)
+			return
+		}
 
-			if r.Why != nil {
-				fmt.Printf("     Why: %s\n", *r.Why)
+		if searchAgentCtx {
+			if err := renderAgentContext(stdout, query, results, searchMaxTokens); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
 			}
 
-			if r.Impact != nil {
-				fmt.Printf("     Impact: %s\n", *r.Impact)
+			return
+		}
+
+		if tmpl != nil {
+			if err := renderSearchTemplate(stdout, tmpl, results); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
 			}
 
-			if r.HasDetails {
-				fmt.Printf("     Details: available (use `pantry retrieve %s`)\n", r.ID)
+			printFacets(stdout, facets)
+
+			return
+		}
+
+		if len(results) == 0 {
+			// A failed lookup only costs the hint.
+			if suggestions, _ := svc.Suggest(query, project); len(suggestions) > 0 {
+				fmt.Fprintf(stdout, "No results. Did you mean: %s?\n", strings.Join(suggestions, ", "))
+			} else {
+				fmt.Fprintln(stdout, "No results found.")
 			}
 
-			fmt.Println()
+			return
+		}
+
+		if searchProjStats {
+			fmt.Fprintln(stdout)
+			printProjectStats(stdout, projectStats(results, facets))
+		}
+
+		fmt.Fprintf(stdout, "\n Results (%d found) \n\n", len(results))
+
+		if searchBySource {
+			printSourceGroups(stdout, results, facets)
+		} else {
+			for i, r := range results {
+				var siblings []models.Item
+
+				if searchSiblings {
+					siblings, err = svc.Siblings(r.ID)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+						os.Exit(1)
+					}
+				}
+
+				printSearchResult(stdout, i+1, r, searchExpand, searchScoreFormat, siblings)
+			}
+		}
+
+		if searchFacets {
+			printFacets(stdout, facets)
+		}
+
+		// Interactive selection only makes sense when a human is at the keyboard.
+		if searchInteractive && isTerminal(os.Stdin) && isTerminal(os.Stdout) {
+			promptRetrieve(os.Stdin, os.Stdout, results, svc.GetDetails)
 		}
 	},
 }
 
+// unlimitedWarnAt is the result count from which --limit 0 warns.
+const unlimitedWarnAt = 1000
+
+// warnUnlimited tells on stderr that a --limit 0 query loaded n results, when
+// that is enough to be slow or heavy on memory.
+func warnUnlimited(n int) {
+	if n >= unlimitedWarnAt {
+		fmt.Fprintf(os.Stderr, "warning: --limit 0 loaded %d notes; narrow the query or set a limit if this is slow\n", n)
+	}
+}
+
+// projectFromDir returns the project name for dir, the same way --project
+// derives it from the working directory. Relative paths are taken from the
+// working directory and trailing separators are ignored.
+func projectFromDir(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+
+	return filepath.Base(filepath.Clean(dir))
+}
+
+// printSearchResult writes one result of the default text output, numbered n.
+// With expand, notes folded into it by --group-threshold are listed too, and
+// siblings (see Service.Siblings) are listed under "Same file".
+// scoreFormat is one of scoreFormats; see formatScore.
+func printSearchResult(w io.Writer, n int, r models.SearchResult, expand bool, scoreFormat string, siblings []models.Item) {
+	cat := ""
+	if r.Category != nil {
+		cat = *r.Category
+	}
+
+	src := ""
+	if r.Source != nil {
+		src = *r.Source
+	}
+
+	fmt.Fprintf(w, " [%d] %s (%s)", n, r.Title, formatScore(r, scoreFormat))
+
+	if r.CrossProject {
+		fmt.Fprintf(w, " [other project]")
+	}
+
+	if len(r.Similar) > 0 {
+		fmt.Fprintf(w, " (+%d similar)", len(r.Similar))
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "     id: %s\n", r.ID)
+	fmt.Fprintf(w, "     %s | %s%s | %s", cat, r.CreatedAt[:10], updatedSuffix(r, time.DateOnly), r.Project)
+
+	if src != "" {
+		fmt.Fprintf(w, " | %s", src)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "     What: %s\n", r.What)
+
+	if r.Why != nil {
+		fmt.Fprintf(w, "     Why: %s\n", *r.Why)
+	}
+
+	if r.Impact != nil {
+		fmt.Fprintf(w, "     Impact: %s\n", *r.Impact)
+	}
+
+	if r.HasDetails {
+		fmt.Fprintf(w, "     Details: available (use `pantry retrieve %s`)\n", r.ID)
+	}
+
+	if expand {
+		for _, sim := range r.Similar {
+			fmt.Fprintf(w, "     ~ %s %s (%s)\n", shortID(sim.ID), sim.Title, formatScore(sim, scoreFormat))
+		}
+	}
+
+	if len(siblings) > 0 {
+		fmt.Fprintf(w, "     Same file:\n")
+
+		for _, sib := range siblings {
+			fmt.Fprintf(w, "       - %s %s\n", shortID(sib.ID), sib.Title)
+		}
+	}
+
+	fmt.Fprintln(w)
+}
+
+// updatedSuffix returns " (updated <date>)" with the date in layout for a
+// revised note, and "" otherwise.
+func updatedSuffix(r models.SearchResult, layout string) string {
+	if !r.Revised() {
+		return ""
+	}
+
+	t, err := time.Parse(time.RFC3339, r.UpdatedAt)
+	if err != nil {
+		return ""
+	}
+
+	return " (updated " + t.Format(layout) + ")"
+}
+
+// sourceGroup is the results from one source, in rank order.
+type sourceGroup struct {
+	Source  string
+	Results []models.SearchResult
+}
+
+// groupBySource splits results by source, ordering the groups by their best
+// ranked result. Notes without a source go under unknownSource.
+func groupBySource(results []models.SearchResult) []sourceGroup {
+	var groups []sourceGroup
+
+	index := make(map[string]int)
+
+	for _, r := range results {
+		src := unknownSource
+		if r.Source != nil && *r.Source != "" {
+			src = *r.Source
+		}
+
+		i, ok := index[src]
+		if !ok {
+			i = len(groups)
+			index[src] = i
+			groups = append(groups, sourceGroup{Source: src})
+		}
+
+		groups[i].Results = append(groups[i].Results, r)
+	}
+
+	return groups
+}
+
+// formatScore renders a result's score for text output: decimal as
+// "score: 0.95", percent as "score: 95%", and raw as the unnormalized FTS5
+// rank and vector distance behind it, for debugging ranking. A result with
+// neither raw value falls back to decimal.
+func formatScore(r models.SearchResult, format string) string {
+	switch format {
+	case "percent":
+		return fmt.Sprintf("score: %.0f%%", r.Score*100)
+	case "raw":
+		var parts []string
+
+		if r.FTSRank != nil {
+			parts = append(parts, fmt.Sprintf("rank: %.4f", *r.FTSRank))
+		}
+
+		if r.Distance != nil {
+			parts = append(parts, fmt.Sprintf("distance: %.4f", *r.Distance))
+		}
+
+		if len(parts) > 0 {
+			return strings.Join(parts, ", ")
+		}
+	}
+
+	return fmt.Sprintf("score: %.2f", r.Score)
+}
+
+// printSourceGroups writes results grouped by source, each under a header
+// with the source's count across all keyword matches from facets. Results
+// keep their overall rank numbers.
+func printSourceGroups(w io.Writer, results []models.SearchResult, facets db.Facets) {
+	matches := make(map[string]int64)
+
+	for _, fc := range facets["source"] {
+		src := fc.Value
+		if src == "" {
+			src = unknownSource
+		}
+
+		matches[src] += fc.Count
+	}
+
+	rank := make(map[string]int, len(results))
+	for i, r := range results {
+		rank[r.ID] = i + 1
+	}
+
+	for _, g := range groupBySource(results) {
+		fmt.Fprintf(w, " == %s: %d shown, %d keyword matches ==\n\n", g.Source, len(g.Results), matches[g.Source])
+
+		for _, r := range g.Results {
+			printSearchResult(w, rank[r.ID], r, searchExpand, searchScoreFormat, nil)
+		}
+	}
+}
+
+// projectStat is one row of search --project-stats.
+type projectStat struct {
+	Project string
+	Shown   int
+	Matches int64
+}
+
+// projectStats counts results per project next to each project's count
+// across all keyword matches from facets. Projects with keyword matches but
+// no result shown are included. Rows are ordered by results shown, then
+// keyword matches, then name.
+func projectStats(results []models.SearchResult, facets db.Facets) []projectStat {
+	index := make(map[string]int)
+
+	var stats []projectStat
+
+	row := func(project string) *projectStat {
+		i, ok := index[project]
+		if !ok {
+			i = len(stats)
+			index[project] = i
+			stats = append(stats, projectStat{Project: project})
+		}
+
+		return &stats[i]
+	}
+
+	for _, r := range results {
+		row(r.Project).Shown++
+	}
+
+	for _, fc := range facets["project"] {
+		row(fc.Value).Matches += fc.Count
+	}
+
+	slices.SortStableFunc(stats, func(a, b projectStat) int {
+		if a.Shown != b.Shown {
+			return b.Shown - a.Shown
+		}
+
+		if a.Matches != b.Matches {
+			return cmp.Compare(b.Matches, a.Matches)
+		}
+
+		return strings.Compare(a.Project, b.Project)
+	})
+
+	return stats
+}
+
+// printProjectStats writes stats as a table headed by the column names.
+func printProjectStats(w io.Writer, stats []projectStat) {
+	rows := [][]string{{"   PROJECT", "SHOWN", "KEYWORD MATCHES"}}
+
+	for _, st := range stats {
+		rows = append(rows, []string{"   " + st.Project, strconv.Itoa(st.Shown), strconv.FormatInt(st.Matches, 10)})
+	}
+
+	fmt.Fprintf(w, " Projects\n")
+	printColumns(w, rows)
+}
+
+// printSearchJSON writes results as a JSON array on one line, or indented
+// with pretty. Keyword matches carry a snippet with the matched terms wrapped
+// in db.SnippetStart/SnippetEnd. With fields, each result has only those keys.
+// With facets (--facets), the array becomes the results of an object that
+// also holds them: {"results": [...], "facets": {...}}.
+func printSearchJSON(w io.Writer, results []models.SearchResult, facets db.Facets, fields []string, pretty bool) error {
+	out := make([]any, len(results))
+
+	for i, r := range results {
+		v, err := searchJSONValue(r, fields)
+		if err != nil {
+			return err
+		}
+
+		out[i] = v
+	}
+
+	var doc any = out
+	if facets != nil {
+		doc = struct {
+			Results []any     `json:"results"`
+			Facets  db.Facets `json:"facets"`
+		}{out, facets}
+	}
+
+	if err := writeJSON(w, doc, pretty); err != nil {
+		return fmt.Errorf("failed to marshal results: %w", err)
+	}
+
+	return nil
+}
+
+// writeJSON writes v as JSON followed by a newline: on one line for scripts,
+// or indented by two spaces with pretty. Commands printing a JSON document
+// share it so --json and --pretty mean the same everywhere.
+func writeJSON(w io.Writer, v any, pretty bool) error {
+	enc := json.NewEncoder(w)
+	if pretty {
+		enc.SetIndent("", "  ")
+	}
+
+	return enc.Encode(v)
+}
+
+// printIDs writes one result ID per line and nothing else, for piping into
+// xargs. Unless full is set the IDs are shortened as in compact output.
+func printIDs(w io.Writer, results []models.SearchResult, full bool) {
+	for _, r := range results {
+		id := r.ID
+		if !full {
+			id = shortID(id)
+		}
+
+		fmt.Fprintln(w, id)
+	}
+}
+
+// streamSearchJSON writes results as NDJSON: one compact JSON object per line,
+// each written as soon as it is encoded. With fields, each result has only
+// those keys.
+func streamSearchJSON(w io.Writer, results []models.SearchResult, fields []string) error {
+	enc := json.NewEncoder(w)
+
+	for _, r := range results {
+		v, err := searchJSONValue(r, fields)
+		if err != nil {
+			return err
+		}
+
+		if err := enc.Encode(v); err != nil {
+			return fmt.Errorf("failed to write result %s: %w", r.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// streamSearchMCP writes results one JSON object per line, each exactly as
+// the pantry_search MCP tool returns it.
+func streamSearchMCP(w io.Writer, results []models.SearchResult) error {
+	enc := json.NewEncoder(w)
+
+	for _, r := range results {
+		if err := enc.Encode(mcp.SearchResultMap(r, nil)); err != nil {
+			return fmt.Errorf("failed to write result %s: %w", r.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// searchJSONFields are the keys of a search --json result, in output order,
+// as accepted by --fields-return.
+var searchJSONFields = []string{
+	"id", "title", "what", "why", "impact", "category", "tags", "project", "source",
+	"created_at", "updated_at", "score", "distance", "fts_rank", "has_details", "snippet", "cross_project", "similar",
+}
+
+// validateSearchFields checks that every name in fields is a search --json key.
+func validateSearchFields(fields []string) error {
+	for _, f := range fields {
+		if !slices.Contains(searchJSONFields, f) {
+			return fmt.Errorf("unknown field %q: must be one of %s", f, strings.Join(searchJSONFields, ", "))
+		}
+	}
+
+	return nil
+}
+
+// searchJSONValue returns r in its JSON output form, trimmed to fields when
+// any are given, with the keys in the order requested. A requested field that
+// is empty for r is left out, as it would be in the full form.
+func searchJSONValue(r models.SearchResult, fields []string) (any, error) {
+	full := newSearchJSONResult(r)
+	if len(fields) == 0 {
+		return full, nil
+	}
+
+	data, err := json.Marshal(full)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result %s: %w", r.ID, err)
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("failed to marshal result %s: %w", r.ID, err)
+	}
+
+	// Built by hand: a map would marshal with its keys sorted.
+	out := []byte{'{'}
+
+	for _, f := range fields {
+		v, ok := all[f]
+		if !ok {
+			continue
+		}
+
+		// A field named twice is written once.
+		delete(all, f)
+
+		if len(out) > 1 {
+			out = append(out, ',')
+		}
+
+		out = strconv.AppendQuote(out, f)
+		out = append(out, ':')
+		out = append(out, v...)
+	}
+
+	return json.RawMessage(append(out, '}')), nil
+}
+
+// newSearchJSONResult converts a result to its JSON output form.
+func newSearchJSONResult(r models.SearchResult) searchJSONResult {
+	return searchJSONResult{
+		ID:           r.ID,
+		Title:        r.Title,
+		What:         r.What,
+		Why:          r.Why,
+		Impact:       r.Impact,
+		Category:     r.Category,
+		Tags:         r.Tags,
+		Project:      r.Project,
+		Source:       r.Source,
+		CreatedAt:    r.CreatedAt,
+		UpdatedAt:    r.UpdatedAt,
+		Score:        r.Score,
+		Distance:     r.Distance,
+		FTSRank:      r.FTSRank,
+		HasDetails:   r.HasDetails,
+		Snippet:      r.Snippet,
+		CrossProject: r.CrossProject,
+		Similar:      similarJSON(r.Similar),
+	}
+}
+
+func similarJSON(similar []models.SearchResult) []similarJSONResult {
+	if len(similar) == 0 {
+		return nil
+	}
+
+	out := make([]similarJSONResult, len(similar))
+	for i, r := range similar {
+		out[i] = similarJSONResult{ID: r.ID, Title: r.Title, Score: r.Score}
+	}
+
+	return out
+}
+
+// renderAgentContext writes results as a compact markdown block meant to be
+// pasted into a system prompt. Notes are added in rank order while the block
+// stays within maxTokens (estimated at charsPerToken characters per token);
+// the rest are counted in a trailing line.
+func renderAgentContext(w io.Writer, query string, results []models.SearchResult, maxTokens int) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "## Pantry notes for %q\n", oneLine(query))
+
+	budget := maxTokens * charsPerToken
+	omitted := 0
+
+	for _, r := range results {
+		entry := agentContextEntry(r)
+		if omitted > 0 || b.Len()+len(entry) > budget {
+			omitted++
+
+			continue
+		}
+
+		b.WriteString(entry)
+	}
+
+	if len(results) == 0 {
+		b.WriteString("\nNo matching notes.\n")
+	} else if omitted > 0 {
+		fmt.Fprintf(&b, "\n_%d more notes omitted to fit the token budget._\n", omitted)
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// agentContextEntry renders one note as a markdown section for
+// renderAgentContext.
+func agentContextEntry(r models.SearchResult) string {
+	var b strings.Builder
+
+	meta := []string{r.Project, r.CreatedAt[:min(10, len(r.CreatedAt))]}
+	if r.Category != nil {
+		meta = append([]string{*r.Category}, meta...)
+	}
+
+	fmt.Fprintf(&b, "\n### %s (%s)\n\n", oneLine(r.Title), strings.Join(meta, ", "))
+	fmt.Fprintf(&b, "%s\n", strings.TrimSpace(r.What))
+
+	var bullets []string
+	if r.Why != nil {
+		bullets = append(bullets, "- Why: "+oneLine(*r.Why))
+	}
+
+	if r.Impact != nil {
+		bullets = append(bullets, "- Impact: "+oneLine(*r.Impact))
+	}
+
+	if len(bullets) > 0 {
+		fmt.Fprintf(&b, "\n%s\n", strings.Join(bullets, "\n"))
+	}
+
+	return b.String()
+}
+
+// oneLine collapses runs of whitespace, including newlines, to single spaces.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// printFacets writes a compact table of facet counts, one line per facet field.
+// Empty values are shown as "(none)". Nothing is printed for nil facets.
+func printFacets(w io.Writer, facets db.Facets) {
+	if facets == nil {
+		return
+	}
+
+	fmt.Fprintf(w, " Facets (all keyword matches)\n")
+
+	for _, field := range db.FacetFields {
+		parts := make([]string, 0, len(facets[field]))
+
+		for _, fc := range facets[field] {
+			value := fc.Value
+			if value == "" {
+				value = "(none)"
+			}
+
+			parts = append(parts, fmt.Sprintf("%s %d", value, fc.Count))
+		}
+
+		if len(parts) == 0 {
+			parts = append(parts, "-")
+		}
+
+		fmt.Fprintf(w, "   %-9s %s\n", field+":", strings.Join(parts, ", "))
+	}
+
+	fmt.Fprintln(w)
+}
+
+// promptRetrieve repeatedly asks the user to pick a numbered result and prints
+// its details, until the user enters "q", an empty line, or input ends.
+func promptRetrieve(in io.Reader, out io.Writer, results []models.SearchResult, getDetails func(string) (*models.ItemDetail, error)) {
+	scanner := bufio.NewScanner(in)
+
+	for {
+		fmt.Fprintf(out, "Enter number to view details (q to quit): ")
+
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+
+			return
+		}
+
+		input := strings.TrimSpace(scanner.Text())
+		if input == "" || strings.EqualFold(input, "q") {
+			return
+		}
+
+		n, err := strconv.Atoi(input)
+		if err != nil || n < 1 || n > len(results) {
+			fmt.Fprintf(out, "Invalid selection %q: enter a number between 1 and %d\n", input, len(results))
+
+			continue
+		}
+
+		r := results[n-1]
+
+		detail, err := getDetails(r.ID)
+		if err != nil {
+			fmt.Fprintf(out, "Error: %v\n", err)
+
+			continue
+		}
+
+		fmt.Fprintf(out, "\n--- %s ---\n", r.Title)
+
+		if detail == nil {
+			fmt.Fprintf(out, "No details found for note %s\n\n", r.ID)
+
+			continue
+		}
+
+		fmt.Fprintf(out, "%s\n\n", detail.Body)
+	}
+}
+
 func init() {
-	searchCmd.Flags().IntVarP(&searchLimit, "limit", "n", 5, "Maximum number of results")
+	searchCmd.Flags().IntVarP(&searchLimit, "limit", "n", 5, "Maximum number of results; 0 returns every keyword match")
 	searchCmd.Flags().BoolVarP(&searchProject, "project", "p", false, "Filter to current project")
 	searchCmd.Flags().StringVarP(&searchSource, "source", "s", "", "Filter by source")
+	searchCmd.Flags().StringVar(&searchProjectDir, "project-from", "", "Filter to the project of this directory, named as --project would name it from there")
+	searchCmd.Flags().StringVar(&searchProjectGlob, "project-glob", "", "Filter to projects matching a glob (e.g. 'acme-*')")
+	searchCmd.Flags().StringArrayVar(&searchMeta, "meta", nil, "Filter by metadata key=value (repeatable; all must match)")
+	searchCmd.Flags().StringVar(&searchTemplate, "output-template", "", "Go template applied to each result, or a preset (compact, full)")
+	searchCmd.Flags().StringVar(&searchFallback, "fallback", "", "With --project, fill sparse results from other projects: all")
+	searchCmd.Flags().StringVar(&searchWithin, "within", "", "Search semantically near the note with this ID (or a unique prefix), refined by the query")
+	searchCmd.Flags().Float64Var(&searchRecentBoost, "recent-boost", 0, "Halve scores for every this many days of a note's age; 0 turns it off (default from config)")
+	searchCmd.Flags().StringVar(&searchNear, "near", "", "Favour notes created around this date (YYYY-MM, YYYY-MM-DD or RFC3339)")
+	searchCmd.Flags().StringVar(&searchWindow, "window", "14d", "Time window for --near, e.g. 14d, 2w or 36h")
+	searchCmd.Flags().StringVar(&searchNearMode, "near-mode", "boost", "How --near applies: boost (rank closer dates higher) or restrict (only notes inside the window)")
+	searchCmd.Flags().IntVar(&searchMinFTS, "min-fts", search.DefaultMinFTSResults, "Embed the query for a hybrid search only when fewer keyword matches than this are found; 0 = keyword only")
+	searchCmd.Flags().DurationVar(&searchTimeout, "timeout", 0, "Return keyword results only if embedding the query takes longer than this, e.g. 2s; 0 = no limit (default from config)")
+	searchCmd.Flags().BoolVar(&searchJSON, "json", false, "Print results as compact JSON, with a highlighted snippet for keyword matches")
+	searchCmd.Flags().BoolVar(&searchPretty, "pretty", false, "With --json, indent the output for reading")
+	searchCmd.Flags().StringSliceVar(&searchFields, "fields-return", nil, "With --json or --json-stream, include only these result fields (e.g. id,title,score)")
+	searchCmd.Flags().BoolVar(&searchAgentCtx, "agent-context", false, "Print results as a compact markdown block for pasting into a prompt")
+	searchCmd.Flags().IntVar(&searchMaxTokens, "max-tokens", 800, "Approximate token budget for --agent-context output")
+	searchCmd.Flags().BoolVar(&searchJSONStream, "json-stream", false, "Print results as NDJSON, one JSON object per line")
+	searchCmd.Flags().StringVar(&searchFormat, "format", "", "Output format: jsonl-mcp prints each result exactly as the pantry_search MCP tool returns it, one per line")
+	searchCmd.Flags().Float64Var(&searchGroup, "group-threshold", 0, "Collapse results whose title and what overlap by at least this much (0..1) into one, shown with \"+N similar\"; 0 = off")
+	searchCmd.Flags().StringVar(&searchDedupBy, "deduplicate-by", "", "Keep only the best-ranked result for each distinct title or what")
+	searchCmd.Flags().BoolVar(&searchCase, "case-sensitive", false, "Only results holding the query terms in the case typed, e.g. API but not api")
+	searchCmd.Flags().BoolVar(&searchSiblings, "siblings", false, "Under each result, list the other notes in the same notes file (same project and day)")
+	searchCmd.Flags().BoolVar(&searchExpand, "expand-similar", false, "With --group-threshold, list the collapsed notes under each result")
+	searchCmd.Flags().StringVar(&searchScoreFormat, "score-format", "decimal", "How scores are shown: decimal, percent, or raw (FTS rank and vector distance)")
+	searchCmd.Flags().BoolVar(&searchOutputIDs, "output-ids", false, "Print only the matching note IDs, one per line")
+	searchCmd.Flags().BoolVar(&searchFullIDs, "full-ids", true, "With --output-ids, print full IDs; --full-ids=false prints short ones")
+	searchCmd.Flags().BoolVar(&searchCountOnly, "count-only", false, "Print only the number of keyword matches")
+	searchCmd.Flags().BoolVar(&searchFacets, "facets", false, "Also print category, source and project counts across all keyword matches; with --json, as {\"results\": [...], \"facets\": {...}}")
+	searchCmd.Flags().BoolVar(&searchProjStats, "project-stats", false, "Above the results, count them per project next to each project's keyword match count")
+	searchCmd.Flags().BoolVar(&searchBySource, "all-sources", false, "Group results by source, with each source's keyword match count")
+	searchCmd.Flags().BoolVar(&searchInteractive, "interactive-retrieve", false, "Prompt to view details of a result (TTY only)")
+
+	searchCmd.MarkFlagsMutuallyExclusive("json", "json-stream", "output-template", "agent-context", "count-only", "all-sources", "output-ids", "format")
+	// These print results in a form with no place for the facets.
+	searchCmd.MarkFlagsMutuallyExclusive("facets", "json-stream", "agent-context", "count-only", "output-ids", "format")
+	searchCmd.MarkFlagsMutuallyExclusive("all-sources", "source")
+	searchCmd.MarkFlagsMutuallyExclusive("siblings", "all-sources")
+	searchCmd.MarkFlagsMutuallyExclusive("project", "project-from")
+	searchCmd.MarkFlagsMutuallyExclusive("case-sensitive", "count-only")
+	searchCmd.MarkFlagsMutuallyExclusive("project-stats", "json", "json-stream", "output-template", "agent-context", "count-only", "output-ids", "format")
 }
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"pantry/internal/models"
+)
+
+// searchTemplatePresets are named templates accepted by --output-template.
+var searchTemplatePresets = map[string]string{
+	"compact": `{{short .ID}} {{.Title}} ({{printf "%.2f" .Score}})`,
+	"full": `{{short .ID}} {{.Title}} ({{printf "%.2f" .Score}})` +
+		` | {{str .Category}} | {{date .CreatedAt}} | {{.Project}} | {{str .Source}}` +
+		` | {{join .Tags ","}} | {{.What}}`,
+}
+
+// searchTemplateFuncs are helpers available inside --output-template.
+var searchTemplateFuncs = template.FuncMap{
+	"short": shortID,
+	"date": func(ts string) string {
+		if len(ts) > 10 {
+			return ts[:10]
+		}
+
+		return ts
+	},
+	"str": func(s *string) string {
+		if s == nil {
+			return ""
+		}
+
+		return *s
+	},
+	"join": strings.Join,
+}
+
+// parseSearchTemplate parses a preset name or a raw Go template applied to each
+// models.SearchResult.
+func parseSearchTemplate(text string) (*template.Template, error) {
+	if preset, ok := searchTemplatePresets[text]; ok {
+		text = preset
+	}
+
+	tmpl, err := template.New("search").Funcs(searchTemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// renderSearchTemplate executes tmpl once per result, one line each.
+func renderSearchTemplate(w io.Writer, tmpl *template.Template, results []models.SearchResult) error {
+	for _, r := range results {
+		if err := tmpl.Execute(w, r); err != nil {
+			return fmt.Errorf("failed to render result %s: %w", r.ID, err)
+		}
+
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,506 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"pantry/internal/core"
+	"pantry/internal/db"
+	"pantry/internal/mcp"
+	"pantry/internal/models"
+)
+
+func testSearchResult() models.SearchResult {
+	cat := "decision"
+
+	return models.SearchResult{
+		ID:        "0123456789abcdef",
+		Title:     "Use JWT auth",
+		What:      "Replaced sessions with JWT",
+		Category:  &cat,
+		Tags:      []string{"auth", "jwt"},
+		Project:   "api",
+		Score:     0.953,
+		CreatedAt: "2026-01-02T03:04:05Z",
+	}
+}
+
+func TestRenderSearchTemplate_Custom(t *testing.T) {
+	tmpl, err := parseSearchTemplate(`{{.Title}} ({{printf "%.2f" .Score}}) {{.Project}} {{str .Category}} {{str .Source}}|`)
+	if err != nil {
+		t.Fatalf("parseSearchTemplate() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := renderSearchTemplate(&buf, tmpl, []models.SearchResult{testSearchResult()}); err != nil {
+		t.Fatalf("renderSearchTemplate() error = %v", err)
+	}
+
+	want := "Use JWT auth (0.95) api decision |\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRenderSearchTemplate_Presets(t *testing.T) {
+	tests := map[string]string{
+		"compact": "01234567 Use JWT auth (0.95)\n",
+		"full":    "01234567 Use JWT auth (0.95) | decision | 2026-01-02 | api |  | auth,jwt | Replaced sessions with JWT\n",
+	}
+
+	for preset, want := range tests {
+		tmpl, err := parseSearchTemplate(preset)
+		if err != nil {
+			t.Fatalf("parseSearchTemplate(%s) error = %v", preset, err)
+		}
+
+		var buf bytes.Buffer
+		if err := renderSearchTemplate(&buf, tmpl, []models.SearchResult{testSearchResult()}); err != nil {
+			t.Fatalf("renderSearchTemplate(%s) error = %v", preset, err)
+		}
+
+		if buf.String() != want {
+			t.Errorf("%s output = %q, want %q", preset, buf.String(), want)
+		}
+	}
+}
+
+func TestParseSearchTemplate_Invalid(t *testing.T) {
+	if _, err := parseSearchTemplate("{{.Title"); err == nil {
+		t.Error("parseSearchTemplate() should fail on malformed template")
+	}
+}
+
+func TestPrintFacets(t *testing.T) {
+	facets := db.Facets{
+		"category": {{Value: "bug", Count: 3}, {Value: "", Count: 1}},
+		"project":  {{Value: "api", Count: 4}},
+	}
+
+	var buf bytes.Buffer
+	printFacets(&buf, facets)
+
+	want := " Facets (all keyword matches)\n" +
+		"   category: bug 3, (none) 1\n" +
+		"   source:   -\n" +
+		"   project:  api 4\n\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	printFacets(&buf, nil)
+
+	if buf.Len() != 0 {
+		t.Errorf("nil facets should print nothing, got %q", buf.String())
+	}
+}
+
+func TestRenderAgentContext_FitsBudget(t *testing.T) {
+	why := "Stateless API\nacross regions"
+
+	results := make([]models.SearchResult, 10)
+	for i := range results {
+		results[i] = testSearchResult()
+		results[i].Why = &why
+	}
+
+	const maxTokens = 60
+
+	var buf bytes.Buffer
+	if err := renderAgentContext(&buf, "auth", results, maxTokens); err != nil {
+		t.Fatalf("renderAgentContext() error = %v", err)
+	}
+
+	out := buf.String()
+
+	if !strings.HasPrefix(out, "## Pantry notes for \"auth\"\n") {
+		t.Errorf("output missing heading:\n%s", out)
+	}
+
+	body, _, _ := strings.Cut(out, "\n_")
+	if len(body) > maxTokens*charsPerToken {
+		t.Errorf("output is %d chars, budget is %d:\n%s", len(body), maxTokens*charsPerToken, out)
+	}
+
+	included := strings.Count(out, "\n### Use JWT auth (decision, api, 2026-01-02)\n\nReplaced sessions with JWT\n\n- Why: Stateless API across regions\n")
+	if included == 0 || included == len(results) {
+		t.Errorf("included %d of %d notes, want a budget-limited subset:\n%s", included, len(results), out)
+	}
+
+	if want := fmt.Sprintf("_%d more notes omitted to fit the token budget._\n", len(results)-included); !strings.HasSuffix(out, want) {
+		t.Errorf("output missing trailer %q:\n%s", want, out)
+	}
+}
+
+func TestStreamSearchJSON_OneObjectPerLine(t *testing.T) {
+	results := []models.SearchResult{testSearchResult(), testSearchResult()}
+	results[1].ID = "fedcba9876543210"
+	results[1].Snippet = "uses **JWT**"
+
+	var buf bytes.Buffer
+	if err := streamSearchJSON(&buf, results, nil); err != nil {
+		t.Fatalf("streamSearchJSON() error = %v", err)
+	}
+
+	scanner := bufio.NewScanner(&buf)
+
+	var ids []string
+
+	for scanner.Scan() {
+		var r searchJSONResult
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("line %d is not valid JSON: %v\n%s", len(ids)+1, err, scanner.Text())
+		}
+
+		ids = append(ids, r.ID)
+	}
+
+	if want := []string{"0123456789abcdef", "fedcba9876543210"}; !slices.Equal(ids, want) {
+		t.Errorf("streamed IDs = %v, want %v", ids, want)
+	}
+}
+
+func TestStreamSearchMCP_MatchesMCPTool(t *testing.T) {
+	svc, err := core.NewService(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	defer func() { _ = svc.Close() }()
+
+	details := "Tokens live for 15 minutes"
+	if _, err := svc.Store(models.RawItemInput{Title: "Use JWT auth", What: "Replaced sessions with JWT", Tags: []string{"auth"}, Details: &details}, "api"); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	if _, err := svc.Store(models.RawItemInput{Title: "Rotate JWT keys", What: "Keys rotate weekly"}, "api"); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	results, err := svc.Search("JWT", 5, nil, nil, true)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	var cli bytes.Buffer
+	if err := streamSearchMCP(&cli, results); err != nil {
+		t.Fatalf("streamSearchMCP() error = %v", err)
+	}
+
+	tool, err := mcp.HandlePantrySearch(svc, map[string]any{"query": "JWT", "limit": float64(5)})
+	if err != nil {
+		t.Fatalf("HandlePantrySearch() error = %v", err)
+	}
+
+	var want bytes.Buffer
+
+	enc := json.NewEncoder(&want)
+	for _, r := range tool {
+		if err := enc.Encode(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(tool) != 2 || cli.String() != want.String() {
+		t.Errorf("CLI output differs from pantry_search:\ncli:\n%s\nmcp:\n%s", cli.String(), want.String())
+	}
+}
+
+func TestPrintSearchJSON_FieldsReturn(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printSearchJSON(&buf, []models.SearchResult{testSearchResult()}, nil, []string{"title", "id", "title"}, false); err != nil {
+		t.Fatalf("printSearchJSON() error = %v", err)
+	}
+
+	if !strings.HasPrefix(buf.String(), `[{"title":`) {
+		t.Errorf("output = %s, want the fields in the requested order", buf.String())
+	}
+
+	var out []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	if len(out) != 1 {
+		t.Fatalf("got %d results, want 1", len(out))
+	}
+
+	keys := slices.Sorted(maps.Keys(out[0]))
+	if want := []string{"id", "title"}; !slices.Equal(keys, want) {
+		t.Errorf("result keys = %v, want %v", keys, want)
+	}
+}
+
+func TestPrintSearchJSON_CompactAndPretty(t *testing.T) {
+	results := []models.SearchResult{testSearchResult(), testSearchResult()}
+	results[1].ID = "fedcba9876543210"
+
+	var compact, pretty bytes.Buffer
+
+	if err := printSearchJSON(&compact, results, nil, nil, false); err != nil {
+		t.Fatalf("printSearchJSON(compact) error = %v", err)
+	}
+
+	if err := printSearchJSON(&pretty, results, nil, nil, true); err != nil {
+		t.Fatalf("printSearchJSON(pretty) error = %v", err)
+	}
+
+	if lines := strings.Count(compact.String(), "\n"); lines != 1 || !strings.HasPrefix(compact.String(), "[{\"id\":") {
+		t.Errorf("compact output spans %d lines, want one:\n%s", lines, compact.String())
+	}
+
+	if !strings.HasPrefix(pretty.String(), "[\n  {\n    \"id\": ") {
+		t.Errorf("pretty output is not indented:\n%s", pretty.String())
+	}
+
+	var a, b []map[string]any
+	if err := json.Unmarshal(compact.Bytes(), &a); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(pretty.Bytes(), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(a) != fmt.Sprint(b) {
+		t.Errorf("compact and pretty output differ in content:\n%v\n%v", a, b)
+	}
+}
+
+func TestPrintSearchJSON_Facets(t *testing.T) {
+	facets := db.Facets{
+		"category": {{Value: "bug", Count: 3}},
+		"source":   {{Value: "claude-code", Count: 2}, {Value: "", Count: 1}},
+		"project":  {{Value: "api", Count: 3}},
+	}
+
+	var buf bytes.Buffer
+	if err := printSearchJSON(&buf, []models.SearchResult{testSearchResult()}, facets, []string{"id"}, false); err != nil {
+		t.Fatalf("printSearchJSON() error = %v", err)
+	}
+
+	var out struct {
+		Results []map[string]any `json:"results"`
+		Facets  db.Facets        `json:"facets"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("output is not a results and facets object: %v\n%s", err, buf.String())
+	}
+
+	if len(out.Results) != 1 || out.Results[0]["id"] != testSearchResult().ID {
+		t.Errorf("results = %v, want the one result", out.Results)
+	}
+
+	if !maps.EqualFunc(out.Facets, facets, slices.Equal[[]db.FacetCount]) {
+		t.Errorf("facets = %v, want %v", out.Facets, facets)
+	}
+}
+
+func TestValidateSearchFields(t *testing.T) {
+	if err := validateSearchFields([]string{"id", "title", "score"}); err != nil {
+		t.Errorf("validateSearchFields(valid) error = %v", err)
+	}
+
+	if err := validateSearchFields([]string{"id", "body"}); err == nil {
+		t.Error("validateSearchFields(body) = nil, want an error")
+	}
+}
+
+func TestFormatScore(t *testing.T) {
+	r := testSearchResult()
+	r.Score = 0.951
+
+	rank, dist := -4.25, 0.3
+	raw := r
+	raw.FTSRank, raw.Distance = &rank, &dist
+
+	tests := []struct {
+		format string
+		r      models.SearchResult
+		want   string
+	}{
+		{"decimal", r, "score: 0.95"},
+		{"percent", r, "score: 95%"},
+		{"raw", r, "score: 0.95"},
+		{"raw", raw, "rank: -4.2500, distance: 0.3000"},
+	}
+
+	for _, tt := range tests {
+		if got := formatScore(tt.r, tt.format); got != tt.want {
+			t.Errorf("formatScore(%s) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestPrintIDs_OnlyIDs(t *testing.T) {
+	results := []models.SearchResult{testSearchResult(), testSearchResult()}
+	results[1].ID = "fedcba9876543210"
+
+	var buf bytes.Buffer
+
+	printIDs(&buf, results, true)
+
+	if got, want := buf.String(), "0123456789abcdef\nfedcba9876543210\n"; got != want {
+		t.Errorf("printIDs(full) = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	printIDs(&buf, results, false)
+
+	if got, want := buf.String(), "01234567\nfedcba98\n"; got != want {
+		t.Errorf("printIDs(short) = %q, want %q", got, want)
+	}
+}
+
+func TestGroupBySource(t *testing.T) {
+	claude, cursor := "claude-code", "cursor"
+
+	results := make([]models.SearchResult, 4)
+	for i, src := range []*string{&claude, nil, &cursor, &claude} {
+		results[i] = testSearchResult()
+		results[i].ID = fmt.Sprintf("id-%d", i)
+		results[i].Source = src
+	}
+
+	groups := groupBySource(results)
+
+	var got []string
+	for _, g := range groups {
+		ids := make([]string, len(g.Results))
+		for i, r := range g.Results {
+			ids[i] = r.ID
+		}
+
+		got = append(got, g.Source+": "+strings.Join(ids, ","))
+	}
+
+	want := []string{"claude-code: id-0,id-3", "(unknown): id-1", "cursor: id-2"}
+	if !slices.Equal(got, want) {
+		t.Errorf("groupBySource() = %q, want %q", got, want)
+	}
+
+	facets := db.Facets{"source": {{Value: "claude-code", Count: 5}, {Value: "", Count: 2}}}
+
+	var buf bytes.Buffer
+	printSourceGroups(&buf, results, facets)
+
+	out := buf.String()
+	for _, header := range []string{
+		" == claude-code: 2 shown, 5 keyword matches ==",
+		" == (unknown): 1 shown, 2 keyword matches ==",
+		" == cursor: 1 shown, 0 keyword matches ==",
+	} {
+		if !strings.Contains(out, header) {
+			t.Errorf("output missing %q:\n%s", header, out)
+		}
+	}
+
+	// Results keep their overall rank: id-3 was fourth.
+	if !strings.Contains(out, " [4] Use JWT auth") {
+		t.Errorf("output lost the overall rank numbers:\n%s", out)
+	}
+}
+
+func TestProjectFromDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd() error = %v", err)
+	}
+
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"/repos/acme-api", "acme-api"},
+		{"/repos/acme-api/", "acme-api"},
+		{"/repos/acme-api//", "acme-api"},
+		{"/repos/acme-api/./", "acme-api"},
+		{".", filepath.Base(wd)},
+		{"../", filepath.Base(filepath.Dir(wd))},
+	}
+
+	for _, tt := range tests {
+		if got := projectFromDir(tt.dir); got != tt.want {
+			t.Errorf("projectFromDir(%q) = %q, want %q", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestProjectStats_MatchesResults(t *testing.T) {
+	svc, err := core.NewService(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	defer func() { _ = svc.Close() }()
+
+	for i, project := range []string{"api", "api", "api", "web", "web", "cli"} {
+		raw := models.RawItemInput{Title: fmt.Sprintf("Cache note %d", i), What: "cache invalidation"}
+		if _, err := svc.Store(raw, project); err != nil {
+			t.Fatalf("Store() error = %v", err)
+		}
+	}
+
+	// A limit below the match count, so shown and keyword matches differ.
+	results, err := svc.Search("cache", 4, nil, nil, false)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	facets, err := svc.Facets("cache", nil, nil)
+	if err != nil {
+		t.Fatalf("Facets() error = %v", err)
+	}
+
+	shown := make(map[string]int)
+	for _, r := range results {
+		shown[r.Project]++
+	}
+
+	stats := projectStats(results, facets)
+
+	total := 0
+	for _, st := range stats {
+		if st.Shown != shown[st.Project] {
+			t.Errorf("%s shown = %d, want %d", st.Project, st.Shown, shown[st.Project])
+		}
+
+		total += st.Shown
+	}
+
+	if total != len(results) {
+		t.Errorf("shown adds up to %d, want %d results", total, len(results))
+	}
+
+	matches := make(map[string]int64)
+	for _, st := range stats {
+		matches[st.Project] = st.Matches
+	}
+
+	want := map[string]int64{"api": 3, "web": 2, "cli": 1}
+	if !maps.Equal(matches, want) {
+		t.Errorf("keyword matches = %v, want %v", matches, want)
+	}
+
+	for i := 1; i < len(stats); i++ {
+		if stats[i].Shown > stats[i-1].Shown {
+			t.Errorf("stats not ordered by results shown: %+v", stats)
+		}
+	}
+
+	var buf bytes.Buffer
+	printProjectStats(&buf, stats)
+
+	if !strings.HasPrefix(buf.String(), " Projects\n   PROJECT  SHOWN  KEYWORD MATCHES\n") {
+		t.Errorf("output = %q", buf.String())
+	}
+}
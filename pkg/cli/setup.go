@@ -2,9 +2,9 @@ package cli
 
 import (
 	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -14,10 +14,150 @@ import (
 var (
 	setupConfigDir string
 	setupProject   bool
+	setupAll       bool
+	uninstallAll   bool
 )
 
 type agentFunc func(configDir string, project bool) (map[string]string, error)
 
+// agents are the canonical agent names, in the order --all visits them.
+var agents = []string{"claude", "cursor", "codex", "opencode", "roocode"}
+
+var setupHandlers = map[string]agentFunc{
+	"claude":      setupClaudeCode,
+	"claude-code": setupClaudeCode,
+	"cursor":      setupCursor,
+	"codex":       setupCodex,
+	"opencode":    func(_ string, project bool) (map[string]string, error) { return setupOpenCode(project) },
+	"roo":         setupRooCode,
+	"roocode":     setupRooCode,
+}
+
+var uninstallHandlers = map[string]agentFunc{
+	"claude":      uninstallClaudeCode,
+	"claude-code": uninstallClaudeCode,
+	"cursor":      uninstallCursor,
+	"codex":       uninstallCodex,
+	"opencode":    func(_ string, project bool) (map[string]string, error) { return uninstallOpenCode(project) },
+	"roo":         uninstallRooCode,
+	"roocode":     uninstallRooCode,
+}
+
+// projectOnlyAgents have no user-scope config that pantry manages.
+var projectOnlyAgents = map[string]bool{"roocode": true}
+
+// agentArgs accepts no agent name when *all is set, and exactly one otherwise.
+func agentArgs(all *bool) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if *all {
+			return cobra.NoArgs(cmd, args)
+		}
+
+		return cobra.ExactArgs(1)(cmd, args)
+	}
+}
+
+// agentInstalled reports whether agent appears to be installed, judged by its
+// config directory (or file) under home, or under cwd for project scope.
+func agentInstalled(agent, home, cwd string, project bool) bool {
+	root := home
+	if project {
+		root = cwd
+	}
+
+	markers := map[string][]string{
+		"claude":   {".claude", ".claude.json", ".mcp.json"},
+		"cursor":   {".cursor"},
+		"codex":    {".codex"},
+		"opencode": {filepath.Join(".config", "opencode"), "opencode.json"},
+		"roocode":  {".roo"},
+	}
+
+	for _, m := range markers[agent] {
+		if _, err := os.Stat(filepath.Join(root, m)); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setupEverywhere runs the setup handler for every detected agent and prints
+// one result line per agent. Returns the number of agents that failed.
+func setupEverywhere(w io.Writer, project bool) int {
+	home, _ := os.UserHomeDir()
+	cwd, _ := os.Getwd()
+
+	failed := 0
+
+	for _, agent := range agents {
+		if !project && projectOnlyAgents[agent] {
+			fmt.Fprintf(w, "  %-9s skipped (project scope only; use --project)\n", agent)
+
+			continue
+		}
+
+		if !agentInstalled(agent, home, cwd, project) {
+			fmt.Fprintf(w, "  %-9s skipped (not detected)\n", agent)
+
+			continue
+		}
+
+		result, err := setupHandlers[agent]("", project)
+		if err != nil {
+			fmt.Fprintf(w, "  %-9s error: %v\n", agent, err)
+
+			failed++
+
+			continue
+		}
+
+		fmt.Fprintf(w, "  %-9s %s\n", agent, resultMessage(result))
+	}
+
+	return failed
+}
+
+// uninstallEverywhere runs every uninstall handler and prints one result line
+// per agent. Handlers are no-ops where pantry is absent. Returns the number of
+// agents that failed.
+func uninstallEverywhere(w io.Writer, project bool) int {
+	failed := 0
+
+	for _, agent := range agents {
+		if !project && projectOnlyAgents[agent] {
+			fmt.Fprintf(w, "  %-9s skipped (project scope only; use --project)\n", agent)
+
+			continue
+		}
+
+		result, err := uninstallHandlers[agent]("", project)
+		if err != nil {
+			fmt.Fprintf(w, "  %-9s error: %v\n", agent, err)
+
+			failed++
+
+			continue
+		}
+
+		fmt.Fprintf(w, "  %-9s %s\n", agent, resultMessage(result))
+	}
+
+	return failed
+}
+
+const dryRunMessage = "Dry run: no files written"
+
+// resultMessage returns a handler's message, or the dry-run note when the
+// handler only printed what it would have written.
+func resultMessage(result map[string]string) string {
+	if setupDryRun {
+		return dryRunMessage
+	}
+
+	return result["message"]
+}
+
 func runAgentCmd(agent string, handlers map[string]agentFunc, configDir string, project bool) {
 	fn, ok := handlers[agent]
 	if !ok {
@@ -32,50 +172,63 @@ func runAgentCmd(agent string, handlers map[string]agentFunc, configDir string,
 		os.Exit(1)
 	}
 
-	fmt.Println(result["message"])
+	fmt.Println(resultMessage(result))
 }
 
 var setupCmd = &cobra.Command{
-	Use:   "setup [agent]",
+	Use:   "setup [agent | --all]",
 	Short: "Install Pantry hooks for an agent",
-	Args:  cobra.ExactArgs(1),
+	Args:  agentArgs(&setupAll),
 	//nolint:revive
 	Run: func(cmd *cobra.Command, args []string) {
-		runAgentCmd(args[0], map[string]agentFunc{
-			"claude":      setupClaudeCode,
-			"claude-code": setupClaudeCode,
-			"cursor":      setupCursor,
-			"codex":       setupCodex,
-			"opencode":    func(_ string, project bool) (map[string]string, error) { return setupOpenCode(project) },
-			"roo":         setupRooCode,
-			"roocode":     setupRooCode,
-		}, setupConfigDir, setupProject)
+		if setupAll {
+			if setupConfigDir != "" {
+				fmt.Fprintf(os.Stderr, "Error: --config-dir cannot be used with --all\n")
+				os.Exit(1)
+			}
+
+			if failed := setupEverywhere(os.Stdout, setupProject); failed > 0 {
+				os.Exit(1)
+			}
+
+			return
+		}
+
+		runAgentCmd(args[0], setupHandlers, setupConfigDir, setupProject)
 	},
 }
 
 var uninstallCmd = &cobra.Command{
-	Use:   "uninstall [agent]",
+	Use:   "uninstall [agent | --all]",
 	Short: "Remove Pantry hooks for an agent",
-	Args:  cobra.ExactArgs(1),
+	Args:  agentArgs(&uninstallAll),
 	//nolint:revive
 	Run: func(cmd *cobra.Command, args []string) {
-		runAgentCmd(args[0], map[string]agentFunc{
-			"claude":      uninstallClaudeCode,
-			"claude-code": uninstallClaudeCode,
-			"cursor":      uninstallCursor,
-			"codex":       uninstallCodex,
-			"opencode":    func(_ string, project bool) (map[string]string, error) { return uninstallOpenCode(project) },
-			"roo":         uninstallRooCode,
-			"roocode":     uninstallRooCode,
-		}, setupConfigDir, setupProject)
+		if uninstallAll {
+			if setupConfigDir != "" {
+				fmt.Fprintf(os.Stderr, "Error: --config-dir cannot be used with --all\n")
+				os.Exit(1)
+			}
+
+			if failed := uninstallEverywhere(os.Stdout, setupProject); failed > 0 {
+				os.Exit(1)
+			}
+
+			return
+		}
+
+		runAgentCmd(args[0], uninstallHandlers, setupConfigDir, setupProject)
 	},
 }
 
 func init() {
 	setupCmd.Flags().StringVar(&setupConfigDir, "config-dir", "", "Path to agent config directory")
 	setupCmd.Flags().BoolVarP(&setupProject, "project", "p", false, "Install in current project instead of globally")
+	setupCmd.Flags().BoolVar(&setupAll, "all", false, "Install for every agent whose config directory exists")
+	setupCmd.Flags().BoolVar(&setupDryRun, "dry-run", false, "Print the resulting config instead of writing it")
 	uninstallCmd.Flags().StringVar(&setupConfigDir, "config-dir", "", "Path to agent config directory")
 	uninstallCmd.Flags().BoolVarP(&setupProject, "project", "p", false, "Uninstall from current project instead of globally")
+	uninstallCmd.Flags().BoolVar(&uninstallAll, "all", false, "Uninstall from every supported agent")
 }
 
 func resolveConfigDir(agentDotDir string, configDir string, project bool) string {
@@ -126,7 +279,7 @@ func setupClaudeCode(configDir string, project bool) (map[string]string, error)
 	}
 
 	msg := "Installed Pantry MCP server in " + configPath
-	if installSkill(skillTarget) {
+	if !setupDryRun && installSkill(skillTarget) {
 		msg += " and skill" //nolint:goconst
 	}
 
@@ -135,13 +288,9 @@ func setupClaudeCode(configDir string, project bool) (map[string]string, error)
 
 // writeMCPJSON writes an MCP server entry into a .mcp.json file (project scope).
 func writeMCPJSON(configPath string, entry map[string]any) error {
-	var config map[string]any
-	if data, err := os.ReadFile(configPath); err == nil {
-		if err := json.Unmarshal(data, &config); err != nil {
-			return fmt.Errorf("failed to parse existing config: %w", err)
-		}
-	} else {
-		config = make(map[string]any)
+	config, err := readJSONConfig(configPath)
+	if err != nil {
+		return err
 	}
 
 	mcpServers, _ := config["mcpServers"].(map[string]any)
@@ -152,27 +301,14 @@ func writeMCPJSON(configPath string, entry map[string]any) error {
 
 	mcpServers["pantry"] = entry
 
-	data, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
-	}
-
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
-	}
-
-	return nil
+	return writeJSONConfig(configPath, config)
 }
 
 // writeClaudeJSONUserMCP writes an MCP server entry into ~/.claude.json top-level mcpServers (user scope).
 func writeClaudeJSONUserMCP(configPath string, entry map[string]any) error {
-	var root map[string]any
-	if data, err := os.ReadFile(configPath); err == nil {
-		if err := json.Unmarshal(data, &root); err != nil {
-			return fmt.Errorf("failed to parse existing config: %w", err)
-		}
-	} else {
-		root = make(map[string]any)
+	root, err := readJSONConfig(configPath)
+	if err != nil {
+		return err
 	}
 
 	mcpServers, _ := root["mcpServers"].(map[string]any)
@@ -183,16 +319,7 @@ func writeClaudeJSONUserMCP(configPath string, entry map[string]any) error {
 
 	mcpServers["pantry"] = entry
 
-	data, err := json.MarshalIndent(root, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
-	}
-
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
-	}
-
-	return nil
+	return writeJSONConfig(configPath, root)
 }
 
 func setupCursor(configDir string, project bool) (map[string]string, error) {
@@ -200,13 +327,9 @@ func setupCursor(configDir string, project bool) (map[string]string, error) {
 	configPath := filepath.Join(target, "mcp.json")
 
 	// Read existing config or create new
-	var config map[string]any
-	if data, err := os.ReadFile(configPath); err == nil {
-		if err := json.Unmarshal(data, &config); err != nil {
-			return nil, fmt.Errorf("failed to parse existing config: %w", err)
-		}
-	} else {
-		config = make(map[string]any)
+	config, err := readJSONConfig(configPath)
+	if err != nil {
+		return nil, err
 	}
 
 	// Add MCP server config
@@ -221,22 +344,12 @@ func setupCursor(configDir string, project bool) (map[string]string, error) {
 		"args":    []string{"mcp"},
 	}
 
-	// Write config
-	if err := os.MkdirAll(target, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %w", err)
-	}
-
-	data, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal config: %w", err)
-	}
-
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
-		return nil, fmt.Errorf("failed to write config: %w", err)
+	if err := writeJSONConfig(configPath, config); err != nil {
+		return nil, err
 	}
 
 	msg := "Installed Pantry MCP server in " + configPath
-	if installSkill(target) {
+	if !setupDryRun && installSkill(target) {
 		msg += " and skill"
 	}
 
@@ -248,15 +361,35 @@ func setupCodex(configDir string, project bool) (map[string]string, error) {
 	configPath := filepath.Join(target, "config.toml")
 	agentsPath := filepath.Join(target, "AGENTS.md")
 
-	if err := os.MkdirAll(target, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %w", err)
-	}
-
 	// Codex uses [mcp_servers.<name>] in config.toml.
 	// Only append the block if it's not already present (idempotent).
 	const pantryTOML = "\n[mcp_servers.pantry]\ncommand = \"pantry\"\nargs = [\"mcp\"]\n"
 
-	existing, _ := os.ReadFile(configPath)
+	existing, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", configPath, err)
+	}
+
+	// checkTOML is a heuristic, so a file it rejects is flagged rather than
+	// left without the pantry block.
+	if err := checkTOML(configPath, existing); err != nil {
+		fmt.Fprintf(warnOut, "warning: %v\n", err)
+	}
+
+	if setupDryRun {
+		if bytes.Contains(existing, []byte("[mcp_servers.pantry]")) {
+			fmt.Fprintf(dryRunOut, "--- %s (dry run, already configured)\n", configPath)
+		} else {
+			fmt.Fprintf(dryRunOut, "--- %s (dry run, would append)\n%s\n", configPath, pantryTOML)
+		}
+
+		return map[string]string{"message": dryRunMessage}, nil
+	}
+
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+
 	if !bytes.Contains(existing, []byte("[mcp_servers.pantry]")) {
 		f, err := os.OpenFile(configPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 		if err != nil {
@@ -312,13 +445,9 @@ func setupOpenCode(project bool) (map[string]string, error) {
 	}
 
 	// Read existing config or create new
-	var config map[string]any
-	if data, err := os.ReadFile(configPath); err == nil {
-		if err := json.Unmarshal(data, &config); err != nil {
-			return nil, fmt.Errorf("failed to parse existing config: %w", err)
-		}
-	} else {
-		config = make(map[string]any)
+	config, err := readJSONConfig(configPath)
+	if err != nil {
+		return nil, err
 	}
 
 	// OpenCode uses a "mcp" key (not "mcpServers"), and command must be an array.
@@ -333,18 +462,8 @@ func setupOpenCode(project bool) (map[string]string, error) {
 		"command": []string{"pantry", "mcp"},
 	}
 
-	// Write config
-	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %w", err)
-	}
-
-	data, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal config: %w", err)
-	}
-
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
-		return nil, fmt.Errorf("failed to write config: %w", err)
+	if err := writeJSONConfig(configPath, config); err != nil {
+		return nil, err
 	}
 
 	return map[string]string{
@@ -355,30 +474,16 @@ func setupOpenCode(project bool) (map[string]string, error) {
 // removePantryFromMCPJSON reads a JSON config file, removes the "pantry" key from
 // "mcpServers", and writes the result back.
 func removePantryFromMCPJSON(configPath string) error {
-	data, err := os.ReadFile(configPath)
+	config, err := readJSONConfig(configPath)
 	if err != nil {
-		return fmt.Errorf("failed to read config: %w", err)
-	}
-
-	var config map[string]any
-	if err := json.Unmarshal(data, &config); err != nil {
-		return fmt.Errorf("failed to parse config: %w", err)
+		return err
 	}
 
 	if mcpServers, ok := config["mcpServers"].(map[string]any); ok {
 		delete(mcpServers, "pantry")
 	}
 
-	newData, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
-	}
-
-	if err := os.WriteFile(configPath, newData, 0644); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
-	}
-
-	return nil
+	return writeJSONConfig(configPath, config)
 }
 
 func uninstallClaudeCode(configDir string, project bool) (map[string]string, error) {
@@ -437,9 +542,16 @@ func uninstallCursor(configDir string, project bool) (map[string]string, error)
 func uninstallCodex(configDir string, project bool) (map[string]string, error) {
 	target := resolveConfigDir(".codex", configDir, project)
 
+	existing, _ := os.ReadFile(filepath.Join(target, "config.toml"))
+	removedSkill := uninstallSkill(target)
+
+	if !bytes.Contains(existing, []byte("[mcp_servers.pantry]")) && !removedSkill {
+		return map[string]string{"message": "Pantry not found in Codex config"}, nil
+	}
+
 	msg := "Codex uninstall: manually remove Pantry entries from .codex/config.toml and AGENTS.md"
 
-	if uninstallSkill(target) {
+	if removedSkill {
 		msg += ". Removed skill."
 	}
 
@@ -461,27 +573,17 @@ func uninstallOpenCode(project bool) (map[string]string, error) {
 		return map[string]string{"message": "Pantry not found in OpenCode config"}, nil
 	}
 
-	data, err := os.ReadFile(configPath)
+	config, err := readJSONConfig(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config: %w", err)
-	}
-
-	var config map[string]any
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+		return nil, err
 	}
 
 	if mcp, ok := config["mcp"].(map[string]any); ok {
 		delete(mcp, "pantry")
 	}
 
-	newData, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal config: %w", err)
-	}
-
-	if err := os.WriteFile(configPath, newData, 0644); err != nil {
-		return nil, fmt.Errorf("failed to write config: %w", err)
+	if err := writeJSONConfig(configPath, config); err != nil {
+		return nil, err
 	}
 
 	return map[string]string{
@@ -503,13 +605,9 @@ func setupRooCode(configDir string, project bool) (map[string]string, error) {
 
 	configPath := filepath.Join(target, "mcp.json")
 
-	var config map[string]any
-	if data, err := os.ReadFile(configPath); err == nil {
-		if err := json.Unmarshal(data, &config); err != nil {
-			return nil, fmt.Errorf("failed to parse existing config: %w", err)
-		}
-	} else {
-		config = make(map[string]any)
+	config, err := readJSONConfig(configPath)
+	if err != nil {
+		return nil, err
 	}
 
 	mcpServers, _ := config["mcpServers"].(map[string]any)
@@ -523,17 +621,8 @@ func setupRooCode(configDir string, project bool) (map[string]string, error) {
 		"args":    []string{"mcp"},
 	}
 
-	if err := os.MkdirAll(target, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %w", err)
-	}
-
-	data, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal config: %w", err)
-	}
-
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
-		return nil, fmt.Errorf("failed to write config: %w", err)
+	if err := writeJSONConfig(configPath, config); err != nil {
+		return nil, err
 	}
 
 	return map[string]string{
@@ -559,27 +648,17 @@ func uninstallRooCode(configDir string, project bool) (map[string]string, error)
 		return map[string]string{"message": "Pantry not found in RooCode config"}, nil
 	}
 
-	data, err := os.ReadFile(configPath)
+	config, err := readJSONConfig(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config: %w", err)
-	}
-
-	var config map[string]any
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+		return nil, err
 	}
 
 	if mcpServers, ok := config["mcpServers"].(map[string]any); ok {
 		delete(mcpServers, "pantry")
 	}
 
-	newData, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal config: %w", err)
-	}
-
-	if err := os.WriteFile(configPath, newData, 0644); err != nil {
-		return nil, fmt.Errorf("failed to write config: %w", err)
+	if err := writeJSONConfig(configPath, config); err != nil {
+		return nil, err
 	}
 
 	return map[string]string{
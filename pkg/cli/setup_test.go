@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUninstallEverywhere_RemovesPresentAndSkipsMissing(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(t.TempDir())
+
+	cursorDir := filepath.Join(home, ".cursor")
+	if _, err := setupCursor(cursorDir, false); err != nil {
+		t.Fatalf("setupCursor() error = %v", err)
+	}
+
+	if _, err := setupOpenCode(false); err != nil {
+		t.Fatalf("setupOpenCode() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if failed := uninstallEverywhere(&buf, false); failed != 0 {
+		t.Fatalf("uninstallEverywhere() failed for %d agents:\n%s", failed, buf.String())
+	}
+
+	out := buf.String()
+
+	for _, want := range []string{
+		"Pantry not found in Claude Code config",
+		"Removed Pantry from " + filepath.Join(cursorDir, "mcp.json") + " and skill",
+		"Pantry not found in Codex config",
+		"Removed Pantry from " + filepath.Join(home, ".config", "opencode", "opencode.json"),
+		"roocode   skipped",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	entries, errs := scanAgentConfigs(home, home)
+	if len(entries) != 0 || len(errs) != 0 {
+		t.Errorf("after uninstall: entries = %+v, errs = %v", entries, errs)
+	}
+
+	if _, err := os.Stat(filepath.Join(cursorDir, "skills", "pantry")); !os.IsNotExist(err) {
+		t.Errorf("cursor skill still present: %v", err)
+	}
+
+	// Running it again is a no-op.
+	buf.Reset()
+
+	if failed := uninstallEverywhere(&buf, false); failed != 0 {
+		t.Errorf("second uninstallEverywhere() failed for %d agents:\n%s", failed, buf.String())
+	}
+}
+
+func TestSetupEverywhere_ConfiguresDetectedAgents(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Chdir(t.TempDir())
+
+	for _, dir := range []string{".cursor", ".codex"} {
+		if err := os.Mkdir(filepath.Join(home, dir), 0755); err != nil {
+			t.Fatalf("Mkdir() error = %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if failed := setupEverywhere(&buf, false); failed != 0 {
+		t.Fatalf("setupEverywhere() failed for %d agents:\n%s", failed, buf.String())
+	}
+
+	entries, errs := scanAgentConfigs(home, home)
+	if len(errs) != 0 {
+		t.Fatalf("scanAgentConfigs() errs = %v", errs)
+	}
+
+	got := make(map[string]bool)
+	for _, e := range entries {
+		got[e.Agent] = true
+	}
+
+	if len(got) != 2 || !got["cursor"] || !got["codex"] {
+		t.Errorf("configured agents = %v, want cursor and codex only\n%s", got, buf.String())
+	}
+
+	for _, want := range []string{"claude    skipped (not detected)", "opencode  skipped (not detected)"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestSetupCursor_DryRunPrintsWithoutWriting(t *testing.T) {
+	target := t.TempDir()
+	configPath := filepath.Join(target, "mcp.json")
+
+	if err := os.WriteFile(configPath, []byte(`{"mcpServers": {"other": {"command": "x"}}}`), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+
+	setupDryRun, dryRunOut = true, &buf
+	t.Cleanup(func() { setupDryRun, dryRunOut = false, os.Stdout })
+
+	if _, err := setupCursor(target, false); err != nil {
+		t.Fatalf("setupCursor() error = %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{configPath + " (dry run, not written)", `"pantry"`, `"other"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	data, _ := os.ReadFile(configPath)
+	if strings.Contains(string(data), "pantry") {
+		t.Errorf("dry run wrote config:\n%s", data)
+	}
+
+	if _, err := os.Stat(filepath.Join(target, "skills")); !os.IsNotExist(err) {
+		t.Errorf("dry run installed skill: %v", err)
+	}
+}
+
+func TestSetupCursor_MalformedConfigNamesFileAndLine(t *testing.T) {
+	target := t.TempDir()
+	configPath := filepath.Join(target, "mcp.json")
+
+	if err := os.WriteFile(configPath, []byte("{\n  \"mcpServers\": {\n    \"other\": ,\n  }\n}\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	_, err := setupCursor(target, false)
+	if err == nil {
+		t.Fatal("setupCursor() error = nil, want parse error")
+	}
+
+	if want := configPath + ":3:"; !strings.Contains(err.Error(), want) {
+		t.Errorf("error = %q, want it to contain %q", err, want)
+	}
+}
+
+func TestSetupCodex_MalformedTOML(t *testing.T) {
+	target := t.TempDir()
+	configPath := filepath.Join(target, "config.toml")
+
+	config := "model = \"o3\"\n\n[profiles.fast\nmodel = \"o4-mini\"\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	var warnings bytes.Buffer
+
+	warnOut = &warnings
+	t.Cleanup(func() { warnOut = os.Stderr })
+
+	if _, err := setupCodex(target, false); err != nil {
+		t.Fatalf("setupCodex() error = %v", err)
+	}
+
+	if want := configPath + ":3:"; !strings.Contains(warnings.String(), want) {
+		t.Errorf("warning = %q, want it to contain %q", warnings.String(), want)
+	}
+
+	data, _ := os.ReadFile(configPath)
+	if !strings.HasPrefix(string(data), config) || !strings.Contains(string(data), "[mcp_servers.pantry]") {
+		t.Errorf("config = %q, want the original with the pantry block appended", data)
+	}
+}
+
+func TestCheckTOML_AcceptsMultiLineValues(t *testing.T) {
+	config := "# comment\n[mcp_servers.x]\nargs = [\n  \"a]\",\n  \"b\", # one ]\n]\nnote = \"\"\"\nfree text = here\n\"\"\"\nraw = '''\n[not a table\n'''\npath = 'C:\\dir[1]'\n[[arr]] # array of tables\nk = { a = \"}\" }\n"
+	if err := checkTOML("config.toml", []byte(config)); err != nil {
+		t.Errorf("checkTOML() error = %v", err)
+	}
+}
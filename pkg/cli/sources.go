@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"pantry/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+var sourcesNormalize bool
+
+var sourcesCmd = &cobra.Command{
+	Use:   "sources",
+	Short: "List note sources with counts (--normalize to rename old spellings)",
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		svc, err := core.NewService("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer func() { _ = svc.Close() }()
+
+		if sourcesNormalize {
+			n, err := svc.NormalizeStoredSources()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Renamed the source of %d notes\n", n)
+		}
+
+		sources, err := svc.ListSources()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(sources) == 0 {
+			fmt.Println("No sources recorded.")
+
+			return
+		}
+
+		for _, src := range sources {
+			fmt.Printf("%6d  %s\n", src.Count, src.Value)
+		}
+	},
+}
+
+func init() {
+	sourcesCmd.Flags().BoolVar(&sourcesNormalize, "normalize", false, "First rewrite the sources of notes stored before normalization, or before an alias in source_aliases was added, to their canonical form")
+}
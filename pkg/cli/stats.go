@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"pantry/internal/core"
+	"pantry/internal/db"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsGrowth  bool
+	statsBy      string
+	statsProject bool
+	statsJSON    bool
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show note counts and database size (--growth for notes created per week or month)",
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		svc, err := core.NewService("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer func() { _ = svc.Close() }()
+
+		var project *string
+
+		if statsProject {
+			dir, _ := os.Getwd()
+			projectName := filepath.Base(dir)
+			project = &projectName
+		}
+
+		if !statsGrowth {
+			stats, err := svc.Stats(project)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			if statsJSON {
+				if err := writeJSON(stdout, stats, false); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					os.Exit(1)
+				}
+
+				return
+			}
+
+			printColumns(stdout, [][]string{
+				{"Notes:", strconv.FormatInt(stats.Items, 10)},
+				{"With details:", strconv.FormatInt(stats.WithDetails, 10)},
+				{"With vectors:", strconv.FormatInt(stats.Vectors, 10)},
+				{"Projects:", strconv.FormatInt(stats.Projects, 10)},
+				{"Sources:", strconv.FormatInt(stats.Sources, 10)},
+				{"Categories:", strconv.FormatInt(stats.Categories, 10)},
+				{"Database:", formatMiB(stats.SizeBytes)},
+			})
+
+			return
+		}
+
+		periods, err := svc.Growth(statsBy, project)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if statsJSON {
+			if periods == nil {
+				periods = []db.PeriodCount{}
+			}
+
+			if err := writeJSON(stdout, periods, false); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			return
+		}
+
+		if len(periods) == 0 {
+			fmt.Fprintln(stdout, "No notes found.")
+
+			return
+		}
+
+		rows := [][]string{{"PERIOD", "NOTES"}}
+		for _, p := range periods {
+			rows = append(rows, []string{p.Period, strconv.FormatInt(p.Count, 10)})
+		}
+
+		printColumns(stdout, rows)
+	},
+}
+
+func init() {
+	statsCmd.Flags().BoolVar(&statsGrowth, "growth", false, "Count the notes created in each period, oldest first")
+	statsCmd.Flags().StringVar(&statsBy, "by", db.PeriodWeek, "Period for --growth: day, week (labelled by its Monday) or month")
+	statsCmd.Flags().BoolVarP(&statsProject, "project", "p", false, "Count only the current project's notes (the database size stays the whole file)")
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "Print as JSON: the counts, or with --growth an array of {period, count}")
+}
@@ -1,11 +1,17 @@
 package cli
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"slices"
 	"strings"
 
 	"pantry/internal/core"
+	"pantry/internal/db"
 	"pantry/internal/models"
 
 	"github.com/spf13/cobra"
@@ -22,83 +28,422 @@ var (
 	storeDetails      string
 	storeSource       string
 	storeProject      string
+	storeDedupScope   string
+	storeForceCreate  bool
+	storeStdinJSON    bool
+	storeShowRedacted bool
+	storeMeta         []string
+	storeUpdateID     string
+	storeDir          string
+	storeRecursive    bool
+	storeGlob         string
+	storeDryRun       bool
 )
 
+// redactableFields are the note fields Store redacts, in report order.
+var redactableFields = []string{"title", "what", "why", "impact", "details", "metadata"}
+
+// stdinNote is the JSON shape accepted by store --stdin-json.
+type stdinNote struct {
+	Title        string            `json:"title"`
+	What         string            `json:"what"`
+	Why          *string           `json:"why"`
+	Impact       *string           `json:"impact"`
+	Tags         []string          `json:"tags"`
+	Category     *string           `json:"category"`
+	RelatedFiles []string          `json:"related_files"`
+	Details      *string           `json:"details"`
+	Source       *string           `json:"source"`
+	Project      string            `json:"project"`
+	Metadata     map[string]string `json:"metadata"`
+}
+
+// readStdinNote decodes a single JSON note from r and checks its required
+// fields and category. It returns the note and its project ("" if unset).
+func readStdinNote(r io.Reader) (models.RawItemInput, string, error) {
+	var note stdinNote
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&note); err != nil {
+		return models.RawItemInput{}, "", fmt.Errorf("invalid note JSON on stdin: %w", err)
+	}
+
+	if dec.More() {
+		return models.RawItemInput{}, "", errors.New("invalid note JSON on stdin: expected a single object")
+	}
+
+	if strings.TrimSpace(note.Title) == "" {
+		return models.RawItemInput{}, "", &core.ValidationError{Field: "title", Message: "is required"}
+	}
+
+	if strings.TrimSpace(note.What) == "" {
+		return models.RawItemInput{}, "", &core.ValidationError{Field: "what", Message: "is required"}
+	}
+
+	if note.Category != nil && !slices.Contains(models.ValidCategories, *note.Category) {
+		return models.RawItemInput{}, "", &core.ValidationError{
+			Field:   "category",
+			Message: fmt.Sprintf("%q is not one of %s", *note.Category, strings.Join(models.ValidCategories, ", ")),
+		}
+	}
+
+	for key := range note.Metadata {
+		if err := db.ValidateMetaKey(key); err != nil {
+			return models.RawItemInput{}, "", &core.ValidationError{Field: "metadata", Message: err.Error()}
+		}
+	}
+
+	return models.RawItemInput{
+		Title:        note.Title,
+		What:         note.What,
+		Why:          note.Why,
+		Impact:       note.Impact,
+		Tags:         note.Tags,
+		Category:     note.Category,
+		RelatedFiles: note.RelatedFiles,
+		Details:      note.Details,
+		Source:       note.Source,
+		Metadata:     note.Metadata,
+	}, note.Project, nil
+}
+
 var storeCmd = &cobra.Command{
 	Use:   "store",
 	Short: "Store a note in the pantry",
 	//nolint:revive
 	Run: func(cmd *cobra.Command, args []string) {
-		if storeTitle == "" || storeWhat == "" {
-			fmt.Fprintf(os.Stderr, "Error: --title and --what are required\n")
-			os.Exit(1)
-		}
+		var raw models.RawItemInput
 
-		raw := models.RawItemInput{
-			Title: storeTitle,
-			What:  storeWhat,
+		project := storeProject
+
+		if storeStdinJSON {
+			note, noteProject, err := readStdinNote(os.Stdin)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			raw = note
+
+			// --project on the command line wins over the JSON field.
+			if project == "" {
+				project = noteProject
+			}
+		} else if storeDir != "" {
+			raw = storeFieldsFromFlags()
+		} else {
+			raw = storeInputFromFlags()
 		}
 
-		if storeWhy != "" {
-			raw.Why = &storeWhy
+		svc, err := core.NewService("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
 
-		if storeImpact != "" {
-			raw.Impact = &storeImpact
+		defer func() { _ = svc.Close() }()
+
+		setDefaultSource(&raw, svc.DefaultSource(core.OriginCLI))
+
+		var opts []core.StoreOption
+		if storeDedupScope != "" {
+			opts = append(opts, core.WithDedupScope(storeDedupScope))
 		}
 
-		if storeCategory != "" {
-			raw.Category = &storeCategory
+		if storeForceCreate {
+			opts = append(opts, core.WithForceCreate())
 		}
 
-		if storeSource != "" {
-			raw.Source = &storeSource
+		if storeUpdateID != "" {
+			opts = append(opts, core.WithUpdateID(storeUpdateID))
 		}
 
-		if storeDetails != "" {
-			raw.Details = &storeDetails
+		if storeDryRun {
+			opts = append(opts, core.WithDryRun())
 		}
 
-		if storeTags != "" {
-			tags := strings.Split(storeTags, ",")
-			for i := range tags {
-				tags[i] = strings.TrimSpace(tags[i])
-			}
+		// Ctrl-C abandons a slow embedding; the note is still stored.
+		ctx, stop := interruptContext()
+		defer stop()
 
-			raw.Tags = tags
-		}
+		opts = append(opts, core.WithStoreContext(ctx))
 
-		if storeRelatedFiles != "" {
-			files := strings.Split(storeRelatedFiles, ",")
-			for i := range files {
-				files[i] = strings.TrimSpace(files[i])
-			}
+		if storeDir != "" {
+			storeFromDir(svc, project, raw, opts)
 
-			raw.RelatedFiles = files
+			return
 		}
 
-		svc, err := core.NewService("")
+		result, err := svc.Store(raw, project, opts...)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 
-		defer func() { _ = svc.Close() }()
+		if storeDryRun {
+			printDryRun(os.Stdout, result)
 
-		result, err := svc.Store(raw, storeProject)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			return
 		}
 
 		id, _ := result["id"].(string)
 		filePath, _ := result["file_path"].(string)
+		action, _ := result["action"].(string)
+		storedProject, _ := result["project"].(string)
+
+		if action == "updated" {
+			title := raw.Title
+			if item, ok := result["item"].(map[string]any); ok {
+				title, _ = item["title"].(string)
+			}
+
+			fmt.Printf("Updated existing note: %s (id: %s, project: %s)\n", title, id, storedProject)
+		} else {
+			fmt.Printf("Stored: %s (id: %s)\n", raw.Title, id)
+		}
 
-		fmt.Printf("Stored: %s (id: %s)\n", storeTitle, id)
 		fmt.Printf("File: %s\n", filePath)
+
+		if related, ok := result["related_to"].(string); ok {
+			fmt.Printf("Linked as related_to similar note %s\n", related)
+		}
+
+		printDuplicateWarning(os.Stderr, result)
+
+		if warning, ok := result["project_warning"].(string); ok {
+			fmt.Fprintf(os.Stderr, "Warning: %s.\n", warning)
+		}
+
+		if storeShowRedacted {
+			redactions, _ := result["redactions"].(map[string]int)
+			fmt.Println(formatRedactions(redactions))
+		}
 	},
 }
 
+// printDryRun describes what a store --dry-run would have done: the action,
+// the note it would merge into, and the note's fields after redaction.
+func printDryRun(w io.Writer, result map[string]any) {
+	id, _ := result["id"].(string)
+	filePath, _ := result["file_path"].(string)
+	action, _ := result["action"].(string)
+	project, _ := result["project"].(string)
+	item, _ := result["item"].(map[string]any)
+	title, _ := item["title"].(string)
+
+	if action == "updated" {
+		fmt.Fprintf(w, "Dry run: would update existing note %q (id: %s, project: %s)\n", title, id, project)
+	} else {
+		fmt.Fprintf(w, "Dry run: would create %q in project %s\n", title, project)
+	}
+
+	fmt.Fprintf(w, "File: %s\n", filePath)
+
+	if related, ok := result["related_to"].(string); ok {
+		fmt.Fprintf(w, "Would link as related_to similar note %s\n", related)
+	}
+
+	what, _ := item["what"].(string)
+	fmt.Fprintf(w, "\n  What: %s\n", what)
+
+	for _, field := range []string{"why", "impact", "category", "source"} {
+		if v, _ := item[field].(*string); v != nil {
+			fmt.Fprintf(w, "  %s: %s\n", strings.ToUpper(field[:1])+field[1:], *v)
+		}
+	}
+
+	if tags, _ := item["tags"].([]string); len(tags) > 0 {
+		fmt.Fprintf(w, "  Tags: %s\n", strings.Join(tags, ", "))
+	}
+
+	if dup, ok := result["possible_duplicate"].(map[string]any); ok {
+		fmt.Fprintf(w, "Warning: this looks like a duplicate of %q (id: %s).\n", dup["title"], dup["id"])
+	}
+
+	if redactions, _ := result["redactions"].(map[string]int); len(redactions) > 0 {
+		fmt.Fprintln(w, formatRedactions(redactions))
+	}
+
+	fmt.Fprintln(w, "Nothing was written.")
+}
+
+// storeFromDir stores each markdown file under --dir as a note, reporting
+// progress as it goes.
+func storeFromDir(svc *core.Service, project string, base models.RawItemInput, opts []core.StoreOption) {
+	result, err := svc.StoreDir(storeDir, project, base, core.StoreDirOptions{
+		Recursive: storeRecursive,
+		Glob:      storeGlob,
+		Progress: func(path string, stored bool) {
+			status := "stored "
+			if !stored {
+				status = "skipped"
+			}
+
+			fmt.Printf("  %s %s\n", status, path)
+		},
+	}, opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Stored %d notes, skipped %d already imported\n", result.Stored, result.Skipped)
+}
+
+// setDefaultSource sets the source of a note stored without one to def
+// (defaults.cli_source). An empty def leaves it unset.
+func setDefaultSource(raw *models.RawItemInput, def string) {
+	if raw.Source == nil && def != "" {
+		raw.Source = &def
+	}
+}
+
+// printDuplicateWarning warns when Store created a note that looks like a
+// reworded copy of an existing one.
+func printDuplicateWarning(w io.Writer, result map[string]any) {
+	dup, ok := result["possible_duplicate"].(map[string]any)
+	if !ok {
+		return
+	}
+
+	id, _ := result["id"].(string)
+	dupID, _ := dup["id"].(string)
+	dupTitle, _ := dup["title"].(string)
+
+	fmt.Fprintf(w, "Warning: this looks like a duplicate of %q (id: %s).\n", dupTitle, dupID)
+	fmt.Fprintf(w, "  To keep one note, add to it with `pantry update %s` and run `pantry remove %s`.\n", dupID, id)
+}
+
+// formatRedactions summarises per-field redaction counts, e.g.
+// "Redactions: 3 (what: 1, details: 2)".
+func formatRedactions(counts map[string]int) string {
+	total := 0
+
+	var parts []string
+
+	for _, field := range redactableFields {
+		if n := counts[field]; n > 0 {
+			total += n
+			parts = append(parts, fmt.Sprintf("%s: %d", field, n))
+		}
+	}
+
+	if total == 0 {
+		return "Redactions: none"
+	}
+
+	return fmt.Sprintf("Redactions: %d (%s)", total, strings.Join(parts, ", "))
+}
+
+// storeInputFromFlags builds the note from the store flags, exiting if the
+// required ones are missing.
+func storeInputFromFlags() models.RawItemInput {
+	if storeTitle == "" || storeWhat == "" {
+		fmt.Fprintf(os.Stderr, "Error: --title and --what are required\n")
+		os.Exit(1)
+	}
+
+	return storeFieldsFromFlags()
+}
+
+// storeFieldsFromFlags builds a note from the store flags without requiring
+// any. store --dir uses it for the fields shared by every file.
+func storeFieldsFromFlags() models.RawItemInput {
+	raw := models.RawItemInput{
+		Title: storeTitle,
+		What:  storeWhat,
+	}
+
+	if storeWhy != "" {
+		raw.Why = &storeWhy
+	}
+
+	if storeImpact != "" {
+		raw.Impact = &storeImpact
+	}
+
+	if storeCategory != "" {
+		raw.Category = &storeCategory
+	}
+
+	if storeSource != "" {
+		raw.Source = &storeSource
+	}
+
+	if storeDetails != "" {
+		raw.Details = &storeDetails
+	}
+
+	if storeTags != "" {
+		tags := strings.Split(storeTags, ",")
+		for i := range tags {
+			tags[i] = strings.TrimSpace(tags[i])
+		}
+
+		raw.Tags = tags
+	}
+
+	if storeRelatedFiles != "" {
+		files := strings.Split(storeRelatedFiles, ",")
+		for i := range files {
+			files[i] = strings.TrimSpace(files[i])
+		}
+
+		raw.RelatedFiles = files
+	}
+
+	metadata, err := parseMeta(storeMeta)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	raw.Metadata = metadata
+
+	return raw
+}
+
+// parseMeta parses repeated --meta key=value flags. Returns nil for none.
+func parseMeta(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	metadata := make(map[string]string, len(pairs))
+
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --meta %q: expected key=value", pair)
+		}
+
+		if err := db.ValidateMetaKey(key); err != nil {
+			return nil, err
+		}
+
+		metadata[key] = value
+	}
+
+	return metadata, nil
+}
+
+// metaSearchOptions turns --meta key=value filters into search options.
+func metaSearchOptions(pairs []string) ([]core.SearchOption, error) {
+	metadata, err := parseMeta(pairs)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := make([]core.SearchOption, 0, len(metadata))
+	for _, key := range slices.Sorted(maps.Keys(metadata)) {
+		opts = append(opts, core.WithMeta(key, metadata[key]))
+	}
+
+	return opts, nil
+}
+
 func init() {
 	storeCmd.Flags().StringVarP(&storeTitle, "title", "t", "", "Title of the note (required)")
 	storeCmd.Flags().StringVarP(&storeWhat, "what", "w", "", "What happened or was learned (required)")
@@ -110,4 +455,25 @@ func init() {
 	storeCmd.Flags().StringVarP(&storeDetails, "details", "d", "", "Extended details or context")
 	storeCmd.Flags().StringVarP(&storeSource, "source", "s", "", "Source of the note")
 	storeCmd.Flags().StringVarP(&storeProject, "project", "p", "", "Project name (defaults to current directory)")
+	storeCmd.Flags().BoolVar(&storeForceCreate, "force-create", false, "Always create a new note, even if one with the same title exists")
+	storeCmd.Flags().BoolVar(&storeStdinJSON, "stdin-json", false, "Read the note as a JSON object from stdin instead of flags")
+	storeCmd.Flags().StringArrayVar(&storeMeta, "meta", nil, "Metadata as key=value (repeatable)")
+	storeCmd.Flags().BoolVar(&storeShowRedacted, "show-redactions", false, "Report how many spans redaction removed from each field")
+	storeCmd.Flags().StringVar(&storeDedupScope, "dedup-scope", "", "Where to look for a note to update: project or global (default from config)")
+	storeCmd.Flags().StringVar(&storeUpdateID, "update", "", "Update the note with this ID (or a unique prefix) instead of matching by title (merges tags, appends details)")
+
+	storeCmd.Flags().BoolVar(&storeDryRun, "dry-run", false, "Show what would be stored (after redaction and dedup) without writing anything")
+
+	storeCmd.Flags().StringVar(&storeDir, "dir", "", "Store each markdown file in this directory as a note (title from the first heading or file name)")
+	storeCmd.Flags().BoolVar(&storeRecursive, "recursive", false, "With --dir, also read subdirectories")
+	storeCmd.Flags().StringVar(&storeGlob, "glob", "", "With --dir, only read files whose name matches this pattern (default \"*.md\")")
+
+	storeCmd.MarkFlagsMutuallyExclusive("update", "force-create")
+	storeCmd.MarkFlagsMutuallyExclusive("update", "dedup-scope")
+	storeCmd.MarkFlagsMutuallyExclusive("dir", "title")
+	storeCmd.MarkFlagsMutuallyExclusive("dir", "what")
+	storeCmd.MarkFlagsMutuallyExclusive("dir", "details")
+	storeCmd.MarkFlagsMutuallyExclusive("dir", "stdin-json")
+	storeCmd.MarkFlagsMutuallyExclusive("dir", "update")
+	storeCmd.MarkFlagsMutuallyExclusive("dir", "dry-run")
 }
@@ -0,0 +1,217 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"pantry/internal/core"
+	"pantry/internal/models"
+)
+
+func TestReadStdinNote_StoresAllFields(t *testing.T) {
+	input := `{
+		"title": "Switched to JWT auth",
+		"what": "Replaced session cookies with JWT",
+		"why": "Stateless API",
+		"tags": ["auth", "jwt"],
+		"category": "decision",
+		"related_files": ["api/auth.go"],
+		"details": "Tokens expire after 15 minutes.",
+		"source": "script",
+		"project": "api"
+	}`
+
+	raw, project, err := readStdinNote(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("readStdinNote() error = %v", err)
+	}
+
+	if project != "api" {
+		t.Errorf("project = %q, want api", project)
+	}
+
+	svc, err := core.NewService(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	defer func() { _ = svc.Close() }()
+
+	result, err := svc.Store(raw, project)
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	item, err := svc.GetItem(result["id"].(string))
+	if err != nil || item == nil {
+		t.Fatalf("GetItem() = %v, %v", item, err)
+	}
+
+	if item.Title != "Switched to JWT auth" || item.What != "Replaced session cookies with JWT" || item.Project != "api" {
+		t.Errorf("stored item = %+v", item)
+	}
+
+	if item.Why == nil || *item.Why != "Stateless API" {
+		t.Errorf("Why = %v, want Stateless API", item.Why)
+	}
+
+	if item.Category == nil || *item.Category != "decision" {
+		t.Errorf("Category = %v, want decision", item.Category)
+	}
+
+	if !slices.Equal(item.Tags, []string{"auth", "jwt"}) || !slices.Equal(item.RelatedFiles, []string{"api/auth.go"}) {
+		t.Errorf("Tags = %v, RelatedFiles = %v", item.Tags, item.RelatedFiles)
+	}
+
+	detail, err := svc.GetDetails(item.ID)
+	if err != nil || detail == nil || detail.Body != "Tokens expire after 15 minutes." {
+		t.Errorf("GetDetails() = %+v, %v", detail, err)
+	}
+}
+
+func TestReadStdinNote_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		field string
+	}{
+		{"malformed", `{"title": "x",`, ""},
+		{"unknown field", `{"title": "x", "what": "y", "summary": "z"}`, ""},
+		{"two objects", `{"title": "x", "what": "y"} {}`, ""},
+		{"missing what", `{"title": "x"}`, "what"},
+		{"bad category", `{"title": "x", "what": "y", "category": "rumor"}`, "category"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := readStdinNote(strings.NewReader(tt.input))
+			if err == nil {
+				t.Fatal("readStdinNote() should fail")
+			}
+
+			var verr *core.ValidationError
+			if tt.field != "" && (!errors.As(err, &verr) || verr.Field != tt.field) {
+				t.Errorf("error = %v, want a validation error on %s", err, tt.field)
+			}
+		})
+	}
+}
+
+func TestFormatRedactions(t *testing.T) {
+	if got := formatRedactions(nil); got != "Redactions: none" {
+		t.Errorf("formatRedactions(nil) = %q", got)
+	}
+
+	got := formatRedactions(map[string]int{"details": 2, "what": 1})
+	if want := "Redactions: 3 (what: 1, details: 2)"; got != want {
+		t.Errorf("formatRedactions() = %q, want %q", got, want)
+	}
+}
+
+func TestParseMeta(t *testing.T) {
+	got, err := parseMeta([]string{"model=opus", "task=T-1=a"})
+	if err != nil {
+		t.Fatalf("parseMeta() error = %v", err)
+	}
+
+	if got["model"] != "opus" || got["task"] != "T-1=a" {
+		t.Errorf("parseMeta() = %v", got)
+	}
+
+	for _, bad := range []string{"novalue", "bad key=x", "=x"} {
+		if _, err := parseMeta([]string{bad}); err == nil {
+			t.Errorf("parseMeta(%q) error = nil", bad)
+		}
+	}
+}
+
+func TestStore_WarnsOnNearDuplicateTitle(t *testing.T) {
+	svc, err := core.NewService(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	defer func() { _ = svc.Close() }()
+
+	first, err := svc.Store(models.RawItemInput{Title: "Fix auth token refresh", What: "Refresh tokens expired early because of clock skew"}, "api")
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	second, err := svc.Store(models.RawItemInput{Title: "Fixed auth token refresh", What: "Refresh tokens expired early because of clock skew"}, "api")
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	if second["action"] != "created" {
+		t.Fatalf("second store action = %v, want created", second["action"])
+	}
+
+	var buf bytes.Buffer
+
+	printDuplicateWarning(&buf, second)
+
+	firstID, _ := first["id"].(string)
+	if out := buf.String(); !strings.Contains(out, `duplicate of "Fix auth token refresh" (id: `+firstID+")") {
+		t.Errorf("warning = %q, want it to name the existing note", out)
+	}
+
+	// An unrelated title gets no warning.
+	third, err := svc.Store(models.RawItemInput{Title: "Clock skew on CI runners", What: "Refresh tokens expired early because of clock skew"}, "api")
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	buf.Reset()
+	printDuplicateWarning(&buf, third)
+
+	if buf.Len() != 0 {
+		t.Errorf("unexpected warning for distinct title: %q", buf.String())
+	}
+}
+
+func TestStore_DefaultSource(t *testing.T) {
+	home := t.TempDir()
+	if err := os.WriteFile(filepath.Join(home, "config.yaml"), []byte("defaults:\n  cli_source: human\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	svc, err := core.NewService(home)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	defer func() { _ = svc.Close() }()
+
+	raw := models.RawItemInput{Title: "Pin Go toolchain", What: "CI uses the go.mod toolchain"}
+	setDefaultSource(&raw, svc.DefaultSource(core.OriginCLI))
+
+	if _, err := svc.Store(raw, "api"); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	source := "human"
+
+	results, err := svc.Search("toolchain", 5, nil, &source, false)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	if len(results) != 1 {
+		t.Fatalf("Search(source human) = %d results, want 1", len(results))
+	}
+
+	// An explicit --source is kept.
+	explicit := "ci-bot"
+	raw = models.RawItemInput{Title: "T", What: "W", Source: &explicit}
+	setDefaultSource(&raw, "human")
+
+	if *raw.Source != "ci-bot" {
+		t.Errorf("Source = %q, want ci-bot", *raw.Source)
+	}
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"pantry/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+var tagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "Rename or delete tags across all notes",
+}
+
+var tagsRenameCmd = &cobra.Command{
+	Use:   "rename <old> <new>",
+	Short: "Rename a tag on every note that has it",
+	Long: `Rename a tag on every note that has it, in the database and in the tags
+frontmatter of the notes files. Tags are matched case-insensitively; a note
+that already has the new tag keeps a single copy.`,
+	Args: cobra.ExactArgs(2),
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		runTagsChange(func(svc *core.Service) (int, error) {
+			return svc.RenameTag(args[0], args[1])
+		}, fmt.Sprintf("Renamed tag %q to %q", args[0], args[1]))
+	},
+}
+
+var tagsDeleteCmd = &cobra.Command{
+	Use:   "delete <tag>",
+	Short: "Remove a tag from every note that has it",
+	Args:  cobra.ExactArgs(1),
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		runTagsChange(func(svc *core.Service) (int, error) {
+			return svc.DeleteTag(args[0])
+		}, fmt.Sprintf("Deleted tag %q", args[0]))
+	},
+}
+
+// runTagsChange runs change and reports how many notes it touched.
+func runTagsChange(change func(*core.Service) (int, error), done string) {
+	svc, err := core.NewService("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	defer func() { _ = svc.Close() }()
+
+	n, err := change(svc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if n == 0 {
+		fmt.Println("No notes have that tag.")
+
+		return
+	}
+
+	fmt.Printf("%s on %d note(s)\n", done, n)
+}
+
+func init() {
+	tagsCmd.AddCommand(tagsRenameCmd)
+	tagsCmd.AddCommand(tagsDeleteCmd)
+}
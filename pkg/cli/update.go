@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"pantry/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	updateWhat           string
+	updateWhy            string
+	updateImpact         string
+	updateTags           string
+	updateDetails        string
+	updateReplaceDetails bool
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update [id]",
+	Short: "Update fields of an existing note",
+	Args:  cobra.ExactArgs(1),
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		itemID := args[0]
+
+		// Only flags that were given are changed, so an explicit "" clears a field.
+		optional := func(name string, value *string) *string {
+			if cmd.Flags().Changed(name) {
+				return value
+			}
+
+			return nil
+		}
+
+		var tags []string
+
+		if cmd.Flags().Changed("tags") {
+			tags = []string{}
+
+			for _, tag := range strings.Split(updateTags, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					tags = append(tags, tag)
+				}
+			}
+		}
+
+		if updateReplaceDetails && !cmd.Flags().Changed("details") {
+			fmt.Fprintf(os.Stderr, "Error: --replace-details needs --details\n")
+			os.Exit(1)
+		}
+
+		svc, err := core.NewService("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer func() { _ = svc.Close() }()
+
+		err = svc.Update(itemID,
+			optional("what", &updateWhat),
+			optional("why", &updateWhy),
+			optional("impact", &updateImpact),
+			tags,
+			optional("details", &updateDetails),
+			updateReplaceDetails)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Updated note %s\n", itemID)
+	},
+}
+
+func init() {
+	updateCmd.Flags().StringVarP(&updateWhat, "what", "w", "", "New what text")
+	updateCmd.Flags().StringVarP(&updateWhy, "why", "y", "", "New why text")
+	updateCmd.Flags().StringVarP(&updateImpact, "impact", "i", "", "New impact text")
+	updateCmd.Flags().StringVarP(&updateTags, "tags", "g", "", "Comma-separated tags, replacing the current ones")
+	updateCmd.Flags().StringVarP(&updateDetails, "details", "d", "", "Details to append to the note")
+	updateCmd.Flags().BoolVar(&updateReplaceDetails, "replace-details", false, "Replace the details body with --details instead of appending")
+}
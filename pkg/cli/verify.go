@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"pantry/internal/core"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyVectors bool
+	verifyFix     bool
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the index for inconsistencies",
+	Long: `Check the index for inconsistencies and optionally repair them.
+
+--vectors looks for rows in the vector table whose note no longer exists.
+These orphans waste space and can surface in semantic search. With no check
+flag, all checks run. Exits with status 1 if problems remain.`,
+	//nolint:revive
+	Run: func(cmd *cobra.Command, args []string) {
+		// With no check selected, run them all.
+		allChecks := !cmd.Flags().Changed("vectors")
+
+		if !verifyVectors && !allChecks {
+			fmt.Println("No checks selected.")
+
+			return
+		}
+
+		svc, err := core.NewService("")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer func() { _ = svc.Close() }()
+
+		orphans, removed, err := svc.VerifyVectors(verifyFix)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		switch {
+		case len(orphans) == 0:
+			fmt.Println("vectors: ok")
+		case verifyFix:
+			fmt.Printf("vectors: removed %d orphaned vector(s)\n", removed)
+		default:
+			fmt.Printf("vectors: %d orphaned vector(s) (rowids %v); run with --fix to remove\n", len(orphans), orphans)
+			_ = svc.Close()
+
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	verifyCmd.Flags().BoolVar(&verifyVectors, "vectors", false, "Check for vectors whose note no longer exists")
+	verifyCmd.Flags().BoolVar(&verifyFix, "fix", false, "Repair the problems found")
+}